@@ -66,3 +66,25 @@ func (sig BdnSignature) VerifyWithPolicy(suite pairing.Suite, msg []byte, pubkey
 
 	return nil
 }
+
+// VerifyWithWeights checks that the signature is correct and that the
+// signers hold at least the weight given by protocol.WeightedThreshold. If
+// no weights are given, it is the same as Verify.
+func (sig BdnSignature) VerifyWithWeights(suite pairing.Suite, msg []byte, pubkeys []kyber.Point, weights []uint64) error {
+	if len(weights) == 0 {
+		return sig.Verify(suite, msg, pubkeys)
+	}
+	if len(weights) != len(pubkeys) {
+		return fmt.Errorf("got %d weights for %d public keys", len(weights), len(pubkeys))
+	}
+
+	err := sig.VerifyWithPolicy(suite, msg, pubkeys, sign.NewThresholdPolicy(1))
+	if err != nil {
+		return err
+	}
+	mask, err := sig.GetMask(suite, pubkeys)
+	if err != nil {
+		return err
+	}
+	return protocol.CheckWeights(mask, weights)
+}
@@ -54,7 +54,11 @@ type BlsCosi struct {
 	Timeout           time.Duration
 	SubleaderFailures int
 	Threshold         int
-	FinalSignature    chan []byte // final signature that is sent back to client
+	// Weights is the optional voting weight of every node of the roster.
+	// If it is set, the signatures are collected until the signers hold
+	// the weight given by WeightedThreshold, instead of Threshold nodes.
+	Weights        []uint64
+	FinalSignature chan []byte // final signature that is sent back to client
 
 	stoppedOnce     sync.Once
 	subProtocols    []*SubBlsCosi
@@ -244,16 +248,61 @@ func (p *BlsCosi) checkIntegrity() error {
 	if p.Threshold < 1 {
 		return fmt.Errorf("threshold of %d smaller than one node", p.Threshold)
 	}
+	if len(p.Weights) > 0 {
+		if len(p.Weights) != len(p.Roster().List) {
+			return fmt.Errorf("got %d weights for %d nodes", len(p.Weights), len(p.Roster().List))
+		}
+		if WeightedThreshold(p.Weights) == 0 {
+			return errors.New("the total weight must be positive")
+		}
+	}
 
 	return nil
 }
 
+// checkThreshold returns true when enough signatures have been collected,
+// using the weights if they are set.
+func (p *BlsCosi) checkThreshold(numSignature int, weight uint64) bool {
+	if len(p.Weights) > 0 {
+		return weight >= WeightedThreshold(p.Weights)
+	}
+	return numSignature >= p.Threshold-1
+}
+
 // checkFailureThreshold returns true when the number of failures
-// is above the threshold
-func (p *BlsCosi) checkFailureThreshold(numFailure int) bool {
+// is above the threshold, using the weights if they are set.
+func (p *BlsCosi) checkFailureThreshold(numFailure int, failedWeight uint64) bool {
+	if len(p.Weights) > 0 {
+		var total uint64
+		for _, w := range p.Weights {
+			total += w
+		}
+		return failedWeight > total-WeightedThreshold(p.Weights)
+	}
 	return numFailure > len(p.Roster().List)-p.Threshold
 }
 
+// subtreeWeights returns the weight of the nodes that signed and of the
+// nodes that failed in the subtree of the given subleader.
+func (p *BlsCosi) subtreeWeights(subleader int, mask *sign.Mask) (signed, failed uint64) {
+	for _, tree := range p.subTrees {
+		if len(tree.Root.Children) == 0 || tree.Root.Children[0].RosterIndex != subleader {
+			continue
+		}
+		for _, tn := range tree.List() {
+			if tn.IsRoot() {
+				continue
+			}
+			if enabled, err := mask.IndexEnabled(tn.RosterIndex); err == nil && enabled {
+				signed += p.Weights[tn.RosterIndex]
+			} else {
+				failed += p.Weights[tn.RosterIndex]
+			}
+		}
+	}
+	return
+}
+
 // startSubProtocol creates, parametrize and starts a subprotocol on a given tree
 // and returns the started protocol.
 func (p *BlsCosi) startSubProtocol(tree *onet.Tree) (*SubBlsCosi, error) {
@@ -347,8 +396,15 @@ func (p *BlsCosi) collectSignatures() (ResponseMap, error) {
 	responseMap := make(ResponseMap)
 	numSignature := 0
 	numFailure := 0
+	// the weights only count if they are set, starting with the root
+	var weight, failedWeight uint64
+	if len(p.Weights) > 0 {
+		_, index := searchPublicKey(p.TreeNodeInstance, p.ServerIdentity())
+		weight = p.Weights[index]
+	}
 	timeout := time.After(p.Timeout)
-	for len(p.subProtocols) > 0 && numSignature < p.Threshold-1 && !p.checkFailureThreshold(numFailure) {
+	for len(p.subProtocols) > 0 && !p.checkThreshold(numSignature, weight) &&
+		!p.checkFailureThreshold(numFailure, failedWeight) {
 		select {
 		case res := <-responsesChan:
 			publics := p.Publics()
@@ -367,6 +423,11 @@ func (p *BlsCosi) collectSignatures() (ResponseMap, error) {
 					count := mask.CountEnabled()
 					numSignature += count
 					numFailure += res.SubtreeCount() + 1 - count
+					if len(p.Weights) > 0 {
+						signed, failed := p.subtreeWeights(index, mask)
+						weight += signed
+						failedWeight += failed
+					}
 
 					responseMap[index] = &res.Response
 				}
@@ -383,7 +444,7 @@ func (p *BlsCosi) collectSignatures() (ResponseMap, error) {
 		}
 	}
 
-	if p.checkFailureThreshold(numFailure) {
+	if p.checkFailureThreshold(numFailure, failedWeight) {
 		return nil, fmt.Errorf("too many refusals (got %d), the threshold of %d cannot be achieved",
 			numFailure, p.Threshold)
 	}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/stretchr/testify/require"
 	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/kyber/v3"
 	"go.dedis.ch/kyber/v3/pairing"
 	"go.dedis.ch/kyber/v3/sign"
 	"go.dedis.ch/onet/v3"
@@ -420,6 +421,37 @@ func getAndVerifySignature(proto *BlsCosi, proposal []byte, policy sign.Policy)
 	return signature, signature.VerifyWithPolicy(testSuite, proto.Msg, publics, policy)
 }
 
+func TestWeightedThreshold(t *testing.T) {
+	require.Equal(t, uint64(0), WeightedThreshold(nil))
+	require.Equal(t, uint64(1), WeightedThreshold([]uint64{1}))
+	require.Equal(t, uint64(3), WeightedThreshold([]uint64{1, 1, 1, 1}))
+	require.Equal(t, uint64(7), WeightedThreshold([]uint64{5, 3, 1, 1}))
+}
+
+func TestCheckWeights(t *testing.T) {
+	publics := make([]kyber.Point, 4)
+	for i := range publics {
+		publics[i] = testSuite.G2().Point().Mul(testSuite.G2().Scalar().SetInt64(int64(i+1)), nil)
+	}
+	weights := []uint64{5, 3, 1, 1}
+
+	mask, err := sign.NewMask(testSuite, publics, nil)
+	require.NoError(t, err)
+	require.NoError(t, mask.SetBit(1, true))
+	require.NoError(t, mask.SetBit(2, true))
+	require.NoError(t, mask.SetBit(3, true))
+	// three nodes out of four, but not enough weight
+	require.Error(t, CheckWeights(mask, weights))
+
+	require.NoError(t, mask.SetBit(0, true))
+	require.NoError(t, mask.SetBit(2, false))
+	require.NoError(t, mask.SetBit(3, false))
+	// two nodes out of four holding enough weight
+	require.NoError(t, CheckWeights(mask, weights))
+
+	require.Error(t, CheckWeights(mask, []uint64{0, 0, 0, 0}))
+}
+
 // Starts a new service. No function needed.
 func newService(c *onet.Context) (onet.Service, error) {
 	s := &testService{
@@ -115,6 +115,68 @@ func (sig BlsSignature) VerifyWithPolicy(ps pairing.Suite, msg []byte, publics [
 	return nil
 }
 
+// VerifyWithWeights checks the signature over the message using the public
+// keys, and that the signers hold at least the weight given by
+// WeightedThreshold. There must be one weight per public key. If no weights
+// are given, it is the same as Verify.
+func (sig BlsSignature) VerifyWithWeights(ps pairing.Suite, msg []byte, publics []kyber.Point, weights []uint64) error {
+	if len(weights) == 0 {
+		return sig.Verify(ps, msg, publics)
+	}
+	if len(weights) != len(publics) {
+		return fmt.Errorf("got %d weights for %d public keys", len(weights), len(publics))
+	}
+
+	err := sig.VerifyWithPolicy(ps, msg, publics, sign.NewThresholdPolicy(1))
+	if err != nil {
+		return err
+	}
+	mask, err := sig.GetMask(ps, publics)
+	if err != nil {
+		return err
+	}
+	return CheckWeights(mask, weights)
+}
+
+// WeightedThreshold computes the minimal weight the signers must hold, given
+// the voting weight of every node. It is the weighted version of
+// DefaultThreshold: with a total weight of 3f+1, the signers must hold at
+// least 2f+1.
+func WeightedThreshold(weights []uint64) uint64 {
+	var total uint64
+	for _, w := range weights {
+		total += w
+	}
+	if total == 0 {
+		return 0
+	}
+	return total - (total-1)/3
+}
+
+// MaskWeight returns the sum of the weights of the participants of the mask.
+func MaskWeight(mask *sign.Mask, weights []uint64) uint64 {
+	var weight uint64
+	for i, w := range weights {
+		if enabled, err := mask.IndexEnabled(i); err == nil && enabled {
+			weight += w
+		}
+	}
+	return weight
+}
+
+// CheckWeights returns an error if the participants of the mask don't hold
+// the weight given by WeightedThreshold.
+func CheckWeights(mask *sign.Mask, weights []uint64) error {
+	threshold := WeightedThreshold(weights)
+	if threshold == 0 {
+		return errors.New("the total weight must be positive")
+	}
+	if weight := MaskWeight(mask, weights); weight < threshold {
+		return fmt.Errorf("the weighted threshold is not reached: got %d, need %d", weight, threshold)
+	}
+	return nil
+}
+
 // Announcement is the blscosi annoucement message.
 type Announcement struct {
 	Msg       []byte // statement to be signed
@@ -2,10 +2,12 @@ package byzcoin
 
 import (
 	"bytes"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"math"
 	"math/rand"
+	"strings"
 	"time"
 
 	"go.dedis.ch/cothority/v3"
@@ -272,6 +274,43 @@ func (c *Client) WaitProof(id InstanceID, interval time.Duration, value []byte)
 	return nil, errors.New("timeout reached and inclusion not found")
 }
 
+// ResolveName returns the target of a name registered with the naming
+// contract. The proof of the name instance is verified.
+func (c *Client) ResolveName(name string) (InstanceID, error) {
+	id := NamingInstanceID(name)
+	p, err := c.GetProof(id.Slice())
+	if err != nil {
+		return InstanceID{}, err
+	}
+	ok, err := p.Proof.InclusionProof.Exists(id.Slice())
+	if err != nil {
+		return InstanceID{}, err
+	}
+	if !ok {
+		return InstanceID{}, fmt.Errorf("name %s is not registered", name)
+	}
+	var entry NameEntry
+	err = p.Proof.VerifyAndDecode(cothority.Suite, ContractNamingID, &entry)
+	if err != nil {
+		return InstanceID{}, err
+	}
+	if len(entry.Target) == 0 {
+		return InstanceID{}, fmt.Errorf("name %s has no target", name)
+	}
+	return NewInstanceID(entry.Target), nil
+}
+
+// ResolveInstanceID returns the instance ID given as a hex string, or the
+// target of the name given instead. It is meant for the command line tools,
+// so that the users can use names wherever an instance ID is expected.
+func (c *Client) ResolveInstanceID(s string) (InstanceID, error) {
+	if isInstanceID(s) {
+		buf, _ := hex.DecodeString(s)
+		return NewInstanceID(buf), nil
+	}
+	return c.ResolveName(s)
+}
+
 // StreamTransactions sends a streaming request to the service. If successful,
 // the handler will be called whenever a new response (a new block) is
 // available. This function blocks, the streaming stops if the client or the
@@ -321,6 +360,152 @@ func (c *Client) GetSignerCounters(ids ...string) (*GetSignerCountersResponse, e
 	return &reply, nil
 }
 
+// GetInstanceVersion looks up the state change of the given instance at the
+// given version.
+func (c *Client) GetInstanceVersion(id InstanceID, version uint64) (*GetInstanceVersionResponse, error) {
+	req := &GetInstanceVersion{
+		SkipChainID: c.ID,
+		InstanceID:  id,
+		Version:     version,
+	}
+	reply := &GetInstanceVersionResponse{}
+	err := c.SendProtobuf(c.getServer(), req, reply)
+	if err != nil {
+		return nil, err
+	}
+	return reply, nil
+}
+
+// GetLastInstanceVersion looks up the latest state change of the given
+// instance.
+func (c *Client) GetLastInstanceVersion(id InstanceID) (*GetInstanceVersionResponse, error) {
+	req := &GetLastInstanceVersion{
+		SkipChainID: c.ID,
+		InstanceID:  id,
+	}
+	reply := &GetInstanceVersionResponse{}
+	err := c.SendProtobuf(c.getServer(), req, reply)
+	if err != nil {
+		return nil, err
+	}
+	return reply, nil
+}
+
+// GetAllInstanceVersion returns all the state changes of the given instance,
+// ordered by version.
+func (c *Client) GetAllInstanceVersion(id InstanceID) (*GetAllInstanceVersionResponse, error) {
+	req := &GetAllInstanceVersion{
+		SkipChainID: c.ID,
+		InstanceID:  id,
+	}
+	reply := &GetAllInstanceVersionResponse{}
+	err := c.SendProtobuf(c.getServer(), req, reply)
+	if err != nil {
+		return nil, err
+	}
+	return reply, nil
+}
+
+// GetEquivocations returns the evidence of the equivocations found by the
+// node on the chain. Each one can be sent with
+// NewRemoveEquivocatorInstruction to remove an offender from the roster.
+func (c *Client) GetEquivocations() (*GetEquivocationsResponse, error) {
+	req := &GetEquivocations{
+		Version:     CurrentVersion,
+		SkipChainID: c.ID,
+	}
+	reply := &GetEquivocationsResponse{}
+	err := c.SendProtobuf(c.getServer(), req, reply)
+	if err != nil {
+		return nil, err
+	}
+	return reply, nil
+}
+
+// GetDeferred returns the deferred instances that can still be executed. If
+// darcID is not nil, only the instances controlled by this darc or proposing
+// an instruction on an instance controlled by this darc are returned. If
+// identity is not empty, only the instances still missing a proof of this
+// identity are returned.
+func (c *Client) GetDeferred(darcID darc.ID, identity string) (*GetDeferredResponse, error) {
+	req := &GetDeferred{
+		Version:     CurrentVersion,
+		SkipChainID: c.ID,
+		DarcID:      darcID,
+		Identity:    identity,
+	}
+	reply := &GetDeferredResponse{}
+	err := c.SendProtobuf(c.getServer(), req, reply)
+	if err != nil {
+		return nil, err
+	}
+	return reply, nil
+}
+
+// GetDeferredMissing returns, for every instruction of the proposed
+// transaction of the deferred instance, the identities of the darc rules that
+// did not add a proof yet. The list of an instruction is empty once its
+// proofs are enough. The instance and the darcs are read with verified
+// proofs.
+func (c *Client) GetDeferredMissing(id InstanceID) ([][]string, error) {
+	p, err := c.GetProof(id.Slice())
+	if err != nil {
+		return nil, err
+	}
+	if !p.Proof.InclusionProof.Match(id.Slice()) {
+		return nil, errors.New("deferred instance not found")
+	}
+	var dd DeferredData
+	err = p.Proof.VerifyAndDecode(cothority.Suite, ContractDeferredID, &dd)
+	if err != nil {
+		return nil, err
+	}
+
+	getDarcByID := func(darcID darc.ID) (*darc.Darc, error) {
+		p, err := c.GetProof(darcID)
+		if err != nil {
+			return nil, err
+		}
+		if !p.Proof.InclusionProof.Match(darcID) {
+			return nil, fmt.Errorf("darc %x not found", darcID)
+		}
+		_, buf, _, _, err := p.Proof.KeyValue()
+		if err != nil {
+			return nil, err
+		}
+		return darc.NewFromProtobuf(buf)
+	}
+	instanceDarc := func(iid InstanceID) (*darc.Darc, error) {
+		p, err := c.GetProof(iid.Slice())
+		if err != nil {
+			return nil, err
+		}
+		if !p.Proof.InclusionProof.Match(iid.Slice()) {
+			return nil, fmt.Errorf("instance %x not found", iid.Slice())
+		}
+		_, _, _, darcID, err := p.Proof.KeyValue()
+		if err != nil {
+			return nil, err
+		}
+		return getDarcByID(darcID)
+	}
+	getDarc := func(s string, latest bool) *darc.Darc {
+		if !strings.HasPrefix(s, "darc:") {
+			return nil
+		}
+		darcID, err := hex.DecodeString(s[5:])
+		if err != nil {
+			return nil
+		}
+		d, err := getDarcByID(darcID)
+		if err != nil {
+			return nil
+		}
+		return d
+	}
+	return dd.MissingProofs(instanceDarc, getDarc)
+}
+
 // DownloadState is used by a new node to ask to download the global state.
 // The first call to DownloadState needs to have start = 0, so that the
 // service creates a snapshot of the current state which it will serve over
@@ -422,6 +607,15 @@ func DefaultGenesisMsg(v Version, r *onet.Roster, rules []string, ids ...darc.Id
 	if err := rs.AddRule("_sign", ownerExpr); err != nil {
 		return nil, err
 	}
+	if err := rs.AddRule("invoke:"+ContractConfigID+".pause", ownerExpr); err != nil {
+		return nil, err
+	}
+	if err := rs.AddRule("invoke:"+ContractConfigID+".resume", ownerExpr); err != nil {
+		return nil, err
+	}
+	if err := rs.AddRule("invoke:"+ContractConfigID+".remove_equivocator", ownerExpr); err != nil {
+		return nil, err
+	}
 	d := darc.NewDarc(rs, []byte("genesis darc"))
 
 	// extra rules
@@ -290,8 +290,11 @@ func newTestService(c *onet.Context) (onet.Service, error) {
 		stateChangeStorage:     newStateChangeStorage(c),
 		heartbeatsTimeout:      make(chan string, 1),
 		closeLeaderMonitorChan: make(chan bool, 1),
+		closeObserverChan:      make(chan bool, 1),
+		closeForkMonitorChan:   make(chan bool, 1),
 		heartbeats:             newHeartbeats(),
 		viewChangeMan:          newViewChangeManager(),
+		txInclusion:            newTxInclusion(),
 		streamingMan:           streamingManager{},
 		closed:                 true,
 	}
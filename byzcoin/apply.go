@@ -0,0 +1,184 @@
+package byzcoin
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.dedis.ch/onet/v3/log"
+)
+
+// StagingStateTrie is a ReadOnlyStateTrie where the state changes of a
+// transaction can be staged. It is implemented by the staging trie of the
+// service, and by the in-memory ledger of contracttest.
+type StagingStateTrie interface {
+	ReadOnlyStateTrie
+	StoreAll(StateChanges) error
+}
+
+// ApplyTransaction verifies and runs the instructions of the transaction the
+// way the leader does when it creates a block. The state changes of every
+// instruction and the new signer counters are stored in st, which must be
+// thrown away if an error is returned. The contracts are created with
+// getContract. It returns all the state changes and the coins left by the
+// last instruction.
+func ApplyTransaction(st StagingStateTrie, tx ClientTransaction,
+	getContract func(string) (ContractFn, bool)) (StateChanges, []Coin, error) {
+	return applyTransaction(st, tx, getContract, nil)
+}
+
+// applyTransaction is ApplyTransaction with observe, if not nil, being called
+// with the time taken by each contract.
+func applyTransaction(st StagingStateTrie, tx ClientTransaction, getContract func(string) (ContractFn, bool),
+	observe func(string, time.Duration)) (StateChanges, []Coin, error) {
+	if err := checkPaused(st, tx); err != nil {
+		return nil, nil, err
+	}
+	h := tx.Instructions.Hash()
+	var statesTemp StateChanges
+	var cin []Coin
+	for _, instr := range tx.Instructions {
+		scs, cout, err := executeInstruction(st, cin, instr, h, getContract, observe)
+		if err != nil {
+			_, _, cid, _, err2 := st.GetValues(instr.InstanceID.Slice())
+			if err2 != nil {
+				err = fmt.Errorf("%s - while getting value: %s", err, err2)
+			}
+			return nil, nil, fmt.Errorf("contract %s got instruction %s and returned error: %s", cid, instr, err)
+		}
+		var counterScs StateChanges
+		if counterScs, err = incrementSignerCounters(st, instr.SignerIdentities); err != nil {
+			return nil, nil, fmt.Errorf("failed to update signature counters: %s", err)
+		}
+
+		// Verify the validity of the state-changes:
+		//  - refuse to update non-existing instances
+		//  - refuse to create existing instances
+		//  - refuse to delete non-existing instances
+		for _, sc := range scs {
+			var reason string
+			_, _, _, _, err := st.GetValues(sc.InstanceID)
+			switch sc.StateAction {
+			case Create:
+				if err != ErrKeyNotSet {
+					reason = "tried to create existing instanceID"
+				}
+			case Update:
+				if err != nil {
+					reason = "tried to update non-existing instanceID"
+				}
+			case Remove:
+				if err != nil {
+					reason = "tried to remove non-existing instanceID"
+				}
+			}
+			if reason != "" {
+				_, _, contractID, _, err := st.GetValues(instr.InstanceID.Slice())
+				if err != nil {
+					return nil, nil, fmt.Errorf("couldn't get contractID from instruction %+v", instr)
+				}
+				return nil, nil, fmt.Errorf("contract %s %s", contractID, reason)
+			}
+			log.Lvlf2("StateChange %s for id %x - contract: %s", sc.StateAction, sc.InstanceID, sc.ContractID)
+			err = st.StoreAll(StateChanges{sc})
+			if err != nil {
+				return nil, nil, fmt.Errorf("StoreAll failed: %s", err)
+			}
+		}
+		if err = st.StoreAll(counterScs); err != nil {
+			return nil, nil, fmt.Errorf("StoreAll failed to add counter changes: %s", err)
+		}
+		statesTemp = append(statesTemp, scs...)
+		statesTemp = append(statesTemp, counterScs...)
+		cin = cout
+	}
+	return statesTemp, cin, nil
+}
+
+// executeInstruction verifies and runs one instruction, and sets the versions
+// of its state changes.
+func executeInstruction(st ReadOnlyStateTrie, cin []Coin, instr Instruction, ctxHash []byte,
+	getContract func(string) (ContractFn, bool), observe func(string, time.Duration)) (scs StateChanges, cout []Coin, err error) {
+	defer func() {
+		if re := recover(); re != nil {
+			err = fmt.Errorf("%s", re)
+		}
+	}()
+
+	contents, _, contractID, _, err := st.GetValues(instr.InstanceID.Slice())
+	if err != ErrKeyNotSet && err != nil {
+		err = errors.New("Couldn't get contract type of instruction: " + err.Error())
+		return
+	}
+
+	contractFactory, exists := getContract(contractID)
+	if !exists && ConfigInstanceID.Equal(instr.InstanceID) {
+		// Special case: first time call to genesis-configuration must return
+		// correct contract type.
+		contractFactory, exists = getContract(ContractConfigID)
+	}
+
+	// If the leader does not have a verifier for this contract, it drops the
+	// transaction.
+	if !exists {
+		err = fmt.Errorf("leader is dropping instruction of unknown contract \"%s\" on instance \"%x\"", contractID, instr.InstanceID.Slice())
+		return
+	}
+	// Now we call the contract function with the data of the key.
+	log.Lvlf3("Calling contract '%s'", contractID)
+
+	c, err := contractFactory(contents)
+	if err != nil {
+		return nil, nil, err
+	}
+	if c == nil {
+		return nil, nil, errors.New("contract factory returned nil contract instance")
+	}
+
+	err = c.VerifyInstruction(st, instr, ctxHash)
+	if err != nil {
+		return nil, nil, fmt.Errorf("instruction verification failed: %v", err)
+	}
+
+	start := time.Now()
+	switch instr.GetType() {
+	case SpawnType:
+		scs, cout, err = c.Spawn(st, instr, cin)
+	case InvokeType:
+		scs, cout, err = c.Invoke(st, instr, cin)
+	case DeleteType:
+		scs, cout, err = c.Delete(st, instr, cin)
+	default:
+		return nil, nil, errors.New("unexpected contract type")
+	}
+	if observe != nil {
+		observe(contractID, time.Since(start))
+	}
+
+	// As the InstanceID of each sc is not necessarily the same as the
+	// instruction, we need to get the version from the trie
+	vv := make(map[string]uint64)
+	for i, sc := range scs {
+		ver, ok := vv[hex.EncodeToString(sc.InstanceID)]
+		if !ok {
+			_, ver, _, _, err = st.GetValues(sc.InstanceID)
+		}
+
+		// this is done at this scope because we must increase
+		// the version only when it's not the first one
+		if err == ErrKeyNotSet {
+			ver = 0
+			err = nil
+		} else if err != nil {
+			return
+		} else {
+			ver++
+		}
+
+		scs[i].Version = ver
+		vv[hex.EncodeToString(sc.InstanceID)] = ver
+	}
+
+	return
+}
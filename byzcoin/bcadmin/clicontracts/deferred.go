@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"time"
 
 	"go.dedis.ch/cothority/v3/byzcoin"
 	"go.dedis.ch/cothority/v3/byzcoin/bcadmin/lib"
@@ -84,6 +85,14 @@ func DeferredSpawn(c *cli.Context) error {
 			},
 		},
 	}
+	if expire := c.Duration("expire"); expire > 0 {
+		expireBuf := make([]byte, 8)
+		binary.LittleEndian.PutUint64(expireBuf, uint64(time.Now().Add(expire).UnixNano()))
+		spawn.Args = append(spawn.Args, byzcoin.Argument{
+			Name:  "expireTimestamp",
+			Value: expireBuf,
+		})
+	}
 
 	ctx := byzcoin.ClientTransaction{
 		Instructions: []byzcoin.Instruction{
@@ -185,9 +194,9 @@ func DeferredInvokeAddProof(c *cli.Context) error {
 	if instID == "" {
 		return errors.New("--instID flag is required")
 	}
-	instIDBuf, err := hex.DecodeString(instID)
+	instIDBuf, err := lib.StringToInstanceID(cl, instID)
 	if err != nil {
-		return errors.New("failed to decode the instID string: " + err.Error())
+		return err
 	}
 
 	instrIdx := c.Uint("instrIdx")
@@ -274,6 +283,89 @@ func DeferredInvokeAddProof(c *cli.Context) error {
 	return nil
 }
 
+// DeferredInvokeRemoveProof is used to withdraw the proof of an instruction
+// added by the identity provided by --sign or, by default, the admin.
+func DeferredInvokeRemoveProof(c *cli.Context) error {
+	bcArg := c.String("bc")
+	if bcArg == "" {
+		return errors.New("--bc flag is required")
+	}
+
+	cfg, cl, err := lib.LoadConfig(bcArg)
+	if err != nil {
+		return err
+	}
+
+	var signer *darc.Signer
+
+	sstr := c.String("sign")
+	if sstr == "" {
+		signer, err = lib.LoadKey(cfg.AdminIdentity)
+	} else {
+		signer, err = lib.LoadKeyFromString(sstr)
+	}
+	if err != nil {
+		return err
+	}
+
+	instID := c.String("instID")
+	if instID == "" {
+		return errors.New("--instID flag is required")
+	}
+	instIDBuf, err := lib.StringToInstanceID(cl, instID)
+	if err != nil {
+		return err
+	}
+
+	indexBuf := make([]byte, 4)
+	binary.LittleEndian.PutUint32(indexBuf, uint32(c.Uint("instrIdx")))
+
+	identity := signer.Identity()
+	identityBuf, err := protobuf.Encode(&identity)
+	if err != nil {
+		return errors.New("coulndn't encode the identity: " + err.Error())
+	}
+
+	counters, err := cl.GetSignerCounters(signer.Identity().String())
+	if err != nil {
+		return err
+	}
+
+	ctx := byzcoin.ClientTransaction{
+		Instructions: []byzcoin.Instruction{{
+			InstanceID: byzcoin.NewInstanceID(instIDBuf),
+			Invoke: &byzcoin.Invoke{
+				ContractID: byzcoin.ContractDeferredID,
+				Command:    "removeProof",
+				Args: []byzcoin.Argument{
+					{
+						Name:  "identity",
+						Value: identityBuf,
+					},
+					{
+						Name:  "index",
+						Value: indexBuf,
+					},
+				},
+			},
+			SignerCounter: []uint64{counters.Counters[0] + 1},
+		}},
+	}
+
+	err = ctx.FillSignersAndSignWith(*signer)
+	if err != nil {
+		return err
+	}
+
+	_, err = cl.AddTransactionAndWait(ctx, 10)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(c.App.Writer, "Removed the proof of %s\n", identity)
+	return err
+}
+
 // ExecProposedTx is used to execute the proposed transaction if all the
 // instructions are correctly signed.
 func ExecProposedTx(c *cli.Context) error {
@@ -311,9 +403,9 @@ func ExecProposedTx(c *cli.Context) error {
 	if instID == "" {
 		return errors.New("--instID flag is required")
 	}
-	instIDBuf, err := hex.DecodeString(instID)
+	instIDBuf, err := lib.StringToInstanceID(cl, instID)
 	if err != nil {
-		return errors.New("failed to decode the instID string")
+		return err
 	}
 
 	// ---
@@ -384,9 +476,9 @@ func DeferredGet(c *cli.Context) error {
 	if instID == "" {
 		return errors.New("--instID flag is required")
 	}
-	instIDBuf, err := hex.DecodeString(instID)
+	instIDBuf, err := lib.StringToInstanceID(cl, instID)
 	if err != nil {
-		return errors.New("failed to decode the instID string")
+		return err
 	}
 
 	pr, err := cl.GetProof(instIDBuf)
@@ -421,9 +513,80 @@ func DeferredGet(c *cli.Context) error {
 
 	fmt.Fprintf(c.App.Writer, "%s\n", result)
 
+	missing, err := cl.GetDeferredMissing(byzcoin.NewInstanceID(instIDBuf))
+	if err != nil {
+		return errors.New("couldn't get the missing identities: " + err.Error())
+	}
+	printMissing(c, missing)
+
+	return nil
+}
+
+// DeferredList lists the deferred contracts that can still be executed,
+// optionally only the ones of a darc or waiting for an identity.
+func DeferredList(c *cli.Context) error {
+	bcArg := c.String("bc")
+	if bcArg == "" {
+		return errors.New("--bc flag is required")
+	}
+
+	_, cl, err := lib.LoadConfig(bcArg)
+	if err != nil {
+		return err
+	}
+
+	var darcID darc.ID
+	if dstr := c.String("darc"); dstr != "" {
+		d, err := lib.GetDarcByString(cl, dstr)
+		if err != nil {
+			return err
+		}
+		darcID = d.GetBaseID()
+	}
+
+	reply, err := cl.GetDeferred(darcID, c.String("identity"))
+	if err != nil {
+		return err
+	}
+	if len(reply.Deferred) == 0 {
+		_, err = fmt.Fprintln(c.App.Writer, "No pending deferred contract")
+		return err
+	}
+
+	for _, pending := range reply.Deferred {
+		result := byzcoin.DeferredData{}
+		err = protobuf.Decode(pending.Data, &result)
+		if err != nil {
+			return errors.New("couldn't decode the result: " + err.Error())
+		}
+		fmt.Fprintf(c.App.Writer, "Deferred contract %x (darc:%x):\n%s", pending.InstanceID.Slice(),
+			pending.DarcID, result)
+		missing := make([][]string, len(pending.Missing))
+		for i, m := range pending.Missing {
+			missing[i] = m.Identities
+		}
+		printMissing(c, missing)
+		fmt.Fprintln(c.App.Writer)
+	}
 	return nil
 }
 
+// printMissing prints the identities missing to sign every instruction of a
+// proposed transaction.
+func printMissing(c *cli.Context, missing [][]string) {
+	fmt.Fprint(c.App.Writer, "- Missing signers:\n")
+	for i, ids := range missing {
+		if len(ids) == 0 {
+			fmt.Fprintf(c.App.Writer, "-- instruction %d: none\n", i)
+			continue
+		}
+		fmt.Fprintf(c.App.Writer, "-- instruction %d:\n", i)
+		for _, id := range ids {
+			fmt.Fprintf(c.App.Writer, "--- %s\n", id)
+		}
+	}
+}
+
 // DeferredDelete delete the deferred instance
 func DeferredDelete(c *cli.Context) error {
 	bcArg := c.String("bc")
@@ -435,15 +598,15 @@ func DeferredDelete(c *cli.Context) error {
 	if instID == "" {
 		return errors.New("--instID flag is required")
 	}
-	instIDBuf, err := hex.DecodeString(instID)
-	if err != nil {
-		return errors.New("failed to decode the instID string")
-	}
 
 	cfg, cl, err := lib.LoadConfig(bcArg)
 	if err != nil {
 		return err
 	}
+	instIDBuf, err := lib.StringToInstanceID(cl, instID)
+	if err != nil {
+		return err
+	}
 
 	dstr := c.String("darc")
 	if dstr == "" {
@@ -0,0 +1,214 @@
+package clicontracts
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/byzcoin/bcadmin/lib"
+	"gopkg.in/urfave/cli.v1"
+)
+
+// NameSpawn registers a new name. A top-level name is spawned from --darc,
+// a name like "dedis/alice" is spawned from the name "dedis".
+func NameSpawn(c *cli.Context) error {
+	bcArg := c.String("bc")
+	if bcArg == "" {
+		return errors.New("--bc flag is required")
+	}
+
+	name := c.String("name")
+	if name == "" {
+		return errors.New("--name flag is required")
+	}
+
+	cfg, cl, err := lib.LoadConfig(bcArg)
+	if err != nil {
+		return err
+	}
+
+	var parent byzcoin.InstanceID
+	part := name
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		parent = byzcoin.NamingInstanceID(name[:i])
+		part = name[i+1:]
+	} else {
+		dstr := c.String("darc")
+		if dstr == "" {
+			dstr = cfg.AdminDarc.GetIdentityString()
+		}
+		d, err := lib.GetDarcByString(cl, dstr)
+		if err != nil {
+			return err
+		}
+		parent = byzcoin.NewInstanceID(d.GetBaseID())
+	}
+
+	args := byzcoin.Arguments{{Name: "name", Value: []byte(part)}}
+	if target := c.String("target"); target != "" {
+		targetBuf, err := lib.StringToInstanceID(cl, target)
+		if err != nil {
+			return err
+		}
+		args = append(args, byzcoin.Argument{Name: "target", Value: targetBuf})
+	}
+	if c.String("owner") != "" {
+		owner, err := nftDarc(c, cl, "owner")
+		if err != nil {
+			return err
+		}
+		args = append(args, byzcoin.Argument{Name: "darcID", Value: owner})
+	}
+
+	ctx := byzcoin.ClientTransaction{
+		Instructions: []byzcoin.Instruction{
+			{
+				InstanceID: parent,
+				Spawn: &byzcoin.Spawn{
+					ContractID: byzcoin.ContractNamingID,
+					Args:       args,
+				},
+			},
+		},
+	}
+	err = signAndSend(c, cfg, cl, &ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(c.App.Writer, "Registered name %s. Instance id is: \n%x\n", name,
+		byzcoin.NamingInstanceID(name).Slice())
+	return err
+}
+
+// NameInvokeSet changes the target of a name. Without the --target flag, the
+// target is removed.
+func NameInvokeSet(c *cli.Context) error {
+	bcArg := c.String("bc")
+	if bcArg == "" {
+		return errors.New("--bc flag is required")
+	}
+
+	name := c.String("name")
+	if name == "" {
+		return errors.New("--name flag is required")
+	}
+
+	cfg, cl, err := lib.LoadConfig(bcArg)
+	if err != nil {
+		return err
+	}
+
+	var targetBuf []byte
+	if target := c.String("target"); target != "" {
+		targetBuf, err = lib.StringToInstanceID(cl, target)
+		if err != nil {
+			return err
+		}
+	}
+
+	ctx := byzcoin.ClientTransaction{
+		Instructions: []byzcoin.Instruction{
+			{
+				InstanceID: byzcoin.NamingInstanceID(name),
+				Invoke: &byzcoin.Invoke{
+					ContractID: byzcoin.ContractNamingID,
+					Command:    "set",
+					Args:       byzcoin.Arguments{{Name: "target", Value: targetBuf}},
+				},
+			},
+		},
+	}
+	err = signAndSend(c, cfg, cl, &ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(targetBuf) == 0 {
+		_, err = fmt.Fprintf(c.App.Writer, "Removed the target of %s\n", name)
+	} else {
+		_, err = fmt.Fprintf(c.App.Writer, "%s points to %x\n", name, targetBuf)
+	}
+	return err
+}
+
+// NameGet checks the proof and prints a name.
+func NameGet(c *cli.Context) error {
+	bcArg := c.String("bc")
+	if bcArg == "" {
+		return errors.New("--bc flag is required")
+	}
+
+	name := c.String("name")
+	if name == "" {
+		return errors.New("--name flag is required")
+	}
+
+	_, cl, err := lib.LoadConfig(bcArg)
+	if err != nil {
+		return err
+	}
+
+	id := byzcoin.NamingInstanceID(name).Slice()
+	pr, err := cl.GetProof(id)
+	if err != nil {
+		return errors.New("couldn't get proof: " + err.Error())
+	}
+	if !pr.Proof.InclusionProof.Match(id) {
+		return fmt.Errorf("name %s is not registered", name)
+	}
+	var entry byzcoin.NameEntry
+	err = pr.Proof.VerifyAndDecode(cothority.Suite, byzcoin.ContractNamingID, &entry)
+	if err != nil {
+		return err
+	}
+	_, _, _, owner, err := pr.Proof.KeyValue()
+	if err != nil {
+		return err
+	}
+
+	w := c.App.Writer
+	fmt.Fprintf(w, "Name: %s\n", entry.Name)
+	if len(entry.Target) > 0 {
+		fmt.Fprintf(w, "Target: %x\n", entry.Target)
+	}
+	fmt.Fprintf(w, "Owner: darc:%x\n", owner)
+	fmt.Fprintf(w, "Names below: %d\n", entry.Children)
+	return nil
+}
+
+// NameDelete removes a name without names below it.
+func NameDelete(c *cli.Context) error {
+	bcArg := c.String("bc")
+	if bcArg == "" {
+		return errors.New("--bc flag is required")
+	}
+
+	name := c.String("name")
+	if name == "" {
+		return errors.New("--name flag is required")
+	}
+
+	cfg, cl, err := lib.LoadConfig(bcArg)
+	if err != nil {
+		return err
+	}
+
+	ctx := byzcoin.ClientTransaction{
+		Instructions: []byzcoin.Instruction{
+			{
+				InstanceID: byzcoin.NamingInstanceID(name),
+				Delete:     &byzcoin.Delete{ContractID: byzcoin.ContractNamingID},
+			},
+		},
+	}
+	err = signAndSend(c, cfg, cl, &ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(c.App.Writer, "Deleted name %s\n", name)
+	return err
+}
@@ -0,0 +1,448 @@
+package clicontracts
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/byzcoin/bcadmin/lib"
+	"go.dedis.ch/cothority/v3/byzcoin/contracts"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/protobuf"
+	"gopkg.in/urfave/cli.v1"
+)
+
+// NFTSpawn is used to spawn a new collection of non-fungible tokens.
+func NFTSpawn(c *cli.Context) error {
+	bcArg := c.String("bc")
+	if bcArg == "" {
+		return errors.New("--bc flag is required")
+	}
+
+	name := c.String("name")
+	if name == "" {
+		return errors.New("--name flag is required")
+	}
+
+	cfg, cl, err := lib.LoadConfig(bcArg)
+	if err != nil {
+		return err
+	}
+
+	dstr := c.String("darc")
+	if dstr == "" {
+		dstr = cfg.AdminDarc.GetIdentityString()
+	}
+	d, err := lib.GetDarcByString(cl, dstr)
+	if err != nil {
+		return err
+	}
+
+	args := byzcoin.Arguments{
+		{Name: "name", Value: []byte(name)},
+		{Name: "schema", Value: []byte(c.String("schema"))},
+	}
+	if coin := c.String("royalty_coin"); coin != "" {
+		coinBuf, err := lib.StringToInstanceID(cl, coin)
+		if err != nil {
+			return err
+		}
+		amountBuf := make([]byte, 8)
+		binary.LittleEndian.PutUint64(amountBuf, c.Uint64("royalty_amount"))
+		args = append(args, byzcoin.Argument{Name: "royalty_coin", Value: coinBuf},
+			byzcoin.Argument{Name: "royalty_amount", Value: amountBuf})
+	}
+
+	ctx := byzcoin.ClientTransaction{
+		Instructions: []byzcoin.Instruction{
+			{
+				InstanceID: byzcoin.NewInstanceID(d.GetBaseID()),
+				Spawn: &byzcoin.Spawn{
+					ContractID: contracts.ContractNFTCollectionID,
+					Args:       args,
+				},
+			},
+		},
+	}
+	err = signAndSend(c, cfg, cl, &ctx)
+	if err != nil {
+		return err
+	}
+
+	instID := ctx.Instructions[0].DeriveID("").Slice()
+	_, err = fmt.Fprintf(c.App.Writer, "Spawned new nft collection. Instance id is: \n%x\n", instID)
+	return err
+}
+
+// NFTInvokeMint mints a new token in a collection. The metadata is given as
+// a list of --attr key=value flags.
+func NFTInvokeMint(c *cli.Context) error {
+	bcArg := c.String("bc")
+	if bcArg == "" {
+		return errors.New("--bc flag is required")
+	}
+
+	var metadata contracts.NFTMetadata
+	for _, attr := range c.StringSlice("attr") {
+		kv := strings.SplitN(attr, "=", 2)
+		if len(kv) != 2 {
+			return fmt.Errorf("attribute %s is not in the form key=value", attr)
+		}
+		metadata.Attributes = append(metadata.Attributes,
+			contracts.NFTAttribute{Key: kv[0], Value: kv[1]})
+	}
+	metadataBuf, err := protobuf.Encode(&metadata)
+	if err != nil {
+		return errors.New("couldn't encode the metadata: " + err.Error())
+	}
+
+	cfg, cl, err := lib.LoadConfig(bcArg)
+	if err != nil {
+		return err
+	}
+	instIDBuf, err := nftInstID(c, cl)
+	if err != nil {
+		return err
+	}
+
+	owner, err := nftDarc(c, cl, "owner")
+	if err != nil {
+		return err
+	}
+
+	pr, err := cl.GetProof(instIDBuf)
+	if err != nil {
+		return errors.New("couldn't get proof: " + err.Error())
+	}
+	var collection contracts.NFTCollection
+	if err = nftDecode(pr.Proof, instIDBuf, contracts.ContractNFTCollectionID, &collection); err != nil {
+		return err
+	}
+
+	ctx := byzcoin.ClientTransaction{
+		Instructions: []byzcoin.Instruction{
+			{
+				InstanceID: byzcoin.NewInstanceID(instIDBuf),
+				Invoke: &byzcoin.Invoke{
+					ContractID: contracts.ContractNFTCollectionID,
+					Command:    "mint",
+					Args: byzcoin.Arguments{
+						{Name: "owner", Value: owner},
+						{Name: "metadata", Value: metadataBuf},
+					},
+				},
+			},
+		},
+	}
+	err = signAndSend(c, cfg, cl, &ctx)
+	if err != nil {
+		return err
+	}
+
+	id := contracts.NFTID(byzcoin.NewInstanceID(instIDBuf), collection.Minted)
+	_, err = fmt.Fprintf(c.App.Writer, "Minted new nft. Instance id is: \n%x\n", id.Slice())
+	return err
+}
+
+// NFTInvokeTransfer gives a token to a new owner. If the collection has a
+// royalty, it is fetched from the coin instance given with --coin.
+func NFTInvokeTransfer(c *cli.Context) error {
+	bcArg := c.String("bc")
+	if bcArg == "" {
+		return errors.New("--bc flag is required")
+	}
+
+	cfg, cl, err := lib.LoadConfig(bcArg)
+	if err != nil {
+		return err
+	}
+	instIDBuf, err := nftInstID(c, cl)
+	if err != nil {
+		return err
+	}
+
+	owner, err := nftDarc(c, cl, "owner")
+	if err != nil {
+		return err
+	}
+
+	pr, err := cl.GetProof(instIDBuf)
+	if err != nil {
+		return errors.New("couldn't get proof: " + err.Error())
+	}
+	var token contracts.NFT
+	if err = nftDecode(pr.Proof, instIDBuf, contracts.ContractNFTID, &token); err != nil {
+		return err
+	}
+	pr, err = cl.GetProof(token.Collection.Slice())
+	if err != nil {
+		return errors.New("couldn't get proof: " + err.Error())
+	}
+	var collection contracts.NFTCollection
+	if err = nftDecode(pr.Proof, token.Collection.Slice(), contracts.ContractNFTCollectionID, &collection); err != nil {
+		return err
+	}
+
+	ctx := byzcoin.ClientTransaction{}
+	if r := collection.Royalty; r != nil && r.Amount > 0 {
+		coin := c.String("coin")
+		if coin == "" {
+			return fmt.Errorf("the collection asks for a royalty of %d coins, "+
+				"--coin flag is required", r.Amount)
+		}
+		coinBuf, err := lib.StringToInstanceID(cl, coin)
+		if err != nil {
+			return err
+		}
+		amountBuf := make([]byte, 8)
+		binary.LittleEndian.PutUint64(amountBuf, r.Amount)
+		ctx.Instructions = append(ctx.Instructions, byzcoin.Instruction{
+			InstanceID: byzcoin.NewInstanceID(coinBuf),
+			Invoke: &byzcoin.Invoke{
+				ContractID: contracts.ContractCoinID,
+				Command:    "fetch",
+				Args:       byzcoin.Arguments{{Name: "coins", Value: amountBuf}},
+			},
+		})
+	}
+	ctx.Instructions = append(ctx.Instructions, byzcoin.Instruction{
+		InstanceID: byzcoin.NewInstanceID(instIDBuf),
+		Invoke: &byzcoin.Invoke{
+			ContractID: contracts.ContractNFTID,
+			Command:    "transfer",
+			Args:       byzcoin.Arguments{{Name: "owner", Value: owner}},
+		},
+	})
+	err = signAndSend(c, cfg, cl, &ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(c.App.Writer, "Nft transferred to darc:%x\n", owner)
+	return err
+}
+
+// NFTInvokeApprove allows another darc to transfer a token once. Without the
+// --darcID flag, the approval is removed.
+func NFTInvokeApprove(c *cli.Context) error {
+	bcArg := c.String("bc")
+	if bcArg == "" {
+		return errors.New("--bc flag is required")
+	}
+
+	cfg, cl, err := lib.LoadConfig(bcArg)
+	if err != nil {
+		return err
+	}
+	instIDBuf, err := nftInstID(c, cl)
+	if err != nil {
+		return err
+	}
+
+	var approved darc.ID
+	if c.String("darcID") != "" {
+		approved, err = nftDarc(c, cl, "darcID")
+		if err != nil {
+			return err
+		}
+	}
+
+	ctx := byzcoin.ClientTransaction{
+		Instructions: []byzcoin.Instruction{
+			{
+				InstanceID: byzcoin.NewInstanceID(instIDBuf),
+				Invoke: &byzcoin.Invoke{
+					ContractID: contracts.ContractNFTID,
+					Command:    "approve",
+					Args:       byzcoin.Arguments{{Name: "darcID", Value: approved}},
+				},
+			},
+		},
+	}
+	err = signAndSend(c, cfg, cl, &ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(approved) == 0 {
+		_, err = fmt.Fprintln(c.App.Writer, "Nft approval removed")
+	} else {
+		_, err = fmt.Fprintf(c.App.Writer, "Nft approved for darc:%x\n", approved)
+	}
+	return err
+}
+
+// NFTGet checks the proof and prints a collection or a token.
+func NFTGet(c *cli.Context) error {
+	bcArg := c.String("bc")
+	if bcArg == "" {
+		return errors.New("--bc flag is required")
+	}
+
+	_, cl, err := lib.LoadConfig(bcArg)
+	if err != nil {
+		return err
+	}
+	instIDBuf, err := nftInstID(c, cl)
+	if err != nil {
+		return err
+	}
+
+	pr, err := cl.GetProof(instIDBuf)
+	if err != nil {
+		return errors.New("couldn't get proof: " + err.Error())
+	}
+	_, _, cid, _, err := pr.Proof.KeyValue()
+	if err != nil {
+		return errors.New("couldn't get value out of proof: " + err.Error())
+	}
+
+	w := c.App.Writer
+	switch cid {
+	case contracts.ContractNFTCollectionID:
+		var collection contracts.NFTCollection
+		if err = nftDecode(pr.Proof, instIDBuf, cid, &collection); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "Collection: %s\n", collection.Name)
+		fmt.Fprintf(w, "Schema: %s\n", strings.Join(collection.Schema, ", "))
+		if r := collection.Royalty; r != nil {
+			fmt.Fprintf(w, "Royalty: %d coins to %x\n", r.Amount, r.Coin.Slice())
+		}
+		fmt.Fprintf(w, "Minted: %d\n", collection.Minted)
+		fmt.Fprintf(w, "Supply: %d\n", collection.Supply)
+	case contracts.ContractNFTID:
+		var token contracts.NFT
+		if err = nftDecode(pr.Proof, instIDBuf, cid, &token); err != nil {
+			return err
+		}
+		_, _, _, owner, err := pr.Proof.KeyValue()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "Collection: %x\n", token.Collection.Slice())
+		fmt.Fprintf(w, "Serial: %d\n", token.Serial)
+		fmt.Fprintf(w, "Owner: darc:%x\n", owner)
+		if len(token.Approved) > 0 {
+			fmt.Fprintf(w, "Approved: darc:%x\n", token.Approved)
+		}
+		for _, a := range token.Metadata {
+			fmt.Fprintf(w, "%s: %s\n", a.Key, a.Value)
+		}
+	default:
+		return fmt.Errorf("instance is a %s, not an nft", cid)
+	}
+
+	return nil
+}
+
+// NFTDelete burns a token, or deletes a collection without tokens.
+func NFTDelete(c *cli.Context) error {
+	bcArg := c.String("bc")
+	if bcArg == "" {
+		return errors.New("--bc flag is required")
+	}
+
+	cfg, cl, err := lib.LoadConfig(bcArg)
+	if err != nil {
+		return err
+	}
+	instIDBuf, err := nftInstID(c, cl)
+	if err != nil {
+		return err
+	}
+
+	pr, err := cl.GetProof(instIDBuf)
+	if err != nil {
+		return errors.New("couldn't get proof: " + err.Error())
+	}
+	_, _, cid, _, err := pr.Proof.KeyValue()
+	if err != nil {
+		return errors.New("couldn't get value out of proof: " + err.Error())
+	}
+	if cid != contracts.ContractNFTID && cid != contracts.ContractNFTCollectionID {
+		return fmt.Errorf("instance is a %s, not an nft", cid)
+	}
+
+	ctx := byzcoin.ClientTransaction{
+		Instructions: []byzcoin.Instruction{
+			{
+				InstanceID: byzcoin.NewInstanceID(instIDBuf),
+				Delete:     &byzcoin.Delete{ContractID: cid},
+			},
+		},
+	}
+	err = signAndSend(c, cfg, cl, &ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(c.App.Writer, "Deleted %s instance %x\n", cid, instIDBuf)
+	return err
+}
+
+// nftInstID returns the instance of the required --instID flag.
+func nftInstID(c *cli.Context, cl *byzcoin.Client) ([]byte, error) {
+	instID := c.String("instID")
+	if instID == "" {
+		return nil, errors.New("--instID flag is required")
+	}
+	return lib.StringToInstanceID(cl, instID)
+}
+
+// nftDarc returns the base ID of the darc given in the flag.
+func nftDarc(c *cli.Context, cl *byzcoin.Client, flag string) (darc.ID, error) {
+	dstr := c.String(flag)
+	if dstr == "" {
+		return nil, fmt.Errorf("--%s flag is required", flag)
+	}
+	d, err := lib.GetDarcByString(cl, dstr)
+	if err != nil {
+		return nil, err
+	}
+	return d.GetBaseID(), nil
+}
+
+// nftDecode verifies the proof and decodes the instance of the given
+// contract.
+func nftDecode(p byzcoin.Proof, key []byte, contractID string, v interface{}) error {
+	if !p.InclusionProof.Match(key) {
+		return errors.New("proof does not match")
+	}
+	return p.VerifyAndDecode(cothority.Suite, contractID, v)
+}
+
+// signAndSend signs the instructions with the signer given by --sign and waits
+// for the transaction to be included.
+func signAndSend(c *cli.Context, cfg lib.Config, cl *byzcoin.Client, ctx *byzcoin.ClientTransaction) error {
+	var signer *darc.Signer
+	var err error
+
+	sstr := c.String("sign")
+	if sstr == "" {
+		signer, err = lib.LoadKey(cfg.AdminIdentity)
+	} else {
+		signer, err = lib.LoadKeyFromString(sstr)
+	}
+	if err != nil {
+		return err
+	}
+
+	counters, err := cl.GetSignerCounters(signer.Identity().String())
+	if err != nil {
+		return err
+	}
+	for i := range ctx.Instructions {
+		ctx.Instructions[i].SignerCounter = []uint64{counters.Counters[0] + uint64(i) + 1}
+	}
+	err = ctx.FillSignersAndSignWith(*signer)
+	if err != nil {
+		return err
+	}
+
+	_, err = cl.AddTransactionAndWait(*ctx, 10)
+	return err
+}
@@ -0,0 +1,42 @@
+# This method should be called from the byzcoin/bcadmin/test.sh script
+
+testContractNFT() {
+    run testContractNFTLifecycle
+}
+
+testContractNFTLifecycle() {
+    # In this test we spawn a collection, mint a token, transfer it and burn
+    # it, before deleting the collection.
+    runCoBG 1 2 3
+    runGrepSed "export BC=" "" runBA create --roster public.toml --interval .5s
+    eval $SED
+    [ -z "$BC" ] && exit 1
+
+    testOK runBA darc add -out_id ./darc_id.txt -out_key ./darc_key.txt -unrestricted
+    ID=`cat ./darc_id.txt`
+    KEY=`cat ./darc_key.txt`
+    testOK runBA darc rule -rule "spawn:nft_collection" --identity "$KEY" --darc "$ID" --sign "$KEY"
+    testOK runBA darc rule -rule "invoke:nft_collection.mint" --identity "$KEY" --darc "$ID" --sign "$KEY"
+    testOK runBA darc rule -rule "delete:nft_collection" --identity "$KEY" --darc "$ID" --sign "$KEY"
+    testOK runBA darc rule -rule "invoke:nft.transfer" --identity "$KEY" --darc "$ID" --sign "$KEY"
+    testOK runBA darc rule -rule "delete:nft" --identity "$KEY" --darc "$ID" --sign "$KEY"
+
+    OUTFILE=res.txt && testOK runBA contract nft spawn --name "lab" --schema "model" --darc "$ID" --sign "$KEY"
+    OUTFILE=""
+    testGrep "Spawned new nft collection. Instance id is:" cat res.txt
+    COLLECTION_ID=`sed -n 2p res.txt`
+
+    # The metadata must follow the schema.
+    testFail runBA contract nft invoke mint --instID $COLLECTION_ID --owner "$ID" --attr "color=red" --sign "$KEY"
+    OUTFILE=res.txt && testOK runBA contract nft invoke mint --instID $COLLECTION_ID --owner "$ID" --attr "model=microscope" --sign "$KEY"
+    OUTFILE=""
+    testGrep "Minted new nft. Instance id is:" cat res.txt
+    NFT_ID=`sed -n 2p res.txt`
+    testGrep "model: microscope" runBA contract nft get --instID $NFT_ID
+
+    testOK runBA contract nft invoke transfer --instID $NFT_ID --owner "$ID" --sign "$KEY"
+    testFail runBA contract nft delete --instID $COLLECTION_ID --sign "$KEY"
+    testOK runBA contract nft delete --instID $NFT_ID --sign "$KEY"
+    testGrep "Supply: 0" runBA contract nft get --instID $COLLECTION_ID
+    testOK runBA contract nft delete --instID $COLLECTION_ID --sign "$KEY"
+}
@@ -2,7 +2,6 @@ package clicontracts
 
 import (
 	"bytes"
-	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
@@ -136,15 +135,15 @@ func ValueInvokeUpdate(c *cli.Context) error {
 	if instID == "" {
 		return errors.New("--instID flag is required")
 	}
-	instIDBuf, err := hex.DecodeString(instID)
-	if err != nil {
-		return errors.New("failed to decode the instID string")
-	}
 
 	cfg, cl, err := lib.LoadConfig(bcArg)
 	if err != nil {
 		return err
 	}
+	instIDBuf, err := lib.StringToInstanceID(cl, instID)
+	if err != nil {
+		return err
+	}
 
 	dstr := c.String("darc")
 	if dstr == "" {
@@ -221,9 +220,9 @@ func ValueGet(c *cli.Context) error {
 	if instID == "" {
 		return errors.New("--instID flag is required")
 	}
-	instIDBuf, err := hex.DecodeString(instID)
+	instIDBuf, err := lib.StringToInstanceID(cl, instID)
 	if err != nil {
-		return errors.New("failed to decode the instID string")
+		return err
 	}
 
 	pr, err := cl.GetProof(instIDBuf)
@@ -266,15 +265,15 @@ func ValueDelete(c *cli.Context) error {
 	if instID == "" {
 		return errors.New("--instID flag is required")
 	}
-	instIDBuf, err := hex.DecodeString(instID)
-	if err != nil {
-		return errors.New("failed to decode the instID string")
-	}
 
 	cfg, cl, err := lib.LoadConfig(bcArg)
 	if err != nil {
 		return err
 	}
+	instIDBuf, err := lib.StringToInstanceID(cl, instID)
+	if err != nil {
+		return err
+	}
 
 	dstr := c.String("darc")
 	if dstr == "" {
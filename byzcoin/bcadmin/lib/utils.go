@@ -33,9 +33,13 @@ func StringToEd25519Buf(pub string) ([]byte, error) {
 	return hex.DecodeString(pub)
 }
 
-// GetDarcByString returns a DARC given its ID as a string
+// GetDarcByString returns a DARC given its ID as a string, with or without
+// the "darc:" prefix, or a name registered with the naming contract.
 func GetDarcByString(cl *byzcoin.Client, id string) (*darc.Darc, error) {
-	xrep, err := StringToDarcID(id)
+	if strings.HasPrefix(id, "darc:") {
+		id = id[5:]
+	}
+	xrep, err := StringToInstanceID(cl, id)
 	if err != nil {
 		return nil, err
 	}
@@ -70,3 +74,16 @@ func GetDarcByID(cl *byzcoin.Client, id []byte) (*darc.Darc, error) {
 
 	return d, nil
 }
+
+// StringToInstanceID converts an instance ID given in hex, or a name
+// registered with the naming contract, to a byte array.
+func StringToInstanceID(cl *byzcoin.Client, id string) ([]byte, error) {
+	if id == "" {
+		return nil, errors.New("no string given")
+	}
+	iid, err := cl.ResolveInstanceID(id)
+	if err != nil {
+		return nil, errors.New("failed to resolve the instance ID: " + err.Error())
+	}
+	return iid.Slice(), nil
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
 	"crypto/sha256"
 	"encoding/binary"
@@ -11,11 +12,14 @@ import (
 	"io"
 	"io/ioutil"
 	"math/rand"
+	"net/http"
 	"os"
+	"os/signal"
 	"path"
 	"sort"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"go.dedis.ch/cothority/v3/byzcoin/bcadmin/clicontracts"
@@ -25,6 +29,8 @@ import (
 	"go.dedis.ch/cothority/v3/byzcoin"
 	"go.dedis.ch/cothority/v3/byzcoin/bcadmin/lib"
 	"go.dedis.ch/cothority/v3/byzcoin/contracts"
+	"go.dedis.ch/cothority/v3/byzcoin/devnet"
+	"go.dedis.ch/cothority/v3/byzcoin/gateway"
 	"go.dedis.ch/cothority/v3/darc"
 	"go.dedis.ch/cothority/v3/darc/expression"
 	_ "go.dedis.ch/cothority/v3/personhood"
@@ -59,10 +65,48 @@ var cmds = cli.Commands{
 				Usage: "the block interval for this ledger",
 				Value: 5 * time.Second,
 			},
+			cli.StringFlag{
+				Name:  "template, t",
+				Usage: "a TOML genesis template with the darcs and instances of the ledger",
+			},
 		},
 		Action: create,
 	},
 
+	{
+		Name:  "devnet",
+		Usage: "run a local ByzCoin network for testing",
+		Description: "Starts conodes in this process and creates a ledger, or reuses the ones of a\n" +
+			"   previous run in the same directory. While it runs, it reads commands from stdin:\n" +
+			"   'blocks n' adds n blocks, 'interval d' changes the block interval, 'quit' stops it.",
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "dir",
+				Usage: "the directory of the devnet, 'devnet' in the config directory if empty",
+			},
+			cli.IntFlag{
+				Name:  "nodes, n",
+				Usage: "the number of conodes",
+				Value: devnet.DefaultNodes,
+			},
+			cli.IntFlag{
+				Name:  "port, p",
+				Usage: "the port of the first conode",
+				Value: devnet.DefaultBasePort,
+			},
+			cli.DurationFlag{
+				Name:  "interval, i",
+				Usage: "the block interval of a new ledger",
+				Value: devnet.DefaultBlockInterval,
+			},
+			cli.StringFlag{
+				Name:  "template, t",
+				Usage: "a TOML genesis template for a new ledger",
+			},
+		},
+		Action: runDevnet,
+	},
+
 	{
 		Name:      "link",
 		Usage:     "link to existing ledger",
@@ -166,6 +210,12 @@ var cmds = cli.Commands{
 				ArgsUsage: "bc-xxx.cfg key-xxx.cfg public.toml",
 				Usage:     "Add a new node to the roster",
 				Action:    rosterAdd,
+				Flags: []cli.Flag{
+					cli.Uint64Flag{
+						Name:  "weight",
+						Usage: "voting weight of the new node, required if the nodes have different weights",
+					},
+				},
 			},
 			{
 				Name:      "del",
@@ -179,6 +229,47 @@ var cmds = cli.Commands{
 				Usage:     "Set a specific node to be the leader",
 				Action:    rosterLeader,
 			},
+			{
+				Name:      "weight",
+				ArgsUsage: "bc-xxx.cfg key-xxx.cfg [public.toml]",
+				Usage:     "Change the voting weight of a node",
+				Action:    rosterWeight,
+				Flags: []cli.Flag{
+					cli.Uint64Flag{
+						Name:  "weight",
+						Usage: "new voting weight of the node",
+					},
+					cli.BoolFlag{
+						Name:  "clear",
+						Usage: "give all the nodes the same weight again",
+					},
+				},
+			},
+			{
+				Name:      "migrate",
+				ArgsUsage: "bc-xxx.cfg key-xxx.cfg target.toml",
+				Usage:     "Replace the roster by the target roster, changing one node at a time",
+				Action:    rosterMigrate,
+				Flags: []cli.Flag{
+					cli.BoolFlag{
+						Name:  "dry-run",
+						Usage: "only print the steps of the migration",
+					},
+					cli.BoolFlag{
+						Name:  "force",
+						Usage: "allow steps that stop the chain until a new node is up to date",
+					},
+					cli.DurationFlag{
+						Name:  "timeout",
+						Usage: "how long to wait for a new node to catch up",
+						Value: 5 * time.Minute,
+					},
+					cli.Uint64Flag{
+						Name:  "weight",
+						Usage: "voting weight of the new nodes, required if the nodes have different weights",
+					},
+				},
+			},
 		},
 	},
 
@@ -195,6 +286,26 @@ var cmds = cli.Commands{
 				Name:  "blockSize",
 				Usage: "adjust the maximum block size",
 			},
+			cli.BoolFlag{
+				Name:  "pause",
+				Usage: "refuse all the transactions except the ones for the config",
+			},
+			cli.BoolFlag{
+				Name:  "resume",
+				Usage: "accept the transactions again after a pause",
+			},
+			cli.IntFlag{
+				Name:  "rotateBlocks",
+				Usage: "rotate the leader every so many blocks, 0 to disable",
+			},
+			cli.StringFlag{
+				Name:  "rotateLatency",
+				Usage: "rotate the leader if a block is later than this duration, 0 to disable",
+			},
+			cli.IntFlag{
+				Name:  "rotateInclusion",
+				Usage: "rotate the leader if a transaction is not included after so many blocks, 0 to disable",
+			},
 		},
 		Action: config,
 	},
@@ -354,6 +465,24 @@ var cmds = cli.Commands{
 		Action: qrcode,
 	},
 
+	{
+		Name:  "gateway",
+		Usage: "runs a JSON/HTTP gateway forwarding requests to the roster of the ByzCoin config",
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:   "bc",
+				EnvVar: "BC",
+				Usage:  "the ByzCoin config to use (required)",
+			},
+			cli.StringFlag{
+				Name:  "listen",
+				Usage: "address to listen on",
+				Value: "localhost:7780",
+			},
+		},
+		Action: runGateway,
+	},
+
 	{
 		Name:  "contract",
 		Usage: "a tool to manipulate contracts",
@@ -410,7 +539,7 @@ var cmds = cli.Commands{
 									},
 									cli.StringFlag{
 										Name:  "instID",
-										Usage: "the instance ID of the value contract",
+										Usage: "the instance ID or name of the value contract",
 									},
 									cli.StringFlag{
 										Name:  "darc",
@@ -436,7 +565,7 @@ var cmds = cli.Commands{
 							},
 							cli.StringFlag{
 								Name:  "instID",
-								Usage: "the instance id (required)",
+								Usage: "the instance id or name (required)",
 							},
 						},
 					},
@@ -453,7 +582,7 @@ var cmds = cli.Commands{
 							},
 							cli.StringFlag{
 								Name:  "instID",
-								Usage: "the instance ID of the value contract",
+								Usage: "the instance ID or name of the value contract",
 							},
 							cli.StringFlag{
 								Name:  "darc",
@@ -485,6 +614,10 @@ var cmds = cli.Commands{
 								Name:  "darc",
 								Usage: "DARC with the right to spawn a deferred contract (default is the admin DARC)",
 							},
+							cli.DurationFlag{
+								Name:  "expire",
+								Usage: "expire the deferred contract after this duration, in addition to the block index",
+							},
 							cli.StringFlag{
 								Name:  "sign",
 								Usage: "public key of the signing entity (default is the admin public key)",
@@ -515,7 +648,7 @@ var cmds = cli.Commands{
 									},
 									cli.StringFlag{
 										Name:  "instID",
-										Usage: "the instance ID of the deferred contract",
+										Usage: "the instance ID or name of the deferred contract",
 									},
 									cli.StringFlag{
 										Name:  "darc",
@@ -527,6 +660,30 @@ var cmds = cli.Commands{
 									},
 								},
 							},
+							{
+								Name:   "removeProof",
+								Usage:  "withdraws the signature of the signing entity on an instruction of the proposed transaction",
+								Action: clicontracts.DeferredInvokeRemoveProof,
+								Flags: []cli.Flag{
+									cli.StringFlag{
+										Name:   "bc",
+										EnvVar: "BC",
+										Usage:  "the ByzCoin config to use (required)",
+									},
+									cli.UintFlag{
+										Name:  "instrIdx",
+										Usage: "the instruction index of the transaction (starts from 0) (default is 0)",
+									},
+									cli.StringFlag{
+										Name:  "instID",
+										Usage: "the instance ID or name of the deferred contract",
+									},
+									cli.StringFlag{
+										Name:  "sign",
+										Usage: "public key of the signing entity (default is the admin public key)",
+									},
+								},
+							},
 							{
 								Name:   "execProposedTx",
 								Usage:  "executes the proposed transaction if the instructions are correctly signed",
@@ -539,7 +696,7 @@ var cmds = cli.Commands{
 									},
 									cli.StringFlag{
 										Name:  "instID",
-										Usage: "the instance ID of the deferred contract",
+										Usage: "the instance ID or name of the deferred contract",
 									},
 									cli.StringFlag{
 										Name:  "darc",
@@ -565,7 +722,27 @@ var cmds = cli.Commands{
 							},
 							cli.StringFlag{
 								Name:  "instID",
-								Usage: "the instance id (required)",
+								Usage: "the instance id or name (required)",
+							},
+						},
+					},
+					{
+						Name:   "list",
+						Usage:  "list the deferred contracts that can still be executed and the identities missing to sign them",
+						Action: clicontracts.DeferredList,
+						Flags: []cli.Flag{
+							cli.StringFlag{
+								Name:   "bc",
+								EnvVar: "BC",
+								Usage:  "the ByzCoin config to use (required)",
+							},
+							cli.StringFlag{
+								Name:  "darc",
+								Usage: "only list the deferred contracts of this DARC or proposing an instruction on an instance of this DARC",
+							},
+							cli.StringFlag{
+								Name:  "identity",
+								Usage: "only list the deferred contracts missing a signature of this identity",
 							},
 						},
 					},
@@ -582,7 +759,7 @@ var cmds = cli.Commands{
 							},
 							cli.StringFlag{
 								Name:  "instID",
-								Usage: "the instance ID of the value contract",
+								Usage: "the instance ID or name of the value contract",
 							},
 							cli.StringFlag{
 								Name:  "darc",
@@ -596,6 +773,274 @@ var cmds = cli.Commands{
 					},
 				},
 			},
+			{
+				Name:  "nft",
+				Usage: "Manipulate the nft collection and nft contracts",
+				Subcommands: cli.Commands{
+					{
+						Name:   "spawn",
+						Usage:  "spawn an nft collection",
+						Action: clicontracts.NFTSpawn,
+						Flags: []cli.Flag{
+							cli.StringFlag{
+								Name:   "bc",
+								EnvVar: "BC",
+								Usage:  "the ByzCoin config to use (required)",
+							},
+							cli.StringFlag{
+								Name:  "name",
+								Usage: "the name of the collection (required)",
+							},
+							cli.StringFlag{
+								Name:  "schema",
+								Usage: "comma separated keys that the metadata of every token must have",
+							},
+							cli.StringFlag{
+								Name:  "royalty_coin",
+								Usage: "the coin instance ID or name receiving a royalty on every transfer",
+							},
+							cli.Uint64Flag{
+								Name:  "royalty_amount",
+								Usage: "the number of coins of the royalty",
+							},
+							cli.StringFlag{
+								Name:  "darc",
+								Usage: "DARC with the right to spawn an nft collection, which also controls the mint (default is the admin DARC)",
+							},
+							cli.StringFlag{
+								Name:  "sign",
+								Usage: "public key of the signing entity (default is the admin public key)",
+							},
+						},
+					},
+					{
+						Name:  "invoke",
+						Usage: "invoke an nft collection or an nft contract",
+						Subcommands: cli.Commands{
+							{
+								Name:   "mint",
+								Usage:  "mint a new token in a collection",
+								Action: clicontracts.NFTInvokeMint,
+								Flags: []cli.Flag{
+									cli.StringFlag{
+										Name:   "bc",
+										EnvVar: "BC",
+										Usage:  "the ByzCoin config to use (required)",
+									},
+									cli.StringFlag{
+										Name:  "instID",
+										Usage: "the instance ID or name of the nft collection",
+									},
+									cli.StringFlag{
+										Name:  "owner",
+										Usage: "DARC owning the new token (required)",
+									},
+									cli.StringSliceFlag{
+										Name:  "attr",
+										Usage: "a key=value attribute of the metadata, can be repeated",
+									},
+									cli.StringFlag{
+										Name:  "sign",
+										Usage: "public key of the signing entity (default is the admin public key)",
+									},
+								},
+							},
+							{
+								Name:   "transfer",
+								Usage:  "transfer a token to a new owner",
+								Action: clicontracts.NFTInvokeTransfer,
+								Flags: []cli.Flag{
+									cli.StringFlag{
+										Name:   "bc",
+										EnvVar: "BC",
+										Usage:  "the ByzCoin config to use (required)",
+									},
+									cli.StringFlag{
+										Name:  "instID",
+										Usage: "the instance ID or name of the nft",
+									},
+									cli.StringFlag{
+										Name:  "owner",
+										Usage: "DARC of the new owner (required)",
+									},
+									cli.StringFlag{
+										Name:  "coin",
+										Usage: "the coin instance ID or name paying the royalty of the collection, if any",
+									},
+									cli.StringFlag{
+										Name:  "sign",
+										Usage: "public key of the signing entity (default is the admin public key)",
+									},
+								},
+							},
+							{
+								Name:   "approve",
+								Usage:  "allow another DARC to transfer a token once",
+								Action: clicontracts.NFTInvokeApprove,
+								Flags: []cli.Flag{
+									cli.StringFlag{
+										Name:   "bc",
+										EnvVar: "BC",
+										Usage:  "the ByzCoin config to use (required)",
+									},
+									cli.StringFlag{
+										Name:  "instID",
+										Usage: "the instance ID or name of the nft",
+									},
+									cli.StringFlag{
+										Name:  "darcID",
+										Usage: "the approved DARC, the approval is removed if it is empty",
+									},
+									cli.StringFlag{
+										Name:  "sign",
+										Usage: "public key of the signing entity (default is the admin public key)",
+									},
+								},
+							},
+						},
+					},
+					{
+						Name:   "get",
+						Usage:  "if the proof matches, print the given nft collection or nft instance ID",
+						Action: clicontracts.NFTGet,
+						Flags: []cli.Flag{
+							cli.StringFlag{
+								Name:   "bc",
+								EnvVar: "BC",
+								Usage:  "the ByzCoin config to use (required)",
+							},
+							cli.StringFlag{
+								Name:  "instID",
+								Usage: "the instance id or name (required)",
+							},
+						},
+					},
+					{
+						Name:   "delete",
+						Usage:  "burn an nft, or delete an nft collection without tokens",
+						Action: clicontracts.NFTDelete,
+						Flags: []cli.Flag{
+							cli.StringFlag{
+								Name:   "bc",
+								EnvVar: "BC",
+								Usage:  "the ByzCoin config to use (required)",
+							},
+							cli.StringFlag{
+								Name:  "instID",
+								Usage: "the instance ID or name of the nft or the nft collection",
+							},
+							cli.StringFlag{
+								Name:  "sign",
+								Usage: "public key of the signing entity (default is the admin public key)",
+							},
+						},
+					},
+				},
+			},
+			{
+				Name:  "name",
+				Usage: "Manipulate the names of instances",
+				Subcommands: cli.Commands{
+					{
+						Name:   "spawn",
+						Usage:  "register a new name",
+						Action: clicontracts.NameSpawn,
+						Flags: []cli.Flag{
+							cli.StringFlag{
+								Name:   "bc",
+								EnvVar: "BC",
+								Usage:  "the ByzCoin config to use (required)",
+							},
+							cli.StringFlag{
+								Name:  "name",
+								Usage: "the full name, like \"dedis/alice\" (required)",
+							},
+							cli.StringFlag{
+								Name:  "target",
+								Usage: "the instance ID or name the name points to",
+							},
+							cli.StringFlag{
+								Name:  "darc",
+								Usage: "DARC with the right to spawn a top-level name (default is the admin DARC)",
+							},
+							cli.StringFlag{
+								Name:  "owner",
+								Usage: "DARC controlling the new name (default is the DARC of the parent)",
+							},
+							cli.StringFlag{
+								Name:  "sign",
+								Usage: "public key of the signing entity (default is the admin public key)",
+							},
+						},
+					},
+					{
+						Name:  "invoke",
+						Usage: "invoke a name",
+						Subcommands: cli.Commands{
+							{
+								Name:   "set",
+								Usage:  "change the target of a name",
+								Action: clicontracts.NameInvokeSet,
+								Flags: []cli.Flag{
+									cli.StringFlag{
+										Name:   "bc",
+										EnvVar: "BC",
+										Usage:  "the ByzCoin config to use (required)",
+									},
+									cli.StringFlag{
+										Name:  "name",
+										Usage: "the name (required)",
+									},
+									cli.StringFlag{
+										Name:  "target",
+										Usage: "the instance ID or name the name points to (default removes the target)",
+									},
+									cli.StringFlag{
+										Name:  "sign",
+										Usage: "public key of the signing entity (default is the admin public key)",
+									},
+								},
+							},
+						},
+					},
+					{
+						Name:   "get",
+						Usage:  "check the proof and print a name",
+						Action: clicontracts.NameGet,
+						Flags: []cli.Flag{
+							cli.StringFlag{
+								Name:   "bc",
+								EnvVar: "BC",
+								Usage:  "the ByzCoin config to use (required)",
+							},
+							cli.StringFlag{
+								Name:  "name",
+								Usage: "the name (required)",
+							},
+						},
+					},
+					{
+						Name:   "delete",
+						Usage:  "delete a name without names below it",
+						Action: clicontracts.NameDelete,
+						Flags: []cli.Flag{
+							cli.StringFlag{
+								Name:   "bc",
+								EnvVar: "BC",
+								Usage:  "the ByzCoin config to use (required)",
+							},
+							cli.StringFlag{
+								Name:  "name",
+								Usage: "the name (required)",
+							},
+							cli.StringFlag{
+								Name:  "sign",
+								Usage: "public key of the signing entity (default is the admin public key)",
+							},
+						},
+					},
+				},
+			},
 		},
 	},
 }
@@ -658,12 +1103,30 @@ func create(c *cli.Context) error {
 
 	owner := darc.NewSignerEd25519(nil, nil)
 
-	req, err := byzcoin.DefaultGenesisMsg(byzcoin.CurrentVersion, r, []string{"spawn:longTermSecret"}, owner.Identity())
-	if err != nil {
-		log.Error(err)
-		return err
+	var req *byzcoin.CreateGenesisBlock
+	var names map[string]byzcoin.InstanceID
+	if tfn := c.String("template"); tfn != "" {
+		tmpl, err := byzcoin.ReadGenesisTemplate(tfn)
+		if err != nil {
+			return err
+		}
+		tmpl.Rules = append(tmpl.Rules, "spawn:longTermSecret")
+		req, names, err = tmpl.GenesisMsg(byzcoin.CurrentVersion, r, owner.Identity())
+		if err != nil {
+			return err
+		}
+		// The block interval of the template wins over the default one.
+		if tmpl.BlockInterval == "" || c.IsSet("interval") {
+			req.BlockInterval = interval
+		}
+	} else {
+		req, err = byzcoin.DefaultGenesisMsg(byzcoin.CurrentVersion, r, []string{"spawn:longTermSecret"}, owner.Identity())
+		if err != nil {
+			log.Error(err)
+			return err
+		}
+		req.BlockInterval = interval
 	}
-	req.BlockInterval = interval
 
 	_, resp, err := byzcoin.NewLedger(req, false)
 	if err != nil {
@@ -694,6 +1157,12 @@ func create(c *cli.Context) error {
 	if err != nil {
 		return err
 	}
+	for _, name := range sortedNames(names) {
+		_, err = fmt.Fprintf(c.App.Writer, "Instance %s: %x\n", name, names[name].Slice())
+		if err != nil {
+			return err
+		}
+	}
 
 	// For the tests to use.
 	c.App.Metadata["BC"] = fn
@@ -701,6 +1170,115 @@ func create(c *cli.Context) error {
 	return nil
 }
 
+func runDevnet(c *cli.Context) error {
+	cfg := devnet.Config{
+		Dir:           c.String("dir"),
+		Nodes:         c.Int("nodes"),
+		BasePort:      c.Int("port"),
+		BlockInterval: c.Duration("interval"),
+	}
+	if cfg.Dir == "" {
+		cfg.Dir = path.Join(lib.ConfigPath, "devnet")
+	}
+	if tfn := c.String("template"); tfn != "" {
+		tmpl, err := byzcoin.ReadGenesisTemplate(tfn)
+		if err != nil {
+			return err
+		}
+		cfg.Template = tmpl
+	}
+
+	d, err := devnet.Start(cfg)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	fn, err := lib.SaveConfig(lib.Config{
+		ByzCoinID:     d.ByzCoinID,
+		Roster:        *d.Roster,
+		AdminDarc:     d.GenesisDarc,
+		AdminIdentity: d.Owner.Identity(),
+	})
+	if err != nil {
+		return err
+	}
+	if err = lib.SaveKey(d.Owner); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.App.Writer, "Devnet with ByzCoin ID %x running in %s\n", d.ByzCoinID, d.Dir)
+	fmt.Fprintf(c.App.Writer, "Roster: %s\n", d.RosterFile())
+	for i, si := range d.Roster.List {
+		fmt.Fprintf(c.App.Writer, "Conode %s, websocket ws://127.0.0.1:%d\n", si.Address, cfg.BasePort+2*i+1)
+	}
+	for _, name := range sortedNames(d.Instances) {
+		fmt.Fprintf(c.App.Writer, "Instance %s: %x\n", name, d.Instances[name].Slice())
+	}
+	fmt.Fprintf(c.App.Writer, "export BC=\"%v\"\n", fn)
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	cmds := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			cmds <- scanner.Text()
+		}
+	}()
+	for {
+		select {
+		case <-sigs:
+			return nil
+		case cmd := <-cmds:
+			args := strings.Fields(cmd)
+			if len(args) == 0 {
+				continue
+			}
+			switch {
+			case args[0] == "quit":
+				return nil
+			case args[0] == "blocks" && len(args) == 2:
+				n, err := strconv.Atoi(args[1])
+				if err != nil {
+					log.Error("invalid number of blocks:", err)
+					continue
+				}
+				index, err := d.AdvanceBlocks(n)
+				if err != nil {
+					log.Error("couldn't add blocks:", err)
+					continue
+				}
+				fmt.Fprintf(c.App.Writer, "Latest block: %d\n", index)
+			case args[0] == "interval" && len(args) == 2:
+				interval, err := time.ParseDuration(args[1])
+				if err != nil {
+					log.Error("invalid interval:", err)
+					continue
+				}
+				if err = d.SetBlockInterval(interval); err != nil {
+					log.Error("couldn't change the interval:", err)
+					continue
+				}
+				fmt.Fprintf(c.App.Writer, "Block interval: %s\n", interval)
+			default:
+				log.Error("unknown command:", cmd)
+			}
+		}
+	}
+}
+
+// sortedNames returns the names of the instances created by a genesis
+// template, in alphabetical order.
+func sortedNames(names map[string]byzcoin.InstanceID) []string {
+	out := make([]string, 0, len(names))
+	for name := range names {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
 func link(c *cli.Context) error {
 	if c.NArg() < 1 {
 		return errors.New("please give the following args: roster.toml [bcid]")
@@ -875,6 +1453,29 @@ func latest(c *cli.Context) error {
 		return err
 	}
 
+	configBuf, _, _, err := p.Proof.Get(byzcoin.ConfigInstanceID.Slice())
+	if err != nil {
+		return err
+	}
+	var chainConfig byzcoin.ChainConfig
+	err = protobuf.DecodeWithConstructors(configBuf, &chainConfig, network.DefaultConstructors(cothority.Suite))
+	if err != nil {
+		return err
+	}
+	if chainConfig.Paused {
+		_, err = fmt.Fprintln(c.App.Writer, "The chain is paused.")
+		if err != nil {
+			return err
+		}
+	}
+	if p := chainConfig.RotationPolicy; p != nil {
+		_, err = fmt.Fprintf(c.App.Writer, "Rotation policy: every %d blocks, latency %s, inclusion %d blocks\n",
+			p.BlockCount, p.MaxLatency, p.InclusionBlocks)
+		if err != nil {
+			return err
+		}
+	}
+
 	if c.Bool("update") {
 		cfg.Roster = *sb.Roster
 		var fn string
@@ -972,22 +1573,29 @@ func getBcKeyPub(c *cli.Context) (cfg lib.Config, cl *byzcoin.Client, signer *da
 }
 
 func updateConfig(cl *byzcoin.Client, signer *darc.Signer, chainConfig byzcoin.ChainConfig) error {
+	ccBuf, err := protobuf.Encode(&chainConfig)
+	if err != nil {
+		return errors.New("couldn't encode chainConfig: " + err.Error())
+	}
+	log.Lvl1("Sending new roster to byzcoin")
+	return invokeConfig(cl, signer, "update_config", byzcoin.Arguments{{Name: "config", Value: ccBuf}})
+}
+
+// invokeConfig sends the command to the config contract and waits for it to
+// be included.
+func invokeConfig(cl *byzcoin.Client, signer *darc.Signer, command string, args byzcoin.Arguments) error {
 	counters, err := cl.GetSignerCounters(signer.Identity().String())
 	if err != nil {
 		return errors.New("couldn't get counters: " + err.Error())
 	}
 	counters.Counters[0]++
-	ccBuf, err := protobuf.Encode(&chainConfig)
-	if err != nil {
-		return errors.New("couldn't encode chainConfig: " + err.Error())
-	}
 	ctx := byzcoin.ClientTransaction{
 		Instructions: byzcoin.Instructions{{
 			InstanceID: byzcoin.ConfigInstanceID,
 			Invoke: &byzcoin.Invoke{
 				ContractID: byzcoin.ContractConfigID,
-				Command:    "update_config",
-				Args:       byzcoin.Arguments{{Name: "config", Value: ccBuf}},
+				Command:    command,
+				Args:       args,
 			},
 			SignerCounter: counters.Counters,
 		}},
@@ -998,7 +1606,6 @@ func updateConfig(cl *byzcoin.Client, signer *darc.Signer, chainConfig byzcoin.C
 		return errors.New("couldn't sign the clientTransaction: " + err.Error())
 	}
 
-	log.Lvl1("Sending new roster to byzcoin")
 	_, err = cl.AddTransactionAndWait(ctx, 10)
 	if err != nil {
 		return errors.New("client transaction wasn't accepted: " + err.Error())
@@ -1012,6 +1619,21 @@ func config(c *cli.Context) error {
 		return err
 	}
 
+	if c.Bool("pause") || c.Bool("resume") {
+		if c.Bool("pause") && c.Bool("resume") {
+			return errors.New("cannot pause and resume at the same time")
+		}
+		command := "pause"
+		if c.Bool("resume") {
+			command = "resume"
+		}
+		if err = invokeConfig(cl, signer, command, nil); err != nil {
+			return err
+		}
+		log.Infof("Chain %sd", command)
+		return nil
+	}
+
 	if interval := c.String("interval"); interval != "" {
 		dur, err := time.ParseDuration(interval)
 		if err != nil {
@@ -1025,6 +1647,30 @@ func config(c *cli.Context) error {
 		}
 		chainConfig.MaxBlockSize = blockSize
 	}
+	if c.IsSet("rotateBlocks") || c.IsSet("rotateLatency") || c.IsSet("rotateInclusion") {
+		var p byzcoin.RotationPolicy
+		if chainConfig.RotationPolicy != nil {
+			p = *chainConfig.RotationPolicy
+		}
+		if c.IsSet("rotateBlocks") {
+			p.BlockCount = c.Int("rotateBlocks")
+		}
+		if c.IsSet("rotateLatency") {
+			dur, err := time.ParseDuration(c.String("rotateLatency"))
+			if err != nil {
+				return errors.New("couldn't parse latency: " + err.Error())
+			}
+			p.MaxLatency = dur
+		}
+		if c.IsSet("rotateInclusion") {
+			p.InclusionBlocks = c.Int("rotateInclusion")
+		}
+		// A policy without any rule is removed from the config.
+		chainConfig.RotationPolicy = nil
+		if p != (byzcoin.RotationPolicy{}) {
+			chainConfig.RotationPolicy = &p
+		}
+	}
 
 	err = updateConfig(cl, signer, chainConfig)
 	if err != nil {
@@ -1197,7 +1843,12 @@ func rosterAdd(c *cli.Context) error {
 		return errors.New("new node is already in roster")
 	}
 	log.Lvl2("Old roster is:", old.List)
-	chainConfig.Roster = *old.Concat(pub)
+	newRoster := old.Concat(pub)
+	chainConfig.Weights, err = chainConfig.WeightsFor(newRoster, c.Uint64("weight"))
+	if err != nil {
+		return fmt.Errorf("%v - use --weight to give one", err)
+	}
+	chainConfig.Roster = *newRoster
 	log.Lvl2("New roster is:", chainConfig.Roster.List)
 
 	err = updateConfig(cl, signer, chainConfig)
@@ -1226,8 +1877,13 @@ func rosterDel(c *cli.Context) error {
 		return errors.New("cannot delete leader from roster")
 	}
 	log.Lvl2("Old roster is:", old.List)
-	list := append(old.List[0:i], old.List[i+1:]...)
-	chainConfig.Roster = *onet.NewRoster(list)
+	list := append([]*network.ServerIdentity{}, old.List[0:i]...)
+	newRoster := onet.NewRoster(append(list, old.List[i+1:]...))
+	chainConfig.Weights, err = chainConfig.WeightsFor(newRoster, 0)
+	if err != nil {
+		return err
+	}
+	chainConfig.Roster = *newRoster
 	log.Lvl2("New roster is:", chainConfig.Roster.List)
 
 	err = updateConfig(cl, signer, chainConfig)
@@ -1256,9 +1912,15 @@ func rosterLeader(c *cli.Context) error {
 		return errors.New("new node is already leader")
 	}
 	log.Lvl2("Old roster is:", old.List)
-	list := []*network.ServerIdentity(old.List)
+	list := append([]*network.ServerIdentity{}, old.List...)
 	list[0], list[i] = list[i], list[0]
-	chainConfig.Roster = *onet.NewRoster(list)
+	newRoster := onet.NewRoster(list)
+	// The weights follow the nodes to their new position.
+	chainConfig.Weights, err = chainConfig.WeightsFor(newRoster, 0)
+	if err != nil {
+		return err
+	}
+	chainConfig.Roster = *newRoster
 	log.Lvl2("New roster is:", chainConfig.Roster.List)
 
 	// Do it twice to make sure the new roster is active - there is an issue ;)
@@ -1274,6 +1936,155 @@ func rosterLeader(c *cli.Context) error {
 	return nil
 }
 
+func rosterWeight(c *cli.Context) error {
+	if c.Bool("clear") {
+		return rosterClearWeights(c)
+	}
+	if c.NArg() < 3 {
+		return errors.New("please give the following arguments: bc-xxx.cfg key-xxx.cfg public.toml")
+	}
+	weight := c.Uint64("weight")
+	if weight == 0 {
+		return errors.New("--weight flag is required")
+	}
+	_, cl, signer, _, chainConfig, pub, err := getBcKeyPub(c)
+	if err != nil {
+		return err
+	}
+
+	i, _ := chainConfig.Roster.Search(pub.ID)
+	if i < 0 {
+		return errors.New("node is not in roster")
+	}
+	weights := append([]uint64{}, chainConfig.Weights...)
+	if len(weights) == 0 {
+		for range chainConfig.Roster.List {
+			weights = append(weights, 1)
+		}
+	}
+	log.Lvl2("Old weights are:", weights)
+	weights[i] = weight
+	chainConfig.Weights = weights
+	log.Lvl2("New weights are:", chainConfig.Weights)
+
+	err = updateConfig(cl, signer, chainConfig)
+	if err != nil {
+		return err
+	}
+	log.Lvl1("New weights are now active")
+	return nil
+}
+
+func rosterClearWeights(c *cli.Context) error {
+	_, cl, signer, _, chainConfig, err := getBcKey(c)
+	if err != nil {
+		return err
+	}
+	chainConfig.Weights = nil
+	ccBuf, err := protobuf.Encode(&chainConfig)
+	if err != nil {
+		return errors.New("couldn't encode chainConfig: " + err.Error())
+	}
+	err = invokeConfig(cl, signer, "update_config", byzcoin.Arguments{
+		{Name: "config", Value: ccBuf},
+		{Name: "clear_weights", Value: []byte{1}},
+	})
+	if err != nil {
+		return err
+	}
+	log.Lvl1("All the nodes have the same weight now")
+	return nil
+}
+
+func rosterMigrate(c *cli.Context) error {
+	if c.NArg() < 3 {
+		return errors.New("please give the following arguments: bc-xxx.cfg key-xxx.cfg target.toml")
+	}
+	cfg, cl, signer, _, chainConfig, err := getBcKey(c)
+	if err != nil {
+		return err
+	}
+	target, err := lib.ReadRoster(c.Args().Get(2))
+	if err != nil {
+		return err
+	}
+
+	// The plan is always created from the roster that is active on the
+	// chain, so running the command again resumes a failed migration.
+	plan, err := byzcoin.NewRosterMigration(chainConfig.Roster, *target, c.Bool("force"))
+	if err != nil {
+		return err
+	}
+	if len(plan.Steps) == 0 {
+		log.Info("Roster is already the target roster")
+		return nil
+	}
+	for i, step := range plan.Steps {
+		log.Infof("Step %d: %s", i+1, step)
+	}
+	if c.Bool("dry-run") {
+		return nil
+	}
+
+	for i, step := range plan.Steps {
+		log.Infof("Applying step %d/%d: %s", i+1, len(plan.Steps), step)
+		chainConfig.Weights, err = chainConfig.WeightsFor(&step.Roster, c.Uint64("weight"))
+		if err != nil {
+			return fmt.Errorf("step %d failed: %v - use --weight to give one", i+1, err)
+		}
+		chainConfig.Roster = step.Roster
+		err = updateConfig(cl, signer, chainConfig)
+		if err == nil && step.Action == byzcoin.RosterLeader {
+			// Do it twice to make sure the new roster is active, like
+			// for 'roster leader'.
+			err = updateConfig(cl, signer, chainConfig)
+		}
+		if err != nil {
+			return fmt.Errorf("step %d failed, run the command again to resume: %v", i+1, err)
+		}
+		cl.Roster = step.Roster
+
+		if step.Action == byzcoin.RosterAdd {
+			err = waitCatchUp(cl, step.Node, chainConfig.BlockInterval, c.Duration("timeout"))
+			if err != nil {
+				return fmt.Errorf("node added in step %d didn't catch up, run the command "+
+					"again to resume: %v", i+1, err)
+			}
+		}
+	}
+
+	cfg.Roster = *target
+	fn, err := lib.SaveConfig(cfg)
+	if err != nil {
+		return err
+	}
+	log.Info("Roster migration done, updated config file:", fn)
+	return nil
+}
+
+// waitCatchUp waits for the node to have the latest block of the chain.
+func waitCatchUp(cl *byzcoin.Client, node *network.ServerIdentity, interval, timeout time.Duration) error {
+	p, err := cl.GetProof(byzcoin.ConfigInstanceID.Slice())
+	if err != nil {
+		return err
+	}
+	latest := p.Proof.Latest.Index
+	nodeCl := byzcoin.NewClient(cl.ID, *onet.NewRoster([]*network.ServerIdentity{node}))
+	for start := time.Now(); time.Since(start) < timeout; time.Sleep(interval) {
+		p, err = nodeCl.GetProof(byzcoin.ConfigInstanceID.Slice())
+		if err != nil {
+			log.Lvl2("node is not ready yet:", err)
+			continue
+		}
+		if p.Proof.Latest.Index >= latest {
+			log.Lvlf1("Node %s is at block %d", node.Address, p.Proof.Latest.Index)
+			return nil
+		}
+		log.Lvlf2("Node %s is at block %d out of %d", node.Address, p.Proof.Latest.Index, latest)
+	}
+	return fmt.Errorf("timeout while waiting for %s", node.Address)
+}
+
 func key(c *cli.Context) error {
 	if f := c.String("print"); f != "" {
 		sig, err := lib.LoadSigner(f)
@@ -1940,6 +2751,23 @@ func qrcode(c *cli.Context) error {
 	return nil
 }
 
+func runGateway(c *cli.Context) error {
+	bcArg := c.String("bc")
+	if bcArg == "" {
+		return errors.New("--bc flag is required")
+	}
+
+	cfg, _, err := lib.LoadConfig(bcArg)
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(gateway.PathPrefix, gateway.New(&cfg.Roster))
+	log.Infof("Serving the gateway for ByzCoin %x on %s", cfg.ByzCoinID, c.String("listen"))
+	return http.ListenAndServe(c.String("listen"), mux)
+}
+
 type configPrivate struct {
 	Owner darc.Signer
 }
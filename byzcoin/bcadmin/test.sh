@@ -19,6 +19,7 @@ export -n BC
 . "../../libtest.sh"
 . "../clicontracts/value_test.sh"
 . "../clicontracts/deferred_test.sh"
+. "../clicontracts/nft_test.sh"
 
 main(){
     startTest
@@ -28,6 +29,7 @@ main(){
     run testLink
     run testCoin
     run testRoster
+    run testRotation
     run testCreateStoreRead
     run testAddDarc
     run testRuleDarc
@@ -39,6 +41,7 @@ main(){
     run testUpdateDarcDesc
     run testContractValue
     run testContractDeferred
+    run testContractNFT
     stopTest
 }
 
@@ -126,6 +129,36 @@ testRoster(){
   # Change the block size to create a new block before verifying the roster
   testOK runBA config --blockSize 1000000 $bc $key
   testGrep "Roster: tls://localhost:2006" runBA latest -server 2 $bc
+
+  # Once the nodes have different weights, a new node needs a weight
+  testFail runBA roster weight $bc $key co4/public.toml
+  testOK runBA roster weight --weight 3 $bc $key co4/public.toml
+  testFail runBA roster add $bc $key co2/public.toml
+  testOK runBA roster add --weight 2 $bc $key co2/public.toml
+  # The weights follow the nodes when the leader changes
+  testOK runBA roster leader $bc $key co1/public.toml
+  testOK runBA config --blockSize 1000000 $bc $key
+  testGrep "Roster: tls://localhost:2002" runBA latest -server 2 $bc
+  # Back to the same weight for all the nodes
+  testOK runBA roster weight --clear $bc $key
+  testOK runBA config --blockSize 1000000 $bc $key
+}
+
+testRotation(){
+  rm -f config/*
+  runCoBG 1 2 3
+  testOK runBA create public.toml --interval .5s
+  bc=config/bc*cfg
+  key=config/key*cfg
+  testNGrep "Rotation policy" runBA latest $bc
+  testFail runBA config --rotateBlocks -1 $bc $key
+  testOK runBA config --rotateBlocks 100 --rotateInclusion 5 $bc $key
+  testGrep "Rotation policy: every 100 blocks, latency 0s, inclusion 5 blocks" runBA latest $bc
+  # Only the given rules are changed
+  testOK runBA config --rotateLatency 5s $bc $key
+  testGrep "Rotation policy: every 100 blocks, latency 5s, inclusion 5 blocks" runBA latest $bc
+  testOK runBA config --rotateBlocks 0 --rotateLatency 0 --rotateInclusion 0 $bc $key
+  testNGrep "Rotation policy" runBA latest $bc
 }
 
 
@@ -0,0 +1,126 @@
+package byzcoin
+
+import (
+	"errors"
+	"fmt"
+
+	"go.dedis.ch/cothority/v3/skipchain"
+	"go.dedis.ch/onet/v3/log"
+	"go.dedis.ch/onet/v3/network"
+)
+
+// ForwardTx is sent by a follower to the other followers when it receives a
+// transaction, so that they can all check whether the leader includes it. The
+// followers also add it to their buffer, so that the leader collects it even
+// if it didn't get it directly.
+// If the leader censors the transaction, enough followers will notice it to
+// agree on a view-change.
+type ForwardTx struct {
+	SkipchainID skipchain.SkipBlockID
+	Transaction ClientTransaction
+}
+
+// watchTx adds a valid transaction to the watch list of this follower. If
+// forward is true, the transaction is also sent to the other followers. The
+// leader doesn't watch the transactions, as it is the one being watched.
+// Nothing is watched nor forwarded unless the InclusionBlocks rule of the
+// rotation policy of the chain enables the censorship detection.
+func (s *Service) watchTx(scID skipchain.SkipBlockID, latest *skipchain.SkipBlock,
+	tx ClientTransaction, forward bool) error {
+	if latest.Roster.List[0].Equal(s.ServerIdentity()) {
+		return nil
+	}
+
+	st, err := s.getStateTrie(scID)
+	if err != nil {
+		return err
+	}
+	config, err := LoadConfigFromTrie(st)
+	if err != nil {
+		return err
+	}
+	if p := config.RotationPolicy; p == nil || p.InclusionBlocks == 0 {
+		return nil
+	}
+
+	// Only a transaction that could be accepted is expected to be in a
+	// block, invalid ones can be ignored by the leader.
+	if err := checkPaused(st, tx); err != nil {
+		return fmt.Errorf("not watching transaction: %v", err)
+	}
+	txHash := tx.Instructions.Hash()
+	for _, instr := range tx.Instructions {
+		if err := instr.VerifyWithOption(st, txHash, false); err != nil {
+			return fmt.Errorf("not watching invalid transaction: %v", err)
+		}
+	}
+	s.txInclusion.add(scID, txHash, latest.Index)
+
+	if !forward {
+		return nil
+	}
+	msg := &ForwardTx{SkipchainID: scID, Transaction: tx}
+	for _, sid := range latest.Roster.List[1:] {
+		if sid.Equal(s.ServerIdentity()) {
+			continue
+		}
+		go func(id *network.ServerIdentity) {
+			if err := s.SendRaw(id, msg); err != nil {
+				log.Warn(s.ServerIdentity(), "Couldn't forward transaction to", id.Address, err)
+			}
+		}(sid)
+	}
+	return nil
+}
+
+// handleForwardTx should be registered as a handler for ForwardTx messages.
+func (s *Service) handleForwardTx(env *network.Envelope) error {
+	req, ok := env.Msg.(*ForwardTx)
+	if !ok {
+		return fmt.Errorf("%v failed to cast to ForwardTx", s.ServerIdentity())
+	}
+	latest, err := s.db().GetLatestByID(req.SkipchainID)
+	if err != nil {
+		return err
+	}
+	if i, _ := latest.Roster.Search(env.ServerIdentity.ID); i < 0 {
+		return errors.New("forwarded transaction from a node outside of the roster")
+	}
+	if i, _ := latest.Roster.Search(s.ServerIdentity().ID); i < 0 {
+		return errors.New("refusing to watch transaction for a chain we're not part of")
+	}
+	if err := s.watchTx(req.SkipchainID, latest, req.Transaction, false); err != nil {
+		return err
+	}
+	// The transaction might never have been sent to the leader, so it must
+	// be collected from here too. Otherwise a member could have an honest
+	// leader replaced by giving a transaction only to the followers.
+	s.txBuffer.add(string(req.SkipchainID), req.Transaction)
+	return nil
+}
+
+// checkCensorship asks for a view-change for every chain where a watched
+// transaction has not been included in time. The deadline is the same as the
+// one used for missing heartbeats.
+func (s *Service) checkCensorship() {
+	for _, scID := range s.txInclusion.chains() {
+		interval, _, err := s.LoadBlockInfo(scID)
+		if err != nil {
+			log.Error(s.ServerIdentity(), err)
+			continue
+		}
+		if s.txInclusion.expired(scID, interval*s.rotationWindow) == 0 {
+			continue
+		}
+		latest, err := s.db().GetLatestByID(scID)
+		if err != nil {
+			log.Errorf("failed to get the latest block: %v", err)
+			continue
+		}
+		if latest.Roster.List[0].Equal(s.ServerIdentity()) {
+			continue
+		}
+		log.Lvlf2("%s: transaction not included in %x, asking for a view-change", s.ServerIdentity(), scID)
+		s.requestViewChange(scID, latest)
+	}
+}
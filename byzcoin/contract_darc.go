@@ -23,7 +23,7 @@ const ContractDarcID = "darc"
 type contractSecureDarc struct {
 	BasicContract
 	darc.Darc
-	s *Service
+	getContract func(string) (ContractFn, bool)
 }
 
 var _ Contract = (*contractSecureDarc)(nil)
@@ -32,12 +32,22 @@ const cmdDarcEvolveUnrestriction = "evolve_unrestricted"
 const cmdDarcEvolve = "evolve"
 
 func (s *Service) contractSecureDarcFromBytes(in []byte) (Contract, error) {
-	d, err := darc.NewFromProtobuf(in)
-	if err != nil {
-		return nil, err
+	return NewContractSecureDarcFn(s.GetContractConstructor)(in)
+}
+
+// NewContractSecureDarcFn returns the factory of the darc contract, which
+// spawns the instances of other contracts with the factories returned by
+// getContract. The service uses its registered contracts, but the darc
+// contract can also run on its own, for example in contracttest.
+func NewContractSecureDarcFn(getContract func(string) (ContractFn, bool)) ContractFn {
+	return func(in []byte) (Contract, error) {
+		d, err := darc.NewFromProtobuf(in)
+		if err != nil {
+			return nil, err
+		}
+		c := &contractSecureDarc{getContract: getContract, Darc: *d}
+		return c, nil
 	}
-	c := &contractSecureDarc{s: s, Darc: *d}
-	return c, nil
 }
 
 // VerifyDeferredInstruction does the same as the standard VerifyInstruction
@@ -83,7 +93,7 @@ func (c *contractSecureDarc) Spawn(rst ReadOnlyStateTrie, inst Instruction, coin
 	// If we got here this is a spawn:xxx in order to spawn
 	// a new instance of contract xxx, so do that.
 
-	cfact, found := c.s.GetContractConstructor(inst.Spawn.ContractID)
+	cfact, found := c.getContract(inst.Spawn.ContractID)
 	if !found {
 		return nil, nil, errors.New("couldn't find this contract type: " + inst.Spawn.ContractID)
 	}
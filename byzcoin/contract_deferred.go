@@ -5,8 +5,11 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/cothority/v3/darc/expression"
 	"go.dedis.ch/protobuf"
 )
 
@@ -47,6 +50,10 @@ type DeferredData struct {
 	// This array is filled with the instruction IDs of each executed
 	// instruction when a successful "executeProposedTx" happens.
 	ExecResult [][]byte
+	// If not zero, any Invoke on the deferred contract is rejected once the
+	// latest block has a later timestamp, in Unix nanoseconds. This
+	// parameter is optional and can be given with ExpireBlockIndex.
+	ExpireTimestamp int64
 }
 
 // String returns a human readable string representation of the deferred data
@@ -58,6 +65,9 @@ func (dd DeferredData) String() string {
 		out += inst.String()
 	}
 	out += fmt.Sprintf("- Expire Block Index: %d\n", dd.ExpireBlockIndex)
+	if dd.ExpireTimestamp != 0 {
+		out += fmt.Sprintf("- Expire Timestamp: %s\n", time.Unix(0, dd.ExpireTimestamp))
+	}
 	out += fmt.Sprint("- Instruction hashes: \n")
 	for i, hash := range dd.InstructionHashes {
 		out += fmt.Sprintf("-- hash %d:\n", i)
@@ -97,6 +107,7 @@ func (c *contractDeferred) Spawn(rst ReadOnlyStateTrie, inst Instruction, coins
 	// Spawn should have those input arguments:
 	//   - proposedTransaction ClientTransaction
 	//   - expireBlockIndex uint64 (optional)
+	//   - expireTimestamp int64 (optional, Unix nanoseconds)
 	cout = coins
 
 	// Find the darcID for this instance.
@@ -124,6 +135,14 @@ func (c *contractDeferred) Spawn(rst ReadOnlyStateTrie, inst Instruction, coins
 		}
 	}
 
+	var expireTimestamp int64
+	if buf := inst.Spawn.Args.Search("expireTimestamp"); buf != nil {
+		if len(buf) != 8 {
+			return nil, nil, errors.New("expireTimestamp must be 8 bytes")
+		}
+		expireTimestamp = int64(binary.LittleEndian.Uint64(buf))
+	}
+
 	numExecution := defaultNumExecution
 
 	// 2. Computes the hashes of each instruction and store it
@@ -138,6 +157,7 @@ func (c *contractDeferred) Spawn(rst ReadOnlyStateTrie, inst Instruction, coins
 		ExpireBlockIndex:    expireBlockIndex,
 		InstructionHashes:   hash,
 		NumExecution:        numExecution,
+		ExpireTimestamp:     expireTimestamp,
 	}
 	var dataBuf []byte
 	dataBuf, err = protobuf.Encode(&data)
@@ -153,12 +173,18 @@ func (c *contractDeferred) Spawn(rst ReadOnlyStateTrie, inst Instruction, coins
 func (c *contractDeferred) Invoke(rst ReadOnlyStateTrie, inst Instruction, coins []Coin) (sc []StateChange, cout []Coin, err error) {
 	// This method should do the following:
 	//   - Handle the "addProof" invocation
+	//   - Handle the "removeProof" invocation
 	//   - Handle the "execProposedTx" invocation
 	//
 	// Invoke:addProof should have the following input argument:
 	//   - identity darc.Identity
 	//   - signature []byte
 	//   - index uint32 (index of the instruction wrt the transaction)
+	//
+	// Invoke:removeProof should have the following input argument, and be
+	// signed by the identity whose proof is removed:
+	//   - identity darc.Identity
+	//   - index uint32 (index of the instruction wrt the transaction)
 	err = c.checkInvoke(rst, inst.Invoke)
 	if err != nil {
 		return nil, nil, errors.New("checks of invoke failed: " + err.Error())
@@ -219,6 +245,54 @@ func (c *contractDeferred) Invoke(rst ReadOnlyStateTrie, inst Instruction, coins
 		sc = append(sc, NewStateChange(Update, inst.InstanceID,
 			ContractDeferredID, cosiDataBuf, darcID))
 		return
+	case "removeProof":
+		// This invocation withdraws a proof added with "addProof". Only the
+		// identity of the proof can withdraw it.
+		indexBuf := inst.Invoke.Args.Search("index")
+		if len(indexBuf) != 4 {
+			return nil, nil, errors.New("index args is missing or wrong length")
+		}
+		index := binary.LittleEndian.Uint32(indexBuf)
+		numInstruction := len(c.DeferredData.ProposedTransaction.Instructions)
+		if index >= uint32(numInstruction) {
+			return nil, nil, fmt.Errorf("index is out of range (%d >= %d)", index, numInstruction)
+		}
+
+		identity := darc.Identity{}
+		err = protobuf.Decode(inst.Invoke.Args.Search("identity"), &identity)
+		if err != nil {
+			return nil, nil, errors.New("couldn't decode Identity")
+		}
+		signed := false
+		for _, signer := range inst.SignerIdentities {
+			if identity.Equal(&signer) {
+				signed = true
+			}
+		}
+		if !signed {
+			return nil, nil, errors.New("only the identity of the proof can remove it")
+		}
+
+		proposed := &c.DeferredData.ProposedTransaction.Instructions[index]
+		found := -1
+		for i, storedIdentity := range proposed.SignerIdentities {
+			if identity.Equal(&storedIdentity) {
+				found = i
+			}
+		}
+		if found < 0 {
+			return nil, nil, errors.New("identity has no proof for this instruction")
+		}
+		proposed.SignerIdentities = append(proposed.SignerIdentities[:found], proposed.SignerIdentities[found+1:]...)
+		proposed.Signatures = append(proposed.Signatures[:found], proposed.Signatures[found+1:]...)
+
+		dataBuf, err2 := protobuf.Encode(&c.DeferredData)
+		if err2 != nil {
+			return nil, nil, errors.New("couldn't encode DeferredData")
+		}
+		sc = append(sc, NewStateChange(Update, inst.InstanceID,
+			ContractDeferredID, dataBuf, darcID))
+		return
 	case "execProposedTx":
 		// This invocation tries to execute the transaction stored with the
 		// "Spawn" invocation. If it is successful, this invocation fills the
@@ -306,7 +380,7 @@ func (c *contractDeferred) Invoke(rst ReadOnlyStateTrie, inst Instruction, coins
 
 		return
 	default:
-		return nil, nil, errors.New("deferred contract can only addProof and execProposedTx")
+		return nil, nil, errors.New("deferred contract can only addProof, removeProof and execProposedTx")
 	}
 }
 
@@ -332,6 +406,8 @@ func (c *contractDeferred) checkInvoke(rst ReadOnlyStateTrie, invoke *Invoke) er
 	//   1. The NumExecution should be greater than 0
 	//   2. the current skipblock index should be lower than the provided
 	//      "expireBlockIndex" argument.
+	//   3. the timestamp of the latest block should not be after the
+	//      provided "expireTimestamp" argument.
 
 	// 1.
 	if c.DeferredData.NumExecution < uint64(1) {
@@ -345,6 +421,15 @@ func (c *contractDeferred) checkInvoke(rst ReadOnlyStateTrie, invoke *Invoke) er
 		return fmt.Errorf("current block index is too high (%d > %d)", currentIndex, expireBlockIndex)
 	}
 
+	// 3.
+	expired, err := c.timeExpired(rst)
+	if err != nil {
+		return err
+	}
+	if expired {
+		return fmt.Errorf("deferred contract expired at %s", time.Unix(0, c.DeferredData.ExpireTimestamp))
+	}
+
 	if invoke.Command == "addProof" {
 		// We will go through 2 checks:
 		//   1. Check if the identity is already stored
@@ -390,8 +475,18 @@ func (c *contractDeferred) checkInvoke(rst ReadOnlyStateTrie, invoke *Invoke) er
 func (c *contractDeferred) VerifyInstruction(rst ReadOnlyStateTrie, inst Instruction, ctxHash []byte) error {
 	// We make a special case for the delete instruction. Anyone should be able
 	// to delete a deferred contract that has expired.
-	if inst.GetType() == DeleteType && uint64(rst.GetIndex()) >= c.DeferredData.ExpireBlockIndex {
-		return nil
+	if inst.GetType() == DeleteType {
+		expired := uint64(rst.GetIndex()) >= c.DeferredData.ExpireBlockIndex
+		if !expired {
+			var err error
+			expired, err = c.timeExpired(rst)
+			if err != nil {
+				return err
+			}
+		}
+		if expired {
+			return nil
+		}
 	}
 	if err := inst.Verify(rst, ctxHash); err != nil {
 		return err
@@ -399,6 +494,85 @@ func (c *contractDeferred) VerifyInstruction(rst ReadOnlyStateTrie, inst Instruc
 	return nil
 }
 
+// timeExpired returns true if the deferred contract has an expiration time
+// and the latest block is later than it. The timestamp of the latest block is
+// used because the one of the block being created is not known yet. An error
+// is returned if the timestamp is not available, so that the instruction is
+// refused rather than executed after the expiration.
+func (c *contractDeferred) timeExpired(rst ReadOnlyStateTrie) (bool, error) {
+	if c.DeferredData.ExpireTimestamp == 0 {
+		return false, nil
+	}
+	ts, err := getTrieTimestamp(rst)
+	if err != nil {
+		return false, err
+	}
+	return ts > c.DeferredData.ExpireTimestamp, nil
+}
+
+// missingSigners returns the identities of the expression that did not sign
+// yet, following the "_sign" rules of the darcs it delegates to. It returns
+// nil if the signers already satisfy the expression.
+func missingSigners(expr expression.Expr, getDarc darc.GetDarc, signers []string) []string {
+	if darc.EvalExpr(expr, getDarc, signers...) == nil {
+		return nil
+	}
+	signed := make(map[string]bool)
+	for _, s := range signers {
+		signed[s] = true
+	}
+	visited := make(map[string]bool)
+	var missing []string
+	var walk func(expression.Expr)
+	walk = func(e expression.Expr) {
+		// The parser calls the function for every identity of the
+		// expression, so returning false visits all of them.
+		expression.Evaluate(expression.InitParser(func(id string) bool {
+			if visited[id] {
+				return false
+			}
+			visited[id] = true
+			if strings.HasPrefix(id, "darc:") {
+				if d := getDarc(id, true); d != nil {
+					walk(d.Rules.GetSignExpr())
+				}
+				return false
+			}
+			if !signed[id] {
+				missing = append(missing, id)
+			}
+			return false
+		}), e)
+	}
+	walk(expr)
+	return missing
+}
+
+// MissingProofs returns, for every instruction of the proposed transaction,
+// the identities of the rule of the darc that did not add a proof yet. The
+// list of an instruction is empty once its proofs satisfy the rule. The
+// darcs are given by getDarc, and instanceDarc returns the darc controlling
+// an instance.
+func (dd DeferredData) MissingProofs(instanceDarc func(InstanceID) (*darc.Darc, error), getDarc darc.GetDarc) ([][]string, error) {
+	missing := make([][]string, len(dd.ProposedTransaction.Instructions))
+	for i, instr := range dd.ProposedTransaction.Instructions {
+		d, err := instanceDarc(instr.InstanceID)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't get darc of instruction %d: %v", i, err)
+		}
+		action := darc.Action(instr.Action())
+		if !d.Rules.Contains(action) {
+			return nil, fmt.Errorf("action '%v' does not exist", action)
+		}
+		var signers []string
+		for _, id := range instr.SignerIdentities {
+			signers = append(signers, id.String())
+		}
+		missing[i] = missingSigners(d.Rules.Get(action), getDarc, signers)
+	}
+	return missing, nil
+}
+
 // This is a modified version of computing the hash of a transaction. In this
 // version, we do not take into account the signers nor the signers counters. We
 // also add to the hash the instanceID of the deferred contract.
@@ -0,0 +1,269 @@
+package byzcoin
+
+import (
+	"errors"
+	"fmt"
+
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/cothority/v3/skipchain"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/network"
+	"go.dedis.ch/protobuf"
+)
+
+// The foreign chains contract holds a registry of other ByzCoin chains whose
+// proofs are trusted by this chain. Contracts can then verify facts of the
+// foreign chains, e.g. to implement bridges or cross-ledger credentials.
+//
+// Spawn creates an empty registry. The following methods are available:
+//  - add takes the argument "genesis", a protobuf-encoded genesis block of
+//    the foreign chain. The roster of the genesis block is the root of trust
+//    for all proofs of this chain.
+//  - remove takes the argument "genesis_id", the ID of the chain to remove.
+//  - update takes the argument ForeignProofArg, a proof of the foreign chain,
+//    and stores its latest block and roster as the latest known ones.
+
+// ContractForeignChainsID denotes a contract holding the registry of
+// trusted foreign chains.
+const ContractForeignChainsID = "foreignchains"
+
+// A proof of a foreign chain is passed to an instruction with these two
+// arguments: ForeignRegistryArg holds the ID of the registry instance and
+// ForeignProofArg the protobuf-encoded Proof. Use NewForeignProofArgs to
+// create them and VerifyForeignProofArgs to verify them in a contract.
+const (
+	ForeignRegistryArg = "foreign_registry"
+	ForeignProofArg    = "foreign_proof"
+)
+
+// ForeignChain is a chain whose proofs are trusted.
+type ForeignChain struct {
+	// GenesisID is the ID of the foreign chain.
+	GenesisID skipchain.SkipBlockID
+	// GenesisRoster is the roster of the genesis block. The verification of
+	// a proof follows the roster evolution starting from this roster.
+	GenesisRoster onet.Roster
+	// GenesisWeights are the voting weights of the nodes of the genesis
+	// roster, or nil if they all have the same weight.
+	GenesisWeights []uint64 `protobuf:"opt"`
+	// LatestID is the ID of the latest known block.
+	LatestID skipchain.SkipBlockID
+	// LatestIndex is the index of the latest known block. Proofs whose
+	// latest block is older than this are rejected as being stale.
+	LatestIndex int
+	// LatestRoster is the roster of the latest known block.
+	LatestRoster onet.Roster
+}
+
+// ForeignChains is the data stored in a foreign chains registry instance.
+type ForeignChains struct {
+	Chains []ForeignChain
+}
+
+// Get returns the chain with the given genesis ID, or nil if it's not in
+// the registry.
+func (fc ForeignChains) Get(id skipchain.SkipBlockID) *ForeignChain {
+	for i := range fc.Chains {
+		if fc.Chains[i].GenesisID.Equal(id) {
+			return &fc.Chains[i]
+		}
+	}
+	return nil
+}
+
+type contractForeignChains struct {
+	BasicContract
+	ForeignChains
+}
+
+func contractForeignChainsFromBytes(in []byte) (Contract, error) {
+	c := &contractForeignChains{}
+	err := protobuf.DecodeWithConstructors(in, &c.ForeignChains, network.DefaultConstructors(cothority.Suite))
+	if err != nil {
+		return nil, errors.New("couldn't unmarshal instance data: " + err.Error())
+	}
+	return c, nil
+}
+
+func (c *contractForeignChains) Spawn(rst ReadOnlyStateTrie, inst Instruction, coins []Coin) (sc []StateChange, cout []Coin, err error) {
+	cout = coins
+
+	var darcID darc.ID
+	_, _, _, darcID, err = rst.GetValues(inst.InstanceID.Slice())
+	if err != nil {
+		return
+	}
+
+	buf, err := protobuf.Encode(&c.ForeignChains)
+	if err != nil {
+		return
+	}
+	sc = []StateChange{
+		NewStateChange(Create, inst.DeriveID(""), ContractForeignChainsID, buf, darcID),
+	}
+	return
+}
+
+func (c *contractForeignChains) Invoke(rst ReadOnlyStateTrie, inst Instruction, coins []Coin) (sc []StateChange, cout []Coin, err error) {
+	cout = coins
+
+	var darcID darc.ID
+	_, _, _, darcID, err = rst.GetValues(inst.InstanceID.Slice())
+	if err != nil {
+		return
+	}
+
+	switch inst.Invoke.Command {
+	case "add":
+		var genesis skipchain.SkipBlock
+		err = protobuf.DecodeWithConstructors(inst.Invoke.Args.Search("genesis"), &genesis,
+			network.DefaultConstructors(cothority.Suite))
+		if err != nil {
+			return nil, nil, errors.New("couldn't decode genesis block: " + err.Error())
+		}
+		if genesis.Index != 0 || genesis.Roster == nil {
+			return nil, nil, errors.New("not a genesis block")
+		}
+		if !genesis.CalculateHash().Equal(genesis.Hash) {
+			return nil, nil, errors.New("wrong hash of genesis block")
+		}
+		if c.Get(genesis.Hash) != nil {
+			return nil, nil, fmt.Errorf("chain %x is already in the registry", genesis.Hash)
+		}
+		c.Chains = append(c.Chains, ForeignChain{
+			GenesisID:      genesis.Hash,
+			GenesisRoster:  *genesis.Roster,
+			GenesisWeights: genesis.Weights,
+			LatestID:       genesis.Hash,
+			LatestIndex:    0,
+			LatestRoster:   *genesis.Roster,
+		})
+	case "remove":
+		id := skipchain.SkipBlockID(inst.Invoke.Args.Search("genesis_id"))
+		found := false
+		for i, chain := range c.Chains {
+			if chain.GenesisID.Equal(id) {
+				c.Chains = append(c.Chains[:i], c.Chains[i+1:]...)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, nil, fmt.Errorf("chain %x is not in the registry", []byte(id))
+		}
+	case "update":
+		var p *Proof
+		p, err = decodeForeignProof(inst.Invoke.Args.Search(ForeignProofArg))
+		if err != nil {
+			return
+		}
+		var chain *ForeignChain
+		chain, err = c.verify(*p)
+		if err != nil {
+			return
+		}
+		chain.LatestID = p.Latest.Hash
+		chain.LatestIndex = p.Latest.Index
+		chain.LatestRoster = *p.Latest.Roster
+	default:
+		return nil, nil, errors.New("foreign chains contract can only add, remove and update")
+	}
+
+	buf, err := protobuf.Encode(&c.ForeignChains)
+	if err != nil {
+		return
+	}
+	sc = []StateChange{
+		NewStateChange(Update, inst.InstanceID, ContractForeignChainsID, buf, darcID),
+	}
+	return
+}
+
+// verify checks the proof against the registry and returns the foreign
+// chain it belongs to.
+func (fc ForeignChains) verify(p Proof) (*ForeignChain, error) {
+	if len(p.Links) == 0 {
+		return nil, ErrorVerifySkipchain
+	}
+	chain := fc.Get(p.Links[0].To)
+	if chain == nil {
+		return nil, fmt.Errorf("chain %x is not in the registry", []byte(p.Links[0].To))
+	}
+	if err := p.VerifyWithWeights(chain.GenesisID, &chain.GenesisRoster, chain.GenesisWeights); err != nil {
+		return nil, err
+	}
+	if p.Latest.Index < chain.LatestIndex {
+		return nil, fmt.Errorf("proof is older than the latest known block %d", chain.LatestIndex)
+	}
+	if p.Latest.Roster == nil {
+		return nil, errors.New("latest block of the proof has no roster")
+	}
+	return chain, nil
+}
+
+func decodeForeignProof(buf []byte) (*Proof, error) {
+	if buf == nil {
+		return nil, fmt.Errorf("argument \"%s\" is missing", ForeignProofArg)
+	}
+	p := &Proof{}
+	err := protobuf.DecodeWithConstructors(buf, p, network.DefaultConstructors(cothority.Suite))
+	if err != nil {
+		return nil, errors.New("couldn't decode foreign proof: " + err.Error())
+	}
+	return p, nil
+}
+
+// VerifyForeignProof verifies the proof of a foreign chain against the
+// registry instance. The proof must belong to a chain of the registry, its
+// forward links must be signed by the rosters that evolved from the genesis
+// roster, and it must not be older than the latest known block of the chain.
+// Note that it doesn't verify the key/value pair of the proof, the caller
+// still needs to check it.
+func VerifyForeignProof(rst ReadOnlyStateTrie, registry InstanceID, p Proof) (*ForeignChain, error) {
+	v, _, cid, _, err := rst.GetValues(registry.Slice())
+	if err != nil {
+		return nil, err
+	}
+	if cid != ContractForeignChainsID {
+		return nil, errors.New("instance is not a foreign chains registry")
+	}
+	var fc ForeignChains
+	err = protobuf.DecodeWithConstructors(v, &fc, network.DefaultConstructors(cothority.Suite))
+	if err != nil {
+		return nil, errors.New("couldn't decode registry: " + err.Error())
+	}
+	return fc.verify(p)
+}
+
+// VerifyForeignProofArgs reads the registry ID and the foreign proof from
+// the arguments of an instruction and verifies the proof using
+// VerifyForeignProof.
+func VerifyForeignProofArgs(rst ReadOnlyStateTrie, args Arguments) (*Proof, *ForeignChain, error) {
+	registry := args.Search(ForeignRegistryArg)
+	if len(registry) != len(InstanceID{}) {
+		return nil, nil, fmt.Errorf("argument \"%s\" must be an InstanceID", ForeignRegistryArg)
+	}
+	p, err := decodeForeignProof(args.Search(ForeignProofArg))
+	if err != nil {
+		return nil, nil, err
+	}
+	chain, err := VerifyForeignProof(rst, NewInstanceID(registry), *p)
+	if err != nil {
+		return nil, nil, err
+	}
+	return p, chain, nil
+}
+
+// NewForeignProofArgs returns the arguments to pass a proof of a foreign
+// chain to an instruction, to be verified against the given registry.
+func NewForeignProofArgs(registry InstanceID, p Proof) (Arguments, error) {
+	buf, err := protobuf.Encode(&p)
+	if err != nil {
+		return nil, err
+	}
+	return Arguments{
+		{Name: ForeignRegistryArg, Value: registry.Slice()},
+		{Name: ForeignProofArg, Value: buf},
+	}, nil
+}
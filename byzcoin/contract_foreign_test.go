@@ -0,0 +1,116 @@
+package byzcoin
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/protobuf"
+)
+
+func TestContractForeignChains(t *testing.T) {
+	// The foreign chain, with at least one block after the genesis block.
+	s := newSer(t, 1, testInterval)
+	defer s.local.CloseAll()
+
+	resp, err := s.service().GetProof(&GetProof{
+		Version: CurrentVersion,
+		Key:     ConfigInstanceID.Slice(),
+		ID:      s.genesis.SkipChainID(),
+	})
+	require.NoError(t, err)
+	proof := resp.Proof
+	require.True(t, proof.Latest.Index > 0)
+
+	// The local chain holding the registry.
+	st, err := newMemStateTrie([]byte("nonce"))
+	require.NoError(t, err)
+	registry := NewInstanceID([]byte("registry"))
+	buf, err := protobuf.Encode(&ForeignChains{})
+	require.NoError(t, err)
+	require.NoError(t, st.StoreAll(StateChanges{
+		NewStateChange(Create, registry, ContractForeignChainsID, buf, s.darc.GetBaseID()),
+	}, 0))
+
+	invoke := func(cmd string, args Arguments) error {
+		v, _, _, _, err := st.GetValues(registry.Slice())
+		require.NoError(t, err)
+		c, err := contractForeignChainsFromBytes(v)
+		require.NoError(t, err)
+		sc, _, err := c.Invoke(st, Instruction{
+			InstanceID: registry,
+			Invoke: &Invoke{
+				ContractID: ContractForeignChainsID,
+				Command:    cmd,
+				Args:       args,
+			},
+		}, nil)
+		if err != nil {
+			return err
+		}
+		return st.StoreAll(sc, st.GetIndex()+1)
+	}
+
+	// The chain is not yet trusted.
+	_, err = VerifyForeignProof(st, registry, proof)
+	require.Error(t, err)
+
+	// A block that is not a genesis block cannot be added.
+	latestBuf, err := protobuf.Encode(&proof.Latest)
+	require.NoError(t, err)
+	require.Error(t, invoke("add", Arguments{{Name: "genesis", Value: latestBuf}}))
+
+	genesisBuf, err := protobuf.Encode(s.genesis)
+	require.NoError(t, err)
+	require.NoError(t, invoke("add", Arguments{{Name: "genesis", Value: genesisBuf}}))
+	require.Error(t, invoke("add", Arguments{{Name: "genesis", Value: genesisBuf}}))
+
+	chain, err := VerifyForeignProof(st, registry, proof)
+	require.NoError(t, err)
+	require.True(t, chain.GenesisID.Equal(s.genesis.SkipChainID()))
+	require.Equal(t, 0, chain.LatestIndex)
+
+	// The roster given in the proof is not trusted.
+	_, other, _ := s.local.GenTree(4, false)
+	require.Error(t, proof.VerifyWithRoster(s.genesis.SkipChainID(), other))
+	require.NoError(t, proof.VerifyWithRoster(s.genesis.SkipChainID(), s.genesis.Roster))
+
+	// Pass the proof as arguments, like a contract would get it.
+	args, err := NewForeignProofArgs(registry, proof)
+	require.NoError(t, err)
+	p, chain, err := VerifyForeignProofArgs(st, args)
+	require.NoError(t, err)
+	require.Equal(t, proof.Latest.Hash, p.Latest.Hash)
+	_, _, err = VerifyForeignProofArgs(st, args[1:])
+	require.Error(t, err)
+
+	// Track the latest block of the foreign chain.
+	require.NoError(t, invoke("update", args[1:]))
+	chain, err = VerifyForeignProof(st, registry, proof)
+	require.NoError(t, err)
+	require.Equal(t, proof.Latest.Index, chain.LatestIndex)
+	require.True(t, chain.LatestID.Equal(proof.Latest.Hash))
+
+	// Once a newer block is known, older proofs are rejected.
+	tx, err := createOneClientTxWithCounter(s.darc.GetBaseID(), dummyContract, s.value, s.signer, 2)
+	require.NoError(t, err)
+	s.sendTxAndWait(t, tx, 10)
+	resp, err = s.service().GetProof(&GetProof{
+		Version: CurrentVersion,
+		Key:     ConfigInstanceID.Slice(),
+		ID:      s.genesis.SkipChainID(),
+	})
+	require.NoError(t, err)
+	require.True(t, resp.Proof.Latest.Index > proof.Latest.Index)
+	args, err = NewForeignProofArgs(registry, resp.Proof)
+	require.NoError(t, err)
+	require.NoError(t, invoke("update", args[1:]))
+	_, err = VerifyForeignProof(st, registry, proof)
+	require.Error(t, err)
+	_, err = VerifyForeignProof(st, registry, resp.Proof)
+	require.NoError(t, err)
+
+	require.NoError(t, invoke("remove", Arguments{{Name: "genesis_id", Value: s.genesis.SkipChainID()}}))
+	_, err = VerifyForeignProof(st, registry, proof)
+	require.Error(t, err)
+	require.Error(t, invoke("remove", Arguments{{Name: "genesis_id", Value: s.genesis.SkipChainID()}}))
+}
@@ -0,0 +1,223 @@
+package byzcoin
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/protobuf"
+)
+
+// The naming contract gives human-readable names to instances. Names are
+// hierarchical, with the parts separated by "/", like "dedis/alice/wallet".
+// Every name is an instance at NamingInstanceID(name), and its darc controls
+// the name: who can change its target, spawn the names below it and delete
+// it. Use Client.ResolveName to find the instance of a name.
+//
+// Spawning from a darc creates a top-level name, spawning from a name
+// instance creates a name below it. Spawn takes the following arguments:
+//  - name is the last part of the name, made of at most NamingMaxPartLength
+//    lowercase letters, digits, ".", "-" and "_"
+//  - target is the optional instance ID the name points to
+//  - darcID is the optional darc of the name instance, the darc of the spawn
+//    instruction by default
+//
+// The following method is available:
+//  - set changes the target to the instance ID in the argument "target". An
+//    empty argument removes the target.
+//
+// A name can only be deleted once all the names below it are deleted.
+
+// ContractNamingID denotes a contract giving names to instances.
+const ContractNamingID = "naming"
+
+const (
+	// NamingMaxPartLength is the maximum length of a part of a name.
+	NamingMaxPartLength = 64
+	// NamingMaxLength is the maximum length of a name.
+	NamingMaxLength = 256
+)
+
+// NameEntry is the data stored in a name instance.
+type NameEntry struct {
+	// Name is the full name, including the names above it.
+	Name string
+	// Target is the instance the name points to. It is empty if the name
+	// is only used as a namespace.
+	Target []byte
+	// Children is the number of names directly below this one.
+	Children uint64
+}
+
+// NamingInstanceID returns the instance ID of the name.
+func NamingInstanceID(name string) InstanceID {
+	h := sha256.New()
+	h.Write([]byte(ContractNamingID + ":"))
+	h.Write([]byte(name))
+	return NewInstanceID(h.Sum(nil))
+}
+
+// checkNamePart returns an error if the part of a name has wrong characters
+// or length.
+func checkNamePart(part string) error {
+	if len(part) == 0 || len(part) > NamingMaxPartLength {
+		return fmt.Errorf("name must have between 1 and %d characters", NamingMaxPartLength)
+	}
+	for _, r := range part {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+		default:
+			return fmt.Errorf("name cannot contain '%c'", r)
+		}
+	}
+	return nil
+}
+
+// isInstanceID returns true if the string is an instance ID in hex. Such
+// names would be ambiguous in the command line tools, so they are refused.
+func isInstanceID(s string) bool {
+	buf, err := hex.DecodeString(s)
+	return err == nil && len(buf) == len(InstanceID{})
+}
+
+type contractNaming struct {
+	BasicContract
+	NameEntry
+}
+
+func contractNamingFromBytes(in []byte) (Contract, error) {
+	c := &contractNaming{}
+	err := protobuf.Decode(in, &c.NameEntry)
+	if err != nil {
+		return nil, errors.New("couldn't unmarshal instance data: " + err.Error())
+	}
+	return c, nil
+}
+
+func (c *contractNaming) Spawn(rst ReadOnlyStateTrie, inst Instruction, coins []Coin) (sc []StateChange, cout []Coin, err error) {
+	cout = coins
+
+	var darcID darc.ID
+	_, _, _, darcID, err = rst.GetValues(inst.InstanceID.Slice())
+	if err != nil {
+		return
+	}
+	parentDarcID := darcID
+	if did := inst.Spawn.Args.Search("darcID"); did != nil {
+		darcID = darc.ID(did)
+	}
+
+	part := string(inst.Spawn.Args.Search("name"))
+	if err = checkNamePart(part); err != nil {
+		return
+	}
+	entry := NameEntry{Name: part}
+	// The contract holds the parent name if the spawn instruction is sent
+	// to a name instance.
+	if c.Name != "" {
+		entry.Name = c.Name + "/" + part
+	}
+	if len(entry.Name) > NamingMaxLength {
+		err = fmt.Errorf("name is longer than %d characters", NamingMaxLength)
+		return
+	}
+	if isInstanceID(entry.Name) {
+		err = errors.New("name cannot be an instance ID")
+		return
+	}
+	if target := inst.Spawn.Args.Search("target"); len(target) > 0 {
+		if len(target) != len(InstanceID{}) {
+			err = errors.New("argument \"target\" is not an instance ID")
+			return
+		}
+		entry.Target = target
+	}
+
+	buf, err := protobuf.Encode(&entry)
+	if err != nil {
+		return
+	}
+	sc = []StateChange{
+		NewStateChange(Create, NamingInstanceID(entry.Name), ContractNamingID, buf, darcID),
+	}
+	if c.Name != "" {
+		c.Children++
+		buf, err = protobuf.Encode(&c.NameEntry)
+		if err != nil {
+			return
+		}
+		sc = append(sc, NewStateChange(Update, inst.InstanceID, ContractNamingID, buf, parentDarcID))
+	}
+	return
+}
+
+func (c *contractNaming) Invoke(rst ReadOnlyStateTrie, inst Instruction, coins []Coin) (sc []StateChange, cout []Coin, err error) {
+	cout = coins
+
+	var darcID darc.ID
+	_, _, _, darcID, err = rst.GetValues(inst.InstanceID.Slice())
+	if err != nil {
+		return
+	}
+
+	switch inst.Invoke.Command {
+	case "set":
+		target := inst.Invoke.Args.Search("target")
+		if len(target) > 0 && len(target) != len(InstanceID{}) {
+			return nil, nil, errors.New("argument \"target\" is not an instance ID")
+		}
+		c.Target = target
+	default:
+		return nil, nil, errors.New("naming contract can only set")
+	}
+
+	buf, err := protobuf.Encode(&c.NameEntry)
+	if err != nil {
+		return
+	}
+	sc = []StateChange{
+		NewStateChange(Update, inst.InstanceID, ContractNamingID, buf, darcID),
+	}
+	return
+}
+
+func (c *contractNaming) Delete(rst ReadOnlyStateTrie, inst Instruction, coins []Coin) (sc []StateChange, cout []Coin, err error) {
+	cout = coins
+
+	var darcID darc.ID
+	_, _, _, darcID, err = rst.GetValues(inst.InstanceID.Slice())
+	if err != nil {
+		return
+	}
+	if c.Children > 0 {
+		return nil, nil, fmt.Errorf("name %s still has %d names below it", c.Name, c.Children)
+	}
+	sc = []StateChange{
+		NewStateChange(Remove, inst.InstanceID, ContractNamingID, nil, darcID),
+	}
+
+	// The parent counts one name less.
+	i := strings.LastIndex(c.Name, "/")
+	if i < 0 {
+		return
+	}
+	parentID := NamingInstanceID(c.Name[:i])
+	buf, _, _, parentDarcID, err := rst.GetValues(parentID.Slice())
+	if err != nil {
+		return nil, nil, errors.New("couldn't get parent name: " + err.Error())
+	}
+	var parent NameEntry
+	if err = protobuf.Decode(buf, &parent); err != nil {
+		return
+	}
+	parent.Children--
+	buf, err = protobuf.Encode(&parent)
+	if err != nil {
+		return
+	}
+	sc = append(sc, NewStateChange(Update, parentID, ContractNamingID, buf, parentDarcID))
+	return
+}
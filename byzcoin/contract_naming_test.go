@@ -0,0 +1,89 @@
+package byzcoin
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/protobuf"
+)
+
+func TestContractNaming(t *testing.T) {
+	st, err := newMemStateTrie([]byte("nonce"))
+	require.NoError(t, err)
+	darcID := darc.ID(NewInstanceID([]byte("darc")).Slice())
+	require.NoError(t, st.StoreAll(StateChanges{
+		NewStateChange(Create, NewInstanceID(darcID), ContractDarcID, nil, darcID),
+	}, 0))
+	target := NewInstanceID([]byte("target"))
+
+	// run sends the instruction to the contract of its instance, or to the
+	// naming contract if it's a spawn from a darc, and stores the result.
+	run := func(inst Instruction) error {
+		v, _, cid, _, err := st.GetValues(inst.InstanceID.Slice())
+		require.NoError(t, err)
+		if cid != ContractNamingID {
+			v = nil
+		}
+		c, err := contractNamingFromBytes(v)
+		require.NoError(t, err)
+		var sc []StateChange
+		switch inst.GetType() {
+		case SpawnType:
+			sc, _, err = c.Spawn(st, inst, nil)
+		case InvokeType:
+			sc, _, err = c.Invoke(st, inst, nil)
+		case DeleteType:
+			sc, _, err = c.Delete(st, inst, nil)
+		}
+		if err != nil {
+			return err
+		}
+		return st.StoreAll(sc, st.GetIndex()+1)
+	}
+	spawn := func(parent InstanceID, name string, args ...Argument) error {
+		return run(Instruction{
+			InstanceID: parent,
+			Spawn: &Spawn{
+				ContractID: ContractNamingID,
+				Args:       append(Arguments{{Name: "name", Value: []byte(name)}}, args...),
+			},
+		})
+	}
+	entry := func(name string) (e NameEntry) {
+		v, _, _, _, err := st.GetValues(NamingInstanceID(name).Slice())
+		require.NoError(t, err)
+		require.NoError(t, protobuf.Decode(v, &e))
+		return
+	}
+
+	require.Error(t, spawn(NewInstanceID(darcID), "Dedis"))
+	require.Error(t, spawn(NewInstanceID(darcID), "a/b"))
+	require.Error(t, spawn(NewInstanceID(darcID), strings.Repeat("a", NamingMaxPartLength+1)))
+	require.Error(t, spawn(NewInstanceID(darcID), strings.Repeat("ab", 32)))
+	require.NoError(t, spawn(NewInstanceID(darcID), "dedis"))
+	require.Error(t, spawn(NewInstanceID(darcID), "dedis"))
+
+	// Names below a name are spawned from its instance.
+	dedis := NamingInstanceID("dedis")
+	require.NoError(t, spawn(dedis, "alice", Argument{Name: "target", Value: target.Slice()}))
+	require.Equal(t, NameEntry{Name: "dedis/alice", Target: target.Slice()}, entry("dedis/alice"))
+	require.Equal(t, uint64(1), entry("dedis").Children)
+
+	alice := NamingInstanceID("dedis/alice")
+	require.NoError(t, run(Instruction{
+		InstanceID: alice,
+		Invoke:     &Invoke{ContractID: ContractNamingID, Command: "set"},
+	}))
+	require.Empty(t, entry("dedis/alice").Target)
+
+	// A name can only be deleted once it has no names below it.
+	del := func(id InstanceID) error {
+		return run(Instruction{InstanceID: id, Delete: &Delete{ContractID: ContractNamingID}})
+	}
+	require.Error(t, del(dedis))
+	require.NoError(t, del(alice))
+	require.Equal(t, uint64(0), entry("dedis").Children)
+	require.NoError(t, del(dedis))
+}
@@ -1,6 +1,7 @@
 package byzcoin
 
 import (
+	"bytes"
 	"encoding/binary"
 	"errors"
 	"fmt"
@@ -175,6 +176,7 @@ func (c *contractConfig) VerifyDeferredInstruction(rst ReadOnlyStateTrie, inst I
 //   - max_block_size int64
 //   - roster         onet.Roster
 //   - darc_contracts darcContractID
+//   - genesis_options genesisOptions, optional
 func (c *contractConfig) Spawn(rst ReadOnlyStateTrie, inst Instruction, coins []Coin) (sc []StateChange, cout []Coin, err error) {
 	cout = coins
 	darcBuf := inst.Spawn.Args.Search("darc")
@@ -203,10 +205,20 @@ func (c *contractConfig) Spawn(rst ReadOnlyStateTrie, inst Instruction, coins []
 		return
 	}
 
+	var opts genesisOptions
+	if buf := inst.Spawn.Args.Search("genesis_options"); buf != nil {
+		err = protobuf.Decode(buf, &opts)
+		if err != nil {
+			return
+		}
+	}
+
 	// create the config to be stored by state changes
 	c.BlockInterval = time.Duration(interval)
 	c.Roster = roster
 	c.MaxBlockSize = int(maxsz)
+	c.Weights = opts.Weights
+	c.RotationPolicy = opts.RotationPolicy
 	if err = c.sanityCheck(nil); err != nil {
 		return
 	}
@@ -225,24 +237,40 @@ func (c *contractConfig) Spawn(rst ReadOnlyStateTrie, inst Instruction, coins []
 		return
 	}
 
+	instances, err := genesisStateChanges(c.DarcContractIDs, d, opts.Instances)
+	if err != nil {
+		return
+	}
+
 	id := d.GetBaseID()
 	sc = []StateChange{
 		NewStateChange(Create, ConfigInstanceID, ContractConfigID, configBuf, id),
 		NewStateChange(Create, NewInstanceID(id), ContractDarcID, darcBuf, id),
 	}
+	sc = append(sc, instances...)
 	return
 }
 
 // Invoke offers the following functions:
 //   - Invoke:update_config
 //   - Invoke:view_change
+//   - Invoke:remove_equivocator
+//   - Invoke:pause
+//   - Invoke:resume
 //
-// Invoke:update_config should have the following input argument:
-//   - config ChainConfig
+// Invoke:update_config should have the following input arguments:
+//   - config        ChainConfig, whose weights are kept by node if they are not given
+//   - clear_weights optional, if present the nodes all get the same weight again
 //
 // Invoke:view_change sould have the following input arguments:
 //   - newview viewchange.NewViewReq
 //   - multisig []byte
+//
+// Invoke:remove_equivocator should have the following input arguments:
+//   - evidence skipchain.Equivocation, with the blocks from the genesis
+//   - node     network.ServerID in the roster of one of the offenders
+//
+// Invoke:pause and Invoke:resume have no arguments.
 func (c *contractConfig) Invoke(rst ReadOnlyStateTrie, inst Instruction, coins []Coin) (sc []StateChange, cout []Coin, err error) {
 	cout = coins
 
@@ -274,32 +302,37 @@ func (c *contractConfig) Invoke(rst ReadOnlyStateTrie, inst Instruction, coins [
 		if err != nil {
 			return
 		}
-		if err = newConfig.sanityCheck(oldConfig); err != nil {
+		// Without new weights, the nodes keep their weight wherever they
+		// are in the new roster, unless the weights are cleared. A new
+		// node of a weighted chain must be given a weight.
+		clearWeights := inst.Invoke.Args.Search("clear_weights") != nil
+		if clearWeights && len(newConfig.Weights) > 0 {
+			err = errors.New("cannot clear the weights and give new ones")
 			return
 		}
-		var val []byte
-		val, _, _, _, err = rst.GetValues(darcID)
-		if err != nil {
+		if len(newConfig.Weights) == 0 && !clearWeights {
+			newConfig.Weights, err = oldConfig.weightsFor(&newConfig.Roster)
+			if err != nil {
+				return
+			}
+		}
+		if err = newConfig.sanityCheck(oldConfig); err != nil {
 			return
 		}
-		var genesisDarc *darc.Darc
-		genesisDarc, err = darc.NewFromProtobuf(val)
+		// Only pause and resume can change the state of the chain.
+		newConfig.Paused = oldConfig.Paused
+		configBuf, err = protobuf.Encode(&newConfig)
 		if err != nil {
 			return
 		}
-		var rules []string
-		for _, p := range newConfig.Roster.Publics() {
-			rules = append(rules, "ed25519:"+p.String())
-		}
-		genesisDarc.Rules.UpdateRule("invoke:"+ContractConfigID+".view_change", expression.InitOrExpr(rules...))
-		var genesisBuf []byte
-		genesisBuf, err = genesisDarc.ToProto()
+		var darcSc StateChange
+		darcSc, err = updateViewChangeRuleSc(rst, darcID, newConfig.Roster)
 		if err != nil {
 			return
 		}
 		sc = []StateChange{
 			NewStateChange(Update, NewInstanceID(nil), ContractConfigID, configBuf, darcID),
-			NewStateChange(Update, NewInstanceID(darcID), ContractDarcID, genesisBuf, darcID),
+			darcSc,
 		}
 		return
 	case "view_change":
@@ -311,13 +344,137 @@ func (c *contractConfig) Invoke(rst ReadOnlyStateTrie, inst Instruction, coins [
 		// If everything is correctly signed, then we trust it, no need
 		// to do additional verification.
 		sigBuf := inst.Invoke.Args.Search("multisig")
-		err = protocol.BlsSignature(sigBuf).Verify(pairingSuite, req.Hash(), req.Roster.ServicePublics(ServiceName))
+		var config *ChainConfig
+		config, err = LoadConfigFromTrie(rst)
+		if err != nil {
+			return
+		}
+		var weights []uint64
+		weights, err = config.weightsFor(&req.Roster)
+		if err != nil {
+			return
+		}
+		err = protocol.BlsSignature(sigBuf).VerifyWithWeights(pairingSuite, req.Hash(),
+			req.Roster.ServicePublics(ServiceName), weights)
 		if err != nil {
 			return
 		}
 
 		sc, err = updateRosterScs(rst, darcID, req.Roster)
 		return
+	case "remove_equivocator":
+		var e *skipchain.Equivocation
+		e, err = decodeEquivocation(inst.Invoke.Args.Search("evidence"))
+		if err != nil {
+			return
+		}
+		// The signatures of the evidence only count if its first block is
+		// a block of this chain, else anybody could sign two blocks with
+		// a roster of their own.
+		var scID skipchain.SkipBlockID
+		scID, err = getTrieChainID(rst)
+		if err != nil {
+			return
+		}
+		if err = e.VerifyChain(scID); err != nil {
+			return
+		}
+		// A view-change block replaces a block the leader didn't
+		// publish, so honest nodes sign it even if they signed the block
+		// it replaces. The leader could frame them with that block.
+		for _, sb := range []*skipchain.SkipBlock{&e.BlockA, &e.BlockB} {
+			var vc bool
+			vc, err = isViewChangeBlock(sb)
+			if err != nil {
+				return
+			}
+			if vc {
+				err = errors.New("one of the blocks is a view-change block")
+				return
+			}
+		}
+		var offenders []*network.ServerIdentity
+		offenders, err = e.Offenders()
+		if err != nil {
+			return
+		}
+
+		var config *ChainConfig
+		config, err = LoadConfigFromTrie(rst)
+		if err != nil {
+			return
+		}
+		nodeID := inst.Invoke.Args.Search("node")
+		i := -1
+		for j, si := range config.Roster.List {
+			if bytes.Equal(si.ID[:], nodeID) {
+				i = j
+			}
+		}
+		if i < 0 {
+			err = errors.New("the node is not in the roster")
+			return
+		}
+		node := config.Roster.List[i]
+		// The offenders are matched by their key, as the rest of the
+		// identity of a node can change.
+		public := node.ServicePublic(skipchain.ServiceName)
+		signed := false
+		for _, si := range offenders {
+			if si.ServicePublic(skipchain.ServiceName).Equal(public) {
+				signed = true
+			}
+		}
+		if !signed {
+			err = errors.New("the node didn't sign both blocks")
+			return
+		}
+		list := append([]*network.ServerIdentity{}, config.Roster.List[:i]...)
+		newRoster := onet.NewRoster(append(list, config.Roster.List[i+1:]...))
+		newConfig := *config
+		newConfig.Roster = *newRoster
+		newConfig.Weights, err = config.weightsFor(newRoster)
+		if err != nil {
+			return
+		}
+		if err = newConfig.sanityCheck(config); err != nil {
+			return
+		}
+
+		sc, err = updateRosterScs(rst, darcID, *newRoster)
+		if err != nil {
+			return
+		}
+		var darcSc StateChange
+		darcSc, err = updateViewChangeRuleSc(rst, darcID, *newRoster)
+		if err != nil {
+			return
+		}
+		log.Lvlf2("removing equivocator %s from the roster", node)
+		sc = append(sc, darcSc)
+		return
+	case "pause", "resume":
+		var config *ChainConfig
+		config, err = LoadConfigFromTrie(rst)
+		if err != nil {
+			return
+		}
+		paused := inst.Invoke.Command == "pause"
+		if config.Paused == paused {
+			err = errors.New("the chain is already in this state")
+			return
+		}
+		config.Paused = paused
+		var configBuf []byte
+		configBuf, err = protobuf.Encode(config)
+		if err != nil {
+			return
+		}
+		log.Lvlf2("%s the chain", inst.Invoke.Command)
+		sc = []StateChange{
+			NewStateChange(Update, NewInstanceID(nil), ContractConfigID, configBuf, darcID),
+		}
+		return
 	default:
 		err = errors.New("invalid invoke command: " + inst.Invoke.Command)
 		return
@@ -329,6 +486,11 @@ func updateRosterScs(rst ReadOnlyStateTrie, darcID darc.ID, newRoster onet.Roste
 	if err != nil {
 		return nil, err
 	}
+	// The weights follow the nodes to their new position in the roster.
+	config.Weights, err = config.weightsFor(&newRoster)
+	if err != nil {
+		return nil, err
+	}
 	config.Roster = newRoster
 	configBuf, err := protobuf.Encode(config)
 	if err != nil {
@@ -340,6 +502,29 @@ func updateRosterScs(rst ReadOnlyStateTrie, darcID darc.ID, newRoster onet.Roste
 	}, nil
 }
 
+// updateViewChangeRuleSc returns the state change allowing only the nodes of
+// the roster to invoke a view-change.
+func updateViewChangeRuleSc(rst ReadOnlyStateTrie, darcID darc.ID, roster onet.Roster) (StateChange, error) {
+	val, _, _, _, err := rst.GetValues(darcID)
+	if err != nil {
+		return StateChange{}, err
+	}
+	genesisDarc, err := darc.NewFromProtobuf(val)
+	if err != nil {
+		return StateChange{}, err
+	}
+	var rules []string
+	for _, p := range roster.Publics() {
+		rules = append(rules, "ed25519:"+p.String())
+	}
+	genesisDarc.Rules.UpdateRule("invoke:"+ContractConfigID+".view_change", expression.InitOrExpr(rules...))
+	genesisBuf, err := genesisDarc.ToProto()
+	if err != nil {
+		return StateChange{}, err
+	}
+	return NewStateChange(Update, NewInstanceID(darcID), ContractDarcID, genesisBuf, darcID), nil
+}
+
 // LoadConfigFromTrie loads the configuration data from the trie.
 func LoadConfigFromTrie(st ReadOnlyStateTrie) (*ChainConfig, error) {
 	// Find the genesis-darc ID.
@@ -368,7 +553,7 @@ func GetValueContract(st ReadOnlyStateTrie, key []byte) (value []byte, version u
 		return
 	}
 	if value == nil {
-		err = errKeyNotSet
+		err = ErrKeyNotSet
 		return
 	}
 	return
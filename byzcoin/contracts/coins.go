@@ -4,6 +4,7 @@ import (
 	"crypto/sha256"
 	"encoding/binary"
 	"errors"
+	"fmt"
 
 	"go.dedis.ch/cothority/v3/byzcoin"
 	"go.dedis.ch/cothority/v3/darc"
@@ -23,11 +24,15 @@ var CoinName = iid("byzCoin")
 // of 0 coins.
 // The following methods are available:
 //  - mint will add the number of coins in the argument "coins" to the
-//    current coin instance. The argument must be a 64-bit uint in LittleEndian
+//    current coin instance. The argument must be a 64-bit uint in LittleEndian.
+//    Coins of a ContractToken cannot be minted here, but only by the token
+//    instance.
 //  - transfer will send the coins given in the argument "coins" to the
 //    instance given in the argument "destination". The "coins"-argument must
 //    be a 64-bit uint in LittleEndian. The "destination" must be a 64-bit
-//    instanceID
+//    instanceID. Instead of these two arguments, the argument "destinations"
+//    can hold a protobuf encoded CoinTransfers to send coins to multiple
+//    instances. Either all the transfers of the list succeed, or none.
 //  - fetch takes "coins" out of the account and returns it as an output
 //    parameter for the next instruction to interpret.
 //  - store puts the coins given to the instance back into the account.
@@ -42,6 +47,18 @@ func contractCoinFromBytes(in []byte) (byzcoin.Contract, error) {
 	return c, nil
 }
 
+// CoinTransfer is one destination of a transfer to multiple instances.
+type CoinTransfer struct {
+	Destination byzcoin.InstanceID
+	Coins       uint64
+}
+
+// CoinTransfers is the "destinations" argument of a transfer to multiple
+// instances.
+type CoinTransfers struct {
+	List []CoinTransfer
+}
+
 type contractCoin struct {
 	byzcoin.BasicContract
 	byzcoin.Coin
@@ -104,8 +121,10 @@ func (c *contractCoin) Invoke(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instru
 	}
 
 	// Invoke is one of "mint", "transfer", "fetch", or "store".
+	destinations := inst.Invoke.Args.Search("destinations")
+	batch := inst.Invoke.Command == "transfer" && destinations != nil
 	var coinsArg uint64
-	if inst.Invoke.Command != "store" {
+	if inst.Invoke.Command != "store" && !batch {
 		coinsBuf := inst.Invoke.Args.Search("coins")
 		if coinsBuf == nil {
 			err = errors.New("argument \"coins\" is missing")
@@ -120,6 +139,12 @@ func (c *contractCoin) Invoke(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instru
 
 	switch inst.Invoke.Command {
 	case "mint":
+		// The coins of a token can only be minted by the token instance,
+		// so that its supply is kept up to date.
+		if isToken(rst, c.Name) {
+			err = errors.New("coins of a token must be minted by the token instance")
+			return
+		}
 		// mint simply adds this amount of coins to the account.
 		log.Lvl2("minting", coinsArg)
 		err = c.SafeAdd(coinsArg)
@@ -127,6 +152,13 @@ func (c *contractCoin) Invoke(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instru
 			return
 		}
 	case "transfer":
+		if batch {
+			sc, err = c.transferMulti(rst, inst.InstanceID, destinations)
+			if err != nil {
+				return
+			}
+			break
+		}
 		// transfer sends a given amount of coins to another account.
 		target := inst.Invoke.Args.Search("destination")
 		var (
@@ -220,6 +252,123 @@ func (c *contractCoin) Delete(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instru
 	return
 }
 
+// transferMulti sends coins to all the destinations of the encoded
+// CoinTransfers. Every destination must be a coin instance holding the same
+// type of coins, controlled by a valid darc, so that no coins are lost.
+func (c *contractCoin) transferMulti(rst byzcoin.ReadOnlyStateTrie, source byzcoin.InstanceID,
+	buf []byte) (sc []byzcoin.StateChange, err error) {
+	var transfers CoinTransfers
+	err = protobuf.Decode(buf, &transfers)
+	if err != nil {
+		return nil, errors.New("couldn't unmarshal destinations: " + err.Error())
+	}
+	if len(transfers.List) == 0 {
+		return nil, errors.New("no destinations")
+	}
+
+	// A destination can appear more than once, so the accounts are updated
+	// in memory and only stored once all the transfers are done.
+	accounts := make(map[byzcoin.InstanceID]*byzcoin.Coin)
+	darcIDs := make(map[byzcoin.InstanceID]darc.ID)
+	var order []byzcoin.InstanceID
+	for _, t := range transfers.List {
+		if t.Destination.Equal(source) {
+			return nil, errors.New("cannot send coins to ourselves")
+		}
+		account, ok := accounts[t.Destination]
+		if !ok {
+			v, _, cid, did, err := rst.GetValues(t.Destination.Slice())
+			if err == nil && cid != ContractCoinID {
+				err = fmt.Errorf("destination %x is not a coin contract", t.Destination.Slice())
+			}
+			if err != nil {
+				return nil, err
+			}
+			if _, err = byzcoin.LoadDarcFromTrie(rst, did); err != nil {
+				return nil, fmt.Errorf("darc of destination %x: %v", t.Destination.Slice(), err)
+			}
+			account = &byzcoin.Coin{}
+			err = protobuf.Decode(v, account)
+			if err != nil {
+				return nil, errors.New("couldn't unmarshal target account: " + err.Error())
+			}
+			if !account.Name.Equal(c.Name) {
+				return nil, fmt.Errorf("destination %x holds a different type of coins", t.Destination.Slice())
+			}
+			accounts[t.Destination] = account
+			darcIDs[t.Destination] = did
+			order = append(order, t.Destination)
+		}
+		if err = c.SafeSub(t.Coins); err != nil {
+			return nil, err
+		}
+		if err = account.SafeAdd(t.Coins); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, id := range order {
+		targetBuf, err := protobuf.Encode(accounts[id])
+		if err != nil {
+			return nil, errors.New("couldn't marshal target account: " + err.Error())
+		}
+		sc = append(sc, byzcoin.NewStateChange(byzcoin.Update, id, ContractCoinID, targetBuf, darcIDs[id]))
+	}
+	log.Lvlf2("transferring to %d accounts", len(order))
+	return sc, nil
+}
+
+// lockCoins sums up the coins with the same name as the first one, for a
+// contract locking them until they are sent to a coin instance. The other
+// coins are returned to be passed on to the next instruction.
+func lockCoins(coins []byzcoin.Coin) (locked byzcoin.Coin, rest []byzcoin.Coin, err error) {
+	if len(coins) == 0 {
+		err = errors.New("no coins to lock")
+		return
+	}
+	locked.Name = coins[0].Name
+	for _, co := range coins {
+		if locked.Name.Equal(co.Name) {
+			err = locked.SafeAdd(co.Value)
+			if err != nil {
+				return
+			}
+		} else {
+			rest = append(rest, co)
+		}
+	}
+	if locked.Value == 0 {
+		err = errors.New("no coins to lock")
+	}
+	return
+}
+
+// getCoinAccount returns the coin instance with the given ID, making sure it
+// holds coins with the given name, so that locked coins can be sent to it.
+func getCoinAccount(rst byzcoin.ReadOnlyStateTrie, id, name byzcoin.InstanceID) (account byzcoin.Coin, darcID darc.ID, err error) {
+	var (
+		v   []byte
+		cid string
+	)
+	v, _, cid, darcID, err = rst.GetValues(id.Slice())
+	if err != nil {
+		return
+	}
+	if cid != ContractCoinID {
+		err = fmt.Errorf("%x is not a coin instance", id.Slice())
+		return
+	}
+	err = protobuf.Decode(v, &account)
+	if err != nil {
+		err = errors.New("couldn't unmarshal account: " + err.Error())
+		return
+	}
+	if !account.Name.Equal(name) {
+		err = fmt.Errorf("%x holds a different type of coins", id.Slice())
+	}
+	return
+}
+
 // iid uses sha256(in) in order to manufacture an InstanceID from in
 // thereby handling the case where len(in) != 32.
 //
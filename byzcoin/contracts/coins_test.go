@@ -7,11 +7,15 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3"
 	"go.dedis.ch/cothority/v3/byzcoin"
 	"go.dedis.ch/cothority/v3/byzcoin/trie"
 	"go.dedis.ch/cothority/v3/darc"
 	"go.dedis.ch/cothority/v3/darc/expression"
+	"go.dedis.ch/kyber/v3/util/key"
+	"go.dedis.ch/onet/v3"
 	"go.dedis.ch/onet/v3/log"
+	"go.dedis.ch/onet/v3/network"
 	"go.dedis.ch/protobuf"
 )
 
@@ -259,6 +263,47 @@ func TestCoin_InvokeTransfer(t *testing.T) {
 	require.Equal(t, byzcoin.NewStateChange(byzcoin.Update, coAddr1, ContractCoinID, ciZero, gdarc.GetBaseID()), sc[1])
 }
 
+func TestCoin_InvokeTransferMulti(t *testing.T) {
+	ct := newCT("invoke:transfer")
+	// The darcs of the destinations are looked up with the darc contracts
+	// of the config.
+	ct.storeConfig()
+
+	source, dst1, dst2, noDarc := iid("source"), iid("dst1"), iid("dst2"), iid("noDarc")
+	ct.Store(source, ciTwo, ContractCoinID, gdarc.GetBaseID())
+	ct.Store(dst1, ciZero, ContractCoinID, gdarc.GetBaseID())
+	ct.Store(dst2, ciZero, ContractCoinID, gdarc.GetBaseID())
+	ct.Store(noDarc, ciZero, ContractCoinID, darc.ID(iid("unknown").Slice()))
+
+	transfer := func(list ...CoinTransfer) ([]byzcoin.StateChange, error) {
+		buf, err := protobuf.Encode(&CoinTransfers{List: list})
+		require.NoError(t, err)
+		sc, _, err := ct.getContract(source).Invoke(ct, byzcoin.Instruction{
+			InstanceID: source,
+			Invoke: &byzcoin.Invoke{
+				Command: "transfer",
+				Args:    byzcoin.Arguments{{Name: "destinations", Value: buf}},
+			},
+		}, nil)
+		return sc, err
+	}
+
+	// Nothing is transferred if one of the transfers fails.
+	_, err := transfer(CoinTransfer{dst1, 1}, CoinTransfer{dst2, 2})
+	require.Error(t, err)
+	_, err = transfer(CoinTransfer{dst1, 1}, CoinTransfer{noDarc, 1})
+	require.Error(t, err)
+	_, err = transfer(CoinTransfer{source, 1})
+	require.Error(t, err)
+
+	sc, err := transfer(CoinTransfer{dst1, 1}, CoinTransfer{dst2, 0}, CoinTransfer{dst1, 1})
+	require.NoError(t, err)
+	require.Equal(t, 3, len(sc))
+	require.Equal(t, byzcoin.NewStateChange(byzcoin.Update, dst1, ContractCoinID, ciTwo, gdarc.GetBaseID()), sc[0])
+	require.Equal(t, byzcoin.NewStateChange(byzcoin.Update, dst2, ContractCoinID, ciZero, gdarc.GetBaseID()), sc[1])
+	require.Equal(t, byzcoin.NewStateChange(byzcoin.Update, source, ContractCoinID, ciZero, gdarc.GetBaseID()), sc[2])
+}
+
 type cvTest struct {
 	values      map[string][]byte
 	contractIDs map[string]string
@@ -330,6 +375,19 @@ func (ct cvTest) setSignatureCounter(id string, v uint64) {
 	ct.darcIDs[string(key[:])] = darc.ID([]byte{})
 }
 
+// storeConfig stores a config, so that darcs can be loaded with
+// byzcoin.LoadDarcFromTrie.
+func (ct *cvTest) storeConfig() {
+	kp := key.NewKeyPair(cothority.Suite)
+	roster := onet.NewRoster([]*network.ServerIdentity{network.NewServerIdentity(kp.Public, "tls://127.0.0.1:2000")})
+	configBuf, err := protobuf.Encode(&byzcoin.ChainConfig{
+		Roster:          *roster,
+		DarcContractIDs: []string{byzcoin.ContractDarcID},
+	})
+	log.ErrFatal(err)
+	ct.Store(byzcoin.ConfigInstanceID, configBuf, byzcoin.ContractConfigID, gdarc.GetBaseID())
+}
+
 func (ct cvTest) getContract(i byzcoin.InstanceID) byzcoin.Contract {
 	c, err := contractCoinFromBytes(ct.values[string(i.Slice())])
 	if err != nil {
@@ -13,6 +13,7 @@ import (
 	"go.dedis.ch/protobuf"
 
 	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/cothority/v3/darc/expression"
 
 	"go.dedis.ch/cothority/v3"
 	"go.dedis.ch/onet/v3"
@@ -2276,3 +2277,150 @@ func TestDeferred_ScenarioUpdateConfig(t *testing.T) {
 
 	local.WaitDone(genesisMsg.BlockInterval)
 }
+
+func TestDeferred_PendingAndRemoveProof(t *testing.T) {
+	// Two identities must sign the proposed spawn of a value. We check the
+	// missing identities while one of them adds and withdraws its proof, and
+	// that the expired deferred contracts are not pending anymore.
+
+	// ------------------------------------------------------------------------
+	// 0. Set up
+	// ------------------------------------------------------------------------
+	local := onet.NewTCPTest(cothority.Suite)
+	defer local.CloseAll()
+
+	signer1 := darc.NewSignerEd25519(nil, nil)
+	signer2 := darc.NewSignerEd25519(nil, nil)
+	_, roster, _ := local.GenTree(3, true)
+
+	genesisMsg, err := byzcoin.DefaultGenesisMsg(byzcoin.CurrentVersion, roster,
+		[]string{"spawn:value", "spawn:deferred", "invoke:deferred.addProof",
+			"invoke:deferred.removeProof"}, signer1.Identity(), signer2.Identity())
+	require.NoError(t, err)
+	gDarc := &genesisMsg.GenesisDarc
+	anyExpr := expression.InitOrExpr(signer1.Identity().String(), signer2.Identity().String())
+	for _, rule := range []darc.Action{"spawn:deferred", "invoke:deferred.addProof", "invoke:deferred.removeProof"} {
+		require.NoError(t, gDarc.Rules.UpdateRule(rule, anyExpr))
+	}
+
+	genesisMsg.BlockInterval = time.Second
+
+	cl, _, err := byzcoin.NewLedger(genesisMsg, false)
+	require.NoError(t, err)
+
+	counter := uint64(0)
+	send := func(inst byzcoin.Instruction) error {
+		counter++
+		inst.SignerCounter = []uint64{counter}
+		ctx := byzcoin.ClientTransaction{Instructions: []byzcoin.Instruction{inst}}
+		require.NoError(t, ctx.FillSignersAndSignWith(signer1))
+		_, err := cl.AddTransactionAndWait(ctx, 10)
+		if err != nil {
+			counter--
+		}
+		return err
+	}
+
+	// ------------------------------------------------------------------------
+	// 1. Spawn a deferred contract and an expired one
+	// ------------------------------------------------------------------------
+	proposedTransactionBuf, err := protobuf.Encode(&byzcoin.ClientTransaction{
+		Instructions: []byzcoin.Instruction{{
+			InstanceID: byzcoin.NewInstanceID(gDarc.GetBaseID()),
+			Spawn: &byzcoin.Spawn{
+				ContractID: "value",
+				Args:       byzcoin.Arguments{{Name: "value", Value: []byte("value")}},
+			},
+		}},
+	})
+	require.NoError(t, err)
+
+	spawn := func(expire time.Time) byzcoin.InstanceID {
+		expireBuf := make([]byte, 8)
+		binary.LittleEndian.PutUint64(expireBuf, uint64(expire.UnixNano()))
+		inst := byzcoin.Instruction{
+			InstanceID: byzcoin.NewInstanceID(gDarc.GetBaseID()),
+			Spawn: &byzcoin.Spawn{
+				ContractID: byzcoin.ContractDeferredID,
+				Args: byzcoin.Arguments{
+					{Name: "proposedTransaction", Value: proposedTransactionBuf},
+					{Name: "expireTimestamp", Value: expireBuf},
+				},
+			},
+		}
+		require.NoError(t, send(inst))
+		return byzcoin.NewInstanceID(inst.DeriveID("").Slice())
+	}
+	myID := spawn(time.Now().Add(time.Hour))
+	expiredID := spawn(time.Now().Add(-time.Hour))
+
+	id1 := signer1.Identity().String()
+	id2 := signer2.Identity().String()
+	missing, err := cl.GetDeferredMissing(myID)
+	require.NoError(t, err)
+	require.Equal(t, [][]string{{id1, id2}}, missing)
+
+	reply, err := cl.GetDeferred(nil, id2)
+	require.NoError(t, err)
+	require.Len(t, reply.Deferred, 1)
+	require.Equal(t, myID, reply.Deferred[0].InstanceID)
+	require.Equal(t, []byzcoin.MissingSigners{{Identities: []string{id1, id2}}}, reply.Deferred[0].Missing)
+	reply, err = cl.GetDeferred(gDarc.GetBaseID(), "")
+	require.NoError(t, err)
+	require.Len(t, reply.Deferred, 1)
+	reply, err = cl.GetDeferred(nil, darc.NewSignerEd25519(nil, nil).Identity().String())
+	require.NoError(t, err)
+	require.Empty(t, reply.Deferred)
+
+	// ------------------------------------------------------------------------
+	// 2. Add and remove a proof
+	// ------------------------------------------------------------------------
+	pr, err := cl.GetProof(myID.Slice())
+	require.NoError(t, err)
+	dataBuf, _, _, err := pr.Proof.Get(myID.Slice())
+	require.NoError(t, err)
+	var dd byzcoin.DeferredData
+	require.NoError(t, protobuf.Decode(dataBuf, &dd))
+
+	indexBuf := make([]byte, 4)
+	identityArg := func(s darc.Signer) byzcoin.Argument {
+		identity := s.Identity()
+		buf, err := protobuf.Encode(&identity)
+		require.NoError(t, err)
+		return byzcoin.Argument{Name: "identity", Value: buf}
+	}
+	invoke := func(id byzcoin.InstanceID, command string, args ...byzcoin.Argument) byzcoin.Instruction {
+		return byzcoin.Instruction{
+			InstanceID: id,
+			Invoke: &byzcoin.Invoke{
+				ContractID: byzcoin.ContractDeferredID,
+				Command:    command,
+				Args:       append(args, byzcoin.Argument{Name: "index", Value: indexBuf}),
+			},
+		}
+	}
+	signature, err := signer1.Sign(dd.InstructionHashes[0])
+	require.NoError(t, err)
+	addProof := func(id byzcoin.InstanceID) byzcoin.Instruction {
+		return invoke(id, "addProof", identityArg(signer1),
+			byzcoin.Argument{Name: "signature", Value: signature})
+	}
+
+	require.Error(t, send(addProof(expiredID)))
+	require.NoError(t, send(addProof(myID)))
+	missing, err = cl.GetDeferredMissing(myID)
+	require.NoError(t, err)
+	require.Equal(t, [][]string{{id2}}, missing)
+	reply, err = cl.GetDeferred(nil, id1)
+	require.NoError(t, err)
+	require.Empty(t, reply.Deferred)
+
+	// Only the identity of a proof can remove it.
+	require.Error(t, send(invoke(myID, "removeProof", identityArg(signer2))))
+	require.NoError(t, send(invoke(myID, "removeProof", identityArg(signer1))))
+	missing, err = cl.GetDeferredMissing(myID)
+	require.NoError(t, err)
+	require.Equal(t, [][]string{{id1, id2}}, missing)
+
+	local.WaitDone(genesisMsg.BlockInterval)
+}
@@ -0,0 +1,304 @@
+package contracts
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/onet/v3/log"
+	"go.dedis.ch/protobuf"
+)
+
+// ContractEscrowID denotes a contract that locks coins until a condition is
+// met.
+const ContractEscrowID = "escrow"
+
+// ContractEscrow locks coins and gives them either to a beneficiary or back
+// to the payer. Contrary to the htlc contract, the release depends on an
+// arbiter or on the state of another instance.
+//
+// Spawn locks all the coins given as input to the instruction, typically
+// fetched from a coin instance in the previous instruction. Coins with a
+// different name than the first one are passed on to the next instruction.
+// It takes the following arguments:
+//  - beneficiary is the coin instance receiving the coins on release
+//  - refund is the coin instance receiving the coins on refund
+//  - expireBlockIndex is the block index, as a 64-bit uint in LittleEndian,
+//    from which the coins can be refunded and not released anymore
+//  - arbiter is the optional darc ID that can release or refund the coins at
+//    any time, using its "invoke:escrow.release" and "invoke:escrow.refund"
+//    rules
+//  - conditionInstance is an optional instance ID that allows the release
+//    of the coins once it holds the value in "conditionValue", or once its
+//    version is at least "conditionVersion", as a 64-bit uint in
+//    LittleEndian
+//  - darcID is the darc of the escrow instance. Optional, the darc of the
+//    spawn instruction by default
+//
+// The following methods are available:
+//  - release gives the coins to the beneficiary, if the arbiter signed it or
+//    the condition is met
+//  - refund gives the coins back to the refund instance, if the arbiter
+//    signed it or the escrow expired
+//
+// An instance can only be deleted once it is settled.
+
+// Escrow is the data stored in an escrow instance.
+type Escrow struct {
+	// Coin holds the locked coins. Its value is 0 once the escrow is
+	// settled.
+	Coin byzcoin.Coin
+	// Beneficiary is the coin instance receiving the coins on release.
+	Beneficiary byzcoin.InstanceID
+	// Refund is the coin instance receiving the coins on refund.
+	Refund byzcoin.InstanceID
+	// ExpireBlockIndex is the first block index where the coins can only be
+	// refunded.
+	ExpireBlockIndex uint64
+	// Arbiter is the darc that can release and refund at any time, if it is
+	// set.
+	Arbiter darc.ID
+	// Condition releases the coins without the arbiter, if it is set.
+	Condition *EscrowCondition `protobuf:"opt"`
+	// Released is set once the coins have been sent to the beneficiary.
+	Released bool
+}
+
+// EscrowCondition is met when the instance holds the value, if it is set,
+// and its version is at least the given version.
+type EscrowCondition struct {
+	InstanceID byzcoin.InstanceID
+	Value      []byte
+	Version    uint64
+}
+
+// Settled returns true if the coins have been released or refunded.
+func (e Escrow) Settled() bool {
+	return e.Coin.Value == 0
+}
+
+func contractEscrowFromBytes(in []byte) (byzcoin.Contract, error) {
+	c := &contractEscrow{}
+	err := protobuf.Decode(in, &c.Escrow)
+	if err != nil {
+		return nil, errors.New("couldn't unmarshal instance data: " + err.Error())
+	}
+	return c, nil
+}
+
+type contractEscrow struct {
+	byzcoin.BasicContract
+	Escrow
+	// arbitrated is set by VerifyInstruction if the arbiter signed the
+	// instruction.
+	arbitrated bool
+}
+
+// VerifyInstruction accepts the release and refund signed by the arbiter,
+// and else falls back to the darc of the instance.
+func (c *contractEscrow) VerifyInstruction(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, msg []byte) error {
+	if len(c.Arbiter) > 0 && inst.Invoke != nil {
+		d, err := byzcoin.LoadDarcFromTrie(rst, c.Arbiter)
+		if err != nil {
+			return errors.New("couldn't load arbiter darc: " + err.Error())
+		}
+		if inst.VerifyWithDarc(rst, msg, d) == nil {
+			c.arbitrated = true
+			return nil
+		}
+	}
+	return inst.Verify(rst, msg)
+}
+
+func (c *contractEscrow) Spawn(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, coins []byzcoin.Coin) (sc []byzcoin.StateChange, cout []byzcoin.Coin, err error) {
+	cout = coins
+
+	var darcID darc.ID
+	_, _, _, darcID, err = rst.GetValues(inst.InstanceID.Slice())
+	if err != nil {
+		return
+	}
+	if did := inst.Spawn.Args.Search("darcID"); did != nil {
+		darcID = darc.ID(did)
+	}
+
+	args := inst.Spawn.Args
+	c.Coin, cout, err = lockCoins(coins)
+	if err != nil {
+		return
+	}
+
+	for _, dst := range []struct {
+		name string
+		id   *byzcoin.InstanceID
+	}{{"beneficiary", &c.Beneficiary}, {"refund", &c.Refund}} {
+		buf := args.Search(dst.name)
+		if len(buf) != len(byzcoin.InstanceID{}) {
+			err = fmt.Errorf("argument \"%s\" must be an InstanceID", dst.name)
+			return
+		}
+		*dst.id = byzcoin.NewInstanceID(buf)
+		if _, _, err = getCoinAccount(rst, *dst.id, c.Coin.Name); err != nil {
+			return
+		}
+	}
+
+	expireBuf := args.Search("expireBlockIndex")
+	if len(expireBuf) != 8 {
+		err = errors.New("argument \"expireBlockIndex\" is missing or wrong length")
+		return
+	}
+	c.ExpireBlockIndex = binary.LittleEndian.Uint64(expireBuf)
+	if c.ExpireBlockIndex <= uint64(rst.GetIndex()) {
+		err = errors.New("escrow would already be expired")
+		return
+	}
+
+	if arbiter := args.Search("arbiter"); arbiter != nil {
+		if _, err = byzcoin.LoadDarcFromTrie(rst, arbiter); err != nil {
+			err = errors.New("invalid arbiter darc: " + err.Error())
+			return
+		}
+		c.Arbiter = darc.ID(arbiter)
+	}
+	if cid := args.Search("conditionInstance"); cid != nil {
+		c.Condition = &EscrowCondition{
+			InstanceID: byzcoin.NewInstanceID(cid),
+			Value:      args.Search("conditionValue"),
+		}
+		if buf := args.Search("conditionVersion"); buf != nil {
+			if len(buf) != 8 {
+				err = errors.New("argument \"conditionVersion\" is wrong length")
+				return
+			}
+			c.Condition.Version = binary.LittleEndian.Uint64(buf)
+		}
+		if len(c.Condition.Value) == 0 && c.Condition.Version == 0 {
+			err = errors.New("condition needs a value or a version")
+			return
+		}
+	}
+	if len(c.Arbiter) == 0 && c.Condition == nil {
+		err = errors.New("escrow needs an arbiter or a condition")
+		return
+	}
+
+	var buf []byte
+	buf, err = protobuf.Encode(&c.Escrow)
+	if err != nil {
+		return nil, nil, errors.New("couldn't encode escrow: " + err.Error())
+	}
+	id := inst.DeriveID("")
+	log.Lvlf2("Locking %d coins in escrow %x", c.Coin.Value, id.Slice())
+	sc = []byzcoin.StateChange{
+		byzcoin.NewStateChange(byzcoin.Create, id, ContractEscrowID, buf, darcID),
+	}
+	return
+}
+
+func (c *contractEscrow) Invoke(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, coins []byzcoin.Coin) (sc []byzcoin.StateChange, cout []byzcoin.Coin, err error) {
+	cout = coins
+
+	var darcID darc.ID
+	_, _, _, darcID, err = rst.GetValues(inst.InstanceID.Slice())
+	if err != nil {
+		return
+	}
+
+	if c.Settled() {
+		err = errors.New("escrow is already settled")
+		return
+	}
+
+	expired := uint64(rst.GetIndex()) >= c.ExpireBlockIndex
+	var target byzcoin.InstanceID
+	switch inst.Invoke.Command {
+	case "release":
+		if expired {
+			err = errors.New("escrow expired and can only be refunded")
+			return
+		}
+		if !c.arbitrated {
+			if err = c.checkCondition(rst); err != nil {
+				return
+			}
+		}
+		c.Released = true
+		target = c.Beneficiary
+	case "refund":
+		if !expired && !c.arbitrated {
+			err = fmt.Errorf("escrow can only be refunded from block %d", c.ExpireBlockIndex)
+			return
+		}
+		target = c.Refund
+	default:
+		err = errors.New("escrow contract can only release and refund")
+		return
+	}
+
+	account, did, err := getCoinAccount(rst, target, c.Coin.Name)
+	if err != nil {
+		return
+	}
+	err = account.SafeAdd(c.Coin.Value)
+	if err != nil {
+		return
+	}
+	accountBuf, err := protobuf.Encode(&account)
+	if err != nil {
+		return nil, nil, errors.New("couldn't marshal target account: " + err.Error())
+	}
+	log.Lvlf2("escrow %x: sending %d coins to %x", inst.InstanceID.Slice(), c.Coin.Value, target.Slice())
+	c.Coin.Value = 0
+
+	buf, err := protobuf.Encode(&c.Escrow)
+	if err != nil {
+		return nil, nil, errors.New("couldn't encode escrow: " + err.Error())
+	}
+	sc = []byzcoin.StateChange{
+		byzcoin.NewStateChange(byzcoin.Update, target, ContractCoinID, accountBuf, did),
+		byzcoin.NewStateChange(byzcoin.Update, inst.InstanceID, ContractEscrowID, buf, darcID),
+	}
+	return
+}
+
+func (c *contractEscrow) Delete(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, coins []byzcoin.Coin) (sc []byzcoin.StateChange, cout []byzcoin.Coin, err error) {
+	cout = coins
+
+	var darcID darc.ID
+	_, _, _, darcID, err = rst.GetValues(inst.InstanceID.Slice())
+	if err != nil {
+		return
+	}
+
+	if !c.Settled() {
+		err = errors.New("cannot delete an escrow that still holds coins")
+		return
+	}
+	sc = byzcoin.StateChanges{
+		byzcoin.NewStateChange(byzcoin.Remove, inst.InstanceID, ContractEscrowID, nil, darcID),
+	}
+	return
+}
+
+// checkCondition returns an error if the condition is not set or not met.
+func (c *contractEscrow) checkCondition(rst byzcoin.ReadOnlyStateTrie) error {
+	cond := c.Condition
+	if cond == nil {
+		return errors.New("only the arbiter can release the coins")
+	}
+	v, version, _, _, err := rst.GetValues(cond.InstanceID.Slice())
+	if err != nil {
+		return errors.New("condition instance is not available: " + err.Error())
+	}
+	if len(cond.Value) > 0 && !bytes.Equal(v, cond.Value) {
+		return errors.New("condition instance doesn't hold the expected value")
+	}
+	if version < cond.Version {
+		return fmt.Errorf("condition instance is at version %d instead of %d", version, cond.Version)
+	}
+	return nil
+}
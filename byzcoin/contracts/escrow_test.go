@@ -0,0 +1,113 @@
+package contracts
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/protobuf"
+)
+
+func TestEscrow(t *testing.T) {
+	// The genesis darc is the arbiter.
+	ct := newCT("invoke:escrow.release", "invoke:escrow.refund")
+	ct.storeConfig()
+	beneficiary := iid("beneficiary")
+	refund := iid("refund")
+	cond := iid("condition")
+	ct.Store(beneficiary, ciZero, ContractCoinID, gdarc.GetBaseID())
+	ct.Store(refund, ciZero, ContractCoinID, gdarc.GetBaseID())
+	ct.Store(cond, []byte("pending"), ContractValueID, gdarc.GetBaseID())
+	ct.index = 0
+
+	expire := make([]byte, 8)
+	binary.LittleEndian.PutUint64(expire, 10)
+	args := byzcoin.Arguments{
+		{Name: "beneficiary", Value: beneficiary.Slice()},
+		{Name: "refund", Value: refund.Slice()},
+		{Name: "expireBlockIndex", Value: expire},
+	}
+	spawn := func(args byzcoin.Arguments) ([]byzcoin.StateChange, error) {
+		c, err := contractEscrowFromBytes(nil)
+		require.NoError(t, err)
+		sc, _, err := c.Spawn(ct, byzcoin.Instruction{
+			InstanceID: byzcoin.NewInstanceID(gdarc.GetBaseID()),
+			Spawn: &byzcoin.Spawn{
+				ContractID: ContractEscrowID,
+				Args:       args,
+			},
+		}, []byzcoin.Coin{{Name: CoinName, Value: 3}})
+		return sc, err
+	}
+
+	// An escrow without arbiter nor condition could only be refunded.
+	_, err := spawn(args)
+	require.Error(t, err)
+	sc, err := spawn(append(args,
+		byzcoin.Argument{Name: "arbiter", Value: gdarc.GetBaseID()},
+		byzcoin.Argument{Name: "conditionInstance", Value: cond.Slice()},
+		byzcoin.Argument{Name: "conditionValue", Value: []byte("done")}))
+	require.NoError(t, err)
+	escrowBuf := sc[0].Value
+	id := iid("escrow")
+
+	ct.Store(id, escrowBuf, ContractEscrowID, darc.ID(iid("owner").Slice()))
+	invoke := func(cmd string, arbiter bool) ([]byzcoin.StateChange, error) {
+		c, err := contractEscrowFromBytes(escrowBuf)
+		require.NoError(t, err)
+		inst := byzcoin.Instruction{
+			InstanceID: id,
+			Invoke: &byzcoin.Invoke{
+				ContractID: ContractEscrowID,
+				Command:    cmd,
+			},
+		}
+		if arbiter {
+			ct.setSignatureCounter(gsigner.Identity().String(), 0)
+			inst.SignerIdentities = []darc.Identity{gsigner.Identity()}
+			inst.SignerCounter = []uint64{1}
+			require.NoError(t, inst.SignWith([]byte("ctx"), gsigner))
+			require.NoError(t, c.VerifyInstruction(ct, inst, []byte("ctx")))
+		}
+		sc, _, err := c.Invoke(ct, inst, nil)
+		return sc, err
+	}
+
+	// The condition is not met and the escrow didn't expire.
+	ct.index = 5
+	_, err = invoke("release", false)
+	require.Error(t, err)
+	_, err = invoke("refund", false)
+	require.Error(t, err)
+
+	ct.Store(cond, []byte("done"), ContractValueID, gdarc.GetBaseID())
+	ct.index = 5
+	sc, err = invoke("release", false)
+	require.NoError(t, err)
+	var coin byzcoin.Coin
+	require.NoError(t, protobuf.Decode(sc[0].Value, &coin))
+	require.Equal(t, beneficiary.Slice(), sc[0].InstanceID)
+	require.Equal(t, uint64(3), coin.Value)
+	var e Escrow
+	require.NoError(t, protobuf.Decode(sc[1].Value, &e))
+	require.True(t, e.Settled())
+	require.True(t, e.Released)
+
+	// The arbiter can refund before the expiry.
+	ct.index = 5
+	sc, err = invoke("refund", true)
+	require.NoError(t, err)
+	require.Equal(t, refund.Slice(), sc[0].InstanceID)
+
+	// Once expired, the coins can only be refunded.
+	ct.index = 10
+	_, err = invoke("release", false)
+	require.Error(t, err)
+	sc, err = invoke("refund", false)
+	require.NoError(t, err)
+	require.NoError(t, protobuf.Decode(sc[1].Value, &e))
+	require.True(t, e.Settled())
+	require.False(t, e.Released)
+}
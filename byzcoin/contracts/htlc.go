@@ -0,0 +1,223 @@
+package contracts
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/onet/v3/log"
+	"go.dedis.ch/protobuf"
+)
+
+// ContractHTLCID denotes a contract that locks coins under a hash and a
+// timeout. It is the building block of atomic swaps between two ledgers.
+const ContractHTLCID = "htlc"
+
+// ContractHTLC is a hash-time-locked contract. It locks coins until either
+// the preimage of a hash is revealed, or a given block index is reached.
+//
+// Spawn locks all the coins given as input to the instruction, typically
+// fetched from a coin instance in the previous instruction. Coins with a
+// different name than the first one are passed on to the next instruction.
+// It takes the following arguments:
+//  - hash is the sha256 hash of the secret preimage
+//  - timeout is the block index, as a 64-bit uint in LittleEndian, from which
+//    the coins can be refunded
+//  - recipient is the coin instance that receives the coins on claim
+//  - refund is the coin instance that receives the coins on refund
+//
+// The following methods are available:
+//  - claim takes the argument "preimage" and sends the locked coins to the
+//    recipient, if the block index is below the timeout. The preimage is
+//    stored in the instance, so that the counterpart of a swap can read it.
+//  - refund sends the locked coins back to the refund instance, if the
+//    block index reached the timeout.
+//
+// Because the destinations of the coins are fixed when spawning the
+// instance, the rules for claim and refund of the darc can be given to both
+// parties of a swap. An instance can only be deleted once it is settled.
+
+func contractHTLCFromBytes(in []byte) (byzcoin.Contract, error) {
+	c := &contractHTLC{}
+	err := protobuf.Decode(in, &c.HTLC)
+	if err != nil {
+		return nil, errors.New("couldn't unmarshal instance data: " + err.Error())
+	}
+	return c, nil
+}
+
+// HTLC is the data stored in a htlc instance.
+type HTLC struct {
+	// Hash is the sha256 hash of the preimage that unlocks the coins.
+	Hash []byte
+	// Timeout is the block index from which the coins can be refunded.
+	Timeout uint64
+	// Recipient is the coin instance that gets the coins on claim.
+	Recipient byzcoin.InstanceID
+	// Refund is the coin instance that gets the coins back after the timeout.
+	Refund byzcoin.InstanceID
+	// Coin holds the locked coins. Its value is 0 once the HTLC is settled.
+	Coin byzcoin.Coin
+	// Preimage is set once the coins have been claimed.
+	Preimage []byte
+}
+
+// Settled returns true if the coins have been claimed or refunded.
+func (h HTLC) Settled() bool {
+	return h.Coin.Value == 0
+}
+
+// Claimed returns true if the coins have been sent to the recipient.
+func (h HTLC) Claimed() bool {
+	return len(h.Preimage) > 0
+}
+
+type contractHTLC struct {
+	byzcoin.BasicContract
+	HTLC
+}
+
+func (c *contractHTLC) Spawn(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, coins []byzcoin.Coin) (sc []byzcoin.StateChange, cout []byzcoin.Coin, err error) {
+	var darcID darc.ID
+	_, _, _, darcID, err = rst.GetValues(inst.InstanceID.Slice())
+	if err != nil {
+		return
+	}
+
+	c.Hash = inst.Spawn.Args.Search("hash")
+	if len(c.Hash) != sha256.Size {
+		err = errors.New("argument \"hash\" must be a sha256 hash")
+		return
+	}
+	timeoutBuf := inst.Spawn.Args.Search("timeout")
+	if len(timeoutBuf) != 8 {
+		err = errors.New("argument \"timeout\" is missing or wrong length")
+		return
+	}
+	c.Timeout = binary.LittleEndian.Uint64(timeoutBuf)
+	if c.Timeout <= uint64(rst.GetIndex()) {
+		err = errors.New("timeout is already reached")
+		return
+	}
+
+	c.Coin, cout, err = lockCoins(coins)
+	if err != nil {
+		return
+	}
+
+	for _, dst := range []struct {
+		name string
+		id   *byzcoin.InstanceID
+	}{{"recipient", &c.Recipient}, {"refund", &c.Refund}} {
+		buf := inst.Spawn.Args.Search(dst.name)
+		if len(buf) != len(byzcoin.InstanceID{}) {
+			err = fmt.Errorf("argument \"%s\" must be an InstanceID", dst.name)
+			return
+		}
+		*dst.id = byzcoin.NewInstanceID(buf)
+		if _, _, err = getCoinAccount(rst, *dst.id, c.Coin.Name); err != nil {
+			return
+		}
+	}
+
+	var buf []byte
+	buf, err = protobuf.Encode(&c.HTLC)
+	if err != nil {
+		return nil, nil, errors.New("couldn't encode htlc: " + err.Error())
+	}
+	id := inst.DeriveID("")
+	log.Lvlf2("Locking %d coins in htlc %x until block %d", c.Coin.Value, id.Slice(), c.Timeout)
+	sc = []byzcoin.StateChange{
+		byzcoin.NewStateChange(byzcoin.Create, id, ContractHTLCID, buf, darcID),
+	}
+	return
+}
+
+func (c *contractHTLC) Invoke(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, coins []byzcoin.Coin) (sc []byzcoin.StateChange, cout []byzcoin.Coin, err error) {
+	cout = coins
+
+	var darcID darc.ID
+	_, _, _, darcID, err = rst.GetValues(inst.InstanceID.Slice())
+	if err != nil {
+		return
+	}
+
+	if c.Settled() {
+		err = errors.New("htlc is already settled")
+		return
+	}
+
+	var target byzcoin.InstanceID
+	switch inst.Invoke.Command {
+	case "claim":
+		if uint64(rst.GetIndex()) >= c.Timeout {
+			err = errors.New("timeout reached, coins can only be refunded")
+			return
+		}
+		preimage := inst.Invoke.Args.Search("preimage")
+		h := sha256.Sum256(preimage)
+		if !bytes.Equal(h[:], c.Hash) {
+			err = errors.New("wrong preimage")
+			return
+		}
+		c.Preimage = preimage
+		target = c.Recipient
+	case "refund":
+		if uint64(rst.GetIndex()) < c.Timeout {
+			err = errors.New("timeout not reached yet")
+			return
+		}
+		target = c.Refund
+	default:
+		err = errors.New("htlc contract can only claim and refund")
+		return
+	}
+
+	account, did, err := getCoinAccount(rst, target, c.Coin.Name)
+	if err != nil {
+		return
+	}
+	err = account.SafeAdd(c.Coin.Value)
+	if err != nil {
+		return
+	}
+	accountBuf, err := protobuf.Encode(&account)
+	if err != nil {
+		return nil, nil, errors.New("couldn't marshal target account: " + err.Error())
+	}
+	log.Lvlf2("htlc %x: sending %d coins to %x", inst.InstanceID.Slice(), c.Coin.Value, target.Slice())
+	c.Coin.Value = 0
+
+	buf, err := protobuf.Encode(&c.HTLC)
+	if err != nil {
+		return nil, nil, errors.New("couldn't encode htlc: " + err.Error())
+	}
+	sc = []byzcoin.StateChange{
+		byzcoin.NewStateChange(byzcoin.Update, target, ContractCoinID, accountBuf, did),
+		byzcoin.NewStateChange(byzcoin.Update, inst.InstanceID, ContractHTLCID, buf, darcID),
+	}
+	return
+}
+
+func (c *contractHTLC) Delete(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, coins []byzcoin.Coin) (sc []byzcoin.StateChange, cout []byzcoin.Coin, err error) {
+	cout = coins
+
+	var darcID darc.ID
+	_, _, _, darcID, err = rst.GetValues(inst.InstanceID.Slice())
+	if err != nil {
+		return
+	}
+
+	if !c.Settled() {
+		err = errors.New("cannot delete a htlc that still holds coins")
+		return
+	}
+	sc = byzcoin.StateChanges{
+		byzcoin.NewStateChange(byzcoin.Remove, inst.InstanceID, ContractHTLCID, nil, darcID),
+	}
+	return
+}
@@ -0,0 +1,310 @@
+package contracts
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/cothority/v3/skipchain"
+	"go.dedis.ch/onet/v3/log"
+	"go.dedis.ch/protobuf"
+)
+
+// swapWait is the number of block intervals to wait for the inclusion of
+// the transactions of a swap.
+const swapWait = 10
+
+// HTLCLedger describes one side of an atomic swap: the coins that are sent
+// on one ledger and who receives them.
+type HTLCLedger struct {
+	// Client is connected to the ledger.
+	Client *byzcoin.Client
+	// Signer signs all the instructions on this ledger. It must be allowed
+	// to fetch coins from From, and to spawn, claim and refund htlc
+	// instances with Darc.
+	Signer darc.Signer
+	// Darc is the darc that guards the htlc instance.
+	Darc darc.ID
+	// From is the coin instance the coins are taken from and refunded to.
+	From byzcoin.InstanceID
+	// To is the coin instance of the counterpart.
+	To byzcoin.InstanceID
+	// Amount is the number of coins sent to the counterpart.
+	Amount uint64
+	// Timeout is the number of blocks, counted from the latest block, after
+	// which the coins can be refunded.
+	Timeout uint64
+}
+
+// HTLCLock holds the htlc instance created by a Lock and the proof of its
+// existence on the ledger.
+type HTLCLock struct {
+	ID    byzcoin.InstanceID
+	Proof byzcoin.Proof
+}
+
+// Lock locks the coins of the ledger in a new htlc instance, that can be
+// claimed with the preimage of the given hash. It waits for the instance to
+// be included in the ledger and returns a proof of its existence.
+func (l HTLCLedger) Lock(hash []byte) (*HTLCLock, error) {
+	p, err := l.Client.GetProof(byzcoin.ConfigInstanceID.Slice())
+	if err != nil {
+		return nil, err
+	}
+	timeout := make([]byte, 8)
+	binary.LittleEndian.PutUint64(timeout, uint64(p.Proof.Latest.Index)+l.Timeout)
+	amount := make([]byte, 8)
+	binary.LittleEndian.PutUint64(amount, l.Amount)
+
+	counters, err := l.Client.GetSignerCounters(l.Signer.Identity().String())
+	if err != nil {
+		return nil, err
+	}
+	ctx := byzcoin.ClientTransaction{
+		Instructions: byzcoin.Instructions{
+			{
+				InstanceID: l.From,
+				Invoke: &byzcoin.Invoke{
+					ContractID: ContractCoinID,
+					Command:    "fetch",
+					Args:       byzcoin.Arguments{{Name: "coins", Value: amount}},
+				},
+				SignerCounter: []uint64{counters.Counters[0] + 1},
+			},
+			{
+				InstanceID: byzcoin.NewInstanceID(l.Darc),
+				Spawn: &byzcoin.Spawn{
+					ContractID: ContractHTLCID,
+					Args: byzcoin.Arguments{
+						{Name: "hash", Value: hash},
+						{Name: "timeout", Value: timeout},
+						{Name: "recipient", Value: l.To.Slice()},
+						{Name: "refund", Value: l.From.Slice()},
+					},
+				},
+				SignerCounter: []uint64{counters.Counters[0] + 2},
+			},
+		},
+	}
+	if err = ctx.FillSignersAndSignWith(l.Signer); err != nil {
+		return nil, err
+	}
+	if _, err = l.Client.AddTransactionAndWait(ctx, swapWait); err != nil {
+		return nil, err
+	}
+
+	lock := &HTLCLock{ID: ctx.Instructions[1].DeriveID("")}
+	p, err = l.Client.GetProof(lock.ID.Slice())
+	if err != nil {
+		return nil, err
+	}
+	if !p.Proof.InclusionProof.Match(lock.ID.Slice()) {
+		return nil, errors.New("htlc has not been created")
+	}
+	lock.Proof = p.Proof
+	return lock, nil
+}
+
+// Claim sends the coins of the htlc instance to its recipient, revealing the
+// preimage on the ledger.
+func (l HTLCLedger) Claim(id byzcoin.InstanceID, preimage []byte) error {
+	return l.invoke(id, "claim", byzcoin.Arguments{{Name: "preimage", Value: preimage}})
+}
+
+// Refund sends the coins of the htlc instance back to its refund instance.
+// It only succeeds once the timeout of the instance has been reached.
+func (l HTLCLedger) Refund(id byzcoin.InstanceID) error {
+	return l.invoke(id, "refund", nil)
+}
+
+// blockInterval returns the block interval of the ledger.
+func (l HTLCLedger) blockInterval() (time.Duration, error) {
+	config, err := l.Client.GetChainConfig()
+	if err != nil {
+		return 0, err
+	}
+	return config.BlockInterval, nil
+}
+
+// GetHTLC returns the current state of the htlc instance.
+func (l HTLCLedger) GetHTLC(id byzcoin.InstanceID) (*HTLC, error) {
+	p, err := l.Client.GetProof(id.Slice())
+	if err != nil {
+		return nil, err
+	}
+	return decodeHTLCProof(p.Proof, id)
+}
+
+func (l HTLCLedger) invoke(id byzcoin.InstanceID, command string, args byzcoin.Arguments) error {
+	counters, err := l.Client.GetSignerCounters(l.Signer.Identity().String())
+	if err != nil {
+		return err
+	}
+	ctx := byzcoin.ClientTransaction{
+		Instructions: byzcoin.Instructions{{
+			InstanceID: id,
+			Invoke: &byzcoin.Invoke{
+				ContractID: ContractHTLCID,
+				Command:    command,
+				Args:       args,
+			},
+			SignerCounter: []uint64{counters.Counters[0] + 1},
+		}},
+	}
+	if err = ctx.FillSignersAndSignWith(l.Signer); err != nil {
+		return err
+	}
+	_, err = l.Client.AddTransactionAndWait(ctx, swapWait)
+	return err
+}
+
+// VerifyHTLCLock verifies, using only the proof given by the counterpart,
+// that the htlc instance with the given ID exists on the ledger with the
+// given genesis ID, and that it locks at least the given amount of coins
+// for the recipient, under the given hash and until at least minTimeout.
+func VerifyHTLCLock(scID skipchain.SkipBlockID, lock HTLCLock, hash []byte,
+	recipient byzcoin.InstanceID, amount uint64, minTimeout uint64) (*HTLC, error) {
+	if err := lock.Proof.Verify(scID); err != nil {
+		return nil, err
+	}
+	h, err := decodeHTLCProof(lock.Proof, lock.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(h.Hash, hash) {
+		return nil, errors.New("htlc is locked with a different hash")
+	}
+	if !h.Recipient.Equal(recipient) {
+		return nil, errors.New("htlc has a different recipient")
+	}
+	if h.Settled() {
+		return nil, errors.New("htlc is already settled")
+	}
+	if h.Coin.Value < amount {
+		return nil, fmt.Errorf("htlc only locks %d coins instead of %d", h.Coin.Value, amount)
+	}
+	if h.Timeout < minTimeout {
+		return nil, fmt.Errorf("htlc times out at block %d, before %d", h.Timeout, minTimeout)
+	}
+	return h, nil
+}
+
+func decodeHTLCProof(p byzcoin.Proof, id byzcoin.InstanceID) (*HTLC, error) {
+	if !p.InclusionProof.Match(id.Slice()) {
+		return nil, errors.New("htlc instance does not exist")
+	}
+	v, cid, _, err := p.Get(id.Slice())
+	if err != nil {
+		return nil, err
+	}
+	if cid != ContractHTLCID {
+		return nil, errors.New("instance is not a htlc")
+	}
+	h := &HTLC{}
+	if err = protobuf.Decode(v, h); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// AtomicSwap swaps the coins of a and b, two ledgers run by different
+// rosters. a is the initiator: it creates the secret and its coins are
+// locked first, so its timeout must be long enough for b to lock its coins,
+// and for the preimage to be read from b's ledger after it is revealed. The
+// swap is refused unless a.Timeout lasts longer than b.Timeout plus swapWait
+// blocks of a's ledger, and b.Timeout is longer than swapWait blocks.
+//
+// The swap goes as follows, each side verifying the lock of the other side
+// through a proof of the other ledger:
+//  1. a locks its coins for a.To, under the hash of a random preimage
+//  2. b verifies the lock of a and locks its coins for b.To
+//  3. a verifies the lock of b and claims the coins on b's ledger, revealing
+//     the preimage
+//  4. the preimage is read from b's ledger and used to claim the coins on
+//     a's ledger
+//
+// If any step fails, the coins that were locked can be refunded after the
+// timeout using Refund, with the IDs of the returned locks.
+func AtomicSwap(a, b HTLCLedger) (lockA, lockB *HTLCLock, err error) {
+	preimage := make([]byte, 32)
+	if _, err = rand.Read(preimage); err != nil {
+		return
+	}
+	hash := sha256.Sum256(preimage)
+
+	// The ledgers can have different block intervals, so the timeouts are
+	// compared in time. The preimage is revealed on b's ledger at the
+	// latest when the lock of b times out, and the lock of a must leave
+	// enough time to use it.
+	intervalA, err := a.blockInterval()
+	if err != nil {
+		return
+	}
+	intervalB, err := b.blockInterval()
+	if err != nil {
+		return
+	}
+	if b.Timeout <= swapWait {
+		err = fmt.Errorf("the timeout of the participant must be longer than %d blocks", swapWait)
+		return
+	}
+	minA := time.Duration(b.Timeout)*intervalB + swapWait*intervalA
+	if time.Duration(a.Timeout)*intervalA <= minA {
+		err = fmt.Errorf("the timeout of the initiator must be longer than %s", minA)
+		return
+	}
+
+	log.Lvl2("Locking coins on the ledger of the initiator")
+	lockA, err = a.Lock(hash[:])
+	if err != nil {
+		return
+	}
+	_, err = VerifyHTLCLock(a.Client.ID, *lockA, hash[:], a.To, a.Amount,
+		uint64(lockA.Proof.Latest.Index)+uint64(minA/intervalA))
+	if err != nil {
+		return
+	}
+
+	log.Lvl2("Locking coins on the ledger of the participant")
+	lockB, err = b.Lock(hash[:])
+	if err != nil {
+		return
+	}
+	_, err = VerifyHTLCLock(b.Client.ID, *lockB, hash[:], b.To, b.Amount,
+		uint64(lockB.Proof.Latest.Index)+swapWait)
+	if err != nil {
+		return
+	}
+
+	log.Lvl2("Claiming coins on the ledger of the participant")
+	if err = b.Claim(lockB.ID, preimage); err != nil {
+		return
+	}
+	hB, err := b.GetHTLC(lockB.ID)
+	if err != nil {
+		return
+	}
+	if !hB.Claimed() {
+		err = errors.New("coins on the participant's ledger have not been claimed")
+		return
+	}
+
+	log.Lvl2("Claiming coins on the ledger of the initiator")
+	if err = a.Claim(lockA.ID, hB.Preimage); err != nil {
+		return
+	}
+	hA, err := a.GetHTLC(lockA.ID)
+	if err != nil {
+		return
+	}
+	if !hA.Claimed() {
+		err = errors.New("coins on the initiator's ledger have not been claimed")
+	}
+	return
+}
@@ -0,0 +1,255 @@
+package contracts
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/protobuf"
+)
+
+func TestHTLC_Spawn(t *testing.T) {
+	ct := newCT("spawn:htlc")
+	recipient := iid("recipient")
+	refund := iid("refund")
+	ct.Store(recipient, ciZero, ContractCoinID, gdarc.GetBaseID())
+	ct.Store(refund, ciZero, ContractCoinID, gdarc.GetBaseID())
+
+	hash := sha256.Sum256([]byte("secret"))
+	timeout := make([]byte, 8)
+	binary.LittleEndian.PutUint64(timeout, 10)
+	args := byzcoin.Arguments{
+		{Name: "hash", Value: hash[:]},
+		{Name: "timeout", Value: timeout},
+		{Name: "recipient", Value: recipient.Slice()},
+		{Name: "refund", Value: refund.Slice()},
+	}
+	inst := byzcoin.Instruction{
+		InstanceID: byzcoin.NewInstanceID(gdarc.GetBaseID()),
+		Spawn: &byzcoin.Spawn{
+			ContractID: ContractHTLCID,
+			Args:       args,
+		},
+	}
+
+	// No coins to lock
+	c, _ := contractHTLCFromBytes(nil)
+	_, _, err := c.Spawn(ct, inst, nil)
+	require.Error(t, err)
+
+	// Coins of another type than the recipient
+	other := byzcoin.Coin{Name: iid("other"), Value: 1}
+	c, _ = contractHTLCFromBytes(nil)
+	_, _, err = c.Spawn(ct, inst, []byzcoin.Coin{other})
+	require.Error(t, err)
+
+	// Timeout already reached
+	ct.index = 10
+	c, _ = contractHTLCFromBytes(nil)
+	_, _, err = c.Spawn(ct, inst, []byzcoin.Coin{{Name: CoinName, Value: 2}})
+	require.Error(t, err)
+	ct.index = 0
+
+	c, _ = contractHTLCFromBytes(nil)
+	sc, co, err := c.Spawn(ct, inst, []byzcoin.Coin{{Name: CoinName, Value: 1}, other,
+		{Name: CoinName, Value: 1}})
+	require.NoError(t, err)
+	require.Equal(t, []byzcoin.Coin{other}, co)
+	require.Equal(t, 1, len(sc))
+	require.Equal(t, inst.DeriveID("").Slice(), sc[0].InstanceID)
+
+	var h HTLC
+	require.NoError(t, protobuf.Decode(sc[0].Value, &h))
+	require.Equal(t, hash[:], h.Hash)
+	require.Equal(t, uint64(10), h.Timeout)
+	require.True(t, h.Recipient.Equal(recipient))
+	require.True(t, h.Refund.Equal(refund))
+	require.Equal(t, uint64(2), h.Coin.Value)
+	require.False(t, h.Settled())
+}
+
+func TestHTLC_Invoke(t *testing.T) {
+	ct := newCT("invoke:htlc.claim", "invoke:htlc.refund")
+	recipient := iid("recipient")
+	refund := iid("refund")
+	ct.Store(recipient, ciZero, ContractCoinID, gdarc.GetBaseID())
+	ct.Store(refund, ciZero, ContractCoinID, gdarc.GetBaseID())
+
+	preimage := []byte("secret")
+	hash := sha256.Sum256(preimage)
+	h := HTLC{
+		Hash:      hash[:],
+		Timeout:   10,
+		Recipient: recipient,
+		Refund:    refund,
+		Coin:      byzcoin.Coin{Name: CoinName, Value: 1},
+	}
+	hBuf, err := protobuf.Encode(&h)
+	require.NoError(t, err)
+	id := iid("htlc")
+	ct.Store(id, hBuf, ContractHTLCID, gdarc.GetBaseID())
+	ct.index = 5
+
+	invoke := func(cmd string, args byzcoin.Arguments) ([]byzcoin.StateChange, error) {
+		c, err := contractHTLCFromBytes(ct.values[string(id.Slice())])
+		require.NoError(t, err)
+		sc, _, err := c.Invoke(ct, byzcoin.Instruction{
+			InstanceID: id,
+			Invoke: &byzcoin.Invoke{
+				ContractID: ContractHTLCID,
+				Command:    cmd,
+				Args:       args,
+			},
+		}, nil)
+		return sc, err
+	}
+
+	// Refund before the timeout
+	_, err = invoke("refund", nil)
+	require.Error(t, err)
+
+	// Claim with the wrong preimage
+	_, err = invoke("claim", byzcoin.Arguments{{Name: "preimage", Value: []byte("wrong")}})
+	require.Error(t, err)
+
+	sc, err := invoke("claim", byzcoin.Arguments{{Name: "preimage", Value: preimage}})
+	require.NoError(t, err)
+	require.Equal(t, 2, len(sc))
+	require.Equal(t, byzcoin.NewStateChange(byzcoin.Update, recipient, ContractCoinID, ciOne,
+		gdarc.GetBaseID()), sc[0])
+	var claimed HTLC
+	require.NoError(t, protobuf.Decode(sc[1].Value, &claimed))
+	require.True(t, claimed.Settled())
+	require.True(t, claimed.Claimed())
+	require.Equal(t, preimage, claimed.Preimage)
+
+	// Claim after the timeout
+	ct.index = 10
+	_, err = invoke("claim", byzcoin.Arguments{{Name: "preimage", Value: preimage}})
+	require.Error(t, err)
+
+	sc, err = invoke("refund", nil)
+	require.NoError(t, err)
+	require.Equal(t, byzcoin.NewStateChange(byzcoin.Update, refund, ContractCoinID, ciOne,
+		gdarc.GetBaseID()), sc[0])
+
+	// Once settled, nothing can be done anymore but deleting the instance
+	ct.Store(id, sc[1].Value, ContractHTLCID, gdarc.GetBaseID())
+	_, err = invoke("refund", nil)
+	require.Error(t, err)
+	c, err := contractHTLCFromBytes(ct.values[string(id.Slice())])
+	require.NoError(t, err)
+	sc, _, err = c.Delete(ct, byzcoin.Instruction{InstanceID: id}, nil)
+	require.NoError(t, err)
+	require.Equal(t, byzcoin.Remove, sc[0].StateAction)
+}
+
+func TestHTLC_AtomicSwap(t *testing.T) {
+	local := onet.NewTCPTest(cothority.Suite)
+	defer local.CloseAll()
+
+	signer := darc.NewSignerEd25519(nil, nil)
+	clA, darcA, aliceA, bobA := newSwapLedger(t, local, signer)
+	clB, darcB, bobB, aliceB := newSwapLedger(t, local, signer)
+
+	a := HTLCLedger{Client: clA, Signer: signer, Darc: darcA, From: aliceA, To: bobA,
+		Amount: 10, Timeout: 30}
+	b := HTLCLedger{Client: clB, Signer: signer, Darc: darcB, From: bobB, To: aliceB,
+		Amount: 20, Timeout: 20}
+
+	// The lock of the initiator must outlast the one of the participant by
+	// the time needed to claim the coins, and nothing is locked otherwise.
+	_, _, err := AtomicSwap(a, b)
+	require.Error(t, err)
+	a.Timeout = 40
+	b.Timeout = swapWait
+	_, _, err = AtomicSwap(a, b)
+	require.Error(t, err)
+
+	b.Timeout = 20
+	lockA, lockB, err := AtomicSwap(a, b)
+	require.NoError(t, err)
+
+	// A lock from the wrong ledger must not verify.
+	hash := sha256.Sum256([]byte("wrong"))
+	_, err = VerifyHTLCLock(clB.ID, *lockA, hash[:], a.To, a.Amount, 0)
+	require.Error(t, err)
+
+	for _, acc := range []struct {
+		cl    *byzcoin.Client
+		id    byzcoin.InstanceID
+		value uint64
+	}{{clA, aliceA, 90}, {clA, bobA, 10}, {clB, bobB, 80}, {clB, aliceB, 20}} {
+		p, err := acc.cl.GetProof(acc.id.Slice())
+		require.NoError(t, err)
+		v, _, _, err := p.Proof.Get(acc.id.Slice())
+		require.NoError(t, err)
+		var coin byzcoin.Coin
+		require.NoError(t, protobuf.Decode(v, &coin))
+		require.Equal(t, acc.value, coin.Value)
+	}
+
+	for _, l := range []struct {
+		ledger HTLCLedger
+		lock   *HTLCLock
+	}{{a, lockA}, {b, lockB}} {
+		h, err := l.ledger.GetHTLC(l.lock.ID)
+		require.NoError(t, err)
+		require.True(t, h.Claimed())
+		require.Error(t, l.ledger.Refund(l.lock.ID))
+	}
+}
+
+// newSwapLedger creates a new ledger with its own roster and two coin
+// accounts, the first one holding 100 coins.
+func newSwapLedger(t *testing.T, local *onet.LocalTest, signer darc.Signer) (
+	*byzcoin.Client, darc.ID, byzcoin.InstanceID, byzcoin.InstanceID) {
+	_, roster, _ := local.GenTree(3, true)
+	genesisMsg, err := byzcoin.DefaultGenesisMsg(byzcoin.CurrentVersion, roster,
+		[]string{"spawn:coin", "invoke:coin.mint", "invoke:coin.fetch",
+			"spawn:htlc", "invoke:htlc.claim", "invoke:htlc.refund"}, signer.Identity())
+	require.NoError(t, err)
+	genesisMsg.BlockInterval = 500 * time.Millisecond
+	cl, _, err := byzcoin.NewLedger(genesisMsg, false)
+	require.NoError(t, err)
+	darcID := genesisMsg.GenesisDarc.GetBaseID()
+
+	var accounts []byzcoin.InstanceID
+	ctx := byzcoin.ClientTransaction{}
+	for _, name := range []string{"sender", "receiver"} {
+		ctx.Instructions = append(ctx.Instructions, byzcoin.Instruction{
+			InstanceID: byzcoin.NewInstanceID(darcID),
+			Spawn: &byzcoin.Spawn{
+				ContractID: ContractCoinID,
+				Args:       byzcoin.Arguments{{Name: "coinID", Value: []byte(name)}},
+			},
+		})
+		h := sha256.New()
+		h.Write([]byte(ContractCoinID))
+		h.Write([]byte(name))
+		accounts = append(accounts, byzcoin.NewInstanceID(h.Sum(nil)))
+	}
+	coins := make([]byte, 8)
+	binary.LittleEndian.PutUint64(coins, 100)
+	ctx.Instructions = append(ctx.Instructions, byzcoin.Instruction{
+		InstanceID: accounts[0],
+		Invoke: &byzcoin.Invoke{
+			ContractID: ContractCoinID,
+			Command:    "mint",
+			Args:       byzcoin.Arguments{{Name: "coins", Value: coins}},
+		},
+	})
+	for i := range ctx.Instructions {
+		ctx.Instructions[i].SignerCounter = []uint64{uint64(i + 1)}
+	}
+	require.NoError(t, ctx.FillSignersAndSignWith(signer))
+	_, err = cl.AddTransactionAndWait(ctx, 10)
+	require.NoError(t, err)
+	return cl, darcID, accounts[0], accounts[1]
+}
@@ -0,0 +1,308 @@
+package contracts
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/byzcoin/trie"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/onet/v3/log"
+	"go.dedis.ch/protobuf"
+)
+
+// ContractMapID denotes a contract that stores a set of key/value pairs.
+const ContractMapID = "map"
+
+const (
+	// MapMaxKeySize is the maximum length of a key in a map instance.
+	MapMaxKeySize = 256
+	// MapMaxValueSize is the maximum length of a value in a map instance.
+	MapMaxValueSize = 4096
+	// mapDefaultMaxEntries is the maximum number of entries of a map
+	// instance if none is given when spawning it.
+	mapDefaultMaxEntries = 1000
+)
+
+// mapNonce is the nonce of the trie used to compute the root of a map. It
+// is fixed, so that the root only depends on the entries.
+var mapNonce = make([]byte, 32)
+
+// ContractMap holds a sorted set of key/value pairs, which can be updated
+// one key at a time. The instance also stores the Merkle root of its
+// entries, so that a single entry can be proven with MapData.Prove and
+// VerifyMapProof, without giving away the other entries.
+//
+// Spawn takes the following optional arguments:
+//  - max_entries is the maximum number of entries, as a 64-bit uint in
+//    LittleEndian. The default is 1000
+//  - darcID is the darc of the map instance. The default is the darc of the
+//    spawn instruction
+//
+// The following methods are available, both taking the argument "key":
+//  - set stores the argument "value" under the key
+//  - delete removes the key, which must exist
+//
+// The darc of the instance can restrict a single key with a rule named
+// after the action and the key, for example "invoke:map.set:maintenance".
+// If such a rule exists, it is used instead of the rule of the action.
+
+// MapData is the data stored in a map instance.
+type MapData struct {
+	// Entries are sorted by key.
+	Entries []MapEntry
+	// Root is the Merkle root of the entries.
+	Root []byte
+	// MaxEntries is the maximum number of entries.
+	MaxEntries uint64
+}
+
+// MapEntry is one key/value pair of a map instance.
+type MapEntry struct {
+	Key   string
+	Value []byte
+}
+
+// Get returns the value of the key, or nil if it doesn't exist.
+func (m MapData) Get(key string) []byte {
+	i := m.search(key)
+	if i < len(m.Entries) && m.Entries[i].Key == key {
+		return m.Entries[i].Value
+	}
+	return nil
+}
+
+// Prove returns the proof that the key is or is not in the map. It can be
+// verified against the root of the map with VerifyMapProof.
+func (m MapData) Prove(key string) (*trie.Proof, error) {
+	t, err := m.trie()
+	if err != nil {
+		return nil, err
+	}
+	return t.GetProof([]byte(key))
+}
+
+// VerifyMapProof checks that the proof comes from the map with the given
+// root. It returns the value of the key, or nil if the key is not in the
+// map.
+func VerifyMapProof(root []byte, key string, p *trie.Proof) ([]byte, error) {
+	if !bytes.Equal(p.GetRoot(), root) {
+		return nil, errors.New("proof is for another root")
+	}
+	ok, err := p.Exists([]byte(key))
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, nil
+	}
+	return p.Get([]byte(key)), nil
+}
+
+// search returns the index of the key, or where it should be inserted.
+func (m MapData) search(key string) int {
+	return sort.Search(len(m.Entries), func(i int) bool {
+		return m.Entries[i].Key >= key
+	})
+}
+
+// trie returns an in-memory trie with all the entries of the map.
+func (m MapData) trie() (*trie.Trie, error) {
+	t, err := trie.NewTrie(trie.NewMemDB(), mapNonce)
+	if err != nil {
+		return nil, err
+	}
+	pairs := make([]trie.KVPair, len(m.Entries))
+	for i, e := range m.Entries {
+		pairs[i] = mapPair{[]byte(e.Key), e.Value}
+	}
+	if err = t.Batch(pairs); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// updateRoot computes the root of the entries.
+func (m *MapData) updateRoot() error {
+	t, err := m.trie()
+	if err != nil {
+		return err
+	}
+	m.Root = t.GetRoot()
+	return nil
+}
+
+// mapPair implements trie.KVPair to add all the entries in one batch.
+type mapPair struct {
+	key   []byte
+	value []byte
+}
+
+func (p mapPair) Op() trie.OpType { return trie.OpSet }
+func (p mapPair) Key() []byte     { return p.key }
+func (p mapPair) Val() []byte     { return p.value }
+
+func contractMapFromBytes(in []byte) (byzcoin.Contract, error) {
+	c := &contractMap{}
+	err := protobuf.Decode(in, &c.MapData)
+	if err != nil {
+		return nil, errors.New("couldn't unmarshal instance data: " + err.Error())
+	}
+	return c, nil
+}
+
+type contractMap struct {
+	byzcoin.BasicContract
+	MapData
+}
+
+// VerifyInstruction uses the rule of the key, if the darc has one.
+func (c *contractMap) VerifyInstruction(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, msg []byte) error {
+	if inst.Invoke == nil {
+		return inst.Verify(rst, msg)
+	}
+	_, _, _, darcID, err := rst.GetValues(inst.InstanceID.Slice())
+	if err != nil {
+		return err
+	}
+	d, err := byzcoin.LoadDarcFromTrie(rst, darcID)
+	if err != nil {
+		return errors.New("darc not found: " + err.Error())
+	}
+	action := darc.Action(inst.Action())
+	expr := d.Rules.Get(action + darc.Action(":"+string(inst.Invoke.Args.Search("key"))))
+	if expr == nil {
+		return inst.Verify(rst, msg)
+	}
+
+	kd := d.Copy()
+	if kd.Rules.Contains(action) {
+		err = kd.Rules.UpdateRule(action, expr)
+	} else {
+		err = kd.Rules.AddRule(action, expr)
+	}
+	if err != nil {
+		return err
+	}
+	return inst.VerifyWithDarc(rst, msg, kd)
+}
+
+func (c *contractMap) Spawn(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, coins []byzcoin.Coin) (sc []byzcoin.StateChange, cout []byzcoin.Coin, err error) {
+	cout = coins
+
+	var darcID darc.ID
+	_, _, _, darcID, err = rst.GetValues(inst.InstanceID.Slice())
+	if err != nil {
+		return
+	}
+	if did := inst.Spawn.Args.Search("darcID"); did != nil {
+		darcID = darc.ID(did)
+	}
+
+	c.MaxEntries = mapDefaultMaxEntries
+	if buf := inst.Spawn.Args.Search("max_entries"); buf != nil {
+		if len(buf) != 8 {
+			err = errors.New("argument \"max_entries\" is wrong length")
+			return
+		}
+		c.MaxEntries = binary.LittleEndian.Uint64(buf)
+	}
+	if err = c.updateRoot(); err != nil {
+		return
+	}
+
+	var buf []byte
+	buf, err = protobuf.Encode(&c.MapData)
+	if err != nil {
+		return nil, nil, errors.New("couldn't encode map: " + err.Error())
+	}
+	id := inst.DeriveID("")
+	log.Lvlf2("Spawning map to %x", id.Slice())
+	sc = []byzcoin.StateChange{
+		byzcoin.NewStateChange(byzcoin.Create, id, ContractMapID, buf, darcID),
+	}
+	return
+}
+
+func (c *contractMap) Invoke(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, coins []byzcoin.Coin) (sc []byzcoin.StateChange, cout []byzcoin.Coin, err error) {
+	cout = coins
+
+	var darcID darc.ID
+	_, _, _, darcID, err = rst.GetValues(inst.InstanceID.Slice())
+	if err != nil {
+		return
+	}
+
+	key := string(inst.Invoke.Args.Search("key"))
+	if key == "" {
+		err = errors.New("argument \"key\" is missing")
+		return
+	}
+	if len(key) > MapMaxKeySize {
+		err = fmt.Errorf("key is longer than %d bytes", MapMaxKeySize)
+		return
+	}
+	i := c.search(key)
+	exists := i < len(c.Entries) && c.Entries[i].Key == key
+
+	switch inst.Invoke.Command {
+	case "set":
+		value := inst.Invoke.Args.Search("value")
+		if len(value) > MapMaxValueSize {
+			err = fmt.Errorf("value is longer than %d bytes", MapMaxValueSize)
+			return
+		}
+		if exists {
+			c.Entries[i].Value = value
+		} else {
+			if uint64(len(c.Entries)) >= c.MaxEntries {
+				err = fmt.Errorf("map is full with %d entries", c.MaxEntries)
+				return
+			}
+			c.Entries = append(c.Entries, MapEntry{})
+			copy(c.Entries[i+1:], c.Entries[i:])
+			c.Entries[i] = MapEntry{Key: key, Value: value}
+		}
+	case "delete":
+		if !exists {
+			err = fmt.Errorf("key %s doesn't exist", key)
+			return
+		}
+		c.Entries = append(c.Entries[:i], c.Entries[i+1:]...)
+	default:
+		err = errors.New("map contract can only set and delete")
+		return
+	}
+	if err = c.updateRoot(); err != nil {
+		return
+	}
+
+	var buf []byte
+	buf, err = protobuf.Encode(&c.MapData)
+	if err != nil {
+		return nil, nil, errors.New("couldn't encode map: " + err.Error())
+	}
+	log.Lvlf2("map %x: %s of key %s", inst.InstanceID.Slice(), inst.Invoke.Command, key)
+	sc = []byzcoin.StateChange{
+		byzcoin.NewStateChange(byzcoin.Update, inst.InstanceID, ContractMapID, buf, darcID),
+	}
+	return
+}
+
+func (c *contractMap) Delete(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, coins []byzcoin.Coin) (sc []byzcoin.StateChange, cout []byzcoin.Coin, err error) {
+	cout = coins
+
+	var darcID darc.ID
+	_, _, _, darcID, err = rst.GetValues(inst.InstanceID.Slice())
+	if err != nil {
+		return
+	}
+
+	sc = byzcoin.StateChanges{
+		byzcoin.NewStateChange(byzcoin.Remove, inst.InstanceID, ContractMapID, nil, darcID),
+	}
+	return
+}
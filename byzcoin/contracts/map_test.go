@@ -0,0 +1,121 @@
+package contracts
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/cothority/v3/darc/expression"
+	"go.dedis.ch/protobuf"
+)
+
+func TestMap_Invoke(t *testing.T) {
+	ct := newCT("spawn:map", "invoke:map.set", "invoke:map.delete")
+	maxEntries := make([]byte, 8)
+	binary.LittleEndian.PutUint64(maxEntries, 2)
+
+	c, err := contractMapFromBytes(nil)
+	require.NoError(t, err)
+	sc, _, err := c.Spawn(ct, byzcoin.Instruction{
+		InstanceID: byzcoin.NewInstanceID(gdarc.GetBaseID()),
+		Spawn: &byzcoin.Spawn{
+			ContractID: ContractMapID,
+			Args:       byzcoin.Arguments{{Name: "max_entries", Value: maxEntries}},
+		},
+	}, nil)
+	require.NoError(t, err)
+	id := iid("map")
+	ct.Store(id, sc[0].Value, ContractMapID, gdarc.GetBaseID())
+
+	invoke := func(cmd, key string, value []byte) error {
+		c, err := contractMapFromBytes(ct.values[string(id.Slice())])
+		require.NoError(t, err)
+		sc, _, err := c.Invoke(ct, byzcoin.Instruction{
+			InstanceID: id,
+			Invoke: &byzcoin.Invoke{
+				ContractID: ContractMapID,
+				Command:    cmd,
+				Args: byzcoin.Arguments{
+					{Name: "key", Value: []byte(key)},
+					{Name: "value", Value: value},
+				},
+			},
+		}, nil)
+		if err == nil {
+			ct.Store(id, sc[0].Value, ContractMapID, gdarc.GetBaseID())
+		}
+		return err
+	}
+
+	require.NoError(t, invoke("set", "b", []byte("2")))
+	require.NoError(t, invoke("set", "a", []byte("1")))
+	require.NoError(t, invoke("set", "a", []byte("one")))
+	require.Error(t, invoke("set", "c", []byte("3")))
+	require.Error(t, invoke("set", "", []byte("3")))
+	require.Error(t, invoke("set", "b", make([]byte, MapMaxValueSize+1)))
+	require.Error(t, invoke("delete", "c", nil))
+
+	var m MapData
+	require.NoError(t, protobuf.Decode(ct.values[string(id.Slice())], &m))
+	require.Equal(t, []MapEntry{{"a", []byte("one")}, {"b", []byte("2")}}, m.Entries)
+	require.Equal(t, []byte("2"), m.Get("b"))
+
+	// A single entry can be proven against the root.
+	p, err := m.Prove("a")
+	require.NoError(t, err)
+	v, err := VerifyMapProof(m.Root, "a", p)
+	require.NoError(t, err)
+	require.Equal(t, []byte("one"), v)
+	p, err = m.Prove("c")
+	require.NoError(t, err)
+	v, err = VerifyMapProof(m.Root, "c", p)
+	require.NoError(t, err)
+	require.Nil(t, v)
+
+	// The proofs change with the root.
+	require.NoError(t, invoke("delete", "a", nil))
+	m = MapData{}
+	require.NoError(t, protobuf.Decode(ct.values[string(id.Slice())], &m))
+	require.Equal(t, []MapEntry{{"b", []byte("2")}}, m.Entries)
+	_, err = VerifyMapProof(m.Root, "c", p)
+	require.Error(t, err)
+}
+
+func TestMap_VerifyInstruction(t *testing.T) {
+	ct := newCT("invoke:map.set")
+	ct.storeConfig()
+	// Only another signer can set the key "locked".
+	other := darc.NewSignerEd25519(nil, nil)
+	require.NoError(t, gdarc.Rules.AddRule("invoke:map.set:locked",
+		expression.Expr(other.Identity().String())))
+	dBuf, err := gdarc.ToProto()
+	require.NoError(t, err)
+	ct.Store(byzcoin.NewInstanceID(gdarc.GetBaseID()), dBuf, byzcoin.ContractDarcID, gdarc.GetBaseID())
+	id := iid("map")
+	ct.Store(id, nil, ContractMapID, gdarc.GetBaseID())
+
+	verify := func(key string, signer darc.Signer) error {
+		ct.setSignatureCounter(signer.Identity().String(), 0)
+		inst := byzcoin.Instruction{
+			InstanceID: id,
+			Invoke: &byzcoin.Invoke{
+				ContractID: ContractMapID,
+				Command:    "set",
+				Args:       byzcoin.Arguments{{Name: "key", Value: []byte(key)}},
+			},
+			SignerIdentities: []darc.Identity{signer.Identity()},
+			SignerCounter:    []uint64{1},
+		}
+		require.NoError(t, inst.SignWith([]byte("ctx"), signer))
+		c, err := contractMapFromBytes(nil)
+		require.NoError(t, err)
+		return c.VerifyInstruction(ct, inst, []byte("ctx"))
+	}
+
+	require.NoError(t, verify("open", gsigner))
+	require.Error(t, verify("open", other))
+	require.Error(t, verify("locked", gsigner))
+	require.NoError(t, verify("locked", other))
+}
@@ -0,0 +1,469 @@
+package contracts
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/onet/v3/log"
+	"go.dedis.ch/protobuf"
+)
+
+// ContractNFTCollectionID denotes a contract that mints unique assets.
+const ContractNFTCollectionID = "nft_collection"
+
+// ContractNFTID denotes a contract that holds one unique asset.
+const ContractNFTID = "nft"
+
+// ContractNFTCollection defines a family of non-fungible tokens. The darc of
+// the collection instance controls who can mint new tokens.
+//
+// Spawn takes the following arguments:
+//  - name of the collection
+//  - schema is the optional, comma separated list of the keys that the
+//    metadata of every token must have
+//  - royalty_coin is an optional coin instance receiving a royalty on every
+//    transfer of a token
+//  - royalty_amount is the number of coins of the royalty, as a 64-bit uint
+//    in LittleEndian
+//  - darcID is the darc controlling the mint. Optional, the darc of the
+//    spawn instruction by default
+//
+// The following method is available:
+//  - mint creates a new token instance at NFTID(collection, serial) with
+//    the arguments "owner", the darc ID controlling the token, and
+//    "metadata", a protobuf encoded NFTMetadata.
+//
+// A collection can only be deleted once all its tokens are burnt.
+
+// ContractNFT is one token of a collection. The darc of the token instance
+// is the darc of its owner, which allows to transfer, approve and burn the
+// token.
+//
+// The following methods are available:
+//  - transfer gives the token to the darc ID in the argument "owner". If the
+//    collection has a royalty, the coins of the royalty must be given to the
+//    instruction, typically fetched from a coin instance in the previous
+//    instruction. The other coins are passed on to the next instruction.
+//  - approve allows the darc ID in the argument "darcID" to transfer the
+//    token once, using its "invoke:nft.transfer" rule. An empty argument
+//    removes the approval.
+//
+// Deleting a token instance burns the token.
+
+// NFTCollection is the data stored in a collection instance.
+type NFTCollection struct {
+	// Name of the collection.
+	Name string
+	// Schema holds the keys that the metadata of every token must have. If
+	// it is empty, any metadata is accepted.
+	Schema []string
+	// Royalty is paid on every transfer of a token, if it is set.
+	Royalty *NFTRoyalty `protobuf:"opt"`
+	// Minted is the number of tokens that have been minted. It is also the
+	// serial of the next token.
+	Minted uint64
+	// Supply is the number of tokens that have not been burnt.
+	Supply uint64
+}
+
+// NFTRoyalty is the amount of coins sent to a coin instance on every
+// transfer of a token.
+type NFTRoyalty struct {
+	Coin   byzcoin.InstanceID
+	Amount uint64
+}
+
+// NFTAttribute is one entry of the metadata of a token.
+type NFTAttribute struct {
+	Key   string
+	Value string
+}
+
+// NFTMetadata is the "metadata" argument of the mint.
+type NFTMetadata struct {
+	Attributes []NFTAttribute
+}
+
+// NFT is the data stored in a token instance.
+type NFT struct {
+	// Collection is the instance ID of the collection of the token.
+	Collection byzcoin.InstanceID
+	// Serial is the unique number of the token in its collection.
+	Serial uint64
+	// Metadata describes the asset.
+	Metadata []NFTAttribute
+	// Approved is the darc that can transfer the token once, if it is set.
+	Approved darc.ID
+}
+
+// NFTID returns the instance ID of a token of the collection.
+func NFTID(collection byzcoin.InstanceID, serial uint64) byzcoin.InstanceID {
+	h := sha256.New()
+	h.Write(collection.Slice())
+	serialBuf := make([]byte, 8)
+	binary.LittleEndian.PutUint64(serialBuf, serial)
+	h.Write(serialBuf)
+	return byzcoin.NewInstanceID(h.Sum(nil))
+}
+
+func contractNFTCollectionFromBytes(in []byte) (byzcoin.Contract, error) {
+	c := &contractNFTCollection{}
+	err := protobuf.Decode(in, &c.NFTCollection)
+	if err != nil {
+		return nil, errors.New("couldn't unmarshal instance data: " + err.Error())
+	}
+	return c, nil
+}
+
+type contractNFTCollection struct {
+	byzcoin.BasicContract
+	NFTCollection
+}
+
+func (c *contractNFTCollection) Spawn(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, coins []byzcoin.Coin) (sc []byzcoin.StateChange, cout []byzcoin.Coin, err error) {
+	cout = coins
+
+	var darcID darc.ID
+	_, _, _, darcID, err = rst.GetValues(inst.InstanceID.Slice())
+	if err != nil {
+		return
+	}
+	if did := inst.Spawn.Args.Search("darcID"); did != nil {
+		darcID = darc.ID(did)
+	}
+
+	c.Name = string(inst.Spawn.Args.Search("name"))
+	if c.Name == "" {
+		err = errors.New("argument \"name\" is missing")
+		return
+	}
+	if schema := string(inst.Spawn.Args.Search("schema")); schema != "" {
+		seen := make(map[string]bool)
+		for _, key := range strings.Split(schema, ",") {
+			key = strings.TrimSpace(key)
+			if key == "" || seen[key] {
+				err = errors.New("schema has empty or duplicate keys")
+				return
+			}
+			seen[key] = true
+			c.Schema = append(c.Schema, key)
+		}
+	}
+	if buf := inst.Spawn.Args.Search("royalty_coin"); buf != nil {
+		if len(buf) != len(byzcoin.InstanceID{}) {
+			err = errors.New("argument \"royalty_coin\" must be an InstanceID")
+			return
+		}
+		amountBuf := inst.Spawn.Args.Search("royalty_amount")
+		if len(amountBuf) != 8 {
+			err = errors.New("argument \"royalty_amount\" is missing or wrong length")
+			return
+		}
+		c.Royalty = &NFTRoyalty{
+			Coin:   byzcoin.NewInstanceID(buf),
+			Amount: binary.LittleEndian.Uint64(amountBuf),
+		}
+		var cid string
+		_, _, cid, _, err = rst.GetValues(buf)
+		if err != nil {
+			return
+		}
+		if cid != ContractCoinID {
+			err = errors.New("royalty_coin is not a coin instance")
+			return
+		}
+	}
+
+	var buf []byte
+	buf, err = protobuf.Encode(&c.NFTCollection)
+	if err != nil {
+		return nil, nil, errors.New("couldn't encode collection: " + err.Error())
+	}
+	id := inst.DeriveID("")
+	log.Lvlf2("Spawning nft collection %s to %x", c.Name, id.Slice())
+	sc = []byzcoin.StateChange{
+		byzcoin.NewStateChange(byzcoin.Create, id, ContractNFTCollectionID, buf, darcID),
+	}
+	return
+}
+
+func (c *contractNFTCollection) Invoke(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, coins []byzcoin.Coin) (sc []byzcoin.StateChange, cout []byzcoin.Coin, err error) {
+	cout = coins
+
+	var darcID darc.ID
+	_, _, _, darcID, err = rst.GetValues(inst.InstanceID.Slice())
+	if err != nil {
+		return
+	}
+
+	if inst.Invoke.Command != "mint" {
+		err = errors.New("nft collection contract can only mint")
+		return
+	}
+
+	owner := darc.ID(inst.Invoke.Args.Search("owner"))
+	if _, err = byzcoin.LoadDarcFromTrie(rst, owner); err != nil {
+		err = errors.New("invalid owner darc: " + err.Error())
+		return
+	}
+	var metadata NFTMetadata
+	err = protobuf.Decode(inst.Invoke.Args.Search("metadata"), &metadata)
+	if err != nil {
+		err = errors.New("couldn't unmarshal metadata: " + err.Error())
+		return
+	}
+	if err = c.checkSchema(metadata.Attributes); err != nil {
+		return
+	}
+
+	token := NFT{
+		Collection: inst.InstanceID,
+		Serial:     c.Minted,
+		Metadata:   metadata.Attributes,
+	}
+	var tokenBuf []byte
+	tokenBuf, err = protobuf.Encode(&token)
+	if err != nil {
+		return nil, nil, errors.New("couldn't encode nft: " + err.Error())
+	}
+	c.Minted++
+	c.Supply++
+	var buf []byte
+	buf, err = protobuf.Encode(&c.NFTCollection)
+	if err != nil {
+		return nil, nil, errors.New("couldn't encode collection: " + err.Error())
+	}
+	id := NFTID(inst.InstanceID, token.Serial)
+	log.Lvlf2("Minting nft %d of %s to %x", token.Serial, c.Name, id.Slice())
+	sc = []byzcoin.StateChange{
+		byzcoin.NewStateChange(byzcoin.Create, id, ContractNFTID, tokenBuf, owner),
+		byzcoin.NewStateChange(byzcoin.Update, inst.InstanceID, ContractNFTCollectionID, buf, darcID),
+	}
+	return
+}
+
+func (c *contractNFTCollection) Delete(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, coins []byzcoin.Coin) (sc []byzcoin.StateChange, cout []byzcoin.Coin, err error) {
+	cout = coins
+
+	var darcID darc.ID
+	_, _, _, darcID, err = rst.GetValues(inst.InstanceID.Slice())
+	if err != nil {
+		return
+	}
+
+	if c.Supply > 0 {
+		err = errors.New("cannot delete a collection that still has tokens")
+		return
+	}
+	sc = byzcoin.StateChanges{
+		byzcoin.NewStateChange(byzcoin.Remove, inst.InstanceID, ContractNFTCollectionID, nil, darcID),
+	}
+	return
+}
+
+// checkSchema makes sure the metadata has exactly the keys of the schema.
+func (c *contractNFTCollection) checkSchema(attrs []NFTAttribute) error {
+	seen := make(map[string]bool)
+	for _, a := range attrs {
+		if seen[a.Key] {
+			return fmt.Errorf("duplicate metadata key %s", a.Key)
+		}
+		seen[a.Key] = true
+	}
+	if len(c.Schema) == 0 {
+		return nil
+	}
+	for _, key := range c.Schema {
+		if !seen[key] {
+			return fmt.Errorf("metadata key %s is missing", key)
+		}
+	}
+	if len(attrs) != len(c.Schema) {
+		return errors.New("metadata has keys that are not in the schema")
+	}
+	return nil
+}
+
+func contractNFTFromBytes(in []byte) (byzcoin.Contract, error) {
+	c := &contractNFT{}
+	err := protobuf.Decode(in, &c.NFT)
+	if err != nil {
+		return nil, errors.New("couldn't unmarshal instance data: " + err.Error())
+	}
+	return c, nil
+}
+
+type contractNFT struct {
+	byzcoin.BasicContract
+	NFT
+}
+
+// VerifyInstruction also accepts a transfer authorized by the approved darc.
+func (c *contractNFT) VerifyInstruction(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, msg []byte) error {
+	err := inst.Verify(rst, msg)
+	if err == nil || len(c.Approved) == 0 || inst.Invoke == nil || inst.Invoke.Command != "transfer" {
+		return err
+	}
+	d, err := byzcoin.LoadDarcFromTrie(rst, c.Approved)
+	if err != nil {
+		return errors.New("couldn't load approved darc: " + err.Error())
+	}
+	return inst.VerifyWithDarc(rst, msg, d)
+}
+
+func (c *contractNFT) Spawn(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, coins []byzcoin.Coin) (sc []byzcoin.StateChange, cout []byzcoin.Coin, err error) {
+	err = errors.New("nft instances are minted by their collection")
+	return
+}
+
+func (c *contractNFT) Invoke(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, coins []byzcoin.Coin) (sc []byzcoin.StateChange, cout []byzcoin.Coin, err error) {
+	cout = coins
+
+	var darcID darc.ID
+	_, _, _, darcID, err = rst.GetValues(inst.InstanceID.Slice())
+	if err != nil {
+		return
+	}
+
+	switch inst.Invoke.Command {
+	case "transfer":
+		owner := darc.ID(inst.Invoke.Args.Search("owner"))
+		if _, err = byzcoin.LoadDarcFromTrie(rst, owner); err != nil {
+			err = errors.New("invalid owner darc: " + err.Error())
+			return
+		}
+		sc, cout, err = c.payRoyalty(rst, coins)
+		if err != nil {
+			return
+		}
+		log.Lvlf2("transferring nft %x to darc %x", inst.InstanceID.Slice(), owner)
+		darcID = owner
+		c.Approved = nil
+	case "approve":
+		approved := darc.ID(inst.Invoke.Args.Search("darcID"))
+		if len(approved) > 0 {
+			if _, err = byzcoin.LoadDarcFromTrie(rst, approved); err != nil {
+				err = errors.New("invalid approved darc: " + err.Error())
+				return
+			}
+		}
+		c.Approved = approved
+	default:
+		err = errors.New("nft contract can only transfer and approve")
+		return
+	}
+
+	var buf []byte
+	buf, err = protobuf.Encode(&c.NFT)
+	if err != nil {
+		return nil, nil, errors.New("couldn't encode nft: " + err.Error())
+	}
+	sc = append(sc, byzcoin.NewStateChange(byzcoin.Update, inst.InstanceID, ContractNFTID, buf, darcID))
+	return
+}
+
+func (c *contractNFT) Delete(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, coins []byzcoin.Coin) (sc []byzcoin.StateChange, cout []byzcoin.Coin, err error) {
+	cout = coins
+
+	var darcID darc.ID
+	_, _, _, darcID, err = rst.GetValues(inst.InstanceID.Slice())
+	if err != nil {
+		return
+	}
+
+	collection, collectionDarc, err := c.getCollection(rst)
+	if err != nil {
+		return
+	}
+	collection.Supply--
+	buf, err := protobuf.Encode(collection)
+	if err != nil {
+		return nil, nil, errors.New("couldn't encode collection: " + err.Error())
+	}
+	log.Lvlf2("burning nft %d of %s", c.Serial, collection.Name)
+	sc = byzcoin.StateChanges{
+		byzcoin.NewStateChange(byzcoin.Remove, inst.InstanceID, ContractNFTID, nil, darcID),
+		byzcoin.NewStateChange(byzcoin.Update, c.Collection, ContractNFTCollectionID, buf, collectionDarc),
+	}
+	return
+}
+
+// getCollection returns the collection of the token.
+func (c *contractNFT) getCollection(rst byzcoin.ReadOnlyStateTrie) (*NFTCollection, darc.ID, error) {
+	v, _, cid, darcID, err := rst.GetValues(c.Collection.Slice())
+	if err != nil {
+		return nil, nil, err
+	}
+	if cid != ContractNFTCollectionID {
+		return nil, nil, errors.New("collection of the nft doesn't exist")
+	}
+	var collection NFTCollection
+	err = protobuf.Decode(v, &collection)
+	if err != nil {
+		return nil, nil, errors.New("couldn't unmarshal collection: " + err.Error())
+	}
+	return &collection, darcID, nil
+}
+
+// payRoyalty sends the royalty of the collection to its coin instance, and
+// returns the remaining coins.
+func (c *contractNFT) payRoyalty(rst byzcoin.ReadOnlyStateTrie, coins []byzcoin.Coin) (sc []byzcoin.StateChange, cout []byzcoin.Coin, err error) {
+	cout = coins
+	collection, _, err := c.getCollection(rst)
+	if err != nil {
+		return
+	}
+	r := collection.Royalty
+	if r == nil || r.Amount == 0 {
+		return
+	}
+
+	v, _, cid, did, err := rst.GetValues(r.Coin.Slice())
+	if err == nil && cid != ContractCoinID {
+		err = errors.New("royalty coin instance doesn't exist")
+	}
+	if err != nil {
+		return
+	}
+	var account byzcoin.Coin
+	err = protobuf.Decode(v, &account)
+	if err != nil {
+		return nil, nil, errors.New("couldn't unmarshal royalty account: " + err.Error())
+	}
+
+	// Take the royalty out of the coins of the right type.
+	cout = []byzcoin.Coin{}
+	due := r.Amount
+	for _, co := range coins {
+		if due > 0 && co.Name.Equal(account.Name) {
+			paid := co.Value
+			if paid > due {
+				paid = due
+			}
+			due -= paid
+			co.Value -= paid
+		}
+		if co.Value > 0 {
+			cout = append(cout, co)
+		}
+	}
+	if due > 0 {
+		return nil, nil, fmt.Errorf("missing %d coins for the royalty", due)
+	}
+	if err = account.SafeAdd(r.Amount); err != nil {
+		return
+	}
+	accountBuf, err := protobuf.Encode(&account)
+	if err != nil {
+		return nil, nil, errors.New("couldn't marshal royalty account: " + err.Error())
+	}
+	sc = []byzcoin.StateChange{
+		byzcoin.NewStateChange(byzcoin.Update, r.Coin, ContractCoinID, accountBuf, did),
+	}
+	return
+}
@@ -0,0 +1,123 @@
+package contracts
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/protobuf"
+)
+
+func TestNFT(t *testing.T) {
+	ct := newCT("spawn:nft_collection", "invoke:nft_collection.mint")
+	ct.storeConfig()
+	owner := gdarc.GetBaseID()
+	royalty := iid("royalty")
+	ct.Store(royalty, ciZero, ContractCoinID, owner)
+	amountBuf := make([]byte, 8)
+	binary.LittleEndian.PutUint64(amountBuf, 2)
+
+	c, err := contractNFTCollectionFromBytes(nil)
+	require.NoError(t, err)
+	inst := byzcoin.Instruction{
+		InstanceID: byzcoin.NewInstanceID(owner),
+		Spawn: &byzcoin.Spawn{
+			ContractID: ContractNFTCollectionID,
+			Args: byzcoin.Arguments{
+				{Name: "name", Value: []byte("lab equipment")},
+				{Name: "schema", Value: []byte("model, serial")},
+				{Name: "royalty_coin", Value: royalty.Slice()},
+				{Name: "royalty_amount", Value: amountBuf},
+			},
+		},
+	}
+	sc, _, err := c.Spawn(ct, inst, nil)
+	require.NoError(t, err)
+	collID := inst.DeriveID("")
+	ct.Store(collID, sc[0].Value, ContractNFTCollectionID, owner)
+
+	mint := func(attrs ...NFTAttribute) ([]byzcoin.StateChange, error) {
+		buf, err := protobuf.Encode(&NFTMetadata{Attributes: attrs})
+		require.NoError(t, err)
+		c, err := contractNFTCollectionFromBytes(ct.values[string(collID.Slice())])
+		require.NoError(t, err)
+		sc, _, err := c.Invoke(ct, byzcoin.Instruction{
+			InstanceID: collID,
+			Invoke: &byzcoin.Invoke{
+				ContractID: ContractNFTCollectionID,
+				Command:    "mint",
+				Args: byzcoin.Arguments{
+					{Name: "owner", Value: owner},
+					{Name: "metadata", Value: buf},
+				},
+			},
+		}, nil)
+		return sc, err
+	}
+
+	// The metadata must follow the schema.
+	_, err = mint(NFTAttribute{"model", "microscope"})
+	require.Error(t, err)
+	_, err = mint(NFTAttribute{"model", "microscope"}, NFTAttribute{"serial", "1"}, NFTAttribute{"color", "red"})
+	require.Error(t, err)
+	sc, err = mint(NFTAttribute{"model", "microscope"}, NFTAttribute{"serial", "1"})
+	require.NoError(t, err)
+	require.Equal(t, 2, len(sc))
+	id := NFTID(collID, 0)
+	require.Equal(t, id, sc[0].InstanceID)
+	ct.Store(id, sc[0].Value, ContractNFTID, owner)
+	ct.Store(collID, sc[1].Value, ContractNFTCollectionID, owner)
+
+	invoke := func(cmd string, args byzcoin.Arguments, coins []byzcoin.Coin) ([]byzcoin.StateChange, []byzcoin.Coin, error) {
+		c, err := contractNFTFromBytes(ct.values[string(id.Slice())])
+		require.NoError(t, err)
+		return c.Invoke(ct, byzcoin.Instruction{
+			InstanceID: id,
+			Invoke: &byzcoin.Invoke{
+				ContractID: ContractNFTID,
+				Command:    cmd,
+				Args:       args,
+			},
+		}, coins)
+	}
+
+	sc, _, err = invoke("approve", byzcoin.Arguments{{Name: "darcID", Value: owner}}, nil)
+	require.NoError(t, err)
+	var token NFT
+	require.NoError(t, protobuf.Decode(sc[0].Value, &token))
+	require.Equal(t, darc.ID(owner), token.Approved)
+	ct.Store(id, sc[0].Value, ContractNFTID, owner)
+
+	// A transfer needs the royalty and a valid owner.
+	_, _, err = invoke("transfer", byzcoin.Arguments{{Name: "owner", Value: owner}},
+		[]byzcoin.Coin{{Name: CoinName, Value: 1}})
+	require.Error(t, err)
+	_, _, err = invoke("transfer", byzcoin.Arguments{{Name: "owner", Value: iid("unknown").Slice()}},
+		[]byzcoin.Coin{{Name: CoinName, Value: 2}})
+	require.Error(t, err)
+	sc, cout, err := invoke("transfer", byzcoin.Arguments{{Name: "owner", Value: owner}},
+		[]byzcoin.Coin{{Name: CoinName, Value: 3}})
+	require.NoError(t, err)
+	require.Equal(t, []byzcoin.Coin{{Name: CoinName, Value: 1}}, cout)
+	require.Equal(t, byzcoin.NewStateChange(byzcoin.Update, royalty, ContractCoinID, ciTwo, owner), sc[0])
+	require.NoError(t, protobuf.Decode(sc[1].Value, &token))
+	require.Empty(t, token.Approved)
+	ct.Store(id, sc[1].Value, ContractNFTID, owner)
+
+	// The collection can only be deleted once its tokens are burnt.
+	cc, err := contractNFTCollectionFromBytes(ct.values[string(collID.Slice())])
+	require.NoError(t, err)
+	_, _, err = cc.Delete(ct, byzcoin.Instruction{InstanceID: collID}, nil)
+	require.Error(t, err)
+	cn, err := contractNFTFromBytes(ct.values[string(id.Slice())])
+	require.NoError(t, err)
+	sc, _, err = cn.Delete(ct, byzcoin.Instruction{InstanceID: id}, nil)
+	require.NoError(t, err)
+	require.Equal(t, byzcoin.Remove, sc[0].StateAction)
+	var collection NFTCollection
+	require.NoError(t, protobuf.Decode(sc[1].Value, &collection))
+	require.Equal(t, uint64(1), collection.Minted)
+	require.Equal(t, uint64(0), collection.Supply)
+}
@@ -0,0 +1,317 @@
+package contracts
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/onet/v3/log"
+	"go.dedis.ch/protobuf"
+)
+
+// ContractOracleID denotes a contract that holds values signed by a set of
+// oracles.
+const ContractOracleID = "oracle"
+
+const (
+	// OracleMaxValueSize is the maximum length of a value of an oracle.
+	OracleMaxValueSize = 4096
+	// oracleDefaultHistory is the number of values kept by an oracle
+	// instance if none is given when spawning it.
+	oracleDefaultHistory = 10
+)
+
+// ContractOracle brings external facts, like prices or results, to the
+// ledger. The instance holds the identities of the oracles and a threshold.
+// A new value is only accepted if at least threshold oracles signed the same
+// value for the same round, so no single oracle can change it. Other
+// contracts read the latest value with OracleLatest.
+//
+// Spawn takes the following arguments:
+//  - oracles is the comma separated list of the identities of the oracles,
+//    for example "ed25519:1234...,ed25519:5678..."
+//  - threshold is the number of oracles that must sign a value, as a 64-bit
+//    uint in LittleEndian. Optional, more than half of the oracles by
+//    default
+//  - history is the number of values kept, as a 64-bit uint in
+//    LittleEndian. Optional, 10 by default
+//  - darcID is the darc of the oracle instance. Optional, the darc of the
+//    spawn instruction by default
+//
+// The following method is available:
+//  - update stores the argument "value" for the argument "round", a 64-bit
+//    uint in LittleEndian that must be higher than the last round. The
+//    argument "signatures" is a protobuf encoded OracleSignatures, where
+//    every oracle signs OracleMessage(instance, round, value).
+//
+// The darc of the instance only controls who can send the updates: the
+// values are authenticated by the signatures of the oracles.
+
+// OracleData is the data stored in an oracle instance.
+type OracleData struct {
+	// Oracles holds the identities of the oracles.
+	Oracles []string
+	// Threshold is the number of oracles that must sign a value.
+	Threshold uint64
+	// HistorySize is the maximum number of values in History.
+	HistorySize uint64
+	// History holds the last accepted values, the latest one last.
+	History []OracleValue
+}
+
+// OracleValue is a value accepted by an oracle instance.
+type OracleValue struct {
+	// Round increases with every value.
+	Round uint64
+	// Value as signed by the oracles.
+	Value []byte
+	// BlockIndex is the index of the block where the value was accepted.
+	BlockIndex uint64
+}
+
+// OracleSignatures is the "signatures" argument of an update.
+type OracleSignatures struct {
+	Signatures []OracleSignature
+}
+
+// OracleSignature is the signature of one oracle, given by its position in
+// OracleData.Oracles.
+type OracleSignature struct {
+	Index     uint64
+	Signature []byte
+}
+
+// Latest returns the last accepted value, or nil if there is none.
+func (o OracleData) Latest() *OracleValue {
+	if len(o.History) == 0 {
+		return nil
+	}
+	return &o.History[len(o.History)-1]
+}
+
+// OracleMessage returns what the oracles sign to accept the value of the
+// round. It includes the instance, so that a signature cannot be used for
+// another oracle instance.
+func OracleMessage(id byzcoin.InstanceID, round uint64, value []byte) []byte {
+	h := sha256.New()
+	h.Write(id.Slice())
+	roundBuf := make([]byte, 8)
+	binary.LittleEndian.PutUint64(roundBuf, round)
+	h.Write(roundBuf)
+	h.Write(value)
+	return h.Sum(nil)
+}
+
+// OracleLatest returns the last value accepted by the oracle instance. It
+// is meant to be used by other contracts, and returns an error if the
+// instance is not an oracle or has no value yet.
+func OracleLatest(rst byzcoin.ReadOnlyStateTrie, id byzcoin.InstanceID) (*OracleValue, error) {
+	buf, _, cid, _, err := rst.GetValues(id.Slice())
+	if err != nil {
+		return nil, err
+	}
+	if cid != ContractOracleID {
+		return nil, fmt.Errorf("instance %x is not an oracle", id.Slice())
+	}
+	var o OracleData
+	if err = protobuf.Decode(buf, &o); err != nil {
+		return nil, errors.New("couldn't unmarshal oracle: " + err.Error())
+	}
+	v := o.Latest()
+	if v == nil {
+		return nil, fmt.Errorf("oracle %x has no value yet", id.Slice())
+	}
+	return v, nil
+}
+
+func contractOracleFromBytes(in []byte) (byzcoin.Contract, error) {
+	c := &contractOracle{}
+	err := protobuf.Decode(in, &c.OracleData)
+	if err != nil {
+		return nil, errors.New("couldn't unmarshal instance data: " + err.Error())
+	}
+	return c, nil
+}
+
+type contractOracle struct {
+	byzcoin.BasicContract
+	OracleData
+}
+
+func (c *contractOracle) Spawn(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, coins []byzcoin.Coin) (sc []byzcoin.StateChange, cout []byzcoin.Coin, err error) {
+	cout = coins
+
+	var darcID darc.ID
+	_, _, _, darcID, err = rst.GetValues(inst.InstanceID.Slice())
+	if err != nil {
+		return
+	}
+	if did := inst.Spawn.Args.Search("darcID"); did != nil {
+		darcID = darc.ID(did)
+	}
+
+	oracles := string(inst.Spawn.Args.Search("oracles"))
+	if oracles == "" {
+		err = errors.New("argument \"oracles\" is missing")
+		return
+	}
+	for _, o := range strings.Split(oracles, ",") {
+		var id darc.Identity
+		id, err = darc.ParseIdentity(o)
+		if err != nil {
+			err = fmt.Errorf("wrong oracle %s: %v", o, err)
+			return
+		}
+		if !id.PrimaryIdentity() {
+			err = fmt.Errorf("oracle %s cannot sign", o)
+			return
+		}
+		for _, other := range c.Oracles {
+			if other == id.String() {
+				err = fmt.Errorf("oracle %s is given twice", o)
+				return
+			}
+		}
+		c.Oracles = append(c.Oracles, id.String())
+	}
+
+	c.Threshold = uint64(len(c.Oracles)/2 + 1)
+	if buf := inst.Spawn.Args.Search("threshold"); buf != nil {
+		if len(buf) != 8 {
+			err = errors.New("argument \"threshold\" is wrong length")
+			return
+		}
+		c.Threshold = binary.LittleEndian.Uint64(buf)
+	}
+	if c.Threshold == 0 || c.Threshold > uint64(len(c.Oracles)) {
+		err = fmt.Errorf("threshold must be between 1 and %d", len(c.Oracles))
+		return
+	}
+	c.HistorySize = oracleDefaultHistory
+	if buf := inst.Spawn.Args.Search("history"); buf != nil {
+		if len(buf) != 8 {
+			err = errors.New("argument \"history\" is wrong length")
+			return
+		}
+		c.HistorySize = binary.LittleEndian.Uint64(buf)
+	}
+	if c.HistorySize == 0 {
+		err = errors.New("history must keep at least one value")
+		return
+	}
+
+	var buf []byte
+	buf, err = protobuf.Encode(&c.OracleData)
+	if err != nil {
+		return nil, nil, errors.New("couldn't encode oracle: " + err.Error())
+	}
+	id := inst.DeriveID("")
+	log.Lvlf2("Spawning oracle to %x with threshold %d of %d", id.Slice(), c.Threshold, len(c.Oracles))
+	sc = []byzcoin.StateChange{
+		byzcoin.NewStateChange(byzcoin.Create, id, ContractOracleID, buf, darcID),
+	}
+	return
+}
+
+func (c *contractOracle) Invoke(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, coins []byzcoin.Coin) (sc []byzcoin.StateChange, cout []byzcoin.Coin, err error) {
+	cout = coins
+
+	var darcID darc.ID
+	_, _, _, darcID, err = rst.GetValues(inst.InstanceID.Slice())
+	if err != nil {
+		return
+	}
+
+	if inst.Invoke.Command != "update" {
+		err = errors.New("oracle contract can only update")
+		return
+	}
+	roundBuf := inst.Invoke.Args.Search("round")
+	if len(roundBuf) != 8 {
+		err = errors.New("argument \"round\" is missing or wrong length")
+		return
+	}
+	round := binary.LittleEndian.Uint64(roundBuf)
+	if last := c.Latest(); last != nil && round <= last.Round {
+		err = fmt.Errorf("round must be higher than %d", last.Round)
+		return
+	}
+	value := inst.Invoke.Args.Search("value")
+	if len(value) > OracleMaxValueSize {
+		err = fmt.Errorf("value is longer than %d bytes", OracleMaxValueSize)
+		return
+	}
+	var sigs OracleSignatures
+	err = protobuf.Decode(inst.Invoke.Args.Search("signatures"), &sigs)
+	if err != nil {
+		return nil, nil, errors.New("couldn't decode signatures: " + err.Error())
+	}
+	if err = c.verifySignatures(OracleMessage(inst.InstanceID, round, value), sigs); err != nil {
+		return
+	}
+
+	c.History = append(c.History, OracleValue{
+		Round:      round,
+		Value:      value,
+		BlockIndex: uint64(rst.GetIndex()),
+	})
+	if uint64(len(c.History)) > c.HistorySize {
+		c.History = c.History[uint64(len(c.History))-c.HistorySize:]
+	}
+
+	var buf []byte
+	buf, err = protobuf.Encode(&c.OracleData)
+	if err != nil {
+		return nil, nil, errors.New("couldn't encode oracle: " + err.Error())
+	}
+	log.Lvlf2("oracle %x: accepted round %d", inst.InstanceID.Slice(), round)
+	sc = []byzcoin.StateChange{
+		byzcoin.NewStateChange(byzcoin.Update, inst.InstanceID, ContractOracleID, buf, darcID),
+	}
+	return
+}
+
+func (c *contractOracle) Delete(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, coins []byzcoin.Coin) (sc []byzcoin.StateChange, cout []byzcoin.Coin, err error) {
+	cout = coins
+
+	var darcID darc.ID
+	_, _, _, darcID, err = rst.GetValues(inst.InstanceID.Slice())
+	if err != nil {
+		return
+	}
+
+	sc = byzcoin.StateChanges{
+		byzcoin.NewStateChange(byzcoin.Remove, inst.InstanceID, ContractOracleID, nil, darcID),
+	}
+	return
+}
+
+// verifySignatures returns an error if less than threshold different
+// oracles signed the message.
+func (c *contractOracle) verifySignatures(msg []byte, sigs OracleSignatures) error {
+	signed := make(map[uint64]bool)
+	for _, s := range sigs.Signatures {
+		if s.Index >= uint64(len(c.Oracles)) {
+			return fmt.Errorf("there is no oracle %d", s.Index)
+		}
+		if signed[s.Index] {
+			continue
+		}
+		id, err := darc.ParseIdentity(c.Oracles[s.Index])
+		if err != nil {
+			return err
+		}
+		if err = id.Verify(msg, s.Signature); err != nil {
+			return fmt.Errorf("wrong signature of oracle %d: %v", s.Index, err)
+		}
+		signed[s.Index] = true
+	}
+	if uint64(len(signed)) < c.Threshold {
+		return fmt.Errorf("got %d signatures but need %d", len(signed), c.Threshold)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package contracts
+
+import (
+	"encoding/binary"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/byzcoin/contracttest"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/protobuf"
+)
+
+func TestOracle(t *testing.T) {
+	l, err := contracttest.NewLedger("spawn:oracle", "invoke:oracle.update")
+	require.NoError(t, err)
+	l.Register(ContractOracleID, contractOracleFromBytes)
+
+	oracles := make([]darc.Signer, 3)
+	var ids []string
+	for i := range oracles {
+		oracles[i] = darc.NewSignerEd25519(nil, nil)
+		ids = append(ids, oracles[i].Identity().String())
+	}
+	uint64Arg := func(name string, v uint64) byzcoin.Argument {
+		buf := make([]byte, 8)
+		binary.LittleEndian.PutUint64(buf, v)
+		return byzcoin.Argument{Name: name, Value: buf}
+	}
+
+	// The threshold cannot be higher than the number of oracles.
+	_, _, err = l.Run(contracttest.Spawn(l.GenesisDarcID(), ContractOracleID,
+		byzcoin.Argument{Name: "oracles", Value: []byte(strings.Join(ids, ","))},
+		uint64Arg("threshold", 4)))
+	require.Error(t, err)
+	scs, _, err := l.Run(contracttest.Spawn(l.GenesisDarcID(), ContractOracleID,
+		byzcoin.Argument{Name: "oracles", Value: []byte(strings.Join(ids, ","))},
+		uint64Arg("history", 2)))
+	require.NoError(t, err)
+	id := byzcoin.NewInstanceID(contracttest.RequireStateChange(t, scs, byzcoin.Create, ContractOracleID).InstanceID)
+
+	_, err = OracleLatest(l, id)
+	require.Error(t, err)
+	_, err = OracleLatest(l, l.GenesisDarcID())
+	require.Error(t, err)
+
+	update := func(round uint64, value string, signers ...int) error {
+		var sigs OracleSignatures
+		for _, i := range signers {
+			sig, err := oracles[i].Sign(OracleMessage(id, round, []byte(value)))
+			require.NoError(t, err)
+			sigs.Signatures = append(sigs.Signatures, OracleSignature{Index: uint64(i), Signature: sig})
+		}
+		sigsBuf, err := protobuf.Encode(&sigs)
+		require.NoError(t, err)
+		_, _, err = l.Run(contracttest.Invoke(id, ContractOracleID, "update",
+			uint64Arg("round", round),
+			byzcoin.Argument{Name: "value", Value: []byte(value)},
+			byzcoin.Argument{Name: "signatures", Value: sigsBuf}))
+		return err
+	}
+
+	// Two of the three oracles must sign.
+	require.Error(t, update(1, "100", 0))
+	require.Error(t, update(1, "100", 0, 0))
+	require.NoError(t, update(1, "100", 0, 2))
+	l.SetIndex(5)
+	require.NoError(t, update(2, "101", 0, 1, 2))
+	v, err := OracleLatest(l, id)
+	require.NoError(t, err)
+	require.Equal(t, &OracleValue{Round: 2, Value: []byte("101"), BlockIndex: 5}, v)
+
+	// The rounds must increase and the signatures must match the value.
+	require.Error(t, update(2, "102", 0, 1))
+	sigs := OracleSignatures{Signatures: []OracleSignature{{Index: 1}}}
+	sigs.Signatures[0].Signature, err = oracles[1].Sign(OracleMessage(id, 3, []byte("103")))
+	require.NoError(t, err)
+	sig, err := oracles[0].Sign(OracleMessage(id, 3, []byte("999")))
+	require.NoError(t, err)
+	sigs.Signatures = append(sigs.Signatures, OracleSignature{Index: 0, Signature: sig})
+	sigsBuf, err := protobuf.Encode(&sigs)
+	require.NoError(t, err)
+	_, _, err = l.Run(contracttest.Invoke(id, ContractOracleID, "update", uint64Arg("round", 3),
+		byzcoin.Argument{Name: "value", Value: []byte("103")},
+		byzcoin.Argument{Name: "signatures", Value: sigsBuf}))
+	require.Error(t, err)
+
+	// Only the last values are kept.
+	require.NoError(t, update(3, "103", 1, 2))
+	var o OracleData
+	require.NoError(t, protobuf.Decode(l.RequireInstance(t, id, ContractOracleID), &o))
+	require.Len(t, o.History, 2)
+	require.Equal(t, uint64(2), o.History[0].Round)
+	require.Equal(t, []byte("103"), o.Latest().Value)
+}
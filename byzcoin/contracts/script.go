@@ -0,0 +1,334 @@
+package contracts
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/onet/v3/log"
+	"go.dedis.ch/protobuf"
+)
+
+// ContractScriptID denotes a contract whose logic is stored in its instance.
+const ContractScriptID = "script"
+
+// scriptMaxStorage is the maximum number of keys in the storage of a script.
+const scriptMaxStorage = 1000
+
+// ContractScript runs code stored in the instance with the interpreter
+// described in vm.go, so that new logic can be deployed without updating
+// the conodes. The code can only change the storage and the coins of its
+// own instance, and send coins to coin instances.
+//
+// Spawn takes the code in the argument "code", created by Assemble, and
+// runs it with the command "spawn", which can initialize the storage. The
+// argument "darcID" gives the darc of the instance, by default the darc of
+// the spawn instruction.
+//
+// Every invoke runs the code with the command of the instruction, so the
+// rules of the darc are "invoke:script.<command>". If the code fails, the
+// instruction is refused and nothing changes.
+//
+// A script instance can only be deleted once it holds no coins.
+
+// Script is the data stored in a script instance.
+type Script struct {
+	// Code is run by every instruction.
+	Code []byte
+	// Storage holds the data of the script, sorted by key.
+	Storage []MapEntry
+	// Coins are held by the script.
+	Coins []byzcoin.Coin
+}
+
+func contractScriptFromBytes(in []byte) (byzcoin.Contract, error) {
+	c := &contractScript{}
+	err := protobuf.Decode(in, &c.Script)
+	if err != nil {
+		return nil, errors.New("couldn't unmarshal instance data: " + err.Error())
+	}
+	return c, nil
+}
+
+type contractScript struct {
+	byzcoin.BasicContract
+	Script
+}
+
+func (c *contractScript) Spawn(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, coins []byzcoin.Coin) (sc []byzcoin.StateChange, cout []byzcoin.Coin, err error) {
+	cout = coins
+
+	var darcID darc.ID
+	_, _, _, darcID, err = rst.GetValues(inst.InstanceID.Slice())
+	if err != nil {
+		return
+	}
+	if did := inst.Spawn.Args.Search("darcID"); did != nil {
+		darcID = darc.ID(did)
+	}
+
+	c.Code = inst.Spawn.Args.Search("code")
+	if len(c.Code) == 0 {
+		err = errors.New("argument \"code\" is missing")
+		return
+	}
+	if err = checkCode(c.Code); err != nil {
+		return
+	}
+
+	id := inst.DeriveID("")
+	log.Lvlf2("Spawning script to %x", id.Slice())
+	return c.run(rst, id, byzcoin.Create, darcID, "spawn", inst.Spawn.Args, coins)
+}
+
+func (c *contractScript) Invoke(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, coins []byzcoin.Coin) (sc []byzcoin.StateChange, cout []byzcoin.Coin, err error) {
+	cout = coins
+
+	var darcID darc.ID
+	_, _, _, darcID, err = rst.GetValues(inst.InstanceID.Slice())
+	if err != nil {
+		return
+	}
+
+	return c.run(rst, inst.InstanceID, byzcoin.Update, darcID, inst.Invoke.Command, inst.Invoke.Args, coins)
+}
+
+func (c *contractScript) Delete(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, coins []byzcoin.Coin) (sc []byzcoin.StateChange, cout []byzcoin.Coin, err error) {
+	cout = coins
+
+	var darcID darc.ID
+	_, _, _, darcID, err = rst.GetValues(inst.InstanceID.Slice())
+	if err != nil {
+		return
+	}
+
+	for _, co := range c.Coins {
+		if co.Value > 0 {
+			err = errors.New("cannot delete a script that still holds coins")
+			return
+		}
+	}
+	sc = byzcoin.StateChanges{
+		byzcoin.NewStateChange(byzcoin.Remove, inst.InstanceID, ContractScriptID, nil, darcID),
+	}
+	return
+}
+
+// run executes the code and returns the state changes of the instance and
+// of the coin instances that got paid.
+func (c *contractScript) run(rst byzcoin.ReadOnlyStateTrie, id byzcoin.InstanceID, action byzcoin.StateAction,
+	darcID darc.ID, command string, args byzcoin.Arguments, coins []byzcoin.Coin) (sc []byzcoin.StateChange, cout []byzcoin.Coin, err error) {
+	h := &scriptHost{
+		rst:     rst,
+		cmd:     command,
+		args:    args,
+		script:  &c.Script,
+		cout:    append([]byzcoin.Coin{}, coins...),
+		pending: make(map[string]*byzcoin.Coin),
+	}
+	m := &vm{code: c.Code, host: h, gas: ScriptMaxGas}
+	if err = m.run(); err != nil {
+		return nil, nil, err
+	}
+	log.Lvlf3("script %x: %s used %d gas", id.Slice(), command, ScriptMaxGas-m.gas)
+	c.Coins = dropEmptyCoins(c.Coins)
+
+	buf, err := protobuf.Encode(&c.Script)
+	if err != nil {
+		return nil, nil, errors.New("couldn't encode script: " + err.Error())
+	}
+	sc = append(sc, byzcoin.NewStateChange(action, id, ContractScriptID, buf, darcID))
+	for _, p := range h.payments {
+		accountBuf, err := protobuf.Encode(h.pending[string(p.id.Slice())])
+		if err != nil {
+			return nil, nil, errors.New("couldn't marshal account: " + err.Error())
+		}
+		sc = append(sc, byzcoin.NewStateChange(byzcoin.Update, p.id, ContractCoinID, accountBuf, p.darcID))
+	}
+
+	cout = dropEmptyCoins(h.cout)
+	return
+}
+
+// scriptHost implements vmHost for one instruction.
+type scriptHost struct {
+	rst    byzcoin.ReadOnlyStateTrie
+	cmd    string
+	args   byzcoin.Arguments
+	script *Script
+	cout   []byzcoin.Coin
+	// pending holds the coin instances paid by the script, and payments
+	// keeps them in order, so that the state changes are the same on all
+	// the nodes.
+	pending  map[string]*byzcoin.Coin
+	payments []scriptPayment
+}
+
+type scriptPayment struct {
+	id     byzcoin.InstanceID
+	darcID darc.ID
+}
+
+func (h *scriptHost) command() string {
+	return h.cmd
+}
+
+func (h *scriptHost) arg(name string) []byte {
+	return h.args.Search(name)
+}
+
+func (h *scriptHost) index() uint64 {
+	return uint64(h.rst.GetIndex())
+}
+
+func (h *scriptHost) search(key string) int {
+	return sort.Search(len(h.script.Storage), func(i int) bool {
+		return h.script.Storage[i].Key >= key
+	})
+}
+
+func (h *scriptHost) load(key string) []byte {
+	i := h.search(key)
+	if i < len(h.script.Storage) && h.script.Storage[i].Key == key {
+		return h.script.Storage[i].Value
+	}
+	return nil
+}
+
+func (h *scriptHost) store(key string, value []byte) error {
+	s := h.script
+	i := h.search(key)
+	exists := i < len(s.Storage) && s.Storage[i].Key == key
+	switch {
+	case len(value) == 0 && exists:
+		s.Storage = append(s.Storage[:i], s.Storage[i+1:]...)
+	case len(value) == 0:
+	case exists:
+		s.Storage[i].Value = value
+	default:
+		if len(s.Storage) >= scriptMaxStorage {
+			return fmt.Errorf("storage is full with %d keys", scriptMaxStorage)
+		}
+		s.Storage = append(s.Storage, MapEntry{})
+		copy(s.Storage[i+1:], s.Storage[i:])
+		s.Storage[i] = MapEntry{Key: key, Value: value}
+	}
+	return nil
+}
+
+func (h *scriptHost) get(id []byte) ([]byte, uint64, string, []byte, error) {
+	v, version, cid, did, err := h.rst.GetValues(id)
+	if err != nil {
+		// The instance doesn't exist.
+		return nil, 0, "", nil, nil
+	}
+	return v, version, cid, did, nil
+}
+
+func (h *scriptHost) coins(name []byte) uint64 {
+	return sumCoins(h.cout, name)
+}
+
+func (h *scriptHost) balance(name []byte) uint64 {
+	return sumCoins(h.script.Coins, name)
+}
+
+func (h *scriptHost) take(name []byte, value uint64) error {
+	if err := subCoins(h.cout, name, value); err != nil {
+		return errors.New("not enough input coins: " + err.Error())
+	}
+	h.script.Coins = addCoins(h.script.Coins, name, value)
+	return nil
+}
+
+func (h *scriptHost) give(name []byte, value uint64) error {
+	if err := subCoins(h.script.Coins, name, value); err != nil {
+		return errors.New("not enough coins in the script: " + err.Error())
+	}
+	h.cout = addCoins(h.cout, name, value)
+	return nil
+}
+
+func (h *scriptHost) pay(coin []byte, value uint64) error {
+	id := byzcoin.NewInstanceID(coin)
+	account, ok := h.pending[string(id.Slice())]
+	if !ok {
+		v, _, cid, did, err := h.rst.GetValues(id.Slice())
+		if err != nil {
+			return err
+		}
+		if cid != ContractCoinID {
+			return fmt.Errorf("%x is not a coin instance", coin)
+		}
+		account = &byzcoin.Coin{}
+		if err = protobuf.Decode(v, account); err != nil {
+			return errors.New("couldn't unmarshal account: " + err.Error())
+		}
+		h.pending[string(id.Slice())] = account
+		h.payments = append(h.payments, scriptPayment{id, did})
+	}
+	if err := subCoins(h.script.Coins, account.Name.Slice(), value); err != nil {
+		return errors.New("not enough coins in the script: " + err.Error())
+	}
+	return account.SafeAdd(value)
+}
+
+// sumCoins returns the value of the coins with the given name, or the
+// maximum value if the sum overflows.
+func sumCoins(coins []byzcoin.Coin, name []byte) (sum uint64) {
+	for _, co := range coins {
+		if co.Name.Equal(byzcoin.NewInstanceID(name)) {
+			if sum+co.Value < sum {
+				return ^uint64(0)
+			}
+			sum += co.Value
+		}
+	}
+	return
+}
+
+// subCoins removes value from the coins with the given name.
+func subCoins(coins []byzcoin.Coin, name []byte, value uint64) error {
+	if sumCoins(coins, name) < value {
+		return fmt.Errorf("missing coins of %x", name)
+	}
+	for i := range coins {
+		if value == 0 {
+			break
+		}
+		if coins[i].Name.Equal(byzcoin.NewInstanceID(name)) {
+			sub := coins[i].Value
+			if sub > value {
+				sub = value
+			}
+			coins[i].Value -= sub
+			value -= sub
+		}
+	}
+	return nil
+}
+
+// addCoins adds value to the coins with the given name.
+func addCoins(coins []byzcoin.Coin, name []byte, value uint64) []byzcoin.Coin {
+	for i := range coins {
+		if coins[i].Name.Equal(byzcoin.NewInstanceID(name)) {
+			if coins[i].SafeAdd(value) == nil {
+				return coins
+			}
+		}
+	}
+	return append(coins, byzcoin.Coin{Name: byzcoin.NewInstanceID(name), Value: value})
+}
+
+// dropEmptyCoins returns the coins that have a value.
+func dropEmptyCoins(coins []byzcoin.Coin) []byzcoin.Coin {
+	out := []byzcoin.Coin{}
+	for _, co := range coins {
+		if co.Value > 0 {
+			out = append(out, co)
+		}
+	}
+	return out
+}
@@ -0,0 +1,161 @@
+package contracts
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/protobuf"
+)
+
+const testScript = `
+	CMD
+	PUSH "spawn"
+	EQ
+	JZ inc
+	PUSH "count" ; the counter starts at 0
+	PUSH 0
+	STORE
+	HALT
+inc:
+	CMD
+	PUSH "inc"
+	EQ
+	JZ deposit
+	PUSH "count"
+	DUP
+	LOAD
+	PUSH 1
+	ADD
+	STORE
+	HALT
+deposit:
+	CMD
+	PUSH "deposit"
+	EQ
+	JZ pay
+	PUSH "name"
+	ARG
+	DUP
+	COINS
+	TAKE
+	HALT
+pay:
+	CMD
+	PUSH "pay"
+	EQ
+	JZ loop
+	PUSH "to"
+	ARG
+	PUSH "amount"
+	ARG
+	PAY
+	HALT
+loop:
+	CMD
+	PUSH "loop"
+	EQ
+	JZ fail
+forever:
+	JMP forever
+fail:
+	PUSH "unknown command"
+	FAIL
+`
+
+func TestScript_Assemble(t *testing.T) {
+	_, err := Assemble("PUSH")
+	require.Error(t, err)
+	_, err = Assemble("JMP nowhere")
+	require.Error(t, err)
+	_, err = Assemble("UNKNOWN")
+	require.Error(t, err)
+	code, err := Assemble(`PUSH "a;b" ; comment`)
+	require.NoError(t, err)
+	require.Equal(t, []byte{opPush, 3, 0, 'a', ';', 'b'}, code)
+
+	// Jumps must land on an operation.
+	require.Error(t, checkCode([]byte{opJmp, 1, 0, 0, 0}))
+	require.Error(t, checkCode([]byte{opPush, 2, 0, 1}))
+	require.Error(t, checkCode([]byte{0}))
+}
+
+func TestScript(t *testing.T) {
+	ct := newCT("spawn:script")
+	account := iid("account")
+	ct.Store(account, ciZero, ContractCoinID, gdarc.GetBaseID())
+	code, err := Assemble(testScript)
+	require.NoError(t, err)
+
+	c, err := contractScriptFromBytes(nil)
+	require.NoError(t, err)
+	sc, _, err := c.Spawn(ct, byzcoin.Instruction{
+		InstanceID: byzcoin.NewInstanceID(gdarc.GetBaseID()),
+		Spawn: &byzcoin.Spawn{
+			ContractID: ContractScriptID,
+			Args:       byzcoin.Arguments{{Name: "code", Value: code}},
+		},
+	}, nil)
+	require.NoError(t, err)
+	id := iid("script")
+	ct.Store(id, sc[0].Value, ContractScriptID, gdarc.GetBaseID())
+
+	invoke := func(cmd string, args byzcoin.Arguments, coins []byzcoin.Coin) ([]byzcoin.StateChange, []byzcoin.Coin, error) {
+		c, err := contractScriptFromBytes(ct.values[string(id.Slice())])
+		require.NoError(t, err)
+		sc, cout, err := c.Invoke(ct, byzcoin.Instruction{
+			InstanceID: id,
+			Invoke: &byzcoin.Invoke{
+				ContractID: ContractScriptID,
+				Command:    cmd,
+				Args:       args,
+			},
+		}, coins)
+		if err == nil {
+			ct.Store(id, sc[0].Value, ContractScriptID, gdarc.GetBaseID())
+		}
+		return sc, cout, err
+	}
+	script := func() (s Script) {
+		require.NoError(t, protobuf.Decode(ct.values[string(id.Slice())], &s))
+		return
+	}
+
+	_, _, err = invoke("inc", nil, nil)
+	require.NoError(t, err)
+	_, _, err = invoke("inc", nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, vmNumber(2), script().Storage[0].Value)
+
+	_, _, err = invoke("unknown", nil, nil)
+	require.Error(t, err)
+	_, _, err = invoke("loop", nil, nil)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "out of gas")
+
+	// The script keeps the coins with the given name.
+	other := byzcoin.Coin{Name: iid("other"), Value: 1}
+	_, cout, err := invoke("deposit", byzcoin.Arguments{{Name: "name", Value: CoinName.Slice()}},
+		[]byzcoin.Coin{{Name: CoinName, Value: 3}, other})
+	require.NoError(t, err)
+	require.Equal(t, []byzcoin.Coin{other}, cout)
+	require.Equal(t, []byzcoin.Coin{{Name: CoinName, Value: 3}}, script().Coins)
+
+	amount := make([]byte, 8)
+	binary.LittleEndian.PutUint64(amount, 4)
+	payArgs := byzcoin.Arguments{{Name: "to", Value: account.Slice()}, {Name: "amount", Value: amount}}
+	_, _, err = invoke("pay", payArgs, nil)
+	require.Error(t, err)
+	binary.LittleEndian.PutUint64(amount, 2)
+	sc, _, err = invoke("pay", payArgs, nil)
+	require.NoError(t, err)
+	require.Equal(t, byzcoin.NewStateChange(byzcoin.Update, account, ContractCoinID, ciTwo, gdarc.GetBaseID()), sc[1])
+	require.Equal(t, []byzcoin.Coin{{Name: CoinName, Value: 1}}, script().Coins)
+
+	// A script can only be deleted once it holds no coins.
+	c, err = contractScriptFromBytes(ct.values[string(id.Slice())])
+	require.NoError(t, err)
+	_, _, err = c.Delete(ct, byzcoin.Instruction{InstanceID: id}, nil)
+	require.Error(t, err)
+}
@@ -33,6 +33,14 @@ func newService(c *onet.Context) (onet.Service, error) {
 	}
 	byzcoin.RegisterContract(c, ContractValueID, contractValueFromBytes)
 	byzcoin.RegisterContract(c, ContractCoinID, contractCoinFromBytes)
+	byzcoin.RegisterContract(c, ContractHTLCID, contractHTLCFromBytes)
+	byzcoin.RegisterContract(c, ContractTokenID, contractTokenFromBytes)
+	byzcoin.RegisterContract(c, ContractNFTCollectionID, contractNFTCollectionFromBytes)
+	byzcoin.RegisterContract(c, ContractNFTID, contractNFTFromBytes)
+	byzcoin.RegisterContract(c, ContractEscrowID, contractEscrowFromBytes)
+	byzcoin.RegisterContract(c, ContractMapID, contractMapFromBytes)
+	byzcoin.RegisterContract(c, ContractScriptID, contractScriptFromBytes)
+	byzcoin.RegisterContract(c, ContractOracleID, contractOracleFromBytes)
 	byzcoin.RegisterContract(c, ContractInsecureDarcID, s.contractInsecureDarcFromBytes)
 	return s, nil
 }
@@ -0,0 +1,250 @@
+package contracts
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/onet/v3/log"
+	"go.dedis.ch/protobuf"
+)
+
+// ContractTokenID denotes a contract that defines a currency and keeps
+// track of its supply.
+const ContractTokenID = "token"
+
+// ContractToken defines a fungible token. The coins of a token are stored
+// in coin instances with the ID of the token instance as name. Contrary to
+// the other coins, they cannot be minted by the coin contract, but only by
+// the token instance, which updates the supply in the same instruction.
+//
+// Spawn creates the token instance at an ID derived from the instruction, so
+// that nobody can know the name of its coins, and hold some, before the
+// token exists. It takes the following arguments:
+//  - symbol is the name of the token
+//  - decimals is the number of decimals used to display the coins, as a
+//    64-bit uint in LittleEndian. Optional, 0 by default
+//  - max_supply is the maximum number of coins that can exist, as a 64-bit
+//    uint in LittleEndian. Optional, 0 means no maximum
+//  - darcID is the darc controlling the mint and burn of the coins.
+//    Optional, the darc of the spawn instruction by default
+//
+// The following methods are available:
+//  - mint creates the number of coins in the argument "coins", as a 64-bit
+//    uint in LittleEndian, in the coin instance given in the argument
+//    "destination".
+//  - burn destroys all the coins of the token given to the instruction,
+//    typically fetched from a coin instance in the previous instruction.
+//    Other coins are passed on to the next instruction.
+//
+// A token instance can only be deleted once all its coins are burnt.
+
+func contractTokenFromBytes(in []byte) (byzcoin.Contract, error) {
+	c := &contractToken{}
+	err := protobuf.Decode(in, &c.Token)
+	if err != nil {
+		return nil, errors.New("couldn't unmarshal instance data: " + err.Error())
+	}
+	return c, nil
+}
+
+// Token is the data stored in a token instance.
+type Token struct {
+	// Symbol is the name of the token.
+	Symbol string
+	// Decimals is the number of decimals used to display the coins.
+	Decimals uint32
+	// MintDarc is the darc of the token instance, whose rules allow to
+	// mint and burn coins.
+	MintDarc darc.ID
+	// MaxSupply is the maximum number of coins, or 0 if there is none.
+	MaxSupply uint64
+	// Supply is the number of coins that have been minted and not burnt.
+	Supply uint64
+}
+
+type contractToken struct {
+	byzcoin.BasicContract
+	Token
+}
+
+func (c *contractToken) Spawn(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, coins []byzcoin.Coin) (sc []byzcoin.StateChange, cout []byzcoin.Coin, err error) {
+	cout = coins
+
+	var darcID darc.ID
+	_, _, _, darcID, err = rst.GetValues(inst.InstanceID.Slice())
+	if err != nil {
+		return
+	}
+	if did := inst.Spawn.Args.Search("darcID"); did != nil {
+		darcID = darc.ID(did)
+	}
+
+	c.Symbol = string(inst.Spawn.Args.Search("symbol"))
+	if c.Symbol == "" {
+		err = errors.New("argument \"symbol\" is missing")
+		return
+	}
+	if buf := inst.Spawn.Args.Search("max_supply"); buf != nil {
+		if len(buf) != 8 {
+			err = errors.New("argument \"max_supply\" is wrong length")
+			return
+		}
+		c.MaxSupply = binary.LittleEndian.Uint64(buf)
+	}
+	if buf := inst.Spawn.Args.Search("decimals"); buf != nil {
+		if len(buf) != 8 {
+			err = errors.New("argument \"decimals\" is wrong length")
+			return
+		}
+		decimals := binary.LittleEndian.Uint64(buf)
+		// A uint64 has at most 19 decimal digits.
+		if decimals > 19 {
+			err = errors.New("too many decimals")
+			return
+		}
+		c.Decimals = uint32(decimals)
+	}
+	c.MintDarc = darcID
+
+	var buf []byte
+	buf, err = protobuf.Encode(&c.Token)
+	if err != nil {
+		return nil, nil, errors.New("couldn't encode token: " + err.Error())
+	}
+	id := inst.DeriveID("")
+	log.Lvlf2("Spawning token %s to %x, with darc %x", c.Symbol, id.Slice(), darcID[:])
+	sc = []byzcoin.StateChange{
+		byzcoin.NewStateChange(byzcoin.Create, id, ContractTokenID, buf, darcID),
+	}
+	return
+}
+
+func (c *contractToken) Invoke(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, coins []byzcoin.Coin) (sc []byzcoin.StateChange, cout []byzcoin.Coin, err error) {
+	cout = coins
+
+	var darcID darc.ID
+	_, _, _, darcID, err = rst.GetValues(inst.InstanceID.Slice())
+	if err != nil {
+		return
+	}
+
+	switch inst.Invoke.Command {
+	case "mint":
+		coinsBuf := inst.Invoke.Args.Search("coins")
+		if len(coinsBuf) != 8 {
+			err = errors.New("argument \"coins\" is missing or wrong length")
+			return
+		}
+		coinsArg := binary.LittleEndian.Uint64(coinsBuf)
+		if err = c.mint(coinsArg); err != nil {
+			return
+		}
+
+		target := byzcoin.NewInstanceID(inst.Invoke.Args.Search("destination"))
+		var (
+			v   []byte
+			cid string
+			did darc.ID
+		)
+		v, _, cid, did, err = rst.GetValues(target.Slice())
+		if err == nil && cid != ContractCoinID {
+			err = errors.New("destination is not a coin contract")
+		}
+		if err != nil {
+			return
+		}
+		var account byzcoin.Coin
+		err = protobuf.Decode(v, &account)
+		if err != nil {
+			return nil, nil, errors.New("couldn't unmarshal target account: " + err.Error())
+		}
+		if !account.Name.Equal(inst.InstanceID) {
+			err = fmt.Errorf("destination doesn't hold coins of %s", c.Symbol)
+			return
+		}
+		err = account.SafeAdd(coinsArg)
+		if err != nil {
+			return
+		}
+		var accountBuf []byte
+		accountBuf, err = protobuf.Encode(&account)
+		if err != nil {
+			return nil, nil, errors.New("couldn't marshal target account: " + err.Error())
+		}
+		log.Lvlf2("minting %d %s to %x", coinsArg, c.Symbol, target.Slice())
+		sc = append(sc, byzcoin.NewStateChange(byzcoin.Update, target, ContractCoinID, accountBuf, did))
+	case "burn":
+		cout = []byzcoin.Coin{}
+		var burnt uint64
+		for _, co := range coins {
+			if co.Name.Equal(inst.InstanceID) {
+				if co.Value > c.Supply-burnt {
+					err = errors.New("burning more coins than the supply")
+					return
+				}
+				burnt += co.Value
+			} else {
+				cout = append(cout, co)
+			}
+		}
+		if burnt == 0 {
+			err = errors.New("no coins to burn")
+			return
+		}
+		c.Supply -= burnt
+		log.Lvlf2("burning %d %s", burnt, c.Symbol)
+	default:
+		err = errors.New("token contract can only mint and burn")
+		return
+	}
+
+	var buf []byte
+	buf, err = protobuf.Encode(&c.Token)
+	if err != nil {
+		return nil, nil, errors.New("couldn't encode token: " + err.Error())
+	}
+	sc = append(sc, byzcoin.NewStateChange(byzcoin.Update, inst.InstanceID, ContractTokenID, buf, darcID))
+	return
+}
+
+func (c *contractToken) Delete(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, coins []byzcoin.Coin) (sc []byzcoin.StateChange, cout []byzcoin.Coin, err error) {
+	cout = coins
+
+	var darcID darc.ID
+	_, _, _, darcID, err = rst.GetValues(inst.InstanceID.Slice())
+	if err != nil {
+		return
+	}
+
+	if c.Supply > 0 {
+		err = errors.New("cannot delete a token that still has coins")
+		return
+	}
+	sc = byzcoin.StateChanges{
+		byzcoin.NewStateChange(byzcoin.Remove, inst.InstanceID, ContractTokenID, nil, darcID),
+	}
+	return
+}
+
+// mint adds coins to the supply, making sure the maximum is not reached.
+func (c *contractToken) mint(coins uint64) error {
+	supply := c.Supply + coins
+	if supply < c.Supply {
+		return errors.New("supply overflow")
+	}
+	if c.MaxSupply > 0 && supply > c.MaxSupply {
+		return fmt.Errorf("minting %d %s would exceed the maximum supply of %d", coins, c.Symbol, c.MaxSupply)
+	}
+	c.Supply = supply
+	return nil
+}
+
+// isToken returns true if the coins with the given name belong to a token.
+func isToken(rst byzcoin.ReadOnlyStateTrie, name byzcoin.InstanceID) bool {
+	_, _, cid, _, err := rst.GetValues(name.Slice())
+	// Most names, like CoinName, are not instances at all.
+	return err == nil && cid == ContractTokenID
+}
@@ -0,0 +1,144 @@
+package contracts
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/protobuf"
+)
+
+func TestToken(t *testing.T) {
+	ct := newCT("spawn:token", "invoke:token.mint", "invoke:token.burn")
+	u64 := func(v uint64) []byte {
+		buf := make([]byte, 8)
+		binary.LittleEndian.PutUint64(buf, v)
+		return buf
+	}
+
+	c, err := contractTokenFromBytes(nil)
+	require.NoError(t, err)
+	spawn := byzcoin.Instruction{
+		InstanceID: byzcoin.NewInstanceID(gdarc.GetBaseID()),
+		Spawn: &byzcoin.Spawn{
+			ContractID: ContractTokenID,
+			Args: byzcoin.Arguments{
+				{Name: "symbol", Value: []byte("CHF")},
+				{Name: "decimals", Value: u64(2)},
+				{Name: "max_supply", Value: u64(100)},
+			},
+		},
+	}
+	sc, _, err := c.Spawn(ct, spawn, nil)
+	require.NoError(t, err)
+	id := spawn.DeriveID("")
+	require.Equal(t, id, sc[0].InstanceID)
+	ct.Store(id, sc[0].Value, ContractTokenID, gdarc.GetBaseID())
+
+	account := iid("account")
+	coinBuf, err := protobuf.Encode(&byzcoin.Coin{Name: id})
+	require.NoError(t, err)
+	ct.Store(account, coinBuf, ContractCoinID, gdarc.GetBaseID())
+	other := iid("other")
+	ct.Store(other, ciZero, ContractCoinID, gdarc.GetBaseID())
+
+	invoke := func(cmd string, args byzcoin.Arguments, coins []byzcoin.Coin) ([]byzcoin.StateChange, []byzcoin.Coin, error) {
+		c, err := contractTokenFromBytes(ct.values[string(id.Slice())])
+		require.NoError(t, err)
+		return c.Invoke(ct, byzcoin.Instruction{
+			InstanceID: id,
+			Invoke: &byzcoin.Invoke{
+				ContractID: ContractTokenID,
+				Command:    cmd,
+				Args:       args,
+			},
+		}, coins)
+	}
+
+	// The coin contract cannot mint coins of a token.
+	_, _, err = ct.getContract(account).Invoke(ct, byzcoin.Instruction{
+		InstanceID: account,
+		Invoke: &byzcoin.Invoke{
+			ContractID: ContractCoinID,
+			Command:    "mint",
+			Args:       byzcoin.Arguments{{Name: "coins", Value: coinOne}},
+		},
+	}, nil)
+	require.Error(t, err)
+
+	// Only coins of the token can be minted.
+	_, _, err = invoke("mint", byzcoin.Arguments{{Name: "coins", Value: u64(10)},
+		{Name: "destination", Value: other.Slice()}}, nil)
+	require.Error(t, err)
+	_, _, err = invoke("mint", byzcoin.Arguments{{Name: "coins", Value: u64(101)},
+		{Name: "destination", Value: account.Slice()}}, nil)
+	require.Error(t, err)
+
+	sc, _, err = invoke("mint", byzcoin.Arguments{{Name: "coins", Value: u64(60)},
+		{Name: "destination", Value: account.Slice()}}, nil)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(sc))
+	var coin byzcoin.Coin
+	require.NoError(t, protobuf.Decode(sc[0].Value, &coin))
+	require.Equal(t, uint64(60), coin.Value)
+	var token Token
+	require.NoError(t, protobuf.Decode(sc[1].Value, &token))
+	require.Equal(t, uint64(60), token.Supply)
+	require.Equal(t, uint32(2), token.Decimals)
+	ct.Store(id, sc[1].Value, ContractTokenID, gdarc.GetBaseID())
+
+	// The maximum supply counts the coins already minted.
+	_, _, err = invoke("mint", byzcoin.Arguments{{Name: "coins", Value: u64(41)},
+		{Name: "destination", Value: account.Slice()}}, nil)
+	require.Error(t, err)
+
+	_, _, err = invoke("burn", nil, []byzcoin.Coin{{Name: id, Value: 61}})
+	require.Error(t, err)
+	sc, cout, err := invoke("burn", nil, []byzcoin.Coin{{Name: id, Value: 60}, {Name: CoinName, Value: 1}})
+	require.NoError(t, err)
+	require.Equal(t, []byzcoin.Coin{{Name: CoinName, Value: 1}}, cout)
+	require.NoError(t, protobuf.Decode(sc[0].Value, &token))
+	require.Equal(t, uint64(0), token.Supply)
+	ct.Store(id, sc[0].Value, ContractTokenID, gdarc.GetBaseID())
+
+	c, err = contractTokenFromBytes(ct.values[string(id.Slice())])
+	require.NoError(t, err)
+	sc, _, err = c.Delete(ct, byzcoin.Instruction{InstanceID: id}, nil)
+	require.NoError(t, err)
+	require.Equal(t, byzcoin.Remove, sc[0].StateAction)
+}
+
+// strictCT is a cvTest that returns byzcoin.ErrKeyNotSet for missing keys,
+// like the trie of a node.
+type strictCT struct {
+	*cvTest
+}
+
+func (ct strictCT) GetValues(key []byte) ([]byte, uint64, string, darc.ID, error) {
+	if _, ok := ct.values[string(key)]; !ok {
+		return nil, 0, "", nil, byzcoin.ErrKeyNotSet
+	}
+	return ct.cvTest.GetValues(key)
+}
+
+func TestToken_MintCoin(t *testing.T) {
+	ct := strictCT{newCT("invoke:mint")}
+	account := iid("account")
+	ct.Store(account, ciZero, ContractCoinID, gdarc.GetBaseID())
+
+	// The name of the coins is not an instance, which doesn't make them
+	// coins of a token.
+	sc, _, err := ct.getContract(account).Invoke(ct, byzcoin.Instruction{
+		InstanceID: account,
+		Invoke: &byzcoin.Invoke{
+			ContractID: ContractCoinID,
+			Command:    "mint",
+			Args:       byzcoin.Arguments{{Name: "coins", Value: coinOne}},
+		},
+	}, nil)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(sc))
+	require.Equal(t, ciOne, sc[0].Value)
+}
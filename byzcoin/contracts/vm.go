@@ -0,0 +1,536 @@
+package contracts
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// This file holds the interpreter of the script contract. It is a stack
+// machine working on byte slices, where numbers are 64-bit uints in
+// LittleEndian, like the arguments of the other contracts. It has no access
+// to the clock, randomness or the network, so every node gets the same
+// result for the same instruction, and every operation costs gas, so that
+// every execution terminates.
+//
+// The code is a sequence of operations of one byte. PUSH is followed by the
+// length of the data on 2 bytes and the data, JMP and JZ are followed by
+// the offset of the target on 4 bytes. Assemble creates the code from a
+// textual form, with one operation per line, "label:" to mark jump targets
+// and ";" to start comments. The data of PUSH can be a number, a "string"
+// or hexadecimal bytes starting with 0x.
+//
+// Below, a is the top of the stack and b the element below it.
+//  - PUSH data, POP, DUP, SWAP, OVER: manipulate the stack
+//  - ADD, SUB, MUL, DIV, MOD: push b op a, failing on overflow
+//  - LT, GT: push 1 if b < a, or b > a, else 0
+//  - EQ: push 1 if a and b are the same bytes, else 0
+//  - NOT: push 1 if a is zero, else 0
+//  - CAT: push b followed by a
+//  - LEN: push the length of a
+//  - JMP label, JZ label: jump always, or if a is zero
+//  - HALT: stop successfully
+//  - FAIL: stop with a as error message, so the instruction is refused
+//  - CMD: push the command of the instruction
+//  - ARG: push the argument named a, empty if it is missing
+//  - INDEX: push the index of the current block
+//  - LOAD: push the value of the key a in the storage of the instance
+//  - STORE: set the key b to the value a, an empty value deletes the key
+//  - GET: push the value, version, contract ID and darc ID of the
+//    instance a, as they were before the instruction. They are all empty if
+//    the instance doesn't exist
+//  - COINS: push the value of the input coins with the name a
+//  - BALANCE: push the value of the coins named a held by the instance
+//  - TAKE: move a input coins named b to the instance
+//  - GIVE: move a coins named b from the instance to the output coins
+//  - PAY: send a coins from the instance to the coin instance b
+//
+// A value is zero if it is empty or has only zero bytes.
+
+const (
+	opPush byte = iota + 1
+	opPop
+	opDup
+	opSwap
+	opOver
+	opAdd
+	opSub
+	opMul
+	opDiv
+	opMod
+	opLt
+	opGt
+	opEq
+	opNot
+	opCat
+	opLen
+	opJmp
+	opJz
+	opHalt
+	opFail
+	opCmd
+	opArg
+	opIndex
+	opLoad
+	opStore
+	opGet
+	opCoins
+	opBalance
+	opTake
+	opGive
+	opPay
+)
+
+var opNames = map[string]byte{
+	"PUSH": opPush, "POP": opPop, "DUP": opDup, "SWAP": opSwap, "OVER": opOver,
+	"ADD": opAdd, "SUB": opSub, "MUL": opMul, "DIV": opDiv, "MOD": opMod,
+	"LT": opLt, "GT": opGt, "EQ": opEq, "NOT": opNot, "CAT": opCat,
+	"LEN": opLen, "JMP": opJmp, "JZ": opJz, "HALT": opHalt, "FAIL": opFail,
+	"CMD": opCmd, "ARG": opArg, "INDEX": opIndex, "LOAD": opLoad,
+	"STORE": opStore, "GET": opGet, "COINS": opCoins, "BALANCE": opBalance,
+	"TAKE": opTake, "GIVE": opGive, "PAY": opPay,
+}
+
+const (
+	// ScriptMaxCodeSize is the maximum size of the code of a script.
+	ScriptMaxCodeSize = 64 * 1024
+	// ScriptMaxGas is the gas available to one execution of a script.
+	ScriptMaxGas = 100000
+	// ScriptMaxStack is the maximum number of elements on the stack.
+	ScriptMaxStack = 256
+	// ScriptMaxValueSize is the maximum size of an element of the stack.
+	ScriptMaxValueSize = 4096
+
+	// gasHost is the cost of the operations that read the global state or
+	// emit state changes, instead of 1 for the other operations.
+	gasHost = 100
+)
+
+// vmHost gives the interpreter access to the ledger.
+type vmHost interface {
+	command() string
+	arg(name string) []byte
+	index() uint64
+	load(key string) []byte
+	store(key string, value []byte) error
+	get(id []byte) (value []byte, version uint64, contractID string, darcID []byte, err error)
+	coins(name []byte) uint64
+	balance(name []byte) uint64
+	take(name []byte, value uint64) error
+	give(name []byte, value uint64) error
+	pay(coin []byte, value uint64) error
+}
+
+// Assemble returns the code for the script contract from its textual form.
+func Assemble(src string) ([]byte, error) {
+	var code []byte
+	labels := make(map[string]uint32)
+	// fixups are the jump targets to fill in once all the labels are known.
+	type fixup struct {
+		pos   int
+		label string
+	}
+	var fixups []fixup
+
+	s := bufio.NewScanner(strings.NewReader(src))
+	for line := 1; s.Scan(); line++ {
+		text := strings.TrimSpace(stripComment(s.Text()))
+		if text == "" {
+			continue
+		}
+		if strings.HasSuffix(text, ":") {
+			label := strings.TrimSuffix(text, ":")
+			if _, ok := labels[label]; ok {
+				return nil, fmt.Errorf("line %d: duplicate label %s", line, label)
+			}
+			labels[label] = uint32(len(code))
+			continue
+		}
+
+		fields := strings.SplitN(text, " ", 2)
+		op, ok := opNames[strings.ToUpper(fields[0])]
+		if !ok {
+			return nil, fmt.Errorf("line %d: unknown operation %s", line, fields[0])
+		}
+		var param string
+		if len(fields) == 2 {
+			param = strings.TrimSpace(fields[1])
+		}
+		hasParam := op == opPush || op == opJmp || op == opJz
+		if hasParam != (param != "") {
+			return nil, fmt.Errorf("line %d: wrong parameters for %s", line, fields[0])
+		}
+		code = append(code, op)
+		switch op {
+		case opPush:
+			data, err := parseData(param)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", line, err)
+			}
+			if len(data) > ScriptMaxValueSize {
+				return nil, fmt.Errorf("line %d: data is too long", line)
+			}
+			code = append(code, byte(len(data)), byte(len(data)>>8))
+			code = append(code, data...)
+		case opJmp, opJz:
+			fixups = append(fixups, fixup{len(code), param})
+			code = append(code, 0, 0, 0, 0)
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	for _, f := range fixups {
+		target, ok := labels[f.label]
+		if !ok {
+			return nil, fmt.Errorf("unknown label %s", f.label)
+		}
+		binary.LittleEndian.PutUint32(code[f.pos:], target)
+	}
+	if err := checkCode(code); err != nil {
+		return nil, err
+	}
+	return code, nil
+}
+
+// stripComment removes the comment of a line, ignoring the ";" in strings.
+func stripComment(line string) string {
+	inString, escaped := false, false
+	for i, c := range line {
+		switch {
+		case escaped:
+			escaped = false
+		case c == '\\' && inString:
+			escaped = true
+		case c == '"':
+			inString = !inString
+		case c == ';' && !inString:
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// parseData returns the bytes of a number, a string or hexadecimal bytes.
+func parseData(param string) ([]byte, error) {
+	switch {
+	case strings.HasPrefix(param, "\""):
+		str, err := strconv.Unquote(param)
+		if err != nil {
+			return nil, errors.New("invalid string " + param)
+		}
+		return []byte(str), nil
+	case strings.HasPrefix(param, "0x"):
+		return hex.DecodeString(param[2:])
+	default:
+		n, err := strconv.ParseUint(param, 10, 64)
+		if err != nil {
+			return nil, errors.New("invalid number " + param)
+		}
+		return vmNumber(n), nil
+	}
+}
+
+// checkCode makes sure the code only has known operations with complete
+// parameters, and that all jumps land on an operation.
+func checkCode(code []byte) error {
+	if len(code) > ScriptMaxCodeSize {
+		return fmt.Errorf("code is larger than %d bytes", ScriptMaxCodeSize)
+	}
+	starts := make(map[uint32]bool)
+	var targets []uint32
+	for pc := 0; pc < len(code); {
+		starts[uint32(pc)] = true
+		op := code[pc]
+		if op < opPush || op > opPay {
+			return fmt.Errorf("unknown operation %d at %d", op, pc)
+		}
+		pc++
+		switch op {
+		case opPush:
+			if pc+2 > len(code) {
+				return errors.New("truncated push")
+			}
+			pc += 2 + int(binary.LittleEndian.Uint16(code[pc:]))
+			if pc > len(code) {
+				return errors.New("truncated push")
+			}
+		case opJmp, opJz:
+			if pc+4 > len(code) {
+				return errors.New("truncated jump")
+			}
+			targets = append(targets, binary.LittleEndian.Uint32(code[pc:]))
+			pc += 4
+		}
+	}
+	for _, t := range targets {
+		if !starts[t] && t != uint32(len(code)) {
+			return fmt.Errorf("jump to %d is not an operation", t)
+		}
+	}
+	return nil
+}
+
+// vmNumber encodes a number for the stack.
+func vmNumber(n uint64) []byte {
+	buf := make([]byte, 8)
+	binary.LittleEndian.PutUint64(buf, n)
+	return buf
+}
+
+// vmIsZero returns true if the value is empty or only has zero bytes.
+func vmIsZero(v []byte) bool {
+	for _, b := range v {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// vm executes one script. The code must have passed checkCode.
+type vm struct {
+	code  []byte
+	host  vmHost
+	stack [][]byte
+	gas   uint64
+}
+
+// run executes the code until HALT, FAIL, the end of the code or an error.
+func (m *vm) run() error {
+	for pc := 0; pc < len(m.code); {
+		op := m.code[pc]
+		pc++
+		cost := uint64(1)
+		switch op {
+		case opGet, opStore, opPay:
+			cost = gasHost
+		}
+		if m.gas < cost {
+			return errors.New("out of gas")
+		}
+		m.gas -= cost
+
+		var err error
+		switch op {
+		case opPush:
+			l := int(binary.LittleEndian.Uint16(m.code[pc:]))
+			pc += 2
+			err = m.push(m.code[pc : pc+l])
+			pc += l
+		case opPop:
+			_, err = m.pop()
+		case opDup, opOver:
+			depth := 1
+			if op == opOver {
+				depth = 2
+			}
+			if len(m.stack) < depth {
+				return errors.New("stack underflow")
+			}
+			err = m.push(m.stack[len(m.stack)-depth])
+		case opSwap:
+			if len(m.stack) < 2 {
+				return errors.New("stack underflow")
+			}
+			l := len(m.stack)
+			m.stack[l-1], m.stack[l-2] = m.stack[l-2], m.stack[l-1]
+		case opAdd, opSub, opMul, opDiv, opMod, opLt, opGt:
+			err = m.arithmetic(op)
+		case opEq:
+			var a, b []byte
+			if a, b, err = m.pop2(); err == nil {
+				err = m.pushBool(bytes.Equal(a, b))
+			}
+		case opNot:
+			var a []byte
+			if a, err = m.pop(); err == nil {
+				err = m.pushBool(vmIsZero(a))
+			}
+		case opCat:
+			var a, b []byte
+			if a, b, err = m.pop2(); err == nil {
+				err = m.push(append(append([]byte{}, b...), a...))
+			}
+		case opLen:
+			var a []byte
+			if a, err = m.pop(); err == nil {
+				err = m.push(vmNumber(uint64(len(a))))
+			}
+		case opJmp:
+			pc = int(binary.LittleEndian.Uint32(m.code[pc:]))
+		case opJz:
+			var a []byte
+			if a, err = m.pop(); err != nil {
+				return err
+			}
+			if vmIsZero(a) {
+				pc = int(binary.LittleEndian.Uint32(m.code[pc:]))
+			} else {
+				pc += 4
+			}
+		case opHalt:
+			return nil
+		case opFail:
+			var a []byte
+			if a, err = m.pop(); err == nil {
+				err = errors.New("script failed: " + string(a))
+			}
+		default:
+			err = m.hostCall(op)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hostCall executes the operations that use the host.
+func (m *vm) hostCall(op byte) error {
+	switch op {
+	case opCmd:
+		return m.push([]byte(m.host.command()))
+	case opIndex:
+		return m.push(vmNumber(m.host.index()))
+	case opArg, opLoad, opCoins, opBalance:
+		a, err := m.pop()
+		if err != nil {
+			return err
+		}
+		switch op {
+		case opArg:
+			return m.push(m.host.arg(string(a)))
+		case opLoad:
+			return m.push(m.host.load(string(a)))
+		case opCoins:
+			return m.push(vmNumber(m.host.coins(a)))
+		default:
+			return m.push(vmNumber(m.host.balance(a)))
+		}
+	case opGet:
+		a, err := m.pop()
+		if err != nil {
+			return err
+		}
+		v, version, cid, did, err := m.host.get(a)
+		if err != nil {
+			return err
+		}
+		for _, e := range [][]byte{v, vmNumber(version), []byte(cid), did} {
+			if err = m.push(e); err != nil {
+				return err
+			}
+		}
+		return nil
+	case opStore:
+		a, b, err := m.pop2()
+		if err != nil {
+			return err
+		}
+		return m.host.store(string(b), a)
+	case opTake, opGive, opPay:
+		a, b, err := m.pop2()
+		if err != nil {
+			return err
+		}
+		if len(a) != 8 {
+			return errors.New("number must be 8 bytes")
+		}
+		value := binary.LittleEndian.Uint64(a)
+		switch op {
+		case opTake:
+			return m.host.take(b, value)
+		case opGive:
+			return m.host.give(b, value)
+		default:
+			return m.host.pay(b, value)
+		}
+	}
+	return fmt.Errorf("unknown operation %d", op)
+}
+
+// arithmetic executes the operations on two numbers.
+func (m *vm) arithmetic(op byte) error {
+	a, b, err := m.pop2()
+	if err != nil {
+		return err
+	}
+	if len(a) != 8 || len(b) != 8 {
+		return errors.New("numbers must be 8 bytes")
+	}
+	x, y := binary.LittleEndian.Uint64(b), binary.LittleEndian.Uint64(a)
+	var r uint64
+	switch op {
+	case opAdd:
+		r = x + y
+		if r < x {
+			return errors.New("addition overflow")
+		}
+	case opSub:
+		if y > x {
+			return errors.New("subtraction underflow")
+		}
+		r = x - y
+	case opMul:
+		r = x * y
+		if x != 0 && r/x != y {
+			return errors.New("multiplication overflow")
+		}
+	case opDiv, opMod:
+		if y == 0 {
+			return errors.New("division by zero")
+		}
+		if op == opDiv {
+			r = x / y
+		} else {
+			r = x % y
+		}
+	case opLt:
+		return m.pushBool(x < y)
+	case opGt:
+		return m.pushBool(x > y)
+	}
+	return m.push(vmNumber(r))
+}
+
+func (m *vm) push(v []byte) error {
+	if len(m.stack) >= ScriptMaxStack {
+		return errors.New("stack overflow")
+	}
+	if len(v) > ScriptMaxValueSize {
+		return errors.New("value is too long")
+	}
+	m.stack = append(m.stack, v)
+	return nil
+}
+
+func (m *vm) pushBool(b bool) error {
+	if b {
+		return m.push(vmNumber(1))
+	}
+	return m.push(vmNumber(0))
+}
+
+func (m *vm) pop() ([]byte, error) {
+	if len(m.stack) == 0 {
+		return nil, errors.New("stack underflow")
+	}
+	v := m.stack[len(m.stack)-1]
+	m.stack = m.stack[:len(m.stack)-1]
+	return v, nil
+}
+
+// pop2 returns the top of the stack and the element below it.
+func (m *vm) pop2() (a, b []byte, err error) {
+	if a, err = m.pop(); err != nil {
+		return
+	}
+	b, err = m.pop()
+	return
+}
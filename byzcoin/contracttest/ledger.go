@@ -0,0 +1,278 @@
+// Package contracttest runs ByzCoin contracts on an in-memory ledger, without
+// conodes or blocks, so that the contracts can be tested in milliseconds.
+//
+// A Ledger starts with the genesis darc and config of a new chain and
+// applies transactions with byzcoin.ApplyTransaction, like the leader does
+// when it creates a block: the darcs and the signer counters are verified by
+// the contracts, the coins are passed from one instruction to the next and
+// the state changes must create, update or remove instances that do (not)
+// exist. A transaction that fails leaves the ledger as it was.
+//
+//	l, err := contracttest.NewLedger("spawn:value", "invoke:value.update")
+//	require.NoError(t, err)
+//	l.Register(ContractValueID, contractValueFromBytes)
+//	scs, _, err := l.Run(contracttest.Spawn(l.GenesisDarcID(), ContractValueID,
+//		byzcoin.Argument{Name: "value", Value: []byte("1")}))
+//	require.NoError(t, err)
+//	sc := contracttest.RequireStateChange(t, scs, byzcoin.Create, ContractValueID)
+//
+// The config contract and the deferred contract are not available, as they
+// need the service. Use Store to put other instances in the ledger.
+package contracttest
+
+import (
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/byzcoin/trie"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/kyber/v3/util/key"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/network"
+	"go.dedis.ch/protobuf"
+)
+
+// Ledger is an in-memory ledger. It implements byzcoin.ReadOnlyStateTrie,
+// so that it can also be given directly to the methods of a contract.
+type Ledger struct {
+	// Signer owns the genesis darc.
+	Signer darc.Signer
+	// GenesisDarc is the darc of the config, with the default rules of
+	// byzcoin.DefaultGenesisMsg and the rules given to NewLedger.
+	GenesisDarc *darc.Darc
+
+	st        *stateTrie
+	contracts map[string]byzcoin.ContractFn
+}
+
+// NewLedger returns a ledger with the genesis darc and config of a new chain
+// with one node. The rules are added to the genesis darc for the Signer.
+// Only the darc contract is registered.
+func NewLedger(rules ...string) (*Ledger, error) {
+	kp := key.NewKeyPair(cothority.Suite)
+	roster := onet.NewRoster([]*network.ServerIdentity{
+		network.NewServerIdentity(kp.Public, "tls://127.0.0.1:2000")})
+	signer := darc.NewSignerEd25519(nil, nil)
+	msg, err := byzcoin.DefaultGenesisMsg(byzcoin.CurrentVersion, roster, rules, signer.Identity())
+	if err != nil {
+		return nil, err
+	}
+
+	memTrie, err := trie.NewTrie(trie.NewMemDB(), []byte("contracttest"))
+	if err != nil {
+		return nil, err
+	}
+	l := &Ledger{
+		Signer:      signer,
+		GenesisDarc: &msg.GenesisDarc,
+		st:          &stateTrie{StagingTrie: memTrie.MakeStagingTrie()},
+		contracts:   make(map[string]byzcoin.ContractFn),
+	}
+	l.Register(byzcoin.ContractDarcID, byzcoin.NewContractSecureDarcFn(l.GetContract))
+
+	darcBuf, err := l.GenesisDarc.ToProto()
+	if err != nil {
+		return nil, err
+	}
+	configBuf, err := protobuf.Encode(&byzcoin.ChainConfig{
+		BlockInterval:   msg.BlockInterval,
+		Roster:          msg.Roster,
+		MaxBlockSize:    msg.MaxBlockSize,
+		DarcContractIDs: msg.DarcContractIDs,
+	})
+	if err != nil {
+		return nil, err
+	}
+	darcID := l.GenesisDarc.GetBaseID()
+	err = l.Store(
+		byzcoin.NewStateChange(byzcoin.Create, byzcoin.ConfigInstanceID, byzcoin.ContractConfigID, configBuf, darcID),
+		byzcoin.NewStateChange(byzcoin.Create, byzcoin.NewInstanceID(darcID), byzcoin.ContractDarcID, darcBuf, darcID),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Register makes the contract available to the transactions.
+func (l *Ledger) Register(contractID string, f byzcoin.ContractFn) {
+	l.contracts[contractID] = f
+}
+
+// GetContract returns the factory of a registered contract.
+func (l *Ledger) GetContract(contractID string) (byzcoin.ContractFn, bool) {
+	f, ok := l.contracts[contractID]
+	return f, ok
+}
+
+// GenesisDarcID returns the instance of the genesis darc, which spawns the
+// instances of the contracts given by the rules of NewLedger.
+func (l *Ledger) GenesisDarcID() byzcoin.InstanceID {
+	return byzcoin.NewInstanceID(l.GenesisDarc.GetBaseID())
+}
+
+// SetIndex sets the index of the block returned by GetIndex, for example to
+// test the expiry of an instance.
+func (l *Ledger) SetIndex(index int) {
+	l.st.index = index
+}
+
+// Store writes the state changes to the ledger without running any
+// contract. The versions of the state changes are kept.
+func (l *Ledger) Store(scs ...byzcoin.StateChange) error {
+	return l.st.StoreAll(scs)
+}
+
+// GetValues returns the value, version, contract and darc of an instance,
+// or byzcoin.ErrKeyNotSet if it doesn't exist.
+func (l *Ledger) GetValues(key []byte) (value []byte, version uint64, contractID string, darcID darc.ID, err error) {
+	return l.st.GetValues(key)
+}
+
+// GetProof returns the proof of the key against the root of the ledger.
+func (l *Ledger) GetProof(key []byte) (*trie.Proof, error) {
+	return l.st.GetProof(key)
+}
+
+// GetIndex returns the index set by SetIndex, 0 by default.
+func (l *Ledger) GetIndex() int {
+	return l.st.GetIndex()
+}
+
+// GetNonce returns the nonce of the trie.
+func (l *Ledger) GetNonce() ([]byte, error) {
+	return l.st.GetNonce()
+}
+
+// ForEach calls f for every key of the ledger.
+func (l *Ledger) ForEach(f func(k, v []byte) error) error {
+	return l.st.ForEach(f)
+}
+
+// Counter returns the signer counter of the identity.
+func (l *Ledger) Counter(id darc.Identity) (uint64, error) {
+	return byzcoin.GetSignerCounter(l.st, id)
+}
+
+// Sign returns a transaction with the instructions signed by the signers.
+// The signer counters are taken from the ledger, so the transaction must be
+// applied before signing the next one.
+func (l *Ledger) Sign(signers []darc.Signer, instrs ...byzcoin.Instruction) (byzcoin.ClientTransaction, error) {
+	ctx := byzcoin.ClientTransaction{Instructions: instrs}
+	var ids []darc.Identity
+	var counters []uint64
+	for _, signer := range signers {
+		c, err := l.Counter(signer.Identity())
+		if err != nil {
+			return ctx, err
+		}
+		ids = append(ids, signer.Identity())
+		counters = append(counters, c)
+	}
+	for i := range ctx.Instructions {
+		ctx.Instructions[i].SignerIdentities = ids
+		ctx.Instructions[i].SignerCounter = make([]uint64, len(counters))
+		for j, c := range counters {
+			ctx.Instructions[i].SignerCounter[j] = c + uint64(i) + 1
+		}
+	}
+	return ctx, ctx.SignWith(signers...)
+}
+
+// Run signs the instructions with the Signer of the ledger and applies them
+// in one transaction.
+func (l *Ledger) Run(instrs ...byzcoin.Instruction) (byzcoin.StateChanges, []byzcoin.Coin, error) {
+	return l.RunAs([]darc.Signer{l.Signer}, instrs...)
+}
+
+// RunAs signs the instructions with the signers and applies them in one
+// transaction.
+func (l *Ledger) RunAs(signers []darc.Signer, instrs ...byzcoin.Instruction) (byzcoin.StateChanges, []byzcoin.Coin, error) {
+	ctx, err := l.Sign(signers, instrs...)
+	if err != nil {
+		return nil, nil, err
+	}
+	return l.Apply(ctx)
+}
+
+// Apply runs the transaction like the leader does, and stores the state
+// changes if all the instructions are accepted. It returns the state
+// changes, including those of the signer counters, and the coins left by
+// the last instruction, which a conode would discard.
+func (l *Ledger) Apply(tx byzcoin.ClientTransaction) (byzcoin.StateChanges, []byzcoin.Coin, error) {
+	st := l.st.clone()
+	scs, cout, err := byzcoin.ApplyTransaction(st, tx, l.GetContract)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := st.Commit(); err != nil {
+		return nil, nil, err
+	}
+	l.st = st
+	return scs, cout, nil
+}
+
+// stateTrie is the in-memory equivalent of the staging trie of the service.
+type stateTrie struct {
+	*trie.StagingTrie
+	index int
+}
+
+func (t *stateTrie) clone() *stateTrie {
+	return &stateTrie{StagingTrie: t.StagingTrie.Clone(), index: t.index}
+}
+
+// StoreAll implements byzcoin.StagingStateTrie.
+func (t *stateTrie) StoreAll(scs byzcoin.StateChanges) error {
+	pairs := make([]trie.KVPair, len(scs))
+	for i := range pairs {
+		pairs[i] = &scs[i]
+	}
+	return t.Batch(pairs)
+}
+
+func (t *stateTrie) GetValues(key []byte) (value []byte, version uint64, contractID string, darcID darc.ID, err error) {
+	var buf []byte
+	buf, err = t.Get(key)
+	if err != nil {
+		return
+	}
+	if buf == nil {
+		err = byzcoin.ErrKeyNotSet
+		return
+	}
+
+	var vals byzcoin.StateChangeBody
+	if err = protobuf.Decode(buf, &vals); err != nil {
+		return
+	}
+	return vals.Value, vals.Version, vals.ContractID, vals.DarcID, nil
+}
+
+func (t *stateTrie) GetIndex() int {
+	return t.index
+}
+
+// Spawn returns an instruction to spawn an instance of the contract from
+// the instance id, usually a darc.
+func Spawn(id byzcoin.InstanceID, contractID string, args ...byzcoin.Argument) byzcoin.Instruction {
+	return byzcoin.Instruction{
+		InstanceID: id,
+		Spawn:      &byzcoin.Spawn{ContractID: contractID, Args: args},
+	}
+}
+
+// Invoke returns an instruction to invoke the command on the instance id.
+func Invoke(id byzcoin.InstanceID, contractID, command string, args ...byzcoin.Argument) byzcoin.Instruction {
+	return byzcoin.Instruction{
+		InstanceID: id,
+		Invoke:     &byzcoin.Invoke{ContractID: contractID, Command: command, Args: args},
+	}
+}
+
+// Delete returns an instruction to delete the instance id.
+func Delete(id byzcoin.InstanceID, contractID string) byzcoin.Instruction {
+	return byzcoin.Instruction{
+		InstanceID: id,
+		Delete:     &byzcoin.Delete{ContractID: contractID},
+	}
+}
@@ -0,0 +1,129 @@
+package contracttest
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/darc"
+)
+
+const contractCounterID = "counter"
+
+var counterCoin = byzcoin.NewInstanceID([]byte("counter coin"))
+
+// contractCounter counts the invocations of "inc" and the coins given to it.
+// "mint" creates coins for the next instruction.
+type contractCounter struct {
+	byzcoin.BasicContract
+	count uint64
+}
+
+func contractCounterFromBytes(in []byte) (byzcoin.Contract, error) {
+	c := &contractCounter{}
+	if in != nil {
+		c.count = binary.LittleEndian.Uint64(in)
+	}
+	return c, nil
+}
+
+func (c *contractCounter) value() []byte {
+	buf := make([]byte, 8)
+	binary.LittleEndian.PutUint64(buf, c.count)
+	return buf
+}
+
+func (c *contractCounter) Spawn(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, coins []byzcoin.Coin) ([]byzcoin.StateChange, []byzcoin.Coin, error) {
+	return []byzcoin.StateChange{
+		byzcoin.NewStateChange(byzcoin.Create, inst.DeriveID(""), contractCounterID, c.value(), darc.ID(inst.InstanceID.Slice())),
+	}, coins, nil
+}
+
+func (c *contractCounter) Invoke(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, coins []byzcoin.Coin) ([]byzcoin.StateChange, []byzcoin.Coin, error) {
+	_, _, _, darcID, err := rst.GetValues(inst.InstanceID.Slice())
+	if err != nil {
+		return nil, nil, err
+	}
+	switch inst.Invoke.Command {
+	case "inc":
+		c.count++
+		for _, co := range coins {
+			c.count += co.Value
+		}
+		coins = nil
+	case "mint":
+		coins = append(coins, byzcoin.Coin{Name: counterCoin, Value: 2})
+	default:
+		return nil, nil, errors.New("unknown command")
+	}
+	return []byzcoin.StateChange{
+		byzcoin.NewStateChange(byzcoin.Update, inst.InstanceID, contractCounterID, c.value(), darcID),
+	}, coins, nil
+}
+
+func (c *contractCounter) Delete(rst byzcoin.ReadOnlyStateTrie, inst byzcoin.Instruction, coins []byzcoin.Coin) ([]byzcoin.StateChange, []byzcoin.Coin, error) {
+	_, _, _, darcID, err := rst.GetValues(inst.InstanceID.Slice())
+	if err != nil {
+		return nil, nil, err
+	}
+	return []byzcoin.StateChange{
+		byzcoin.NewStateChange(byzcoin.Remove, inst.InstanceID, contractCounterID, nil, darcID),
+	}, coins, nil
+}
+
+func TestLedger(t *testing.T) {
+	l, err := NewLedger("spawn:counter", "invoke:counter.inc", "invoke:counter.mint",
+		"invoke:counter.fail", "delete:counter")
+	require.NoError(t, err)
+	l.Register(contractCounterID, contractCounterFromBytes)
+
+	scs, _, err := l.Run(Spawn(l.GenesisDarcID(), contractCounterID))
+	require.NoError(t, err)
+	id := byzcoin.NewInstanceID(RequireStateChange(t, scs, byzcoin.Create, contractCounterID).InstanceID)
+	require.Equal(t, make([]byte, 8), l.RequireInstance(t, id, contractCounterID))
+	counter, err := l.Counter(l.Signer.Identity())
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), counter)
+
+	// The coins of an instruction are given to the next one, and the
+	// versions increase within the transaction.
+	scs, cout, err := l.Run(Invoke(id, contractCounterID, "mint"), Invoke(id, contractCounterID, "inc"))
+	require.NoError(t, err)
+	require.Empty(t, cout)
+	require.Equal(t, uint64(1), scs[0].Version)
+	require.Equal(t, uint64(2), scs[2].Version)
+	_, version, _, _, err := l.GetValues(id.Slice())
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), version)
+	require.Equal(t, uint64(3), binary.LittleEndian.Uint64(l.RequireInstance(t, id, contractCounterID)))
+
+	_, cout, err = l.Run(Invoke(id, contractCounterID, "mint"))
+	require.NoError(t, err)
+	require.Equal(t, []byzcoin.Coin{{Name: counterCoin, Value: 2}}, cout)
+
+	// A failing instruction drops the whole transaction.
+	_, _, err = l.Run(Invoke(id, contractCounterID, "inc"), Invoke(id, contractCounterID, "fail"))
+	require.Error(t, err)
+	require.Equal(t, uint64(3), binary.LittleEndian.Uint64(l.RequireInstance(t, id, contractCounterID)))
+	counter, err = l.Counter(l.Signer.Identity())
+	require.NoError(t, err)
+	require.Equal(t, uint64(4), counter)
+
+	// The darc and the counters are verified.
+	_, _, err = l.RunAs([]darc.Signer{darc.NewSignerEd25519(nil, nil)}, Invoke(id, contractCounterID, "inc"))
+	require.Error(t, err)
+	tx, err := l.Sign([]darc.Signer{l.Signer}, Invoke(id, contractCounterID, "inc"))
+	require.NoError(t, err)
+	_, _, err = l.Apply(tx)
+	require.NoError(t, err)
+	_, _, err = l.Apply(tx)
+	require.Error(t, err)
+
+	_, _, err = l.Run(Delete(id, contractCounterID))
+	require.NoError(t, err)
+	l.RequireNoInstance(t, id)
+	_, _, err = l.Run(Delete(id, contractCounterID))
+	require.Error(t, err)
+}
@@ -0,0 +1,33 @@
+package contracttest
+
+import (
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3/byzcoin"
+)
+
+// RequireStateChange fails the test unless scs holds a state change with the
+// action for the contract, and returns the first one.
+func RequireStateChange(t require.TestingT, scs byzcoin.StateChanges, action byzcoin.StateAction, contractID string) byzcoin.StateChange {
+	for _, sc := range scs {
+		if sc.StateAction == action && sc.ContractID == contractID {
+			return sc
+		}
+	}
+	require.FailNow(t, "missing state change", "no %s of contract %s in %v", action, contractID, scs)
+	return byzcoin.StateChange{}
+}
+
+// RequireInstance fails the test unless the instance exists for the
+// contract, and returns its value.
+func (l *Ledger) RequireInstance(t require.TestingT, id byzcoin.InstanceID, contractID string) []byte {
+	value, _, cid, _, err := l.GetValues(id.Slice())
+	require.NoError(t, err)
+	require.Equal(t, contractID, cid)
+	return value
+}
+
+// RequireNoInstance fails the test if the instance exists.
+func (l *Ledger) RequireNoInstance(t require.TestingT, id byzcoin.InstanceID) {
+	_, _, _, _, err := l.GetValues(id.Slice())
+	require.Equal(t, byzcoin.ErrKeyNotSet, err)
+}
@@ -0,0 +1,363 @@
+// Package devnet runs a local ByzCoin network of in-process conodes, so that
+// applications can be tested against a real chain without the onet test
+// harness.
+//
+// All the files of a devnet are stored in one directory: the configuration
+// of the conodes, their databases, the roster and the state of the devnet.
+// Starting a devnet again in the same directory reuses the same keys, ports
+// and chain, so a devnet only needs to be set up once.
+package devnet
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/byzcoin/contracts"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/cothority/v3/skipchain"
+	"go.dedis.ch/kyber/v3/util/encoding"
+	"go.dedis.ch/kyber/v3/util/key"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/app"
+	"go.dedis.ch/onet/v3/log"
+	"go.dedis.ch/onet/v3/network"
+	"go.dedis.ch/protobuf"
+)
+
+const (
+	// DefaultNodes is the number of conodes of a devnet if none is given.
+	DefaultNodes = 4
+	// DefaultBasePort is the port of the first conode if none is given.
+	// Conode i uses BasePort+2*i and BasePort+2*i+1 for its websocket.
+	DefaultBasePort = 7800
+	// DefaultBlockInterval is short to get fast tests.
+	DefaultBlockInterval = 500 * time.Millisecond
+
+	stateFile  = "devnet.cfg"
+	rosterFile = "roster.toml"
+	// clockName is the value instance updated by AdvanceBlocks.
+	clockName = "devnet-clock"
+)
+
+// Config holds the parameters of a devnet. Only Dir is required.
+type Config struct {
+	// Dir holds all the files of the devnet.
+	Dir      string
+	Nodes    int
+	BasePort int
+	// BlockInterval is used unless the template gives one.
+	BlockInterval time.Duration
+	// Template is the optional genesis template of the chain.
+	Template *byzcoin.GenesisTemplate
+}
+
+// state is stored in the directory of the devnet to restart it.
+type state struct {
+	ByzCoinID   skipchain.SkipBlockID
+	Owner       darc.Signer
+	GenesisDarc darc.Darc
+	Instances   []namedInstance
+}
+
+type namedInstance struct {
+	Name string
+	ID   byzcoin.InstanceID
+}
+
+// Devnet is a running local network with one ByzCoin chain.
+type Devnet struct {
+	Dir     string
+	Roster  *onet.Roster
+	Servers []*onet.Server
+	// ByzCoinID is the ID of the chain.
+	ByzCoinID skipchain.SkipBlockID
+	// Owner can sign for the genesis darc.
+	Owner       darc.Signer
+	GenesisDarc darc.Darc
+	// Instances are the darcs and instances of the template, by name.
+	Instances map[string]byzcoin.InstanceID
+}
+
+// Start starts the conodes of the devnet and creates the chain if it doesn't
+// exist yet. As the conodes store their databases in the directory given
+// by the CONODE_SERVICE_PATH environment variable, Start sets it to the
+// directory of the devnet, and only one devnet can run in a process.
+func Start(cfg Config) (*Devnet, error) {
+	if cfg.Dir == "" {
+		return nil, errors.New("need a directory for the devnet")
+	}
+	if cfg.Nodes == 0 {
+		cfg.Nodes = DefaultNodes
+	}
+	if cfg.BasePort == 0 {
+		cfg.BasePort = DefaultBasePort
+	}
+	if cfg.BlockInterval == 0 {
+		cfg.BlockInterval = DefaultBlockInterval
+	}
+	if err := os.MkdirAll(cfg.Dir, 0700); err != nil {
+		return nil, err
+	}
+	if err := os.Setenv("CONODE_SERVICE_PATH", cfg.Dir); err != nil {
+		return nil, err
+	}
+
+	d := &Devnet{Dir: cfg.Dir, Roster: &onet.Roster{}}
+	var sis []*network.ServerIdentity
+	for i := 0; i < cfg.Nodes; i++ {
+		port := cfg.BasePort + 2*i
+		fn := filepath.Join(cfg.Dir, "conode-"+strconv.Itoa(i), app.DefaultServerConfig)
+		if _, err := os.Stat(fn); os.IsNotExist(err) {
+			if err := writeServerConfig(fn, port); err != nil {
+				return nil, err
+			}
+		}
+		srv, err := startServer(fn, port)
+		if err != nil {
+			d.Close()
+			return nil, err
+		}
+		d.Servers = append(d.Servers, srv)
+		sis = append(sis, srv.ServerIdentity)
+	}
+	d.Roster = onet.NewRoster(sis)
+	group := &app.Group{Roster: d.Roster}
+	if err := group.Save(cothority.Suite, filepath.Join(cfg.Dir, rosterFile)); err != nil {
+		d.Close()
+		return nil, err
+	}
+
+	st, err := loadState(filepath.Join(cfg.Dir, stateFile))
+	if os.IsNotExist(err) {
+		st, err = d.createChain(cfg)
+		if err == nil {
+			err = saveState(filepath.Join(cfg.Dir, stateFile), st)
+		}
+	}
+	if err != nil {
+		d.Close()
+		return nil, err
+	}
+	d.ByzCoinID = st.ByzCoinID
+	d.Owner = st.Owner
+	d.GenesisDarc = st.GenesisDarc
+	d.Instances = map[string]byzcoin.InstanceID{}
+	for _, ni := range st.Instances {
+		d.Instances[ni.Name] = ni.ID
+	}
+	return d, nil
+}
+
+// writeServerConfig creates the keys of a new conode listening on the given
+// port of localhost.
+func writeServerConfig(fn string, port int) error {
+	if err := os.MkdirAll(filepath.Dir(fn), 0700); err != nil {
+		return err
+	}
+	kp := key.NewKeyPair(cothority.Suite)
+	pub, err := encoding.PointToStringHex(cothority.Suite, kp.Public)
+	if err != nil {
+		return err
+	}
+	priv, err := encoding.ScalarToStringHex(cothority.Suite, kp.Private)
+	if err != nil {
+		return err
+	}
+	conf := &app.CothorityConfig{
+		Suite:       cothority.Suite.String(),
+		Public:      pub,
+		Private:     priv,
+		Address:     network.NewAddress(network.TLS, net.JoinHostPort("127.0.0.1", strconv.Itoa(port))),
+		Description: fmt.Sprintf("devnet conode %d", port),
+		Services:    app.GenerateServiceKeyPairs(),
+	}
+	return conf.Save(fn)
+}
+
+// startServer starts a conode and waits for its websocket to accept
+// connections.
+func startServer(fn string, port int) (*onet.Server, error) {
+	ccfg, err := app.LoadCothority(fn)
+	if err != nil {
+		return nil, err
+	}
+	si, err := ccfg.GetServerIdentity()
+	if err != nil {
+		return nil, err
+	}
+	srv := onet.NewServerTCPWithListenAddr(si, cothority.Suite, ccfg.ListenAddress)
+	go srv.Start()
+
+	wsAddr := net.JoinHostPort("127.0.0.1", strconv.Itoa(port+1))
+	for start := time.Now(); time.Since(start) < 10*time.Second; time.Sleep(50 * time.Millisecond) {
+		if c, err := net.Dial("tcp", wsAddr); err == nil {
+			c.Close()
+			return srv, nil
+		}
+	}
+	srv.Close()
+	return nil, fmt.Errorf("conode on port %d didn't start", port)
+}
+
+// createChain creates the genesis block from the template, with an
+// additional value instance for AdvanceBlocks.
+func (d *Devnet) createChain(cfg Config) (*state, error) {
+	var tmpl byzcoin.GenesisTemplate
+	if cfg.Template != nil {
+		tmpl = *cfg.Template
+	}
+	if tmpl.BlockInterval == "" {
+		tmpl.BlockInterval = cfg.BlockInterval.String()
+	}
+	// Copy the slices so that the template of the caller is unchanged.
+	tmpl.Rules = append(append([]string{}, tmpl.Rules...),
+		"invoke:"+contracts.ContractValueID+".update")
+	tmpl.Instances = append(append([]byzcoin.TemplateInstance{}, tmpl.Instances...), byzcoin.TemplateInstance{
+		Name:     clockName,
+		Contract: contracts.ContractValueID,
+		Value:    "0",
+	})
+
+	owner := darc.NewSignerEd25519(nil, nil)
+	msg, names, err := tmpl.GenesisMsg(byzcoin.CurrentVersion, d.Roster, owner.Identity())
+	if err != nil {
+		return nil, err
+	}
+	_, resp, err := byzcoin.NewLedger(msg, false)
+	if err != nil {
+		return nil, err
+	}
+	log.Lvlf1("Created devnet chain %x", resp.Skipblock.SkipChainID())
+
+	st := &state{
+		ByzCoinID:   resp.Skipblock.SkipChainID(),
+		Owner:       owner,
+		GenesisDarc: msg.GenesisDarc,
+	}
+	for name, id := range names {
+		st.Instances = append(st.Instances, namedInstance{Name: name, ID: id})
+	}
+	return st, nil
+}
+
+func loadState(fn string) (*state, error) {
+	buf, err := ioutil.ReadFile(fn)
+	if err != nil {
+		return nil, err
+	}
+	st := &state{}
+	err = protobuf.DecodeWithConstructors(buf, st, network.DefaultConstructors(cothority.Suite))
+	if err != nil {
+		return nil, err
+	}
+	return st, nil
+}
+
+func saveState(fn string, st *state) error {
+	buf, err := protobuf.Encode(st)
+	if err != nil {
+		return err
+	}
+	// The file holds the private key of the owner.
+	return ioutil.WriteFile(fn, buf, 0600)
+}
+
+// Close stops all the conodes. The files stay in the directory of the
+// devnet, so it can be started again.
+func (d *Devnet) Close() error {
+	var errs []error
+	for _, srv := range d.Servers {
+		if err := srv.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	d.Servers = nil
+	if len(errs) > 0 {
+		return fmt.Errorf("couldn't close all conodes: %v", errs)
+	}
+	return nil
+}
+
+// RosterFile returns the path of the roster of the devnet, for the tools
+// taking a roster.toml file.
+func (d *Devnet) RosterFile() string {
+	return filepath.Join(d.Dir, rosterFile)
+}
+
+// Client returns a ByzCoin client for the chain of the devnet.
+func (d *Devnet) Client() *byzcoin.Client {
+	return byzcoin.NewClient(d.ByzCoinID, *d.Roster)
+}
+
+// SetBlockInterval changes the block interval of the chain, e.g. to slow it
+// down while debugging.
+func (d *Devnet) SetBlockInterval(interval time.Duration) error {
+	cl := d.Client()
+	config, err := cl.GetChainConfig()
+	if err != nil {
+		return err
+	}
+	config.BlockInterval = interval
+	buf, err := protobuf.Encode(config)
+	if err != nil {
+		return err
+	}
+	return d.send(cl, byzcoin.Instruction{
+		InstanceID: byzcoin.ConfigInstanceID,
+		Invoke: &byzcoin.Invoke{
+			ContractID: byzcoin.ContractConfigID,
+			Command:    "update_config",
+			Args:       byzcoin.Arguments{{Name: "config", Value: buf}},
+		},
+	})
+}
+
+// AdvanceBlocks adds n blocks to the chain, e.g. to make deferred
+// transactions expire, and returns the index of the latest block.
+func (d *Devnet) AdvanceBlocks(n int) (int, error) {
+	cl := d.Client()
+	for i := 0; i < n; i++ {
+		value := make([]byte, 8)
+		binary.LittleEndian.PutUint64(value, uint64(time.Now().UnixNano()))
+		err := d.send(cl, byzcoin.Instruction{
+			InstanceID: d.Instances[clockName],
+			Invoke: &byzcoin.Invoke{
+				ContractID: contracts.ContractValueID,
+				Command:    "update",
+				Args:       byzcoin.Arguments{{Name: "value", Value: value}},
+			},
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+	reply, err := cl.GetProof(byzcoin.ConfigInstanceID.Slice())
+	if err != nil {
+		return 0, err
+	}
+	return reply.Proof.Latest.Index, nil
+}
+
+// send signs the instruction with the owner and waits for it to be included.
+func (d *Devnet) send(cl *byzcoin.Client, instr byzcoin.Instruction) error {
+	counters, err := cl.GetSignerCounters(d.Owner.Identity().String())
+	if err != nil {
+		return err
+	}
+	instr.SignerCounter = []uint64{counters.Counters[0] + 1}
+	ctx := byzcoin.ClientTransaction{Instructions: byzcoin.Instructions{instr}}
+	if err = ctx.FillSignersAndSignWith(d.Owner); err != nil {
+		return err
+	}
+	_, err = cl.AddTransactionAndWait(ctx, 10)
+	return err
+}
@@ -0,0 +1,53 @@
+package devnet
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3/byzcoin/bcadmin/lib"
+	"go.dedis.ch/onet/v3/log"
+)
+
+func TestMain(m *testing.M) {
+	log.MainTest(m)
+}
+
+func TestDevnet(t *testing.T) {
+	dir, err := ioutil.TempDir("", "devnet")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	cfg := Config{
+		Dir:           dir,
+		BasePort:      7900,
+		BlockInterval: 200 * time.Millisecond,
+	}
+	d, err := Start(cfg)
+	require.NoError(t, err)
+	require.Equal(t, DefaultNodes, len(d.Roster.List))
+	r, err := lib.ReadRoster(d.RosterFile())
+	require.NoError(t, err)
+	require.Equal(t, d.Roster.List[0].Address, r.List[0].Address)
+
+	index, err := d.AdvanceBlocks(2)
+	require.NoError(t, err)
+	require.Equal(t, 2, index)
+	require.NoError(t, d.SetBlockInterval(300*time.Millisecond))
+	id := d.ByzCoinID
+	require.NoError(t, d.Close())
+
+	// Starting again in the same directory gives the same chain.
+	d, err = Start(cfg)
+	require.NoError(t, err)
+	defer d.Close()
+	require.Equal(t, id, d.ByzCoinID)
+	config, err := d.Client().GetChainConfig()
+	require.NoError(t, err)
+	require.Equal(t, 300*time.Millisecond, config.BlockInterval)
+	index, err = d.AdvanceBlocks(1)
+	require.NoError(t, err)
+	require.Equal(t, 4, index)
+}
@@ -0,0 +1,165 @@
+package explorer
+
+import (
+	"sync"
+	"unicode/utf8"
+
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/byzcoin/contracts"
+	"go.dedis.ch/cothority/v3/byzcoin/gateway"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/onet/v3/network"
+	"go.dedis.ch/protobuf"
+)
+
+// Decoder turns the value of an instance into a structure that can be
+// encoded in JSON and shown in the HTML pages.
+type Decoder func(value []byte) (interface{}, error)
+
+var decoders = struct {
+	sync.Mutex
+	m map[string]Decoder
+}{m: map[string]Decoder{
+	byzcoin.ContractDarcID:     decodeDarc,
+	byzcoin.ContractConfigID:   decodeConfig,
+	byzcoin.ContractDeferredID: decodeDeferred,
+	contracts.ContractCoinID:   decodeCoin,
+	contracts.ContractValueID:  decodeValue,
+}}
+
+// RegisterDecoder adds or replaces the decoder of the instances of the given
+// contract. The instances of contracts without a decoder are shown with
+// their raw value.
+func RegisterDecoder(contractID string, d Decoder) {
+	decoders.Lock()
+	defer decoders.Unlock()
+	decoders.m[contractID] = d
+}
+
+// decode returns the decoded value, or nil if there is no decoder for that
+// contract.
+func decode(contractID string, value []byte) (interface{}, error) {
+	decoders.Lock()
+	d, ok := decoders.m[contractID]
+	decoders.Unlock()
+	if !ok {
+		return nil, nil
+	}
+	return d(value)
+}
+
+func decodeDarc(value []byte) (interface{}, error) {
+	d, err := darc.NewFromProtobuf(value)
+	if err != nil {
+		return nil, err
+	}
+	return gateway.NewDarc(*d), nil
+}
+
+// Config is the JSON encoding of byzcoin.ChainConfig.
+type Config struct {
+	BlockInterval   string   `json:"block_interval"`
+	MaxBlockSize    int      `json:"max_block_size"`
+	Roster          []Node   `json:"roster"`
+	DarcContractIDs []string `json:"darc_contract_ids"`
+}
+
+// Node is a conode of a roster, with its voting weight if the chain uses
+// weighted voting.
+type Node struct {
+	Address string `json:"address"`
+	Public  string `json:"public"`
+	Weight  uint64 `json:"weight,omitempty"`
+}
+
+func newConfig(c byzcoin.ChainConfig) Config {
+	out := Config{
+		BlockInterval:   c.BlockInterval.String(),
+		MaxBlockSize:    c.MaxBlockSize,
+		DarcContractIDs: c.DarcContractIDs,
+	}
+	for i, si := range c.Roster.List {
+		n := Node{Address: si.Address.String(), Public: si.Public.String()}
+		if i < len(c.Weights) {
+			n.Weight = c.Weights[i]
+		}
+		out.Roster = append(out.Roster, n)
+	}
+	return out
+}
+
+func decodeConfig(value []byte) (interface{}, error) {
+	var c byzcoin.ChainConfig
+	err := protobuf.DecodeWithConstructors(value, &c, network.DefaultConstructors(cothority.Suite))
+	if err != nil {
+		return nil, err
+	}
+	return newConfig(c), nil
+}
+
+// Deferred is the JSON encoding of byzcoin.DeferredData.
+type Deferred struct {
+	ProposedTransaction gateway.ClientTransaction `json:"proposed_transaction"`
+	ExpireBlockIndex    uint64                    `json:"expire_block_index"`
+	ExpireTimestamp     int64                     `json:"expire_timestamp,omitempty"`
+	NumExecution        uint64                    `json:"num_execution"`
+	ExecResult          []gateway.HexBytes        `json:"exec_result,omitempty"`
+}
+
+func decodeDeferred(value []byte) (interface{}, error) {
+	var dd byzcoin.DeferredData
+	err := protobuf.DecodeWithConstructors(value, &dd, network.DefaultConstructors(cothority.Suite))
+	if err != nil {
+		return nil, err
+	}
+	out := Deferred{
+		ProposedTransaction: gateway.NewClientTransaction(dd.ProposedTransaction),
+		ExpireBlockIndex:    dd.ExpireBlockIndex,
+		ExpireTimestamp:     dd.ExpireTimestamp,
+		NumExecution:        dd.NumExecution,
+	}
+	for _, r := range dd.ExecResult {
+		out.ExecResult = append(out.ExecResult, gateway.HexBytes(r))
+	}
+	return out, nil
+}
+
+// Coin is the JSON encoding of byzcoin.Coin.
+type Coin struct {
+	Name  gateway.HexBytes `json:"name"`
+	Value uint64           `json:"value"`
+}
+
+func decodeCoin(value []byte) (interface{}, error) {
+	var c byzcoin.Coin
+	if err := protobuf.Decode(value, &c); err != nil {
+		return nil, err
+	}
+	return Coin{Name: c.Name.Slice(), Value: c.Value}, nil
+}
+
+// Value is the JSON encoding of a value instance. If the value is valid
+// UTF-8 without control characters, it is also given as Text.
+type Value struct {
+	Raw  []byte `json:"raw"`
+	Text string `json:"text,omitempty"`
+}
+
+func decodeValue(value []byte) (interface{}, error) {
+	out := Value{Raw: value}
+	if isText(value) {
+		out.Text = string(value)
+	}
+	return out, nil
+}
+
+func isText(buf []byte) bool {
+	s := string(buf)
+	for _, r := range s {
+		if r == utf8.RuneError || (r < 0x20 && r != '\n' && r != '\t') {
+			return false
+		}
+	}
+	return len(s) > 0
+}
@@ -0,0 +1,373 @@
+// Package explorer implements a read-only HTTP service to browse ByzCoin
+// chains. Every page is available as simple HTML for people and as JSON for
+// programs, by adding "?format=json" to the URL or asking for
+// "application/json" in the Accept header.
+//
+// All pages take the ByzCoin ID, as a hexadecimal string, as the first
+// element of the path:
+//
+//	GET /explorer/{id}                         latest block and configuration
+//	GET /explorer/{id}/block/{index}           block with its transactions
+//	GET /explorer/{id}/instance/{hex}          current value of an instance
+//	GET /explorer/{id}/instance/{hex}/history  all versions of an instance
+//	GET /explorer/{id}/rosters                 roster changes of the chain
+//
+// The values of the instances are decoded for the contracts that have a
+// Decoder, see RegisterDecoder.
+package explorer
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/byzcoin/gateway"
+	"go.dedis.ch/cothority/v3/skipchain"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/log"
+	"go.dedis.ch/onet/v3/network"
+	"go.dedis.ch/protobuf"
+)
+
+// PathPrefix is the prefix of all the pages of the explorer.
+const PathPrefix = "/explorer/"
+
+// Explorer is an http.Handler that fetches the data from the conodes of a
+// roster.
+type Explorer struct {
+	roster *onet.Roster
+}
+
+// New returns an explorer getting the data from the nodes of the given
+// roster. When running inside a conode, the roster holds only that conode.
+func New(r *onet.Roster) *Explorer {
+	return &Explorer{roster: r}
+}
+
+// Chain is the overview of a chain.
+type Chain struct {
+	ID          gateway.HexBytes `json:"id"`
+	LatestIndex int              `json:"latest_index"`
+	LatestID    gateway.HexBytes `json:"latest_id"`
+	Config      Config           `json:"config"`
+}
+
+// Block is a block with its decoded transactions and its roster.
+type Block struct {
+	gateway.Block
+	ChainID gateway.HexBytes `json:"chain_id"`
+	Time    string           `json:"time"`
+	Roster  []Node           `json:"roster"`
+}
+
+// Instance is the current value of an instance.
+type Instance struct {
+	ChainID    gateway.HexBytes `json:"chain_id"`
+	ID         gateway.HexBytes `json:"id"`
+	ContractID string           `json:"contract_id"`
+	DarcID     gateway.HexBytes `json:"darc_id"`
+	BlockIndex int              `json:"block_index"`
+	Value      []byte           `json:"value"`
+	Decoded    interface{}      `json:"decoded,omitempty"`
+}
+
+// Version is one version of an instance, as found in its history. For a darc
+// instance, the history shows the evolution of the darc.
+type Version struct {
+	Version     uint64      `json:"version"`
+	BlockIndex  int         `json:"block_index"`
+	StateAction string      `json:"state_action"`
+	ContractID  string      `json:"contract_id"`
+	Value       []byte      `json:"value"`
+	Decoded     interface{} `json:"decoded,omitempty"`
+}
+
+// History holds all the versions of an instance.
+type History struct {
+	ChainID  gateway.HexBytes `json:"chain_id"`
+	ID       gateway.HexBytes `json:"id"`
+	Versions []Version        `json:"versions"`
+}
+
+// RosterChange is a version of the configuration that changed the roster.
+type RosterChange struct {
+	Version    uint64 `json:"version"`
+	BlockIndex int    `json:"block_index"`
+	Roster     []Node `json:"roster"`
+}
+
+// Rosters holds all the roster changes of a chain.
+type Rosters struct {
+	ChainID gateway.HexBytes `json:"chain_id"`
+	Changes []RosterChange   `json:"changes"`
+}
+
+// ServeHTTP implements http.Handler.
+func (e *Explorer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		e.writeError(w, r, http.StatusMethodNotAllowed, errors.New("method must be GET"))
+		return
+	}
+
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, PathPrefix), "/"), "/")
+	id, err := hex.DecodeString(parts[0])
+	if err != nil || len(id) == 0 {
+		e.writeError(w, r, http.StatusBadRequest, errors.New("invalid byzcoin ID"))
+		return
+	}
+	scID := skipchain.SkipBlockID(id)
+
+	switch {
+	case len(parts) == 1:
+		e.getChain(scID, w, r)
+	case parts[1] == "block" && len(parts) == 3:
+		e.getBlock(scID, parts[2], w, r)
+	case parts[1] == "instance" && len(parts) == 3:
+		e.getInstance(scID, parts[2], w, r)
+	case parts[1] == "instance" && len(parts) == 4 && parts[3] == "history":
+		e.getHistory(scID, parts[2], w, r)
+	case parts[1] == "rosters" && len(parts) == 2:
+		e.getRosters(scID, w, r)
+	default:
+		e.writeError(w, r, http.StatusNotFound, errors.New("unknown page"))
+	}
+}
+
+func (e *Explorer) getChain(scID skipchain.SkipBlockID, w http.ResponseWriter, r *http.Request) {
+	reply, err := skipchain.NewClient().GetUpdateChain(e.roster, scID)
+	if err != nil {
+		e.writeError(w, r, http.StatusBadGateway, err)
+		return
+	}
+	if len(reply.Update) == 0 {
+		e.writeError(w, r, http.StatusNotFound, errors.New("unknown chain"))
+		return
+	}
+	latest := reply.Update[len(reply.Update)-1]
+
+	proof, err := byzcoin.NewClient(scID, *e.roster).GetProof(byzcoin.ConfigInstanceID.Slice())
+	if err != nil {
+		e.writeError(w, r, http.StatusBadGateway, err)
+		return
+	}
+	value, _, _, err := proof.Proof.Get(byzcoin.ConfigInstanceID.Slice())
+	if err != nil {
+		e.writeError(w, r, http.StatusBadGateway, err)
+		return
+	}
+	config, err := decodeConfig(value)
+	if err != nil {
+		e.writeError(w, r, http.StatusBadGateway, err)
+		return
+	}
+
+	e.write(w, r, "chain", Chain{
+		ID:          gateway.HexBytes(scID),
+		LatestIndex: latest.Index,
+		LatestID:    gateway.HexBytes(latest.Hash),
+		Config:      config.(Config),
+	})
+}
+
+func (e *Explorer) getBlock(scID skipchain.SkipBlockID, indexStr string, w http.ResponseWriter, r *http.Request) {
+	index, err := strconv.Atoi(indexStr)
+	if err != nil || index < 0 {
+		e.writeError(w, r, http.StatusBadRequest, errors.New("invalid block index"))
+		return
+	}
+	reply, err := skipchain.NewClient().GetSingleBlockByIndex(e.roster, scID, index)
+	if err != nil {
+		e.writeError(w, r, http.StatusBadGateway, err)
+		return
+	}
+	b, err := gateway.NewBlock(reply.SkipBlock)
+	if err != nil {
+		e.writeError(w, r, http.StatusBadGateway, err)
+		return
+	}
+
+	out := Block{
+		Block:   b,
+		ChainID: gateway.HexBytes(scID),
+		Time:    time.Unix(0, b.Timestamp).UTC().Format(time.RFC3339),
+	}
+	sb := reply.SkipBlock
+	for i, si := range sb.Roster.List {
+		n := Node{Address: si.Address.String(), Public: si.Public.String()}
+		if i < len(sb.Weights) {
+			n.Weight = sb.Weights[i]
+		}
+		out.Roster = append(out.Roster, n)
+	}
+	e.write(w, r, "block", out)
+}
+
+func (e *Explorer) getInstance(scID skipchain.SkipBlockID, idStr string, w http.ResponseWriter, r *http.Request) {
+	iid, err := parseInstanceID(idStr)
+	if err != nil {
+		e.writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+	reply, err := byzcoin.NewClient(scID, *e.roster).GetProof(iid.Slice())
+	if err != nil {
+		e.writeError(w, r, http.StatusBadGateway, err)
+		return
+	}
+	if !reply.Proof.InclusionProof.Match(iid.Slice()) {
+		e.writeError(w, r, http.StatusNotFound, errors.New("unknown instance"))
+		return
+	}
+	value, cid, did, err := reply.Proof.Get(iid.Slice())
+	if err != nil {
+		e.writeError(w, r, http.StatusBadGateway, err)
+		return
+	}
+
+	out := Instance{
+		ChainID:    gateway.HexBytes(scID),
+		ID:         iid.Slice(),
+		ContractID: cid,
+		DarcID:     gateway.HexBytes(did),
+		BlockIndex: reply.Proof.Latest.Index,
+		Value:      value,
+	}
+	out.Decoded, err = decode(cid, value)
+	if err != nil {
+		log.Lvlf2("couldn't decode instance %x: %v", iid[:], err)
+	}
+	e.write(w, r, "instance", out)
+}
+
+func (e *Explorer) getHistory(scID skipchain.SkipBlockID, idStr string, w http.ResponseWriter, r *http.Request) {
+	iid, err := parseInstanceID(idStr)
+	if err != nil {
+		e.writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+	versions, err := e.getVersions(scID, iid)
+	if err != nil {
+		e.writeError(w, r, http.StatusBadGateway, err)
+		return
+	}
+	if len(versions) == 0 {
+		e.writeError(w, r, http.StatusNotFound, errors.New("unknown instance"))
+		return
+	}
+	e.write(w, r, "history", History{
+		ChainID:  gateway.HexBytes(scID),
+		ID:       iid.Slice(),
+		Versions: versions,
+	})
+}
+
+func (e *Explorer) getVersions(scID skipchain.SkipBlockID, iid byzcoin.InstanceID) ([]Version, error) {
+	reply, err := byzcoin.NewClient(scID, *e.roster).GetAllInstanceVersion(iid)
+	if err != nil {
+		return nil, err
+	}
+	versions := make([]Version, len(reply.StateChanges))
+	for i, resp := range reply.StateChanges {
+		sc := resp.StateChange
+		versions[i] = Version{
+			Version:     sc.Version,
+			BlockIndex:  resp.BlockIndex,
+			StateAction: sc.StateAction.String(),
+			ContractID:  sc.ContractID,
+			Value:       sc.Value,
+		}
+		if sc.StateAction == byzcoin.Remove {
+			continue
+		}
+		versions[i].Decoded, err = decode(sc.ContractID, sc.Value)
+		if err != nil {
+			log.Lvlf2("couldn't decode version %d of %x: %v", sc.Version, iid[:], err)
+		}
+	}
+	return versions, nil
+}
+
+// getRosters uses the history of the configuration instance to find all the
+// changes of the roster.
+func (e *Explorer) getRosters(scID skipchain.SkipBlockID, w http.ResponseWriter, r *http.Request) {
+	reply, err := byzcoin.NewClient(scID, *e.roster).GetAllInstanceVersion(byzcoin.ConfigInstanceID)
+	if err != nil {
+		e.writeError(w, r, http.StatusBadGateway, err)
+		return
+	}
+
+	out := Rosters{ChainID: gateway.HexBytes(scID)}
+	var previous string
+	for _, resp := range reply.StateChanges {
+		var c byzcoin.ChainConfig
+		err := protobuf.DecodeWithConstructors(resp.StateChange.Value, &c, network.DefaultConstructors(cothority.Suite))
+		if err != nil {
+			log.Lvlf2("couldn't decode version %d of the config: %v", resp.StateChange.Version, err)
+			continue
+		}
+		// The order of the nodes and the weights are part of the change,
+		// as a view-change only rotates the roster.
+		nodes := newConfig(c).Roster
+		key := fmt.Sprint(nodes)
+		if key == previous {
+			continue
+		}
+		previous = key
+		out.Changes = append(out.Changes, RosterChange{
+			Version:    resp.StateChange.Version,
+			BlockIndex: resp.BlockIndex,
+			Roster:     nodes,
+		})
+	}
+	e.write(w, r, "rosters", out)
+}
+
+func parseInstanceID(s string) (byzcoin.InstanceID, error) {
+	buf, err := hex.DecodeString(s)
+	if err != nil || len(buf) != len(byzcoin.InstanceID{}) {
+		return byzcoin.InstanceID{}, errors.New("invalid instance ID")
+	}
+	return byzcoin.NewInstanceID(buf), nil
+}
+
+// wantsJSON returns true if the client asks for JSON instead of HTML.
+func wantsJSON(r *http.Request) bool {
+	return r.URL.Query().Get("format") == "json" ||
+		strings.Contains(r.Header.Get("Accept"), "application/json")
+}
+
+func (e *Explorer) write(w http.ResponseWriter, r *http.Request, page string, v interface{}) {
+	if wantsJSON(r) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(v); err != nil {
+			log.Error("couldn't write reply:", err)
+		}
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := pages.ExecuteTemplate(w, page, v); err != nil {
+		log.Error("couldn't write page:", err)
+	}
+}
+
+func (e *Explorer) writeError(w http.ResponseWriter, r *http.Request, code int, err error) {
+	if wantsJSON(r) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(struct {
+			Error string `json:"error"`
+		}{err.Error()})
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(code)
+	if err := pages.ExecuteTemplate(w, "error", err.Error()); err != nil {
+		log.Error("couldn't write page:", err)
+	}
+}
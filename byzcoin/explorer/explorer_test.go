@@ -0,0 +1,79 @@
+package explorer
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/byzcoin/contracts"
+	"go.dedis.ch/cothority/v3/byzcoin/gateway"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/protobuf"
+)
+
+func TestDecoders(t *testing.T) {
+	buf, err := protobuf.Encode(&byzcoin.Coin{Name: byzcoin.NewInstanceID([]byte("token")), Value: 10})
+	require.NoError(t, err)
+	v, err := decode(contracts.ContractCoinID, buf)
+	require.NoError(t, err)
+	require.Equal(t, uint64(10), v.(Coin).Value)
+
+	v, err = decode(contracts.ContractValueID, []byte("hello"))
+	require.NoError(t, err)
+	require.Equal(t, "hello", v.(Value).Text)
+	v, err = decode(contracts.ContractValueID, []byte{0, 1, 0xff})
+	require.NoError(t, err)
+	require.Equal(t, "", v.(Value).Text)
+
+	signer := darc.NewSignerEd25519(nil, nil)
+	d := darc.NewDarc(darc.InitRules([]darc.Identity{signer.Identity()},
+		[]darc.Identity{signer.Identity()}), []byte("test darc"))
+	buf, err = d.ToProto()
+	require.NoError(t, err)
+	v, err = decode(byzcoin.ContractDarcID, buf)
+	require.NoError(t, err)
+	require.Equal(t, "test darc", v.(gateway.Darc).Description)
+
+	// Unknown contracts are not decoded, and decoders can be added.
+	v, err = decode("test", []byte("test"))
+	require.NoError(t, err)
+	require.Nil(t, v)
+	RegisterDecoder("test", decodeValue)
+	v, err = decode("test", []byte("test"))
+	require.NoError(t, err)
+	require.Equal(t, "test", v.(Value).Text)
+}
+
+func TestExplorer_Errors(t *testing.T) {
+	e := New(&onet.Roster{})
+	for _, test := range []struct {
+		method, path string
+		code         int
+	}{
+		{"GET", "/explorer/", http.StatusBadRequest},
+		{"GET", "/explorer/xyz", http.StatusBadRequest},
+		{"POST", "/explorer/0102", http.StatusMethodNotAllowed},
+		{"GET", "/explorer/0102/unknown", http.StatusNotFound},
+		{"GET", "/explorer/0102/block/xyz", http.StatusBadRequest},
+		{"GET", "/explorer/0102/block/-1", http.StatusBadRequest},
+		{"GET", "/explorer/0102/instance/0102", http.StatusBadRequest},
+		{"GET", "/explorer/0102/instance/0102/history", http.StatusBadRequest},
+	} {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(test.method, test.path, nil))
+		require.Equal(t, test.code, rec.Code, test.path)
+		require.Contains(t, rec.Header().Get("Content-Type"), "text/html")
+
+		rec = httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(test.method, test.path+"?format=json", nil))
+		require.Equal(t, test.code, rec.Code, test.path)
+		require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+		var reply struct{ Error string }
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &reply))
+		require.NotEqual(t, "", reply.Error)
+	}
+}
@@ -0,0 +1,81 @@
+package explorer
+
+import (
+	"encoding/json"
+	"html/template"
+)
+
+// pages holds one template per page of the explorer. They are kept minimal
+// on purpose: the JSON endpoints are there for richer front-ends.
+var pages = template.Must(template.New("").Funcs(template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		buf, err := json.MarshalIndent(v, "", "  ")
+		return string(buf), err
+	},
+	"prev": func(i int) int { return i - 1 },
+	"next": func(i int) int { return i + 1 },
+}).Parse(`
+{{define "header"}}<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>ByzCoin explorer</title>
+<style>body{font-family:sans-serif;margin:2em}td,th{padding:0 1em 0 0;text-align:left}pre{background:#eee;padding:1em}</style>
+</head><body>{{end}}
+
+{{define "footer"}}</body></html>{{end}}
+
+{{define "roster"}}<table><tr><th>Address</th><th>Public key</th><th>Weight</th></tr>
+{{range .}}<tr><td>{{.Address}}</td><td><code>{{.Public}}</code></td><td>{{if .Weight}}{{.Weight}}{{else}}-{{end}}</td></tr>
+{{end}}</table>{{end}}
+
+{{define "error"}}{{template "header"}}
+<h1>Error</h1><p>{{.}}</p>
+{{template "footer"}}{{end}}
+
+{{define "chain"}}{{template "header"}}
+<h1>Chain {{printf "%x" .ID}}</h1>
+<p>Latest block: <a href="{{printf "%x" .ID}}/block/{{.LatestIndex}}">{{.LatestIndex}}</a> ({{printf "%x" .LatestID}})</p>
+<p><a href="{{printf "%x" .ID}}/rosters">Roster changes</a></p>
+<h2>Configuration</h2>
+<p>Block interval: {{.Config.BlockInterval}}, maximum block size: {{.Config.MaxBlockSize}}</p>
+{{template "roster" .Config.Roster}}
+{{template "footer"}}{{end}}
+
+{{define "block"}}{{template "header"}}
+<h1>Block {{.Index}}</h1>
+<p><a href="../../{{printf "%x" .ChainID}}">Chain {{printf "%x" .ChainID}}</a></p>
+<p>ID: {{printf "%x" .ID}}<br>Time: {{.Time}}<br>
+{{if .Index}}<a href="{{.Index | prev}}">Previous</a> | {{end}}<a href="{{.Index | next}}">Next</a></p>
+<h2>Roster</h2>
+{{template "roster" .Roster}}
+<h2>Transactions</h2>
+{{range $i, $tx := .TxResults}}<h3>Transaction {{$i}}: {{if $tx.Accepted}}accepted{{else}}refused{{end}}</h3>
+<pre>{{json $tx.ClientTransaction}}</pre>
+{{else}}<p>No transactions.</p>{{end}}
+{{template "footer"}}{{end}}
+
+{{define "instance"}}{{template "header"}}
+<h1>Instance {{printf "%x" .ID}}</h1>
+<p><a href="../../{{printf "%x" .ChainID}}">Chain {{printf "%x" .ChainID}}</a></p>
+<p>Contract: {{.ContractID}}<br>Darc: {{printf "%x" .DarcID}}<br>
+Proof from block {{.BlockIndex}}<br>
+<a href="{{printf "%x" .ID}}/history">History</a></p>
+{{if .Decoded}}<pre>{{json .Decoded}}</pre>{{else}}<pre>{{printf "%x" .Value}}</pre>{{end}}
+{{template "footer"}}{{end}}
+
+{{define "history"}}{{template "header"}}
+<h1>History of {{printf "%x" .ID}}</h1>
+<p><a href="../../../{{printf "%x" .ChainID}}">Chain {{printf "%x" .ChainID}}</a></p>
+{{range .Versions}}<h2>Version {{.Version}}: {{.StateAction}} {{.ContractID}} in block
+<a href="../../block/{{.BlockIndex}}">{{.BlockIndex}}</a></h2>
+{{if .Decoded}}<pre>{{json .Decoded}}</pre>{{else if .Value}}<pre>{{printf "%x" .Value}}</pre>{{end}}
+{{end}}
+{{template "footer"}}{{end}}
+
+{{define "rosters"}}{{template "header"}}
+<h1>Roster changes</h1>
+<p><a href="../{{printf "%x" .ChainID}}">Chain {{printf "%x" .ChainID}}</a></p>
+{{range .Changes}}<h2>Configuration version {{.Version}}, block
+<a href="block/{{.BlockIndex}}">{{.BlockIndex}}</a></h2>
+{{template "roster" .Roster}}
+{{end}}
+{{template "footer"}}{{end}}
+`))
@@ -0,0 +1,285 @@
+package byzcoin
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/skipchain"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/log"
+	"go.dedis.ch/onet/v3/network"
+	"go.dedis.ch/protobuf"
+)
+
+// forkCheckInterval is how often the blocks of this node are compared with
+// the ones of the other nodes of the roster.
+var forkCheckInterval = time.Minute
+
+// forkMonitor remembers up to which block the chains have been compared, and
+// the equivocations that have been found.
+type forkMonitor struct {
+	sync.Mutex
+	checked       map[string]int
+	equivocations map[string][]skipchain.Equivocation
+}
+
+func newForkMonitor() forkMonitor {
+	return forkMonitor{
+		checked:       make(map[string]int),
+		equivocations: make(map[string][]skipchain.Equivocation),
+	}
+}
+
+func (fm *forkMonitor) add(scID skipchain.SkipBlockID, e skipchain.Equivocation) {
+	fm.Lock()
+	defer fm.Unlock()
+	for _, old := range fm.equivocations[string(scID)] {
+		if old.BlockA.Hash.Equal(e.BlockA.Hash) && old.BlockB.Hash.Equal(e.BlockB.Hash) {
+			return
+		}
+	}
+	fm.equivocations[string(scID)] = append(fm.equivocations[string(scID)], e)
+}
+
+func (fm *forkMonitor) get(scID skipchain.SkipBlockID) []skipchain.Equivocation {
+	fm.Lock()
+	defer fm.Unlock()
+	return append([]skipchain.Equivocation{}, fm.equivocations[string(scID)]...)
+}
+
+// monitorForks starts a go-routine that regularly compares the blocks of
+// all the chains of this node with the blocks the other nodes of the roster
+// return for the same index. If two blocks differ, the forward links to them
+// are kept as evidence of the equivocation, see GetEquivocations.
+func (s *Service) monitorForks() {
+	s.closedMutex.Lock()
+	if s.closed {
+		s.closedMutex.Unlock()
+		return
+	}
+	s.working.Add(1)
+	defer s.working.Done()
+	s.closedMutex.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(forkCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				gasr, err := s.skService().GetAllSkipChainIDs(&skipchain.GetAllSkipChainIDs{})
+				if err != nil {
+					log.Error(s.ServerIdentity(), "couldn't get the chains:", err)
+					continue
+				}
+				for _, gen := range gasr.IDs {
+					if !s.hasByzCoinVerification(gen) {
+						continue
+					}
+					if err := s.checkForks(gen); err != nil {
+						log.Warnf("%s couldn't compare the blocks of %x: %v", s.ServerIdentity(), gen, err)
+					}
+				}
+			case <-s.closeForkMonitorChan:
+				log.Lvl2(s.ServerIdentity(), "closing fork monitor")
+				return
+			}
+		}
+	}()
+}
+
+// checkForks compares the blocks added since the last check with the ones of
+// the other nodes. The first check of a chain only compares the latest block.
+func (s *Service) checkForks(gen skipchain.SkipBlockID) error {
+	latest, err := s.db().GetLatestByID(gen)
+	if err != nil {
+		return err
+	}
+	s.forkMonitor.Lock()
+	checked, ok := s.forkMonitor.checked[string(gen)]
+	s.forkMonitor.Unlock()
+	if !ok {
+		checked = latest.Index - 1
+	}
+
+	for index := checked + 1; index <= latest.Index; index++ {
+		if err := s.compareBlock(gen, latest.Roster, index); err != nil {
+			return err
+		}
+		s.forkMonitor.Lock()
+		s.forkMonitor.checked[string(gen)] = index
+		s.forkMonitor.Unlock()
+	}
+	return nil
+}
+
+// compareBlock asks every other node of the roster for the block at the
+// given index. A node that doesn't answer is ignored, as it might be catching
+// up.
+func (s *Service) compareBlock(gen skipchain.SkipBlockID, roster *onet.Roster, index int) error {
+	ours, err := s.skService().GetSingleBlockByIndex(&skipchain.GetSingleBlockByIndex{Genesis: gen, Index: index})
+	if err != nil {
+		return err
+	}
+	cl := skipchain.NewClient()
+	for _, si := range roster.List {
+		if si.Equal(s.ServerIdentity()) {
+			continue
+		}
+		theirs, err := cl.GetSingleBlockByIndex(onet.NewRoster([]*network.ServerIdentity{si}), gen, index)
+		if err != nil {
+			log.Lvlf2("%s couldn't get block %d from %s: %v", s.ServerIdentity(), index, si, err)
+			continue
+		}
+		if theirs.SkipBlock.Hash.Equal(ours.SkipBlock.Hash) {
+			continue
+		}
+
+		log.Errorf("%s: node %s has block %x at index %d instead of %x", s.ServerIdentity(), si,
+			theirs.SkipBlock.Hash, index, ours.SkipBlock.Hash)
+		e, err := s.findEquivocation(gen, si, ours.SkipBlock, theirs.SkipBlock)
+		if err != nil {
+			log.Errorf("%s couldn't get the evidence of the fork: %v", s.ServerIdentity(), err)
+			continue
+		}
+		offenders, err := e.Offenders()
+		if err != nil {
+			return err
+		}
+		log.Errorf("%s: equivocation at index %d signed by %v", s.ServerIdentity(), index, offenders)
+		s.forkMonitor.add(gen, *e)
+	}
+	return nil
+}
+
+// findEquivocation builds the evidence for two different blocks at the same
+// index, using the forward links of the block before them.
+func (s *Service) findEquivocation(gen skipchain.SkipBlockID, si *network.ServerIdentity, ours, theirs *skipchain.SkipBlock) (*skipchain.Equivocation, error) {
+	if ours.Index == 0 {
+		return nil, errors.New("different genesis blocks")
+	}
+	ourFrom, err := s.skService().GetSingleBlockByIndex(&skipchain.GetSingleBlockByIndex{Genesis: gen, Index: ours.Index - 1})
+	if err != nil {
+		return nil, err
+	}
+	theirFrom, err := skipchain.NewClient().GetSingleBlockByIndex(onet.NewRoster([]*network.ServerIdentity{si}), gen, ours.Index-1)
+	if err != nil {
+		return nil, err
+	}
+	if !ourFrom.SkipBlock.Hash.Equal(theirFrom.SkipBlock.Hash) {
+		return nil, fmt.Errorf("the chains already differ at index %d", ours.Index-1)
+	}
+	ourLink := ourFrom.SkipBlock.GetForward(0)
+	theirLink := theirFrom.SkipBlock.GetForward(0)
+	if ourLink == nil || theirLink == nil {
+		return nil, errors.New("missing forward link")
+	}
+	e, err := skipchain.NewEquivocation(ourFrom.SkipBlock, ours, theirs, ourLink, theirLink)
+	if err != nil {
+		return nil, err
+	}
+	e.Chain, err = s.chainTo(gen, ourFrom.SkipBlock)
+	if err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// chainTo returns the blocks from the genesis to the given block, following
+// the highest forward links that don't go past it.
+func (s *Service) chainTo(gen skipchain.SkipBlockID, to *skipchain.SkipBlock) ([]*skipchain.SkipBlock, error) {
+	sb := s.db().GetByID(gen)
+	if sb == nil {
+		return nil, errors.New("unknown genesis block")
+	}
+	chain := []*skipchain.SkipBlock{sb}
+	for sb.Index < to.Index {
+		var next *skipchain.SkipBlock
+		for h := len(sb.ForwardLink) - 1; h >= 0 && next == nil; h-- {
+			fl := sb.GetForward(h)
+			if fl == nil {
+				continue
+			}
+			next = s.db().GetByID(fl.To)
+			if next != nil && next.Index > to.Index {
+				next = nil
+			}
+		}
+		if next == nil {
+			return nil, fmt.Errorf("no forward link from block %d towards block %d", sb.Index, to.Index)
+		}
+		chain = append(chain, next)
+		sb = next
+	}
+	if !sb.Hash.Equal(to.Hash) {
+		return nil, errors.New("the block is not part of the chain")
+	}
+	return chain, nil
+}
+
+// GetEquivocations returns the evidence of the equivocations found by this
+// node on the given chain.
+func (s *Service) GetEquivocations(req *GetEquivocations) (*GetEquivocationsResponse, error) {
+	if req.Version != CurrentVersion {
+		return nil, errors.New("version mismatch")
+	}
+	return &GetEquivocationsResponse{Equivocations: s.forkMonitor.get(req.SkipChainID)}, nil
+}
+
+// NewRemoveEquivocatorInstruction returns the instruction removing a node
+// that signed two conflicting blocks from the roster of the chain. The
+// instruction must be signed by the invoke:config.remove_equivocator rule.
+// The node must be one of the offenders of the evidence, the evidence must
+// hold the blocks from the genesis to its first block, and neither of the
+// two blocks can be a view-change block.
+func NewRemoveEquivocatorInstruction(e *skipchain.Equivocation, node *network.ServerIdentity) (Instruction, error) {
+	buf, err := protobuf.Encode(e)
+	if err != nil {
+		return Instruction{}, err
+	}
+	return Instruction{
+		InstanceID: ConfigInstanceID,
+		Invoke: &Invoke{
+			ContractID: ContractConfigID,
+			Command:    "remove_equivocator",
+			Args: Arguments{
+				{Name: "evidence", Value: buf},
+				{Name: "node", Value: node.ID[:]},
+			},
+		},
+	}, nil
+}
+
+// decodeEquivocation decodes and verifies the evidence of an instruction.
+func decodeEquivocation(buf []byte) (*skipchain.Equivocation, error) {
+	var e skipchain.Equivocation
+	err := protobuf.DecodeWithConstructors(buf, &e, network.DefaultConstructors(cothority.Suite))
+	if err != nil {
+		return nil, err
+	}
+	if err = e.Verify(); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// isViewChangeBlock returns whether the block holds a view-change
+// transaction. The body of the block must match its header.
+func isViewChangeBlock(sb *skipchain.SkipBlock) (bool, error) {
+	var header DataHeader
+	if err := protobuf.Decode(sb.Data, &header); err != nil {
+		return false, err
+	}
+	var body DataBody
+	if err := protobuf.Decode(sb.Payload, &body); err != nil {
+		return false, err
+	}
+	if !bytes.Equal(header.ClientTransactionHash, body.TxResults.Hash()) {
+		return false, errors.New("the body of the block doesn't match its header")
+	}
+	return isViewChangeTx(body.TxResults) != nil, nil
+}
@@ -0,0 +1,220 @@
+package byzcoin
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3/byzcoin/viewchange"
+	"go.dedis.ch/cothority/v3/byzcoinx"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/cothority/v3/skipchain"
+	"go.dedis.ch/kyber/v3/sign"
+	"go.dedis.ch/kyber/v3/sign/bls"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/network"
+	"go.dedis.ch/protobuf"
+)
+
+// newEquivocation returns two forward links from the genesis block to
+// different blocks, signed by the given nodes. The second block holds txsB.
+func (s *ser) newEquivocation(t *testing.T, signersA, signersB []int, txsB TxResults) *skipchain.Equivocation {
+	publics := s.roster.ServicePublics(skipchain.ServiceName)
+	newBlock := func(timestamp int64, txs TxResults, signers []int) (*skipchain.SkipBlock, *skipchain.ForwardLink) {
+		var err error
+		sb := skipchain.NewSkipBlock()
+		sb.Index = 1
+		sb.Roster = s.roster
+		sb.Data, err = protobuf.Encode(&DataHeader{
+			ClientTransactionHash: txs.Hash(),
+			Timestamp:             timestamp,
+		})
+		require.NoError(t, err)
+		sb.Payload, err = protobuf.Encode(&DataBody{TxResults: txs})
+		require.NoError(t, err)
+		sb.GenesisID = s.genesis.SkipChainID()
+		sb.BackLinkIDs = []skipchain.SkipBlockID{s.genesis.Hash}
+		sb.Hash = sb.CalculateHash()
+
+		fl := skipchain.NewForwardLink(s.genesis, sb)
+		mask, err := sign.NewMask(pairingSuite, publics, nil)
+		require.NoError(t, err)
+		var sigs [][]byte
+		for _, i := range signers {
+			priv := s.hosts[i].ServerIdentity.ServicePrivate(skipchain.ServiceName)
+			sig, err := bls.Sign(pairingSuite, priv, fl.Hash())
+			require.NoError(t, err)
+			sigs = append(sigs, sig)
+			require.NoError(t, mask.SetBit(i, true))
+		}
+		agg, err := bls.AggregateSignatures(pairingSuite, sigs...)
+		require.NoError(t, err)
+		fl.Signature = byzcoinx.FinalSignature{Msg: fl.Hash(), Sig: append(agg, mask.Mask()...)}
+		return sb, fl
+	}
+	a, linkA := newBlock(1, nil, signersA)
+	b, linkB := newBlock(2, txsB, signersB)
+	e, err := skipchain.NewEquivocation(s.genesis, a, b, linkA, linkB)
+	require.NoError(t, err)
+	e.Chain = []*skipchain.SkipBlock{s.genesis}
+	return e
+}
+
+// newForgedEquivocation returns evidence against the given node that passes
+// the verification of the signatures, using a made-up block with a roster of
+// the node and a rogue key whose aggregate with the key of the node is known.
+func (s *ser) newForgedEquivocation(t *testing.T, node int) *skipchain.Equivocation {
+	x := pairingSuite.G2().Scalar().Pick(pairingSuite.RandomStream())
+	victim := s.roster.List[node].ServicePublic(skipchain.ServiceName)
+	rogue := pairingSuite.G2().Point().Sub(pairingSuite.G2().Point().Mul(x, nil), victim)
+	roster := onet.NewRoster([]*network.ServerIdentity{s.roster.List[node],
+		network.NewServerIdentity(rogue, network.Address("tls://127.0.0.1:1"))})
+
+	from := skipchain.NewSkipBlock()
+	from.Index = 1
+	from.Roster = roster
+	from.GenesisID = s.genesis.SkipChainID()
+	from.BackLinkIDs = []skipchain.SkipBlockID{s.genesis.Hash}
+	from.Hash = from.CalculateHash()
+	newBlock := func(data string) (*skipchain.SkipBlock, *skipchain.ForwardLink) {
+		sb := skipchain.NewSkipBlock()
+		sb.Index = 2
+		sb.Roster = roster
+		sb.Data = []byte(data)
+		sb.GenesisID = s.genesis.SkipChainID()
+		sb.BackLinkIDs = []skipchain.SkipBlockID{from.Hash}
+		sb.Hash = sb.CalculateHash()
+
+		fl := skipchain.NewForwardLink(from, sb)
+		mask, err := sign.NewMask(pairingSuite, roster.ServicePublics(skipchain.ServiceName), nil)
+		require.NoError(t, err)
+		require.NoError(t, mask.SetBit(0, true))
+		require.NoError(t, mask.SetBit(1, true))
+		sig, err := bls.Sign(pairingSuite, x, fl.Hash())
+		require.NoError(t, err)
+		fl.Signature = byzcoinx.FinalSignature{Msg: fl.Hash(), Sig: append(sig, mask.Mask()...)}
+		return sb, fl
+	}
+	a, linkA := newBlock("a")
+	b, linkB := newBlock("b")
+	e, err := skipchain.NewEquivocation(from, a, b, linkA, linkB)
+	require.NoError(t, err)
+	e.Chain = []*skipchain.SkipBlock{s.genesis, from}
+	return e
+}
+
+// removeEquivocator sends the signed instruction removing the node at the
+// given index with the evidence.
+func (s *ser) removeEquivocator(t *testing.T, e *skipchain.Equivocation, node int, counter uint64) error {
+	inst, err := NewRemoveEquivocatorInstruction(e, s.roster.List[node])
+	require.NoError(t, err)
+	inst.SignerIdentities = []darc.Identity{s.signer.Identity()}
+	inst.SignerCounter = []uint64{counter}
+	ctx, err := combineInstrsAndSign(s.signer, inst)
+	require.NoError(t, err)
+	_, err = s.service().AddTransaction(&AddTxRequest{
+		Version:       CurrentVersion,
+		SkipchainID:   s.genesis.SkipChainID(),
+		Transaction:   ctx,
+		InclusionWait: 10,
+	})
+	return err
+}
+
+func TestForkMonitor_RemoveEquivocator(t *testing.T) {
+	s := newSer(t, 1, testInterval)
+	defer s.local.CloseAll()
+
+	e := s.newEquivocation(t, []int{0, 2, 3}, []int{1, 2, 3}, nil)
+	s.service().forkMonitor.add(s.genesis.SkipChainID(), *e)
+	// The same evidence is only kept once.
+	s.service().forkMonitor.add(s.genesis.SkipChainID(), *e)
+	resp, err := s.service().GetEquivocations(&GetEquivocations{
+		Version:     CurrentVersion,
+		SkipChainID: s.genesis.SkipChainID(),
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(resp.Equivocations))
+
+	// The instruction must be signed.
+	inst, err := NewRemoveEquivocatorInstruction(e, s.roster.List[3])
+	require.NoError(t, err)
+	_, err = s.service().AddTransaction(&AddTxRequest{
+		Version:       CurrentVersion,
+		SkipchainID:   s.genesis.SkipChainID(),
+		Transaction:   ClientTransaction{Instructions: Instructions{inst}},
+		InclusionWait: 10,
+	})
+	require.Error(t, err)
+
+	// Only a node that signed both blocks can be removed.
+	require.Error(t, s.removeEquivocator(t, e, 1, 1))
+	config, err := s.service().LoadConfig(s.genesis.SkipChainID())
+	require.NoError(t, err)
+	require.Equal(t, 4, len(config.Roster.List))
+
+	require.NoError(t, s.removeEquivocator(t, e, 3, 1))
+	config, err = s.service().LoadConfig(s.genesis.SkipChainID())
+	require.NoError(t, err)
+	require.Equal(t, 3, len(config.Roster.List))
+	i, _ := config.Roster.Search(s.roster.List[3].ID)
+	require.Equal(t, -1, i)
+}
+
+func TestForkMonitor_ForgedEquivocation(t *testing.T) {
+	s := newSer(t, 1, testInterval)
+	defer s.local.CloseAll()
+
+	// The signatures of the forged evidence are correct, but its first
+	// block is not a block of the chain.
+	forged := s.newForgedEquivocation(t, 3)
+	offenders, err := forged.Offenders()
+	require.NoError(t, err)
+	require.Equal(t, 2, len(offenders))
+	require.Error(t, s.removeEquivocator(t, forged, 3, 1))
+
+	// Nor is real evidence accepted without the blocks from the genesis.
+	e := s.newEquivocation(t, []int{0, 2, 3}, []int{1, 2, 3}, nil)
+	e.Chain = nil
+	require.Error(t, s.removeEquivocator(t, e, 3, 1))
+
+	config, err := s.service().LoadConfig(s.genesis.SkipChainID())
+	require.NoError(t, err)
+	require.Equal(t, 4, len(config.Roster.List))
+}
+
+func TestForkMonitor_ViewChangeEquivocation(t *testing.T) {
+	s := newSer(t, 1, testInterval)
+	defer s.local.CloseAll()
+
+	// The leader had the first block signed by everybody but withheld it,
+	// so the nodes signed the view-change block at the same index.
+	newView, err := protobuf.Encode(&viewchange.NewViewReq{
+		Proof: []viewchange.InitReq{{View: viewchange.View{
+			ID:          s.genesis.Hash,
+			Gen:         s.genesis.SkipChainID(),
+			LeaderIndex: 1,
+		}}},
+	})
+	require.NoError(t, err)
+	txs := TxResults{{
+		ClientTransaction: ClientTransaction{Instructions: Instructions{{
+			InstanceID: ConfigInstanceID,
+			Invoke: &Invoke{
+				ContractID: ContractConfigID,
+				Command:    "view_change",
+				Args:       Arguments{{Name: "newview", Value: newView}},
+			},
+		}}},
+		Accepted: true,
+	}}
+	e := s.newEquivocation(t, []int{0, 1, 2, 3}, []int{1, 2, 3}, txs)
+	require.Error(t, s.removeEquivocator(t, e, 3, 1))
+
+	// Nor can the view-change block be hidden by dropping its body.
+	e.BlockB.Payload = nil
+	require.Error(t, s.removeEquivocator(t, e, 3, 1))
+
+	config, err := s.service().LoadConfig(s.genesis.SkipChainID())
+	require.NoError(t, err)
+	require.Equal(t, 4, len(config.Roster.List))
+}
@@ -0,0 +1,276 @@
+// Package gateway implements a JSON/HTTP gateway to the ByzCoin service. It
+// translates the HTTP requests into the usual protobuf requests sent to the
+// conodes of a roster, so that clients without protobuf support can read
+// and write instances.
+//
+// All endpoints take the ByzCoin ID, as a hexadecimal string, as the first
+// element of the path:
+//
+//	GET  /byzcoin/{id}/proof?key={hex}
+//	POST /byzcoin/{id}/transaction?wait={block intervals}
+//	GET  /byzcoin/{id}/counters?id={identity}&id={identity}...
+//	GET  /byzcoin/{id}/instance/{hex}/version[?version={version}]
+//	GET  /byzcoin/{id}/stream
+//
+// The stream endpoint uses server-sent events: every new block is sent as a
+// "block" event holding its JSON encoding.
+package gateway
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/skipchain"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/log"
+)
+
+// PathPrefix is the prefix of all the endpoints of the gateway.
+const PathPrefix = "/byzcoin/"
+
+// Gateway is an http.Handler that forwards the requests to the conodes of a
+// roster.
+type Gateway struct {
+	roster *onet.Roster
+}
+
+// New returns a gateway sending the requests to the nodes of the given
+// roster. When running inside a conode, the roster holds only that conode.
+func New(r *onet.Roster) *Gateway {
+	return &Gateway{roster: r}
+}
+
+// ServeHTTP implements http.Handler.
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	if r.Method == http.MethodOptions {
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		return
+	}
+
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, PathPrefix), "/"), "/")
+	if len(parts) < 2 {
+		writeError(w, http.StatusNotFound, errors.New("unknown endpoint"))
+		return
+	}
+	id, err := hex.DecodeString(parts[0])
+	if err != nil || len(id) == 0 {
+		writeError(w, http.StatusBadRequest, errors.New("invalid byzcoin ID"))
+		return
+	}
+	cl := byzcoin.NewClient(skipchain.SkipBlockID(id), *g.roster)
+
+	switch {
+	case parts[1] == "proof" && len(parts) == 2:
+		g.getProof(cl, w, r)
+	case parts[1] == "transaction" && len(parts) == 2:
+		g.addTransaction(cl, w, r)
+	case parts[1] == "counters" && len(parts) == 2:
+		g.getSignerCounters(cl, w, r)
+	case parts[1] == "instance" && len(parts) == 4 && parts[3] == "version":
+		g.getInstanceVersion(cl, parts[2], w, r)
+	case parts[1] == "stream" && len(parts) == 2:
+		g.stream(cl, w, r)
+	default:
+		writeError(w, http.StatusNotFound, errors.New("unknown endpoint"))
+	}
+}
+
+func (g *Gateway) getProof(cl *byzcoin.Client, w http.ResponseWriter, r *http.Request) {
+	if !checkMethod(w, r, http.MethodGet) {
+		return
+	}
+	key, err := hex.DecodeString(r.URL.Query().Get("key"))
+	if err != nil || len(key) == 0 {
+		writeError(w, http.StatusBadRequest, errors.New("invalid or missing key"))
+		return
+	}
+	reply, err := cl.GetProof(key)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	p, err := NewProof(key, reply.Proof)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	writeJSON(w, p)
+}
+
+func (g *Gateway) addTransaction(cl *byzcoin.Client, w http.ResponseWriter, r *http.Request) {
+	if !checkMethod(w, r, http.MethodPost) {
+		return
+	}
+	wait := 0
+	if s := r.URL.Query().Get("wait"); s != "" {
+		var err error
+		wait, err = strconv.Atoi(s)
+		if err != nil || wait < 0 {
+			writeError(w, http.StatusBadRequest, errors.New("invalid wait"))
+			return
+		}
+	}
+
+	var jtx ClientTransaction
+	if err := json.NewDecoder(r.Body).Decode(&jtx); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("couldn't decode transaction: %v", err))
+		return
+	}
+	tx, err := jtx.ToByzCoin()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if _, err := cl.AddTransactionAndWait(tx, wait); err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	writeJSON(w, struct {
+		ID HexBytes `json:"id"`
+	}{tx.Instructions.Hash()})
+}
+
+func (g *Gateway) getSignerCounters(cl *byzcoin.Client, w http.ResponseWriter, r *http.Request) {
+	if !checkMethod(w, r, http.MethodGet) {
+		return
+	}
+	ids := r.URL.Query()["id"]
+	if len(ids) == 0 {
+		writeError(w, http.StatusBadRequest, errors.New("missing id"))
+		return
+	}
+	reply, err := cl.GetSignerCounters(ids...)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	writeJSON(w, struct {
+		Counters []uint64 `json:"counters"`
+	}{reply.Counters})
+}
+
+func (g *Gateway) getInstanceVersion(cl *byzcoin.Client, idStr string, w http.ResponseWriter, r *http.Request) {
+	if !checkMethod(w, r, http.MethodGet) {
+		return
+	}
+	buf, err := hex.DecodeString(idStr)
+	if err != nil || len(buf) != len(byzcoin.InstanceID{}) {
+		writeError(w, http.StatusBadRequest, errors.New("invalid instance ID"))
+		return
+	}
+	iid := byzcoin.NewInstanceID(buf)
+
+	var reply *byzcoin.GetInstanceVersionResponse
+	if s := r.URL.Query().Get("version"); s != "" {
+		version, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, errors.New("invalid version"))
+			return
+		}
+		reply, err = cl.GetInstanceVersion(iid, version)
+	} else {
+		reply, err = cl.GetLastInstanceVersion(iid)
+	}
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	writeJSON(w, InstanceVersion{
+		StateChange: NewStateChange(reply.StateChange),
+		BlockIndex:  reply.BlockIndex,
+	})
+}
+
+// stream forwards the new blocks as server-sent events until either the
+// HTTP client or the conode closes the connection.
+func (g *Gateway) stream(cl *byzcoin.Client, w http.ResponseWriter, r *http.Request) {
+	if !checkMethod(w, r, http.MethodGet) {
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New("streaming is not supported"))
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	blocks := make(chan Block)
+	done := make(chan error, 1)
+	go func() {
+		done <- cl.StreamTransactions(func(resp byzcoin.StreamingResponse, err error) {
+			if err != nil {
+				log.Lvl2("stream error:", err)
+				return
+			}
+			b, err := NewBlock(resp.Block)
+			if err != nil {
+				log.Error("couldn't decode block:", err)
+				return
+			}
+			select {
+			case blocks <- b:
+			case <-r.Context().Done():
+			}
+		})
+	}()
+
+	for {
+		select {
+		case b := <-blocks:
+			buf, err := json.Marshal(b)
+			if err != nil {
+				log.Error(err)
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: block\ndata: %s\n\n", buf); err != nil {
+				cl.Close()
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			// Closing the client stops StreamTransactions.
+			cl.Close()
+			return
+		case err := <-done:
+			if err != nil {
+				fmt.Fprintf(w, "event: error\ndata: %q\n\n", err.Error())
+				flusher.Flush()
+			}
+			return
+		}
+	}
+}
+
+func checkMethod(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method != method {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method must be %s", method))
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Error("couldn't write reply:", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(struct {
+		Error string `json:"error"`
+	}{err.Error()})
+}
@@ -0,0 +1,110 @@
+package gateway
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/onet/v3"
+)
+
+func TestJSON_ClientTransaction(t *testing.T) {
+	signer := darc.NewSignerEd25519(nil, nil)
+	tx := byzcoin.ClientTransaction{
+		Instructions: byzcoin.Instructions{
+			{
+				InstanceID: byzcoin.NewInstanceID([]byte("darc")),
+				Spawn: &byzcoin.Spawn{
+					ContractID: "value",
+					Args:       byzcoin.Arguments{{Name: "value", Value: []byte("abc")}},
+				},
+				SignerCounter: []uint64{1},
+			},
+			{
+				InstanceID: byzcoin.NewInstanceID([]byte("value")),
+				Invoke: &byzcoin.Invoke{
+					ContractID: "value",
+					Command:    "update",
+				},
+				SignerCounter: []uint64{2},
+			},
+		},
+	}
+	require.NoError(t, tx.FillSignersAndSignWith(signer))
+
+	buf, err := json.Marshal(NewClientTransaction(tx))
+	require.NoError(t, err)
+	require.Contains(t, string(buf), `"instance_id":"`+tx.Instructions[0].InstanceID.String()+`"`)
+	require.Contains(t, string(buf), `"signer_identities":["`+signer.Identity().String()+`"]`)
+
+	var jtx ClientTransaction
+	require.NoError(t, json.Unmarshal(buf, &jtx))
+	tx2, err := jtx.ToByzCoin()
+	require.NoError(t, err)
+	require.Equal(t, tx.Instructions.Hash(), tx2.Instructions.Hash())
+	for i := range tx.Instructions {
+		require.Equal(t, tx.Instructions[i].Signatures, tx2.Instructions[i].Signatures)
+		require.True(t, tx.Instructions[i].SignerIdentities[0].Equal(&tx2.Instructions[i].SignerIdentities[0]))
+	}
+
+	// Invalid instructions
+	_, err = ClientTransaction{}.ToByzCoin()
+	require.Error(t, err)
+	jtx.Instructions[0].Delete = &Delete{ContractID: "value"}
+	_, err = jtx.ToByzCoin()
+	require.Error(t, err)
+	jtx.Instructions[0].Delete = nil
+	jtx.Instructions[0].InstanceID = HexBytes{1, 2, 3}
+	_, err = jtx.ToByzCoin()
+	require.Error(t, err)
+	jtx.Instructions[0].InstanceID = tx.Instructions[0].InstanceID.Slice()
+	jtx.Instructions[0].SignerIdentities = []string{"unknown:1234"}
+	_, err = jtx.ToByzCoin()
+	require.Error(t, err)
+}
+
+func TestJSON_Darc(t *testing.T) {
+	signer := darc.NewSignerEd25519(nil, nil)
+	d := darc.NewDarc(darc.InitRules([]darc.Identity{signer.Identity()},
+		[]darc.Identity{signer.Identity()}), []byte("test darc"))
+
+	jd := NewDarc(*d)
+	require.Equal(t, HexBytes(d.GetBaseID()), jd.BaseID)
+	require.Equal(t, "test darc", jd.Description)
+	require.Equal(t, len(d.Rules.List), len(jd.Rules))
+	require.Equal(t, "_sign", jd.Rules[1].Action)
+	require.Equal(t, signer.Identity().String(), jd.Rules[1].Expr)
+
+	buf, err := json.Marshal(jd)
+	require.NoError(t, err)
+	require.Contains(t, string(buf), `"base_id":"`+hex.EncodeToString(d.GetBaseID())+`"`)
+}
+
+func TestGateway_Errors(t *testing.T) {
+	g := New(&onet.Roster{})
+	for _, test := range []struct {
+		method, path string
+		code         int
+	}{
+		{"GET", "/byzcoin/", http.StatusNotFound},
+		{"GET", "/byzcoin/xyz/proof", http.StatusBadRequest},
+		{"GET", "/byzcoin/0102/unknown", http.StatusNotFound},
+		{"POST", "/byzcoin/0102/proof?key=01", http.StatusMethodNotAllowed},
+		{"GET", "/byzcoin/0102/proof?key=xyz", http.StatusBadRequest},
+		{"GET", "/byzcoin/0102/counters", http.StatusBadRequest},
+		{"GET", "/byzcoin/0102/instance/0102/version", http.StatusBadRequest},
+		{"POST", "/byzcoin/0102/transaction?wait=-1", http.StatusBadRequest},
+		{"POST", "/byzcoin/0102/transaction", http.StatusBadRequest},
+	} {
+		rec := httptest.NewRecorder()
+		g.ServeHTTP(rec, httptest.NewRequest(test.method, test.path, strings.NewReader("{}")))
+		require.Equal(t, test.code, rec.Code, test.path)
+		require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	}
+}
@@ -0,0 +1,369 @@
+package gateway
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"go.dedis.ch/cothority/v3"
+	"go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/cothority/v3/skipchain"
+	"go.dedis.ch/onet/v3/network"
+	"go.dedis.ch/protobuf"
+)
+
+// The JSON encodings use hexadecimal strings for identifiers (instance IDs,
+// darc IDs, block IDs) and base64 strings, the default of encoding/json, for
+// all other binary values. Identities are given in their string form, e.g.
+// "ed25519:0123...".
+
+// HexBytes is a byte slice that is encoded as a hexadecimal string.
+type HexBytes []byte
+
+// MarshalJSON implements json.Marshaler.
+func (h HexBytes) MarshalJSON() ([]byte, error) {
+	return json.Marshal(hex.EncodeToString(h))
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (h *HexBytes) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	buf, err := hex.DecodeString(s)
+	if err != nil {
+		return err
+	}
+	*h = buf
+	return nil
+}
+
+// Argument is the JSON encoding of byzcoin.Argument.
+type Argument struct {
+	Name  string `json:"name"`
+	Value []byte `json:"value"`
+}
+
+// Spawn is the JSON encoding of byzcoin.Spawn.
+type Spawn struct {
+	ContractID string     `json:"contract_id"`
+	Args       []Argument `json:"args,omitempty"`
+}
+
+// Invoke is the JSON encoding of byzcoin.Invoke.
+type Invoke struct {
+	ContractID string     `json:"contract_id"`
+	Command    string     `json:"command"`
+	Args       []Argument `json:"args,omitempty"`
+}
+
+// Delete is the JSON encoding of byzcoin.Delete.
+type Delete struct {
+	ContractID string `json:"contract_id"`
+}
+
+// Instruction is the JSON encoding of byzcoin.Instruction. Exactly one of
+// Spawn, Invoke or Delete must be set.
+type Instruction struct {
+	InstanceID       HexBytes `json:"instance_id"`
+	Spawn            *Spawn   `json:"spawn,omitempty"`
+	Invoke           *Invoke  `json:"invoke,omitempty"`
+	Delete           *Delete  `json:"delete,omitempty"`
+	SignerCounter    []uint64 `json:"signer_counter"`
+	SignerIdentities []string `json:"signer_identities"`
+	Signatures       [][]byte `json:"signatures"`
+}
+
+// ClientTransaction is the JSON encoding of byzcoin.ClientTransaction.
+type ClientTransaction struct {
+	Instructions []Instruction `json:"instructions"`
+}
+
+// TxResult is the JSON encoding of byzcoin.TxResult.
+type TxResult struct {
+	ClientTransaction ClientTransaction `json:"client_transaction"`
+	Accepted          bool              `json:"accepted"`
+}
+
+func newArguments(args byzcoin.Arguments) []Argument {
+	out := make([]Argument, len(args))
+	for i, a := range args {
+		out[i] = Argument{Name: a.Name, Value: a.Value}
+	}
+	return out
+}
+
+func toArguments(args []Argument) byzcoin.Arguments {
+	if len(args) == 0 {
+		return nil
+	}
+	out := make(byzcoin.Arguments, len(args))
+	for i, a := range args {
+		out[i] = byzcoin.Argument{Name: a.Name, Value: a.Value}
+	}
+	return out
+}
+
+// NewInstruction returns the JSON encoding of the instruction.
+func NewInstruction(instr byzcoin.Instruction) Instruction {
+	out := Instruction{
+		InstanceID:       instr.InstanceID.Slice(),
+		SignerCounter:    instr.SignerCounter,
+		SignerIdentities: instr.GetIdentityStrings(),
+		Signatures:       instr.Signatures,
+	}
+	switch {
+	case instr.Spawn != nil:
+		out.Spawn = &Spawn{
+			ContractID: instr.Spawn.ContractID,
+			Args:       newArguments(instr.Spawn.Args),
+		}
+	case instr.Invoke != nil:
+		out.Invoke = &Invoke{
+			ContractID: instr.Invoke.ContractID,
+			Command:    instr.Invoke.Command,
+			Args:       newArguments(instr.Invoke.Args),
+		}
+	case instr.Delete != nil:
+		out.Delete = &Delete{ContractID: instr.Delete.ContractID}
+	}
+	return out
+}
+
+// ToByzCoin converts the JSON encoding back to a byzcoin.Instruction. It
+// returns an error if the instruction is malformed, but it does not verify
+// the signatures.
+func (instr Instruction) ToByzCoin() (byzcoin.Instruction, error) {
+	if len(instr.InstanceID) != len(byzcoin.InstanceID{}) {
+		return byzcoin.Instruction{}, fmt.Errorf("instance_id must be %d bytes long",
+			len(byzcoin.InstanceID{}))
+	}
+	out := byzcoin.Instruction{
+		InstanceID:    byzcoin.NewInstanceID(instr.InstanceID),
+		SignerCounter: instr.SignerCounter,
+		Signatures:    instr.Signatures,
+	}
+
+	count := 0
+	if instr.Spawn != nil {
+		count++
+		out.Spawn = &byzcoin.Spawn{
+			ContractID: instr.Spawn.ContractID,
+			Args:       toArguments(instr.Spawn.Args),
+		}
+	}
+	if instr.Invoke != nil {
+		count++
+		out.Invoke = &byzcoin.Invoke{
+			ContractID: instr.Invoke.ContractID,
+			Command:    instr.Invoke.Command,
+			Args:       toArguments(instr.Invoke.Args),
+		}
+	}
+	if instr.Delete != nil {
+		count++
+		out.Delete = &byzcoin.Delete{ContractID: instr.Delete.ContractID}
+	}
+	if count != 1 {
+		return byzcoin.Instruction{}, errors.New("exactly one of spawn, invoke or delete must be given")
+	}
+
+	for _, s := range instr.SignerIdentities {
+		id, err := darc.ParseIdentity(s)
+		if err != nil {
+			return byzcoin.Instruction{}, fmt.Errorf("invalid signer identity %s: %v", s, err)
+		}
+		out.SignerIdentities = append(out.SignerIdentities, id)
+	}
+	return out, nil
+}
+
+// NewClientTransaction returns the JSON encoding of the transaction.
+func NewClientTransaction(tx byzcoin.ClientTransaction) ClientTransaction {
+	out := ClientTransaction{Instructions: make([]Instruction, len(tx.Instructions))}
+	for i, instr := range tx.Instructions {
+		out.Instructions[i] = NewInstruction(instr)
+	}
+	return out
+}
+
+// ToByzCoin converts the JSON encoding back to a byzcoin.ClientTransaction.
+func (tx ClientTransaction) ToByzCoin() (byzcoin.ClientTransaction, error) {
+	if len(tx.Instructions) == 0 {
+		return byzcoin.ClientTransaction{}, errors.New("no instructions given")
+	}
+	out := byzcoin.ClientTransaction{Instructions: make(byzcoin.Instructions, len(tx.Instructions))}
+	for i, instr := range tx.Instructions {
+		var err error
+		out.Instructions[i], err = instr.ToByzCoin()
+		if err != nil {
+			return byzcoin.ClientTransaction{}, fmt.Errorf("instruction %d: %v", i, err)
+		}
+	}
+	return out, nil
+}
+
+// Rule is the JSON encoding of darc.Rule.
+type Rule struct {
+	Action string `json:"action"`
+	Expr   string `json:"expr"`
+}
+
+// Signature is the JSON encoding of darc.Signature.
+type Signature struct {
+	Signature []byte `json:"signature"`
+	Signer    string `json:"signer"`
+}
+
+// Darc is the JSON encoding of darc.Darc. The verification darcs are not
+// included.
+type Darc struct {
+	ID          HexBytes    `json:"id"`
+	BaseID      HexBytes    `json:"base_id"`
+	PrevID      HexBytes    `json:"prev_id"`
+	Version     uint64      `json:"version"`
+	Description string      `json:"description"`
+	Rules       []Rule      `json:"rules"`
+	Signatures  []Signature `json:"signatures,omitempty"`
+}
+
+// NewDarc returns the JSON encoding of the darc.
+func NewDarc(d darc.Darc) Darc {
+	out := Darc{
+		ID:          HexBytes(d.GetID()),
+		BaseID:      HexBytes(d.GetBaseID()),
+		PrevID:      HexBytes(d.PrevID),
+		Version:     d.Version,
+		Description: string(d.Description),
+		Rules:       make([]Rule, len(d.Rules.List)),
+	}
+	for i, r := range d.Rules.List {
+		out.Rules[i] = Rule{Action: string(r.Action), Expr: string(r.Expr)}
+	}
+	for _, s := range d.Signatures {
+		out.Signatures = append(out.Signatures, Signature{
+			Signature: s.Signature,
+			Signer:    s.Signer.String(),
+		})
+	}
+	return out
+}
+
+// Proof is the JSON encoding of byzcoin.Proof. As verifying the proof needs
+// the full data-structure, it is also given in its protobuf encoding in Raw.
+// If the key is a darc instance, the darc is decoded in Darc.
+type Proof struct {
+	Key        HexBytes `json:"key"`
+	Exists     bool     `json:"exists"`
+	Value      []byte   `json:"value,omitempty"`
+	ContractID string   `json:"contract_id,omitempty"`
+	DarcID     HexBytes `json:"darc_id,omitempty"`
+	Darc       *Darc    `json:"darc,omitempty"`
+	BlockIndex int      `json:"block_index"`
+	BlockID    HexBytes `json:"block_id"`
+	Raw        []byte   `json:"raw"`
+}
+
+// NewProof returns the JSON encoding of the proof for the given key.
+func NewProof(key []byte, p byzcoin.Proof) (Proof, error) {
+	raw, err := protobuf.Encode(&p)
+	if err != nil {
+		return Proof{}, err
+	}
+	out := Proof{
+		Key:        key,
+		Exists:     p.InclusionProof.Match(key),
+		BlockIndex: p.Latest.Index,
+		BlockID:    HexBytes(p.Latest.Hash),
+		Raw:        raw,
+	}
+	if !out.Exists {
+		return out, nil
+	}
+
+	value, cid, did, err := p.Get(key)
+	if err != nil {
+		return Proof{}, err
+	}
+	out.Value = value
+	out.ContractID = cid
+	out.DarcID = HexBytes(did)
+	if cid == byzcoin.ContractDarcID {
+		d, err := darc.NewFromProtobuf(value)
+		if err != nil {
+			return Proof{}, err
+		}
+		jd := NewDarc(*d)
+		out.Darc = &jd
+	}
+	return out, nil
+}
+
+// StateChange is the JSON encoding of byzcoin.StateChange.
+type StateChange struct {
+	StateAction string   `json:"state_action"`
+	InstanceID  HexBytes `json:"instance_id"`
+	ContractID  string   `json:"contract_id"`
+	Value       []byte   `json:"value"`
+	DarcID      HexBytes `json:"darc_id"`
+	Version     uint64   `json:"version"`
+}
+
+// NewStateChange returns the JSON encoding of the state change.
+func NewStateChange(sc byzcoin.StateChange) StateChange {
+	return StateChange{
+		StateAction: sc.StateAction.String(),
+		InstanceID:  sc.InstanceID,
+		ContractID:  sc.ContractID,
+		Value:       sc.Value,
+		DarcID:      HexBytes(sc.DarcID),
+		Version:     sc.Version,
+	}
+}
+
+// InstanceVersion is the JSON encoding of byzcoin.GetInstanceVersionResponse.
+type InstanceVersion struct {
+	StateChange StateChange `json:"state_change"`
+	BlockIndex  int         `json:"block_index"`
+}
+
+// Block is the JSON encoding of a ByzCoin block, as sent by the streaming
+// endpoint.
+type Block struct {
+	Index     int        `json:"index"`
+	ID        HexBytes   `json:"id"`
+	Timestamp int64      `json:"timestamp"`
+	TxResults []TxResult `json:"tx_results"`
+}
+
+// NewBlock decodes the header and the body of the skipblock and returns its
+// JSON encoding.
+func NewBlock(sb *skipchain.SkipBlock) (Block, error) {
+	var header byzcoin.DataHeader
+	err := protobuf.DecodeWithConstructors(sb.Data, &header, network.DefaultConstructors(cothority.Suite))
+	if err != nil {
+		return Block{}, err
+	}
+	var body byzcoin.DataBody
+	err = protobuf.DecodeWithConstructors(sb.Payload, &body, network.DefaultConstructors(cothority.Suite))
+	if err != nil {
+		return Block{}, err
+	}
+
+	out := Block{
+		Index:     sb.Index,
+		ID:        HexBytes(sb.Hash),
+		Timestamp: header.Timestamp,
+		TxResults: make([]TxResult, len(body.TxResults)),
+	}
+	for i, tx := range body.TxResults {
+		out.TxResults[i] = TxResult{
+			ClientTransaction: NewClientTransaction(tx.ClientTransaction),
+			Accepted:          tx.Accepted,
+		}
+	}
+	return out, nil
+}
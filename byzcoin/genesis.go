@@ -0,0 +1,328 @@
+package byzcoin
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/cothority/v3/darc/expression"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/protobuf"
+)
+
+// GenesisTemplate describes the initial state of a new ledger: the chain
+// configuration, the darcs and the instances that are created in the
+// genesis block, so that a multi-darc setup doesn't need follow-up
+// transactions. It is usually read from a TOML file:
+//
+//	BlockInterval = "2s"
+//	DarcContractIDs = ["darc"]
+//	Rules = ["spawn:value"]
+//
+//	[[Darcs]]
+//	Name = "users"
+//	Description = "darc of the users"
+//	[Darcs.Rules]
+//	"_sign" = "${owner}"
+//	"invoke:value.update" = "${owner} | ${darc:admin}"
+//
+//	[[Instances]]
+//	Name = "greeting"
+//	Contract = "value"
+//	Darc = "users"
+//	Value = "hello"
+//
+// The rule expressions can refer to the owners of the ledger with
+// ${owner}, to the genesis darc with ${genesis} and to a darc defined
+// before with ${darc:name}.
+type GenesisTemplate struct {
+	// BlockInterval is parsed by time.ParseDuration.
+	BlockInterval   string
+	MaxBlockSize    int
+	DarcContractIDs []string
+	Weights         []uint64
+	RotationPolicy  *TemplateRotationPolicy
+	// Rules are added to the genesis darc, with the owners as expression.
+	Rules     []string
+	Darcs     []TemplateDarc
+	Instances []TemplateInstance
+}
+
+// TemplateRotationPolicy is the RotationPolicy of a template.
+type TemplateRotationPolicy struct {
+	BlockCount int
+	// MaxLatency is parsed by time.ParseDuration.
+	MaxLatency      string
+	InclusionBlocks int
+}
+
+// TemplateDarc is a darc of a template. The instance ID of the darc is its
+// base ID.
+type TemplateDarc struct {
+	Name        string
+	Description string
+	// Rules maps the actions to their expressions.
+	Rules map[string]string
+}
+
+// TemplateInstance is an instance of a template. Its ID is given by
+// GenesisInstanceID. At most one of Value, Hex or Coin can be set.
+type TemplateInstance struct {
+	Name     string
+	Contract string
+	// Darc is the name of the darc controlling the instance. The genesis
+	// darc is used if it is empty.
+	Darc string
+	// Value is stored as is, e.g. for a value instance.
+	Value string
+	// Hex is the hexadecimal encoding of the value, for the contracts that
+	// store a protobuf structure, e.g. a spawner.
+	Hex string
+	// Coin is encoded as a Coin.
+	Coin *TemplateCoin
+}
+
+// TemplateCoin is the value of a coin instance.
+type TemplateCoin struct {
+	// Type is the name of the coin, hashed like contracts.CoinName.
+	Type  string
+	Value uint64
+}
+
+// genesisOptions holds the optional arguments of the spawn of the genesis
+// config.
+type genesisOptions struct {
+	Weights        []uint64          `protobuf:"opt"`
+	RotationPolicy *RotationPolicy   `protobuf:"opt"`
+	Instances      []GenesisInstance `protobuf:"opt"`
+}
+
+var templateRef = regexp.MustCompile(`\$\{([^}]*)\}`)
+
+// ReadGenesisTemplate reads a template from a TOML file.
+func ReadGenesisTemplate(fn string) (*GenesisTemplate, error) {
+	buf, err := ioutil.ReadFile(fn)
+	if err != nil {
+		return nil, err
+	}
+	return ParseGenesisTemplate(buf)
+}
+
+// ParseGenesisTemplate parses a template in TOML.
+func ParseGenesisTemplate(buf []byte) (*GenesisTemplate, error) {
+	t := &GenesisTemplate{}
+	md, err := toml.Decode(string(buf), t)
+	if err != nil {
+		return nil, err
+	}
+	if undec := md.Undecoded(); len(undec) > 0 {
+		return nil, fmt.Errorf("unknown field %s in template", undec[0])
+	}
+	return t, nil
+}
+
+// GenesisInstanceID returns the ID of the instance with the given name in a
+// template.
+func GenesisInstanceID(name string) InstanceID {
+	h := sha256.Sum256([]byte(name))
+	return NewInstanceID(h[:])
+}
+
+// GenesisMsg returns the message to create a ledger following the template,
+// owned by the given identities. The genesis darc is the one of
+// DefaultGenesisMsg with the additional rules of the template. It also
+// returns the IDs of the darcs and the instances, by name.
+func (t GenesisTemplate) GenesisMsg(v Version, r *onet.Roster, ids ...darc.Identity) (*CreateGenesisBlock, map[string]InstanceID, error) {
+	m, err := DefaultGenesisMsg(v, r, t.Rules, ids...)
+	if err != nil {
+		return nil, nil, err
+	}
+	if t.BlockInterval != "" {
+		m.BlockInterval, err = time.ParseDuration(t.BlockInterval)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid block interval: %v", err)
+		}
+	}
+	m.MaxBlockSize = t.MaxBlockSize
+	if len(t.DarcContractIDs) > 0 {
+		m.DarcContractIDs = t.DarcContractIDs
+	}
+	m.Weights = t.Weights
+	if p := t.RotationPolicy; p != nil {
+		m.RotationPolicy = &RotationPolicy{
+			BlockCount:      p.BlockCount,
+			InclusionBlocks: p.InclusionBlocks,
+		}
+		if p.MaxLatency != "" {
+			m.RotationPolicy.MaxLatency, err = time.ParseDuration(p.MaxLatency)
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid maximum latency: %v", err)
+			}
+		}
+	}
+
+	ownerIDs := make([]string, len(ids))
+	for i, o := range ids {
+		ownerIDs[i] = o.String()
+	}
+	owner := string(expression.InitAndExpr(ownerIDs...))
+	if len(ids) > 1 {
+		owner = "(" + owner + ")"
+	}
+	darcIDs := map[string]darc.ID{"genesis": m.GenesisDarc.GetBaseID()}
+	names := map[string]InstanceID{}
+
+	for _, td := range t.Darcs {
+		if _, ok := darcIDs[td.Name]; ok || td.Name == "" {
+			return nil, nil, fmt.Errorf("darc name \"%s\" is empty or used twice", td.Name)
+		}
+		// The rules are sorted so that the base ID of the darc only
+		// depends on the template.
+		actions := make([]string, 0, len(td.Rules))
+		for action := range td.Rules {
+			actions = append(actions, action)
+		}
+		sort.Strings(actions)
+		rules := darc.NewRules()
+		for _, action := range actions {
+			expr, err := resolveTemplateExpr(td.Rules[action], owner, darcIDs)
+			if err != nil {
+				return nil, nil, fmt.Errorf("darc %s: %v", td.Name, err)
+			}
+			if err = rules.AddRule(darc.Action(action), expression.Expr(expr)); err != nil {
+				return nil, nil, fmt.Errorf("darc %s: %v", td.Name, err)
+			}
+		}
+		d := darc.NewDarc(rules, []byte(td.Description))
+		buf, err := d.ToProto()
+		if err != nil {
+			return nil, nil, err
+		}
+		darcIDs[td.Name] = d.GetBaseID()
+		names[td.Name] = NewInstanceID(d.GetBaseID())
+		m.Instances = append(m.Instances, GenesisInstance{
+			InstanceID: NewInstanceID(d.GetBaseID()),
+			ContractID: ContractDarcID,
+			DarcID:     d.GetBaseID(),
+			Value:      buf,
+		})
+	}
+
+	for _, ti := range t.Instances {
+		if _, ok := names[ti.Name]; ok || ti.Name == "" {
+			return nil, nil, fmt.Errorf("instance name \"%s\" is empty or used twice", ti.Name)
+		}
+		darcName := ti.Darc
+		if darcName == "" {
+			darcName = "genesis"
+		}
+		did, ok := darcIDs[darcName]
+		if !ok {
+			return nil, nil, fmt.Errorf("instance %s: unknown darc %s", ti.Name, darcName)
+		}
+		value, err := ti.value()
+		if err != nil {
+			return nil, nil, fmt.Errorf("instance %s: %v", ti.Name, err)
+		}
+		id := GenesisInstanceID(ti.Name)
+		names[ti.Name] = id
+		m.Instances = append(m.Instances, GenesisInstance{
+			InstanceID: id,
+			ContractID: ti.Contract,
+			DarcID:     did,
+			Value:      value,
+		})
+	}
+	return m, names, nil
+}
+
+func (ti TemplateInstance) value() ([]byte, error) {
+	set := 0
+	for _, ok := range []bool{ti.Value != "", ti.Hex != "", ti.Coin != nil} {
+		if ok {
+			set++
+		}
+	}
+	if set > 1 {
+		return nil, errors.New("only one of Value, Hex and Coin can be set")
+	}
+	switch {
+	case ti.Hex != "":
+		return hex.DecodeString(ti.Hex)
+	case ti.Coin != nil:
+		name := sha256.Sum256([]byte(ti.Coin.Type))
+		return protobuf.Encode(&Coin{Name: NewInstanceID(name[:]), Value: ti.Coin.Value})
+	}
+	return []byte(ti.Value), nil
+}
+
+// resolveTemplateExpr replaces the references of an expression of a
+// template.
+func resolveTemplateExpr(expr, owner string, darcIDs map[string]darc.ID) (string, error) {
+	var err error
+	out := templateRef.ReplaceAllStringFunc(expr, func(ref string) string {
+		name := templateRef.FindStringSubmatch(ref)[1]
+		switch {
+		case name == "owner":
+			return owner
+		case name == "genesis":
+			return darc.NewIdentityDarc(darcIDs["genesis"]).String()
+		case strings.HasPrefix(name, "darc:"):
+			if id, ok := darcIDs[strings.TrimPrefix(name, "darc:")]; ok {
+				return darc.NewIdentityDarc(id).String()
+			}
+		}
+		err = fmt.Errorf("unknown reference %s", ref)
+		return ref
+	})
+	return out, err
+}
+
+// genesisStateChanges checks the instances of the genesis block and returns
+// the state changes creating them. The darcs must be defined before the
+// instances they control.
+func genesisStateChanges(darcContractIDs []string, genesis *darc.Darc, instances []GenesisInstance) (StateChanges, error) {
+	darcs := map[string]bool{string(genesis.GetBaseID()): true}
+	used := map[InstanceID]bool{
+		ConfigInstanceID:                   true,
+		NewInstanceID(genesis.GetBaseID()): true,
+	}
+	var scs StateChanges
+	for _, gi := range instances {
+		if used[gi.InstanceID] || gi.InstanceID.Equal(NewInstanceID(nil)) {
+			return nil, fmt.Errorf("invalid or duplicate instance ID %x", gi.InstanceID[:])
+		}
+		used[gi.InstanceID] = true
+
+		isDarc := false
+		for _, id := range darcContractIDs {
+			isDarc = isDarc || id == gi.ContractID
+		}
+		if isDarc {
+			d, err := darc.NewFromProtobuf(gi.Value)
+			if err != nil {
+				return nil, fmt.Errorf("couldn't decode darc %x: %v", gi.InstanceID[:], err)
+			}
+			if err = d.Verify(true); err != nil {
+				return nil, fmt.Errorf("invalid darc %x: %v", gi.InstanceID[:], err)
+			}
+			if !NewInstanceID(d.GetBaseID()).Equal(gi.InstanceID) {
+				return nil, fmt.Errorf("darc %x must use its base ID as instance ID", gi.InstanceID[:])
+			}
+			darcs[string(d.GetBaseID())] = true
+		}
+		if !darcs[string(gi.DarcID)] {
+			return nil, fmt.Errorf("instance %x uses an unknown darc", gi.InstanceID[:])
+		}
+		scs = append(scs, NewStateChange(Create, gi.InstanceID, gi.ContractID, gi.Value, gi.DarcID))
+	}
+	return scs, nil
+}
@@ -0,0 +1,131 @@
+package byzcoin
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/protobuf"
+)
+
+var testTemplate = `
+BlockInterval = "1s"
+Rules = ["spawn:dummy"]
+Weights = [1, 1, 1, 2]
+
+[RotationPolicy]
+MaxLatency = "10s"
+
+[[Darcs]]
+Name = "admin"
+Description = "admin darc"
+[Darcs.Rules]
+"_sign" = "${owner}"
+"invoke:darc.evolve" = "${owner} | ${genesis}"
+
+[[Darcs]]
+Name = "users"
+Description = "users darc"
+[Darcs.Rules]
+"_sign" = "${darc:admin}"
+
+[[Instances]]
+Name = "greeting"
+Contract = "dummy"
+Darc = "users"
+Value = "hello"
+
+[[Instances]]
+Name = "treasury"
+Contract = "dummy"
+[Instances.Coin]
+Type = "byzCoin"
+Value = 1000
+`
+
+func TestGenesisTemplate(t *testing.T) {
+	local := onet.NewLocalTestT(tSuite, t)
+	defer local.CloseAll()
+	hosts, roster, _ := local.GenTree(4, true)
+	registerDummy(hosts)
+	s := local.GetServices(hosts, ByzCoinID)[0].(*Service)
+
+	tmpl, err := ParseGenesisTemplate([]byte(testTemplate))
+	require.NoError(t, err)
+	signer := darc.NewSignerEd25519(nil, nil)
+	msg, names, err := tmpl.GenesisMsg(CurrentVersion, roster, signer.Identity())
+	require.NoError(t, err)
+	require.Equal(t, time.Second, msg.BlockInterval)
+	require.Equal(t, 4, len(names))
+
+	// The same template gives the same darcs.
+	msg2, _, err := tmpl.GenesisMsg(CurrentVersion, roster, signer.Identity())
+	require.NoError(t, err)
+	require.Equal(t, msg.Instances[0].InstanceID, msg2.Instances[0].InstanceID)
+
+	resp, err := s.CreateGenesisBlock(msg)
+	require.NoError(t, err)
+	scID := resp.Skipblock.SkipChainID()
+	require.Equal(t, []uint64{1, 1, 1, 2}, resp.Skipblock.Weights)
+
+	getValues := func(id InstanceID) ([]byte, string, darc.ID) {
+		rep, err := s.GetProof(&GetProof{Version: CurrentVersion, ID: scID, Key: id.Slice()})
+		require.NoError(t, err)
+		v, cid, did, err := rep.Proof.Get(id.Slice())
+		require.NoError(t, err)
+		return v, cid, did
+	}
+
+	buf, cid, did := getValues(names["users"])
+	require.Equal(t, ContractDarcID, cid)
+	users, err := darc.NewFromProtobuf(buf)
+	require.NoError(t, err)
+	require.Equal(t, darc.NewIdentityDarc(names["admin"].Slice()).String(),
+		string(users.Rules.Get("_sign")))
+	require.Equal(t, darc.ID(names["users"].Slice()), did)
+
+	buf, cid, did = getValues(names["greeting"])
+	require.Equal(t, []byte("hello"), buf)
+	require.Equal(t, dummyContract, cid)
+	require.Equal(t, darc.ID(names["users"].Slice()), did)
+
+	buf, _, did = getValues(GenesisInstanceID("treasury"))
+	var coin Coin
+	require.NoError(t, protobuf.Decode(buf, &coin))
+	require.Equal(t, uint64(1000), coin.Value)
+	require.Equal(t, msg.GenesisDarc.GetBaseID(), did)
+
+	config, err := s.LoadConfig(scID)
+	require.NoError(t, err)
+	require.Equal(t, 10*time.Second, config.RotationPolicy.MaxLatency)
+
+	// Invalid templates
+	for _, tmpl := range []string{
+		`Unknown = 1`,
+		`[[Darcs]]
+		Name = "genesis"`,
+		`[[Darcs]]
+		Name = "a"
+		Rules = {"_sign" = "${darc:b}"}`,
+		`[[Instances]]
+		Name = "a"
+		Darc = "b"`,
+		`[[Instances]]
+		Name = "a"
+		Value = "a"
+		Hex = "01"`,
+	} {
+		gt, err := ParseGenesisTemplate([]byte(tmpl))
+		if err == nil {
+			_, _, err = gt.GenesisMsg(CurrentVersion, roster, signer.Identity())
+		}
+		require.Error(t, err, tmpl)
+	}
+
+	// Instances must use a darc defined before them.
+	msg.Instances[1], msg.Instances[2] = msg.Instances[2], msg.Instances[1]
+	_, err = s.CreateGenesisBlock(msg)
+	require.Error(t, err)
+}
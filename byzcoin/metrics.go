@@ -0,0 +1,321 @@
+package byzcoin
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.dedis.ch/cothority/v3/byzcoin/trie"
+	"go.dedis.ch/cothority/v3/skipchain"
+)
+
+// The metrics are exported in the Prometheus text format, version 0.0.4. We
+// don't depend on the Prometheus client library as we only need counters,
+// gauges and summaries (sum and count only), which are easy enough to
+// produce by hand.
+const metricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+const (
+	metricBlocks            = "byzcoin_blocks_total"
+	metricBlockInterval     = "byzcoin_block_interval_seconds"
+	metricBlockTxs          = "byzcoin_block_transactions"
+	metricTxAccepted        = "byzcoin_transactions_accepted_total"
+	metricTxRefused         = "byzcoin_transactions_refused_total"
+	metricTxBuffer          = "byzcoin_tx_buffer_length"
+	metricViewChanges       = "byzcoin_view_changes_total"
+	metricCatchUp           = "byzcoin_catchup_duration_seconds"
+	metricTrieNodes         = "byzcoin_trie_nodes"
+	metricContractExecution = "byzcoin_contract_execution_seconds"
+)
+
+type metricType string
+
+const (
+	metricCounter metricType = "counter"
+	metricGauge   metricType = "gauge"
+	metricSummary metricType = "summary"
+)
+
+type metricDesc struct {
+	typ  metricType
+	help string
+}
+
+var metricDescs = map[string]metricDesc{
+	metricBlocks:            {metricCounter, "Number of blocks added to the chain."},
+	metricBlockInterval:     {metricSummary, "Time between the timestamps of two consecutive blocks."},
+	metricBlockTxs:          {metricSummary, "Number of transactions per block."},
+	metricTxAccepted:        {metricCounter, "Number of accepted transactions."},
+	metricTxRefused:         {metricCounter, "Number of refused transactions."},
+	metricTxBuffer:          {metricGauge, "Number of transactions waiting in the buffer of this node."},
+	metricViewChanges:       {metricCounter, "Number of view-changes that were committed."},
+	metricCatchUp:           {metricSummary, "Time it took to catch up with the chain."},
+	metricTrieNodes:         {metricGauge, "Number of nodes in the state trie."},
+	metricContractExecution: {metricSummary, "Time spent executing the instructions of a contract."},
+}
+
+// metricLabels is a sorted list of name/value pairs, rendered as
+// `{name="value",...}`.
+type metricLabels []string
+
+func newMetricLabels(kv ...string) metricLabels {
+	if len(kv)%2 != 0 {
+		panic("labels must be given as name/value pairs")
+	}
+	var pairs []string
+	for i := 0; i < len(kv); i += 2 {
+		v := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(kv[i+1])
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, kv[i], v))
+	}
+	sort.Strings(pairs)
+	return pairs
+}
+
+func (l metricLabels) String() string {
+	if len(l) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(l, ",") + "}"
+}
+
+type summaryValue struct {
+	sum   float64
+	count uint64
+}
+
+// metricsRegistry holds all the values of the metrics of all the services
+// running in this process. Every value is labelled with the conode and the
+// chain it belongs to, so that tests with many services in one process
+// don't mix up their values.
+type metricsRegistry struct {
+	sync.Mutex
+	values     map[string]map[string]float64
+	summaries  map[string]map[string]*summaryValue
+	collectors map[string]func()
+	// lastTimestamp holds the timestamp of the latest block of each
+	// conode/chain, so that we can compute the block interval.
+	lastTimestamp map[string]int64
+}
+
+func newMetricsRegistry() *metricsRegistry {
+	return &metricsRegistry{
+		values:        make(map[string]map[string]float64),
+		summaries:     make(map[string]map[string]*summaryValue),
+		collectors:    make(map[string]func()),
+		lastTimestamp: make(map[string]int64),
+	}
+}
+
+// metrics is shared by all the services of this process.
+var metrics = newMetricsRegistry()
+
+func (m *metricsRegistry) add(name string, l metricLabels, v float64) {
+	m.Lock()
+	defer m.Unlock()
+	if m.values[name] == nil {
+		m.values[name] = make(map[string]float64)
+	}
+	m.values[name][l.String()] += v
+}
+
+func (m *metricsRegistry) set(name string, l metricLabels, v float64) {
+	m.Lock()
+	defer m.Unlock()
+	if m.values[name] == nil {
+		m.values[name] = make(map[string]float64)
+	}
+	m.values[name][l.String()] = v
+}
+
+func (m *metricsRegistry) observe(name string, l metricLabels, v float64) {
+	m.Lock()
+	defer m.Unlock()
+	if m.summaries[name] == nil {
+		m.summaries[name] = make(map[string]*summaryValue)
+	}
+	s, ok := m.summaries[name][l.String()]
+	if !ok {
+		s = &summaryValue{}
+		m.summaries[name][l.String()] = s
+	}
+	s.sum += v
+	s.count++
+}
+
+// registerCollector adds a function that is called before the metrics are
+// written. It is used for the gauges that are cheaper to compute on demand
+// than to keep up-to-date. Registering a new collector with the same key
+// replaces the previous one.
+func (m *metricsRegistry) registerCollector(key string, f func()) {
+	m.Lock()
+	defer m.Unlock()
+	m.collectors[key] = f
+}
+
+// blockAdded updates all the metrics related to a new block.
+func (m *metricsRegistry) blockAdded(l metricLabels, timestamp int64, txs TxResults) {
+	m.add(metricBlocks, l, 1)
+	m.observe(metricBlockTxs, l, float64(len(txs)))
+	for _, tx := range txs {
+		if tx.Accepted {
+			m.add(metricTxAccepted, l, 1)
+		} else {
+			m.add(metricTxRefused, l, 1)
+		}
+	}
+
+	m.Lock()
+	last, ok := m.lastTimestamp[l.String()]
+	m.lastTimestamp[l.String()] = timestamp
+	m.Unlock()
+	if ok && timestamp > last {
+		m.observe(metricBlockInterval, l, time.Duration(timestamp-last).Seconds())
+	}
+}
+
+// writeTo writes all metrics in the Prometheus text format.
+func (m *metricsRegistry) writeTo(w io.Writer) error {
+	m.Lock()
+	collectors := make([]func(), 0, len(m.collectors))
+	for _, c := range m.collectors {
+		collectors = append(collectors, c)
+	}
+	m.Unlock()
+	for _, c := range collectors {
+		c()
+	}
+
+	m.Lock()
+	defer m.Unlock()
+	names := make([]string, 0, len(metricDescs))
+	for name := range metricDescs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		desc := metricDescs[name]
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, desc.help, name, desc.typ); err != nil {
+			return err
+		}
+		if desc.typ == metricSummary {
+			for _, l := range sortedKeys(m.summaries[name]) {
+				s := m.summaries[name][l]
+				if _, err := fmt.Fprintf(w, "%s_sum%s %g\n%s_count%s %d\n", name, l, s.sum, name, l, s.count); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		for _, l := range sortedKeys(m.values[name]) {
+			if _, err := fmt.Fprintf(w, "%s%s %g\n", name, l, m.values[name][l]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func sortedKeys(m interface{}) (keys []string) {
+	switch mt := m.(type) {
+	case map[string]float64:
+		for k := range mt {
+			keys = append(keys, k)
+		}
+	case map[string]*summaryValue:
+		for k := range mt {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return
+}
+
+// MetricsHandler returns an http.Handler that serves the metrics of all the
+// ByzCoin services of this process in the Prometheus text format.
+func MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", metricsContentType)
+		if err := metrics.writeTo(w); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+// metricLabels returns the labels to use for all metrics of this conode on
+// the given chain.
+func (s *Service) metricLabels(scID skipchain.SkipBlockID) metricLabels {
+	return newMetricLabels("conode", s.ServerIdentity().Address.String(),
+		"chain", fmt.Sprintf("%x", []byte(scID)))
+}
+
+// trieNodes caches the number of nodes of the state tries, so that they are
+// only counted again once a new block has been applied.
+type trieNodes struct {
+	sync.Mutex
+	counts map[string]trieNodesCount
+}
+
+type trieNodesCount struct {
+	index int
+	nodes int
+}
+
+func newTrieNodes() trieNodes {
+	return trieNodes{counts: make(map[string]trieNodesCount)}
+}
+
+// count returns the number of nodes of the trie. The trie is only walked if
+// its index changed since the last count.
+func (t *trieNodes) count(key string, st *stateTrie) (int, error) {
+	t.Lock()
+	defer t.Unlock()
+	index := st.GetIndex()
+	if c, ok := t.counts[key]; ok && c.index == index {
+		return c.nodes, nil
+	}
+
+	var nodes int
+	err := st.DB().View(func(b trie.Bucket) error {
+		return b.ForEach(func(k, v []byte) error {
+			nodes++
+			return nil
+		})
+	})
+	if err != nil {
+		return 0, err
+	}
+	t.counts[key] = trieNodesCount{index: index, nodes: nodes}
+	return nodes, nil
+}
+
+// collectMetrics updates the gauges of this service. It is called by the
+// registry right before the metrics are written.
+func (s *Service) collectMetrics() {
+	s.stateTriesLock.Lock()
+	tries := make(map[string]*stateTrie)
+	for k, v := range s.stateTries {
+		tries[k] = v
+	}
+	s.stateTriesLock.Unlock()
+
+	for idStr, st := range tries {
+		scID, err := hex.DecodeString(idStr)
+		if err != nil {
+			continue
+		}
+		l := s.metricLabels(scID)
+		metrics.set(metricTxBuffer, l, float64(s.txBuffer.len(string(scID))))
+
+		nodes, err := s.trieNodes.count(idStr, st)
+		if err != nil {
+			continue
+		}
+		metrics.set(metricTrieNodes, l, float64(nodes))
+	}
+}
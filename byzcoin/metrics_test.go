@@ -0,0 +1,76 @@
+package byzcoin
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetrics_Labels(t *testing.T) {
+	l := newMetricLabels("chain", "abcd", "conode", `tls://"a"`)
+	require.Equal(t, `{chain="abcd",conode="tls://\"a\""}`, l.String())
+	require.Equal(t, "", newMetricLabels().String())
+}
+
+func TestMetrics_WriteTo(t *testing.T) {
+	m := newMetricsRegistry()
+	l := newMetricLabels("chain", "01")
+
+	now := time.Now().UnixNano()
+	m.blockAdded(l, now, TxResults{{Accepted: true}, {Accepted: false}, {Accepted: true}})
+	m.blockAdded(l, now+int64(2*time.Second), TxResults{{Accepted: true}})
+	m.registerCollector("test", func() {
+		m.set(metricTxBuffer, l, 5)
+	})
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, m.writeTo(buf))
+	out := buf.String()
+
+	require.Contains(t, out, "# TYPE byzcoin_blocks_total counter\n")
+	require.Contains(t, out, `byzcoin_blocks_total{chain="01"} 2`+"\n")
+	require.Contains(t, out, `byzcoin_transactions_accepted_total{chain="01"} 3`+"\n")
+	require.Contains(t, out, `byzcoin_transactions_refused_total{chain="01"} 1`+"\n")
+	require.Contains(t, out, `byzcoin_block_transactions_sum{chain="01"} 4`+"\n")
+	require.Contains(t, out, `byzcoin_block_transactions_count{chain="01"} 2`+"\n")
+	require.Contains(t, out, `byzcoin_block_interval_seconds_sum{chain="01"} 2`+"\n")
+	require.Contains(t, out, `byzcoin_block_interval_seconds_count{chain="01"} 1`+"\n")
+	require.Contains(t, out, `byzcoin_tx_buffer_length{chain="01"} 5`+"\n")
+}
+
+func TestMetrics_Handler(t *testing.T) {
+	rec := httptest.NewRecorder()
+	MetricsHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	require.Equal(t, 200, rec.Code)
+	require.True(t, strings.HasPrefix(rec.Header().Get("Content-Type"), "text/plain"))
+	require.Contains(t, rec.Body.String(), "# TYPE byzcoin_contract_execution_seconds summary")
+}
+
+func TestMetrics_TrieNodes(t *testing.T) {
+	st, err := newMemStateTrie([]byte("nonce"))
+	require.NoError(t, err)
+	tn := newTrieNodes()
+
+	sc := StateChange{StateAction: Create, InstanceID: []byte("key1"), Value: []byte("value")}
+	require.NoError(t, st.StoreAll([]StateChange{sc}, 1))
+	nodes, err := tn.count("chain", st)
+	require.NoError(t, err)
+	require.True(t, nodes > 0)
+
+	// The trie is only counted again once the index changed.
+	sc.InstanceID = []byte("key2")
+	require.NoError(t, st.StoreAll([]StateChange{sc}, 1))
+	cached, err := tn.count("chain", st)
+	require.NoError(t, err)
+	require.Equal(t, nodes, cached)
+
+	sc.InstanceID = []byte("key3")
+	require.NoError(t, st.StoreAll([]StateChange{sc}, 2))
+	updated, err := tn.count("chain", st)
+	require.NoError(t, err)
+	require.True(t, updated > nodes)
+}
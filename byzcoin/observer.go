@@ -0,0 +1,115 @@
+package byzcoin
+
+import (
+	"errors"
+	"time"
+
+	"go.dedis.ch/cothority/v3/skipchain"
+	"go.dedis.ch/onet/v3/log"
+)
+
+// observerPollInterval is how often an observer asks the roster of the chains
+// it follows for new blocks.
+var observerPollInterval = 5 * time.Second
+
+// monitorObservedChains starts a go-routine that keeps a copy of the byzcoin
+// chains this conode follows, but is not part of the roster of. The chains
+// are added with skipchain.AddFollow, using FollowSearch or FollowLookup.
+// Such an observer stores the verified blocks and the state trie, so it can
+// serve GetProof and the streaming of the blocks, but it never takes part in
+// the consensus.
+func (s *Service) monitorObservedChains() {
+	s.closedMutex.Lock()
+	if s.closed {
+		s.closedMutex.Unlock()
+		return
+	}
+	s.working.Add(1)
+	defer s.working.Done()
+	s.closedMutex.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(observerPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				for _, sb := range s.skService().GetFollowed() {
+					if err := s.observeChain(sb); err != nil {
+						log.Warnf("%s couldn't observe chain %x: %v", s.ServerIdentity(),
+							sb.SkipChainID(), err)
+					}
+				}
+			case <-s.closeObserverChan:
+				log.Lvl2(s.ServerIdentity(), "closing observer monitor")
+				return
+			}
+		}
+	}()
+}
+
+// observeChain fetches the new blocks of a followed chain. If the genesis
+// block is not known yet, it is fetched first. The following blocks are
+// verified when they are stored, and the state trie is updated by
+// updateTrieCallback.
+func (s *Service) observeChain(followed *skipchain.SkipBlock) error {
+	if !isByzCoinBlock(followed) {
+		return nil
+	}
+	scID := followed.SkipChainID()
+	cl := skipchain.NewClient()
+
+	if s.db().GetByID(scID) == nil {
+		genesis, err := cl.GetSingleBlock(followed.Roster, scID)
+		if err != nil {
+			return err
+		}
+		if genesis.Index != 0 || !genesis.CalculateHash().Equal(scID) {
+			return errors.New("got a wrong genesis block")
+		}
+		log.Lvlf2("%s starts observing chain %x", s.ServerIdentity(), scID)
+		if _, err := s.db().StoreBlocks([]*skipchain.SkipBlock{genesis}); err != nil {
+			return err
+		}
+	}
+
+	latest, err := s.db().GetLatestByID(scID)
+	if err != nil {
+		return err
+	}
+	if i, _ := latest.Roster.Search(s.ServerIdentity().ID); i >= 0 {
+		// Members of the roster get the blocks during the consensus.
+		return nil
+	}
+	reply, err := cl.GetUpdateChain(latest.Roster, latest.Hash)
+	if err != nil {
+		return err
+	}
+	if len(reply.Update) == 0 {
+		return errors.New("got an empty update")
+	}
+	remote := reply.Update[len(reply.Update)-1]
+	if remote.Index <= latest.Index {
+		return nil
+	}
+
+	s.updateTrieLock.Lock()
+	if s.catchingUp {
+		s.updateTrieLock.Unlock()
+		return nil
+	}
+	s.catchingUp = true
+	s.updateTrieLock.Unlock()
+	s.catchUp(remote)
+	return nil
+}
+
+// isByzCoinBlock returns true if the block is from a byzcoin chain.
+func isByzCoinBlock(sb *skipchain.SkipBlock) bool {
+	for _, x := range sb.VerifierIDs {
+		if x.Equal(Verify) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,46 @@
+package byzcoin
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3/skipchain"
+	"go.dedis.ch/onet/v3"
+)
+
+func TestService_Observer(t *testing.T) {
+	s := newSer(t, 2, testInterval)
+	defer s.local.CloseAll()
+
+	observerServer := s.local.GenServers(1)[0]
+	observer := observerServer.Service(ServiceName).(*Service)
+	registerDummy([]*onet.Server{observerServer})
+
+	_, err := observer.skService().AddFollow(&skipchain.AddFollow{
+		SkipchainID: s.genesis.SkipChainID(),
+		Follow:      skipchain.FollowLookup,
+		Conode:      s.roster.List[0],
+	})
+	require.NoError(t, err)
+	followed := observer.skService().GetFollowed()
+	require.Equal(t, 1, len(followed))
+
+	// The first call gets the genesis block and catches up with the chain.
+	require.NoError(t, observer.observeChain(followed[0]))
+
+	serKey := s.tx.Instructions[0].Hash()
+	rep, err := observer.GetProof(&GetProof{
+		Version: CurrentVersion,
+		ID:      s.genesis.SkipChainID(),
+		Key:     serKey,
+	})
+	require.NoError(t, err)
+	require.True(t, rep.Proof.InclusionProof.Match(serKey))
+	require.NoError(t, rep.Proof.Verify(s.genesis.SkipChainID()))
+
+	// The observer is not part of the consensus.
+	latest, err := observer.db().GetLatestByID(s.genesis.SkipChainID())
+	require.NoError(t, err)
+	i, _ := latest.Roster.Search(observer.ServerIdentity().ID)
+	require.True(t, i < 0)
+}
@@ -0,0 +1,55 @@
+package byzcoin
+
+import (
+	"errors"
+)
+
+// errChainPaused is returned for the transactions that cannot be accepted
+// while the chain is paused.
+var errChainPaused = errors.New("the chain is paused")
+
+// configOnly returns true if all the instructions of the transaction are
+// for the config contract, so that the chain can still be managed while it
+// is paused.
+func configOnly(tx ClientTransaction) bool {
+	for _, instr := range tx.Instructions {
+		if instr.InstanceID != ConfigInstanceID {
+			return false
+		}
+	}
+	return true
+}
+
+// checkPaused returns errChainPaused if the chain is paused and the
+// transaction is not only for the config contract. Before the genesis block,
+// there is no config and all the transactions are accepted.
+func checkPaused(st ReadOnlyStateTrie, tx ClientTransaction) error {
+	config, err := LoadConfigFromTrie(st)
+	if err != nil {
+		if err == ErrKeyNotSet {
+			return nil
+		}
+		return err
+	}
+	if config.Paused && !configOnly(tx) {
+		return errChainPaused
+	}
+	return nil
+}
+
+// checkEmptyBlock returns an error if a block has no transactions, unless the
+// chain is paused. The leader of a paused chain creates empty blocks at every
+// interval to show that it is still alive.
+func checkEmptyBlock(st ReadOnlyStateTrie, txs TxResults) error {
+	if len(txs) > 0 {
+		return nil
+	}
+	config, err := LoadConfigFromTrie(st)
+	if err != nil {
+		return err
+	}
+	if !config.Paused {
+		return errors.New("no transactions")
+	}
+	return nil
+}
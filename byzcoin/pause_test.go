@@ -0,0 +1,96 @@
+package byzcoin
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/protobuf"
+)
+
+func (s *ser) invokeConfig(t *testing.T, command string, counter uint64) error {
+	instr := Instruction{
+		InstanceID: ConfigInstanceID,
+		Invoke: &Invoke{
+			ContractID: ContractConfigID,
+			Command:    command,
+		},
+		SignerIdentities: []darc.Identity{s.signer.Identity()},
+		SignerCounter:    []uint64{counter},
+	}
+	ctx, err := combineInstrsAndSign(s.signer, instr)
+	require.NoError(t, err)
+	_, err = s.service().AddTransaction(&AddTxRequest{
+		Version:       CurrentVersion,
+		SkipchainID:   s.genesis.SkipChainID(),
+		Transaction:   ctx,
+		InclusionWait: 10,
+	})
+	return err
+}
+
+func TestService_Pause(t *testing.T) {
+	s := newSer(t, 1, testInterval)
+	defer s.local.CloseAll()
+
+	// Resuming a running chain is refused.
+	require.Error(t, s.invokeConfig(t, "resume", 1))
+	require.NoError(t, s.invokeConfig(t, "pause", 1))
+	config, err := s.service().LoadConfig(s.genesis.SkipChainID())
+	require.NoError(t, err)
+	require.True(t, config.Paused)
+
+	// Only the transactions for the config are accepted.
+	_, _, err, _ = sendTransaction(t, s, 0, dummyContract, 0)
+	require.Equal(t, errChainPaused, err)
+
+	// An update of the config doesn't change the state of the chain.
+	ctx, _ := createConfigTxWithCounter(t, testInterval, *s.roster, defaultMaxBlockSize, s, 2)
+	s.sendTxAndWait(t, ctx, 10)
+	config, err = s.service().LoadConfig(s.genesis.SkipChainID())
+	require.NoError(t, err)
+	require.True(t, config.Paused)
+
+	require.NoError(t, s.invokeConfig(t, "resume", 3))
+	config, err = s.service().LoadConfig(s.genesis.SkipChainID())
+	require.NoError(t, err)
+	require.False(t, config.Paused)
+	pr, key, err, err2 := sendTransaction(t, s, 0, dummyContract, 10)
+	require.NoError(t, err)
+	require.NoError(t, err2)
+	require.True(t, pr.InclusionProof.Match(key))
+}
+
+func TestService_PauseEmptyBlocks(t *testing.T) {
+	s := newSer(t, 1, testInterval)
+	defer s.local.CloseAll()
+	latestIndex := func() int {
+		latest, err := s.service().db().GetLatestByID(s.genesis.SkipChainID())
+		require.NoError(t, err)
+		return latest.Index
+	}
+
+	// The leader of a paused chain creates blocks without transactions.
+	require.NoError(t, s.invokeConfig(t, "pause", 1))
+	index := latestIndex()
+	time.Sleep(4 * s.interval)
+	latest, err := s.service().db().GetLatestByID(s.genesis.SkipChainID())
+	require.NoError(t, err)
+	require.True(t, latest.Index > index+1)
+	var body DataBody
+	require.NoError(t, protobuf.Decode(latest.Payload, &body))
+	require.Empty(t, body.TxResults)
+
+	// Once resumed, only the transactions make new blocks.
+	require.NoError(t, s.invokeConfig(t, "resume", 2))
+	time.Sleep(s.interval)
+	index = latestIndex()
+	time.Sleep(4 * s.interval)
+	require.Equal(t, index, latestIndex())
+
+	// A running chain doesn't accept empty blocks.
+	st, err := s.service().getStateTrie(s.genesis.SkipChainID())
+	require.NoError(t, err)
+	require.Error(t, checkEmptyBlock(st, TxResults{}))
+}
@@ -9,6 +9,7 @@ import (
 	"go.dedis.ch/cothority/v3/skipchain"
 	"go.dedis.ch/kyber/v3"
 	"go.dedis.ch/kyber/v3/pairing"
+	"go.dedis.ch/onet/v3"
 	"go.dedis.ch/onet/v3/network"
 	"go.dedis.ch/protobuf"
 )
@@ -29,9 +30,10 @@ func NewProof(c ReadOnlyStateTrie, s *skipchain.SkipBlockDB, id skipchain.SkipBl
 		return nil, errors.New("didn't find skipchain")
 	}
 	p.Links = []skipchain.ForwardLink{{
-		From:      []byte{},
-		To:        id,
-		NewRoster: sb.Roster,
+		From:       []byte{},
+		To:         id,
+		NewRoster:  sb.Roster,
+		NewWeights: sb.Weights,
 	}}
 	for len(sb.ForwardLink) > 0 && sb.Index < c.GetIndex() {
 		var link *skipchain.ForwardLink
@@ -77,6 +79,34 @@ var ErrorVerifyHash = errors.New("last forward link does not point to the latest
 // skipchain. If all verifications are correct, the error will be nil. It does
 // not verify whether a certain key/value pair exists in the proof.
 func (p Proof) Verify(scID skipchain.SkipBlockID) error {
+	var genesisRoster *onet.Roster
+	var genesisWeights []uint64
+	if len(p.Links) > 0 {
+		genesisRoster = p.Links[0].NewRoster
+		genesisWeights = p.Links[0].NewWeights
+	}
+	return p.verify(scID, genesisRoster, genesisWeights)
+}
+
+// VerifyWithRoster is like Verify, but instead of trusting the roster given
+// in the proof for the genesis block, it uses the given roster. This is
+// needed when the proof comes from an untrusted source, for example inside
+// a contract verifying a proof of another chain. The roster evolution is
+// then followed through the forward links. All nodes of the genesis roster
+// have the same weight, use VerifyWithWeights for a chain with weighted
+// voting.
+func (p Proof) VerifyWithRoster(scID skipchain.SkipBlockID, genesisRoster *onet.Roster) error {
+	return p.verify(scID, genesisRoster, nil)
+}
+
+// VerifyWithWeights is like VerifyWithRoster, but also trusts the given
+// voting weights of the nodes of the genesis roster.
+func (p Proof) VerifyWithWeights(scID skipchain.SkipBlockID, genesisRoster *onet.Roster,
+	genesisWeights []uint64) error {
+	return p.verify(scID, genesisRoster, genesisWeights)
+}
+
+func (p Proof) verify(scID skipchain.SkipBlockID, genesisRoster *onet.Roster, genesisWeights []uint64) error {
 	var header DataHeader
 	err := protobuf.DecodeWithConstructors(p.Latest.Data, &header, network.DefaultConstructors(cothority.Suite))
 	if err != nil {
@@ -88,17 +118,18 @@ func (p Proof) Verify(scID skipchain.SkipBlockID) error {
 
 	sbID := scID
 	var publics []kyber.Point
+	weights := genesisWeights
 	for i, l := range p.Links {
 		if i == 0 {
 			// The first forward link is a pointer from []byte{} to the genesis
 			// block and holds the roster of the genesis block.
-			if !l.To.Equal(scID) {
+			if !l.To.Equal(scID) || genesisRoster == nil {
 				return ErrorVerifySkipchain
 			}
-			publics = l.NewRoster.ServicePublics(skipchain.ServiceName)
+			publics = genesisRoster.ServicePublics(skipchain.ServiceName)
 			continue
 		}
-		if err = l.Verify(pairing.NewSuiteBn256(), publics); err != nil {
+		if err = l.VerifyWithWeights(pairing.NewSuiteBn256(), publics, weights); err != nil {
 			return ErrorVerifySkipchain
 		}
 		if !l.From.Equal(sbID) {
@@ -107,6 +138,7 @@ func (p Proof) Verify(scID skipchain.SkipBlockID) error {
 		sbID = l.To
 		if l.NewRoster != nil {
 			publics = l.NewRoster.ServicePublics(skipchain.ServiceName)
+			weights = l.NewWeights
 		}
 	}
 
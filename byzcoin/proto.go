@@ -67,6 +67,23 @@ type CreateGenesisBlock struct {
 	// DarcContracts is the set of contracts that can be parsed as a DARC.
 	// At least one contract must be given.
 	DarcContractIDs []string
+	// Weights is the optional voting weight of each node of the roster.
+	Weights []uint64 `protobuf:"opt"`
+	// RotationPolicy is the optional leader rotation policy.
+	RotationPolicy *RotationPolicy `protobuf:"opt"`
+	// Instances are created in the genesis block, along with the config and
+	// the genesis darc.
+	// optional
+	Instances []GenesisInstance `protobuf:"opt"`
+}
+
+// GenesisInstance is an instance created in the genesis block. Its value is
+// stored as is, without calling its contract.
+type GenesisInstance struct {
+	InstanceID InstanceID
+	ContractID string
+	DarcID     darc.ID
+	Value      []byte
 }
 
 // CreateGenesisBlockResponse holds the genesis-block of the new skipchain.
@@ -144,6 +161,32 @@ type ChainConfig struct {
 	Roster          onet.Roster
 	MaxBlockSize    int
 	DarcContractIDs []string
+	// Weights is the optional voting weight of each node of the roster, in
+	// the same order. If it is set, a block needs to be signed by nodes
+	// holding more than two thirds of the total weight. The weights are
+	// recorded in every block so that proofs can be verified on their own.
+	Weights []uint64 `protobuf:"opt"`
+	// RotationPolicy is optional and tells when the leader must be replaced
+	// even if it is still alive.
+	RotationPolicy *RotationPolicy `protobuf:"opt"`
+	// Paused is set by the pause command of the config contract. While the
+	// chain is paused, only the instructions for the config contract are
+	// accepted.
+	Paused bool `protobuf:"opt"`
+}
+
+// RotationPolicy holds the rules that trigger a view-change on a leader that
+// is slow or censors transactions. Every rule is disabled by its zero value.
+// A view-change only happens if enough nodes agree that the rule applies.
+type RotationPolicy struct {
+	// BlockCount rotates the leader every BlockCount blocks.
+	BlockCount int
+	// MaxLatency rotates the leader if a block arrives more than MaxLatency
+	// after the timestamp the leader gave it.
+	MaxLatency time.Duration
+	// InclusionBlocks rotates the leader if a transaction submitted to a
+	// follower is not included after InclusionBlocks blocks.
+	InclusionBlocks int
 }
 
 // Proof represents everything necessary to verify a given
@@ -370,6 +413,55 @@ type GetAllInstanceVersionResponse struct {
 	StateChanges []GetInstanceVersionResponse
 }
 
+// GetEquivocations is a request for the evidence of the equivocations found
+// by a node on a chain.
+type GetEquivocations struct {
+	Version     Version
+	SkipChainID skipchain.SkipBlockID
+}
+
+// GetEquivocationsResponse holds the evidence of the equivocations. Each one
+// can be sent in a remove_equivocator instruction.
+type GetEquivocationsResponse struct {
+	Equivocations []skipchain.Equivocation
+}
+
+// GetDeferred is a request for the deferred instances of a chain that can
+// still be executed.
+type GetDeferred struct {
+	Version     Version
+	SkipChainID skipchain.SkipBlockID
+	// DarcID, if given, only returns the instances controlled by the darc
+	// or proposing an instruction on an instance controlled by the darc.
+	DarcID darc.ID `protobuf:"opt"`
+	// Identity, if given, only returns the instances still missing a proof
+	// of the identity.
+	Identity string `protobuf:"opt"`
+}
+
+// GetDeferredResponse holds the deferred instances that can still be
+// executed.
+type GetDeferredResponse struct {
+	Deferred []PendingDeferred
+}
+
+// PendingDeferred is a deferred instance that can still be executed.
+type PendingDeferred struct {
+	InstanceID InstanceID
+	DarcID     darc.ID
+	// Data is the protobuf-encoded DeferredData of the instance.
+	Data []byte
+	// Missing holds the identities missing for every instruction of the
+	// proposed transaction.
+	Missing []MissingSigners
+}
+
+// MissingSigners are the identities of a rule that did not sign yet. It is
+// empty once the rule is satisfied.
+type MissingSigners struct {
+	Identities []string
+}
+
 // CheckStateChangeValidity is a request to get the list
 // of state changes belonging to the same block as the
 // targeted one to compute the hash
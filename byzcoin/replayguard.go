@@ -13,7 +13,7 @@ import (
 // counter from the Trie.
 func getSignerCounter(st ReadOnlyStateTrie, id string) (uint64, error) {
 	val, _, _, _, err := st.GetValues(publicVersionKey(id))
-	if err == errKeyNotSet {
+	if err == ErrKeyNotSet {
 		return 0, nil
 	}
 	if err != nil {
@@ -23,6 +23,12 @@ func getSignerCounter(st ReadOnlyStateTrie, id string) (uint64, error) {
 	return ver, nil
 }
 
+// GetSignerCounter returns the counter of the last transaction signed by the
+// identity, or 0 if it never signed one.
+func GetSignerCounter(st ReadOnlyStateTrie, id darc.Identity) (uint64, error) {
+	return getSignerCounter(st, id.String())
+}
+
 // incrementSignerCounters loads the existing counters from sigs and then
 // increments all of them by 1.
 func incrementSignerCounters(st ReadOnlyStateTrie, ids []darc.Identity) (StateChanges, error) {
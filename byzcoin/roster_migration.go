@@ -0,0 +1,157 @@
+package byzcoin
+
+import (
+	"errors"
+	"fmt"
+
+	"go.dedis.ch/cothority/v3/byzcoinx"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/network"
+)
+
+// RosterAction is the change applied to the roster by one step of a
+// RosterMigration.
+type RosterAction string
+
+const (
+	// RosterAdd adds a node at the end of the roster.
+	RosterAdd = RosterAction("add")
+	// RosterRemove removes a node from the roster.
+	RosterRemove = RosterAction("remove")
+	// RosterLeader makes a node of the roster the new leader.
+	RosterLeader = RosterAction("leader")
+	// RosterOrder reorders the roster to match the target roster.
+	RosterOrder = RosterAction("order")
+)
+
+// RosterMigrationStep is one update of the roster in the chain config.
+type RosterMigrationStep struct {
+	Action RosterAction
+	// Node is the node that is added, removed or made leader. It is nil
+	// for RosterOrder.
+	Node *network.ServerIdentity
+	// Roster is the roster once the step has been applied.
+	Roster onet.Roster
+}
+
+// String returns a short description of the step.
+func (s RosterMigrationStep) String() string {
+	if s.Node == nil {
+		return string(s.Action)
+	}
+	return fmt.Sprintf("%s %s", s.Action, s.Node.Address)
+}
+
+// RosterMigration is a plan to replace the roster of a chain by a target
+// roster. As the config contract only accepts to change one node per
+// update, the migration is split in steps that each produce a valid update.
+// Nodes are added and removed alternately, so that the roster never shrinks
+// more than needed, and the leader is moved to a node of the target roster
+// before it is removed.
+type RosterMigration struct {
+	Current onet.Roster
+	Target  onet.Roster
+	Steps   []RosterMigrationStep
+}
+
+// NewRosterMigration creates the steps to go from the current roster to the
+// target roster. Every step is checked to be accepted by the config
+// contract. Unless force is true, it also checks that every step keeps the
+// chain alive if a newly added node didn't catch up yet: the nodes that
+// were already in the roster must be enough to reach the threshold of
+// byzcoinx.
+//
+// As the plan only depends on the current roster, a failed migration is
+// resumed by creating a new plan from the roster that is active on the
+// chain.
+func NewRosterMigration(current, target onet.Roster, force bool) (*RosterMigration, error) {
+	if len(current.List) == 0 || len(target.List) == 0 {
+		return nil, errors.New("current and target rosters cannot be empty")
+	}
+	for i, si := range target.List {
+		if idx, _ := target.Search(si.ID); idx != i {
+			return nil, fmt.Errorf("node %s is twice in the target roster", si.Address)
+		}
+	}
+
+	var toAdd, toRemove []*network.ServerIdentity
+	for _, si := range target.List {
+		if i, _ := current.Search(si.ID); i < 0 {
+			toAdd = append(toAdd, si)
+		}
+	}
+	for _, si := range current.List {
+		if i, _ := target.Search(si.ID); i < 0 {
+			toRemove = append(toRemove, si)
+		}
+	}
+
+	m := &RosterMigration{Current: current, Target: target}
+	roster := onet.NewRoster(current.List)
+	addStep := func(action RosterAction, node *network.ServerIdentity, list []*network.ServerIdentity) {
+		roster = onet.NewRoster(list)
+		m.Steps = append(m.Steps, RosterMigrationStep{action, node, *roster})
+	}
+	for i := 0; i < len(toAdd) || i < len(toRemove); i++ {
+		if i < len(toAdd) {
+			list := append([]*network.ServerIdentity{}, roster.List...)
+			addStep(RosterAdd, toAdd[i], append(list, toAdd[i]))
+		}
+		if i < len(toRemove) {
+			if roster.List[0].Equal(toRemove[i]) {
+				leader := migrationLeader(*roster, target)
+				if leader < 0 {
+					return nil, errors.New("no node of the target roster can become leader")
+				}
+				list := append([]*network.ServerIdentity{}, roster.List...)
+				list[0], list[leader] = list[leader], list[0]
+				addStep(RosterLeader, list[0], list)
+			}
+			idx, _ := roster.Search(toRemove[i].ID)
+			list := append([]*network.ServerIdentity{}, roster.List[:idx]...)
+			addStep(RosterRemove, toRemove[i], append(list, roster.List[idx+1:]...))
+		}
+	}
+	if !roster.List[0].Equal(target.List[0]) {
+		idx, _ := roster.Search(target.List[0].ID)
+		list := append([]*network.ServerIdentity{}, roster.List...)
+		list[0], list[idx] = list[idx], list[0]
+		addStep(RosterLeader, list[0], list)
+	}
+	for i, si := range roster.List {
+		if !si.Equal(target.List[i]) {
+			addStep(RosterOrder, nil, target.List)
+			break
+		}
+	}
+
+	prev := current
+	for i, step := range m.Steps {
+		if err := (ChainConfig{Roster: prev}).checkNewRoster(step.Roster); err != nil {
+			return nil, fmt.Errorf("step %d (%s) is invalid: %v", i+1, step, err)
+		}
+		if !force && step.Action == RosterAdd &&
+			len(prev.List) < byzcoinx.Threshold(len(step.Roster.List)) {
+			return nil, fmt.Errorf("step %d (%s) stops the chain until the new node "+
+				"is up to date, as the roster of %d nodes tolerates no fault",
+				i+1, step, len(step.Roster.List))
+		}
+		prev = step.Roster
+	}
+	return m, nil
+}
+
+// migrationLeader returns the index of the node of the roster that should
+// take over the leadership: the leader of the target roster if possible,
+// else the first node that is also in the target roster.
+func migrationLeader(roster, target onet.Roster) int {
+	if i, _ := roster.Search(target.List[0].ID); i > 0 {
+		return i
+	}
+	for i, si := range roster.List[1:] {
+		if j, _ := target.Search(si.ID); j >= 0 {
+			return i + 1
+		}
+	}
+	return -1
+}
@@ -0,0 +1,66 @@
+package byzcoin
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/network"
+)
+
+func TestRosterMigration(t *testing.T) {
+	nodes, _ := genRoster(8)
+	roster := func(idx ...int) onet.Roster {
+		var list []*network.ServerIdentity
+		for _, i := range idx {
+			list = append(list, nodes.List[i])
+		}
+		return *onet.NewRoster(list)
+	}
+
+	// Nothing to do
+	m, err := NewRosterMigration(roster(0, 1, 2, 3), roster(0, 1, 2, 3), false)
+	require.NoError(t, err)
+	require.Equal(t, 0, len(m.Steps))
+
+	// Replace half of the roster, including the leader
+	m, err = NewRosterMigration(roster(0, 1, 2, 3), roster(2, 3, 4, 5), false)
+	require.NoError(t, err)
+	var actions []RosterAction
+	for _, step := range m.Steps {
+		actions = append(actions, step.Action)
+	}
+	require.Equal(t, []RosterAction{RosterAdd, RosterLeader, RosterRemove, RosterAdd,
+		RosterRemove}, actions)
+	require.True(t, m.Steps[1].Node.Equal(nodes.List[2]))
+	last := m.Steps[len(m.Steps)-1].Roster
+	require.Equal(t, len(m.Target.List), len(last.List))
+	for i := range last.List {
+		require.True(t, last.List[i].Equal(m.Target.List[i]))
+	}
+
+	// The same nodes in another order
+	m, err = NewRosterMigration(roster(0, 1, 2, 3), roster(0, 3, 2, 1), false)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(m.Steps))
+	require.Equal(t, RosterOrder, m.Steps[0].Action)
+
+	// Only a new leader
+	m, err = NewRosterMigration(roster(0, 1, 2, 3), roster(1, 0, 2, 3), false)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(m.Steps))
+	require.Equal(t, RosterLeader, m.Steps[0].Action)
+
+	// A small roster cannot add nodes without stopping the chain
+	_, err = NewRosterMigration(roster(0, 1), roster(2, 3), false)
+	require.Error(t, err)
+	m, err = NewRosterMigration(roster(0, 1), roster(2, 3), true)
+	require.NoError(t, err)
+	require.True(t, m.Steps[len(m.Steps)-1].Roster.List[0].Equal(nodes.List[2]))
+
+	// Invalid target rosters
+	_, err = NewRosterMigration(roster(0, 1, 2, 3), onet.Roster{}, false)
+	require.Error(t, err)
+	_, err = NewRosterMigration(roster(0, 1, 2, 3), roster(0, 1, 2, 2), false)
+	require.Error(t, err)
+}
@@ -0,0 +1,180 @@
+package byzcoin
+
+import (
+	"sync"
+	"time"
+
+	"go.dedis.ch/cothority/v3/byzcoin/viewchange"
+	"go.dedis.ch/cothority/v3/skipchain"
+	"go.dedis.ch/onet/v3/log"
+)
+
+// txInclusion keeps track of the transactions that have been submitted to
+// this node, so that a leader that doesn't include them can be replaced. The
+// first key is the skipchain ID, the second one the hash of the instructions.
+type txInclusion struct {
+	sync.Mutex
+	pending map[string]map[string]pendingTx
+}
+
+// pendingTx holds the index of the latest block and the time when the
+// transaction has been submitted.
+type pendingTx struct {
+	index int
+	added time.Time
+}
+
+func newTxInclusion() txInclusion {
+	return txInclusion{
+		pending: make(map[string]map[string]pendingTx),
+	}
+}
+
+// add remembers a transaction submitted when the latest block had the given
+// index. If the transaction is already known, it is kept as it was.
+func (t *txInclusion) add(scID skipchain.SkipBlockID, txHash []byte, index int) {
+	t.Lock()
+	defer t.Unlock()
+	txs, ok := t.pending[string(scID)]
+	if !ok {
+		txs = make(map[string]pendingTx)
+		t.pending[string(scID)] = txs
+	}
+	if _, ok := txs[string(txHash)]; !ok {
+		txs[string(txHash)] = pendingTx{index: index, added: time.Now()}
+	}
+}
+
+// included removes the transactions of a block, whether they have been
+// accepted or not.
+func (t *txInclusion) included(scID skipchain.SkipBlockID, txs TxResults) {
+	t.Lock()
+	defer t.Unlock()
+	pending := t.pending[string(scID)]
+	for _, tx := range txs {
+		delete(pending, string(tx.ClientTransaction.Instructions.Hash()))
+	}
+}
+
+// late returns the number of transactions that are still waiting to be
+// included at the given index after more than k blocks. Those transactions
+// are forgotten, so that each of them only triggers one view-change.
+func (t *txInclusion) late(scID skipchain.SkipBlockID, index, k int) int {
+	t.Lock()
+	defer t.Unlock()
+	pending := t.pending[string(scID)]
+	var count int
+	for h, tx := range pending {
+		if index-tx.index > k {
+			delete(pending, h)
+			count++
+		}
+	}
+	return count
+}
+
+// expired is like late, but returns the number of transactions that have been
+// waiting for more than the deadline.
+func (t *txInclusion) expired(scID skipchain.SkipBlockID, deadline time.Duration) int {
+	t.Lock()
+	defer t.Unlock()
+	pending := t.pending[string(scID)]
+	var count int
+	for h, tx := range pending {
+		if time.Since(tx.added) > deadline {
+			delete(pending, h)
+			count++
+		}
+	}
+	return count
+}
+
+// chains returns the IDs of the skipchains with pending transactions.
+func (t *txInclusion) chains() []skipchain.SkipBlockID {
+	t.Lock()
+	defer t.Unlock()
+	var ids []skipchain.SkipBlockID
+	for id, pending := range t.pending {
+		if len(pending) > 0 {
+			ids = append(ids, skipchain.SkipBlockID(id))
+		}
+	}
+	return ids
+}
+
+// restart gives a new leader the full delay to include the pending
+// transactions, starting at the given index.
+func (t *txInclusion) restart(scID skipchain.SkipBlockID, index int) {
+	t.Lock()
+	defer t.Unlock()
+	pending := t.pending[string(scID)]
+	for h := range pending {
+		pending[h] = pendingTx{index: index, added: time.Now()}
+	}
+}
+
+// stop forgets all the transactions of a skipchain.
+func (t *txInclusion) stop(scID skipchain.SkipBlockID) {
+	t.Lock()
+	defer t.Unlock()
+	delete(t.pending, string(scID))
+}
+
+// checkRotationPolicy is called for every new block that is not a
+// view-change, and asks for a view-change if one of the rules of the
+// rotation policy applies to the leader. The view-change only happens if
+// enough nodes ask for it.
+func (s *Service) checkRotationPolicy(config *ChainConfig, sb *skipchain.SkipBlock,
+	header DataHeader, body DataBody) {
+	s.txInclusion.included(sb.SkipChainID(), body.TxResults)
+	if config.Paused {
+		// The leader drops the pending transactions of a paused chain.
+		s.txInclusion.stop(sb.SkipChainID())
+	}
+
+	p := config.RotationPolicy
+	if p == nil || sb.Index == 0 {
+		return
+	}
+
+	var reason string
+	if p.BlockCount > 0 && sb.Index%p.BlockCount == 0 {
+		reason = "round-robin"
+	}
+	if latency := time.Since(time.Unix(0, header.Timestamp)); p.MaxLatency > 0 &&
+		latency > p.MaxLatency {
+		reason = "latency of " + latency.String()
+	}
+	if p.InclusionBlocks > 0 && s.txInclusion.late(sb.SkipChainID(), sb.Index, p.InclusionBlocks) > 0 {
+		reason = "transaction not included"
+	}
+	if reason == "" {
+		return
+	}
+
+	latest, err := s.db().GetLatestByID(sb.SkipChainID())
+	if err != nil {
+		log.Errorf("failed to get the latest block: %v", err)
+		return
+	}
+	if !latest.Hash.Equal(sb.Hash) {
+		// We are catching up, the leader is judged on the latest block.
+		return
+	}
+	log.Lvlf2("%s: asking to rotate the leader of %x: %s", s.ServerIdentity(), sb.SkipChainID(), reason)
+	s.requestViewChange(sb.SkipChainID(), latest)
+}
+
+// requestViewChange tells the view-change controller that this node wants the
+// leader of the latest block to be replaced.
+func (s *Service) requestViewChange(gen skipchain.SkipBlockID, latest *skipchain.SkipBlock) {
+	req := viewchange.InitReq{
+		SignerID: s.ServerIdentity().ID,
+		View: viewchange.View{
+			ID:          latest.Hash,
+			Gen:         gen,
+			LeaderIndex: 1,
+		},
+	}
+	s.viewChangeMan.addReq(req)
+}
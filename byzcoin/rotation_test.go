@@ -0,0 +1,164 @@
+package byzcoin
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3/skipchain"
+)
+
+func TestTxInclusion(t *testing.T) {
+	ti := newTxInclusion()
+	scID := skipchain.SkipBlockID{1}
+
+	tx1 := ClientTransaction{Instructions: Instructions{{InstanceID: NewInstanceID([]byte{1})}}}
+	tx2 := ClientTransaction{Instructions: Instructions{{InstanceID: NewInstanceID([]byte{2})}}}
+	ti.add(scID, tx1.Instructions.Hash(), 1)
+	ti.add(scID, tx2.Instructions.Hash(), 2)
+	// Adding again must not change the index of submission.
+	ti.add(scID, tx1.Instructions.Hash(), 3)
+
+	require.Equal(t, 0, ti.late(scID, 3, 2))
+	ti.included(scID, TxResults{{ClientTransaction: tx2}})
+	require.Equal(t, 1, ti.late(scID, 4, 2))
+	// A late transaction only counts once.
+	require.Equal(t, 0, ti.late(scID, 5, 2))
+
+	ti.add(scID, tx2.Instructions.Hash(), 5)
+	ti.stop(scID)
+	require.Equal(t, 0, ti.late(scID, 10, 2))
+}
+
+func TestTxInclusion_Expired(t *testing.T) {
+	ti := newTxInclusion()
+	scID := skipchain.SkipBlockID{1}
+	require.Empty(t, ti.chains())
+
+	tx := ClientTransaction{Instructions: Instructions{{InstanceID: NewInstanceID([]byte{1})}}}
+	ti.add(scID, tx.Instructions.Hash(), 1)
+	require.Equal(t, []skipchain.SkipBlockID{scID}, ti.chains())
+	require.Equal(t, 0, ti.expired(scID, time.Hour))
+
+	// A new leader gets the full delay again.
+	ti.restart(scID, 5)
+	require.Equal(t, 0, ti.late(scID, 6, 2))
+	require.Equal(t, 1, ti.expired(scID, 0))
+	require.Empty(t, ti.chains())
+}
+
+func TestService_WatchTxWithoutPolicy(t *testing.T) {
+	s := newSer(t, 1, testInterval)
+	defer s.local.CloseAll()
+
+	// Without a rotation policy, the followers neither watch nor forward
+	// the transactions.
+	tx, err := createOneClientTx(s.darc.GetBaseID(), dummyContract, s.value, s.signer)
+	require.NoError(t, err)
+	s.sendTxTo(t, tx, 1)
+	require.Empty(t, s.services[1].txInclusion.chains())
+	s.waitProof(t, NewInstanceID(tx.Instructions[0].Hash()))
+}
+
+func TestService_RotationPolicyBlockCount(t *testing.T) {
+	s := newSerRotation(t, &RotationPolicy{BlockCount: 3})
+	defer s.local.CloseAll()
+
+	// The leader is replaced once the third block is created, even though
+	// it is alive and includes all the transactions.
+	for i := uint64(1); i <= 3; i++ {
+		tx, err := createOneClientTxWithCounter(s.darc.GetBaseID(), dummyContract, s.value, s.signer, i)
+		require.NoError(t, err)
+		s.sendTxToAndWait(t, tx, 0, 10)
+	}
+	s.waitLeader(t, 1)
+}
+
+func TestService_RotationPolicyInclusion(t *testing.T) {
+	s := newSerRotation(t, &RotationPolicy{InclusionBlocks: 1})
+	defer s.local.CloseAll()
+
+	// The censored transaction is only watched by the followers and is in
+	// no buffer, so the leader behaves as if it was ignoring it.
+	censored, err := createOneClientTx(s.darc.GetBaseID(), dummyContract, []byte("censored"), s.signer)
+	require.NoError(t, err)
+	for _, service := range s.services[1:] {
+		latest, err := service.db().GetLatestByID(s.genesis.SkipChainID())
+		require.NoError(t, err)
+		require.NoError(t, service.watchTx(s.genesis.SkipChainID(), latest, censored, false))
+		require.Equal(t, []skipchain.SkipBlockID{s.genesis.SkipChainID()}, service.txInclusion.chains())
+	}
+
+	// Two blocks without the transaction are enough for the followers to
+	// ask for a new leader.
+	for i := uint64(1); i <= 2; i++ {
+		tx, err := createOneClientTxWithCounter(s.darc.GetBaseID(), dummyContract, s.value, s.signer, i)
+		require.NoError(t, err)
+		s.sendTxToAndWait(t, tx, 0, 10)
+	}
+	s.waitLeader(t, 1)
+}
+
+func TestService_RotationPolicyForwardedTx(t *testing.T) {
+	s := newSerRotation(t, &RotationPolicy{InclusionBlocks: 1})
+	defer s.local.CloseAll()
+
+	// The transaction is only forwarded to the followers and never sent to
+	// the leader, which must still collect it and stay the leader.
+	tx, err := createOneClientTx(s.darc.GetBaseID(), dummyContract, s.value, s.signer)
+	require.NoError(t, err)
+	latest, err := s.services[1].db().GetLatestByID(s.genesis.SkipChainID())
+	require.NoError(t, err)
+	require.NoError(t, s.services[1].watchTx(s.genesis.SkipChainID(), latest, tx, true))
+	require.Equal(t, 0, s.services[0].txBuffer.len(string(s.genesis.SkipChainID())))
+	s.waitProof(t, NewInstanceID(tx.Instructions[0].Hash()))
+
+	for i := uint64(2); i <= 3; i++ {
+		tx, err := createOneClientTxWithCounter(s.darc.GetBaseID(), dummyContract, s.value, s.signer, i)
+		require.NoError(t, err)
+		s.sendTxToAndWait(t, tx, 0, 10)
+	}
+	for _, service := range s.services {
+		leader, err := service.getLeader(s.genesis.SkipChainID())
+		require.NoError(t, err)
+		require.True(t, leader.Equal(s.services[0].ServerIdentity()))
+	}
+}
+
+// newSerRotation creates a chain of four nodes that uses the given rotation
+// policy. The view-change timeout is long enough for the policy to be the
+// only reason of a new leader.
+func newSerRotation(t *testing.T, p *RotationPolicy) *ser {
+	s := newSerN(t, 0, testInterval, 4, 10)
+	genesisMsg, err := DefaultGenesisMsg(CurrentVersion, s.roster,
+		[]string{"spawn:" + dummyContract}, s.signer.Identity())
+	require.NoError(t, err)
+	genesisMsg.BlockInterval = testInterval
+	genesisMsg.RotationPolicy = p
+	s.darc = &genesisMsg.GenesisDarc
+	s.interval = genesisMsg.BlockInterval
+
+	resp, err := s.service().CreateGenesisBlock(genesisMsg)
+	require.NoError(t, err)
+	s.genesis = resp.Skipblock
+	for i := range s.services {
+		s.waitProofWithIdx(t, InstanceID{}.Slice(), i)
+	}
+	return s
+}
+
+// waitLeader waits until all the nodes agree that the node at the given
+// index is the leader.
+func (s *ser) waitLeader(t *testing.T, idx int) {
+	for _, service := range s.services {
+		for i := 0; ; i++ {
+			leader, err := service.getLeader(s.genesis.SkipChainID())
+			require.NoError(t, err)
+			if leader.Equal(s.services[idx].ServerIdentity()) {
+				break
+			}
+			require.True(t, i < 50, "leader has not been replaced")
+			time.Sleep(s.interval)
+		}
+	}
+}
@@ -59,12 +59,17 @@ const defaultRotationWindow time.Duration = 10
 
 const noTimeout time.Duration = 0
 
+// censorshipCheckInterval is how often the followers check whether the
+// transactions they watch have been included.
+const censorshipCheckInterval = time.Second
+
 const collectTxProtocol = "CollectTxProtocol"
 
 const viewChangeSubFtCosi = "viewchange_sub_ftcosi"
 const viewChangeFtCosi = "viewchange_ftcosi"
 
 var viewChangeMsgID network.MessageTypeID
+var forwardTxMsgID network.MessageTypeID
 
 // ByzCoinID can be used to refer to this service.
 var ByzCoinID onet.ServiceID
@@ -78,6 +83,7 @@ func init() {
 	log.ErrFatal(err)
 	network.RegisterMessages(&bcStorage{}, &DataHeader{}, &DataBody{})
 	viewChangeMsgID = network.RegisterMessage(&viewchange.InitReq{})
+	forwardTxMsgID = network.RegisterMessage(&ForwardTx{})
 }
 
 // GenNonce returns a random nonce.
@@ -116,6 +122,16 @@ type Service struct {
 	heartbeats             heartbeats
 	heartbeatsTimeout      chan string
 	closeLeaderMonitorChan chan bool
+	closeObserverChan      chan bool
+	closeForkMonitorChan   chan bool
+
+	// forkMonitor keeps the evidence of the equivocations found by
+	// comparing the blocks with the other nodes.
+	forkMonitor forkMonitor
+
+	// txInclusion tracks the transactions submitted to this node for the
+	// rotation policy.
+	txInclusion txInclusion
 
 	// contracts map kinds to kind specific verification functions
 	contracts map[string]ContractFn
@@ -143,6 +159,9 @@ type Service struct {
 
 	downloadState downloadState
 
+	// trieNodes caches the size of the state tries for the metrics.
+	trieNodes trieNodes
+
 	rotationWindow time.Duration
 }
 
@@ -238,6 +257,22 @@ func (s *Service) CreateGenesisBlock(req *CreateGenesisBlock) (
 		return nil, err
 	}
 
+	// The instances of the genesis block are checked by the config
+	// contract, but it cannot know the contracts of this service.
+	for _, gi := range req.Instances {
+		if _, ok := s.GetContractConstructor(gi.ContractID); !ok {
+			return nil, errors.New("the given contract \"" + gi.ContractID + "\" does not exist")
+		}
+	}
+	optionsBuf, err := protobuf.Encode(&genesisOptions{
+		Weights:        req.Weights,
+		RotationPolicy: req.RotationPolicy,
+		Instances:      req.Instances,
+	})
+	if err != nil {
+		return nil, err
+	}
+
 	// This is the nonce for the trie.
 	// TODO this nonce is picked by the root, how to make sure it's secure?
 	nonce := GenNonce()
@@ -251,6 +286,7 @@ func (s *Service) CreateGenesisBlock(req *CreateGenesisBlock) (
 			{Name: "roster", Value: rosterBuf},
 			{Name: "trie_nonce", Value: nonce[:]},
 			{Name: "darc_contracts", Value: darcContractIDsBuf},
+			{Name: "genesis_options", Value: optionsBuf},
 		},
 	}
 
@@ -309,10 +345,25 @@ func (s *Service) AddTransaction(req *AddTxRequest) (*AddTxResponse, error) {
 		return nil, errors.New("transaction too large")
 	}
 
+	st, err := s.GetReadOnlyStateTrie(req.SkipchainID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkPaused(st, req.Transaction); err != nil {
+		return nil, err
+	}
+
 	for i, instr := range req.Transaction.Instructions {
 		log.Lvlf2("Instruction[%d]: %s", i, instr.Action())
 	}
 
+	// If the rotation policy asks for it, watch the transaction and tell
+	// the other followers about it, so that the leader can be replaced if
+	// it doesn't include it.
+	if err := s.watchTx(req.SkipchainID, latest, req.Transaction, true); err != nil {
+		log.Lvl2(s.ServerIdentity(), err)
+	}
+
 	// Note to my future self: s.txBuffer.add used to be out here. It used to work
 	// even. But while investigating other race conditions, we realized that
 	// IF there will be a wait channel, THEN it must exist before the call to add().
@@ -475,7 +526,7 @@ func (s *Service) GetSignerCounters(req *GetSignerCounters) (*GetSignerCountersR
 	for i := range req.SignerIDs {
 		key := publicVersionKey(req.SignerIDs[i])
 		buf, _, _, _, err := st.GetValues(key)
-		if err == errKeyNotSet {
+		if err == ErrKeyNotSet {
 			out[i] = 0
 			continue
 		}
@@ -562,7 +613,7 @@ query:
 
 func entryToResponse(sce *StateChangeEntry, ok bool, err error) (*GetInstanceVersionResponse, error) {
 	if !ok {
-		err = errKeyNotSet
+		err = ErrKeyNotSet
 	}
 	if err != nil {
 		return nil, err
@@ -614,7 +665,7 @@ func (s *Service) GetAllInstanceVersion(req *GetAllInstanceVersion) (res *GetAll
 func (s *Service) CheckStateChangeValidity(req *CheckStateChangeValidity) (*CheckStateChangeValidityResponse, error) {
 	sce, ok, err := s.stateChangeStorage.getByVersion(req.InstanceID[:], req.Version, req.SkipChainID)
 	if !ok {
-		err = errKeyNotSet
+		err = ErrKeyNotSet
 	}
 	if err != nil {
 		return nil, err
@@ -644,6 +695,88 @@ func (s *Service) CheckStateChangeValidity(req *CheckStateChangeValidity) (*Chec
 	}, nil
 }
 
+// GetDeferred returns the deferred instances that can still be executed,
+// with the identities missing to sign their proposed transaction.
+func (s *Service) GetDeferred(req *GetDeferred) (*GetDeferredResponse, error) {
+	if req.Version != CurrentVersion {
+		return nil, errors.New("version mismatch")
+	}
+	st, err := s.getStateTrie(req.SkipChainID)
+	if err != nil {
+		return nil, err
+	}
+	config, err := LoadConfigFromTrie(st)
+	if err != nil {
+		return nil, err
+	}
+	index := st.GetIndex()
+	ts, err := getTrieTimestamp(st)
+	if err != nil {
+		return nil, err
+	}
+
+	// The instances are decoded once the iteration is done, as the
+	// darcs cannot be read from the trie during the iteration.
+	type deferredBody struct {
+		id   []byte
+		body StateChangeBody
+	}
+	var bodies []deferredBody
+	err = st.ForEach(func(k, v []byte) error {
+		body, err := decodeStateChangeBody(v)
+		if err != nil || body.ContractID != ContractDeferredID {
+			return nil
+		}
+		bodies = append(bodies, deferredBody{id: append([]byte{}, k...), body: body})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	instanceDarc := func(id InstanceID) (*darc.Darc, error) {
+		return getInstanceDarc(st, id, config.DarcContractIDs)
+	}
+	resp := &GetDeferredResponse{}
+	for _, b := range bodies {
+		var dd DeferredData
+		if err := protobuf.Decode(b.body.Value, &dd); err != nil {
+			continue
+		}
+		if dd.NumExecution == 0 || uint64(index) > dd.ExpireBlockIndex ||
+			(dd.ExpireTimestamp != 0 && ts > dd.ExpireTimestamp) {
+			continue
+		}
+		missing, err := dd.MissingProofs(instanceDarc, trieGetDarc(st))
+		if err != nil {
+			log.Lvlf2("%s: skipping deferred instance %x: %v", s.ServerIdentity(), b.id, err)
+			continue
+		}
+
+		matchDarc := len(req.DarcID) == 0 || req.DarcID.Equal(b.body.DarcID)
+		matchIdentity := req.Identity == ""
+		pending := PendingDeferred{
+			InstanceID: NewInstanceID(b.id),
+			DarcID:     b.body.DarcID,
+			Data:       b.body.Value,
+		}
+		for i, ids := range missing {
+			if !matchDarc {
+				d, err := instanceDarc(dd.ProposedTransaction.Instructions[i].InstanceID)
+				matchDarc = err == nil && req.DarcID.Equal(d.GetBaseID())
+			}
+			for _, id := range ids {
+				matchIdentity = matchIdentity || id == req.Identity
+			}
+			pending.Missing = append(pending.Missing, MissingSigners{Identities: ids})
+		}
+		if matchDarc && matchIdentity {
+			resp.Deferred = append(resp.Deferred, pending)
+		}
+	}
+	return resp, nil
+}
+
 type leafNode struct {
 	Prefix []bool
 	Key    []byte
@@ -778,6 +911,7 @@ func (s *Service) DebugRemove(req *DebugRemoveRequest) (*DebugResponse, error) {
 
 	log.Lvl2("Stopping view change monitor")
 	s.viewChangeMan.stop(skipchain.SkipBlockID(req.ByzCoinID))
+	s.txInclusion.stop(skipchain.SkipBlockID(req.ByzCoinID))
 
 	s.save()
 	return &DebugResponse{}, nil
@@ -852,8 +986,8 @@ func (s *Service) createNewBlock(scID skipchain.SkipBlockID, r *onet.Roster, tx
 
 	log.Lvl3("Creating state changes")
 	mr, txRes, scs, _ = s.createStateChanges(sst, scID, tx, noTimeout)
-	if len(txRes) == 0 {
-		return nil, errors.New("no transactions")
+	if err = checkEmptyBlock(sst, txRes); err != nil {
+		return nil, err
 	}
 
 	// Store transactions in the body
@@ -877,6 +1011,21 @@ func (s *Service) createNewBlock(scID skipchain.SkipBlockID, r *onet.Roster, tx
 	if r != nil {
 		sb.Roster = r
 	}
+
+	// Record the voting weights of the new roster in the block, so that the
+	// forward links can be verified without the state.
+	if err = sst.StoreAll(scs); err != nil {
+		return nil, err
+	}
+	config, err := LoadConfigFromTrie(sst)
+	if err != nil {
+		return nil, err
+	}
+	sb.Weights, err = config.weightsFor(sb.Roster)
+	if err != nil {
+		return nil, err
+	}
+
 	var ssb = skipchain.StoreSkipBlock{
 		NewBlock:          sb,
 		TargetSkipChainID: scID,
@@ -1013,6 +1162,11 @@ func (s *Service) downloadDB(sb *skipchain.SkipBlock) error {
 			if !bytes.Equal(st.GetRoot(), header.TrieRoot) {
 				return errors.New("got wrong database, merkle roots don't work out")
 			}
+			// The metadata of the block might be missing if the node we
+			// downloaded from didn't apply a block since it got it.
+			if err := st.setBlockInfo(sb.SkipChainID(), header.Timestamp); err != nil {
+				return errors.New("couldn't store block info: " + err.Error())
+			}
 
 			// Finally initialize the stateTrie using the new database.
 			s.stateTriesLock.Lock()
@@ -1121,6 +1275,10 @@ func (s *Service) catchUp(sb *skipchain.SkipBlock) {
 	}()
 
 	log.Lvlf2("%v Catching up %x / %d", s.ServerIdentity(), sb.SkipChainID(), sb.Index)
+	start := time.Now()
+	defer func() {
+		metrics.observe(metricCatchUp, s.metricLabels(sb.SkipChainID()), time.Since(start).Seconds())
+	}()
 
 	// Load the trie.
 	download := false
@@ -1291,7 +1449,7 @@ func (s *Service) updateTrieCallback(sbID skipchain.SkipBlockID) error {
 
 	log.Lvlf3("%s Storing index %d with %d state changes %v", s.ServerIdentity(), sb.Index, len(scs), scs.ShortStrings())
 	// Update our global state using all state changes.
-	if err = st.VerifiedStoreAll(scs, sb.Index, header.TrieRoot); err != nil {
+	if err = st.VerifiedStoreAll(scs, sb.Index, sb.SkipChainID(), header.Timestamp, header.TrieRoot); err != nil {
 		return err
 	}
 
@@ -1307,6 +1465,11 @@ func (s *Service) updateTrieCallback(sbID skipchain.SkipBlockID) error {
 	}
 	s.notifications.informBlock(sb.SkipChainID())
 
+	metrics.blockAdded(s.metricLabels(sb.SkipChainID()), header.Timestamp, body.TxResults)
+	if sb.Index > 0 && isViewChangeTx(body.TxResults) != nil {
+		metrics.add(metricViewChanges, s.metricLabels(sb.SkipChainID()), 1)
+	}
+
 	// If we are adding a genesis block, then look into it for the darc ID
 	// and add it to the darcToSc hash map.
 	if sb.Index == 0 {
@@ -1379,6 +1542,7 @@ func (s *Service) updateTrieCallback(sbID skipchain.SkipBlockID) error {
 
 		if s.viewChangeMan.started(sb.SkipChainID()) && view != nil {
 			s.viewChangeMan.done(*view)
+			s.txInclusion.restart(sb.SkipChainID(), sb.Index)
 		} else {
 			// clean previous states as a new block has been added in the mean time
 			// making them thus invalid
@@ -1388,6 +1552,9 @@ func (s *Service) updateTrieCallback(sbID skipchain.SkipBlockID) error {
 			log.Lvlf2("%s started viewchangeMonitor for %x", s.ServerIdentity(), sb.SkipChainID())
 			s.viewChangeMan.add(s.sendViewChangeReq, s.sendNewView, s.isLeader, string(sb.SkipChainID()))
 			s.viewChangeMan.start(s.ServerIdentity().ID, sb.SkipChainID(), initialDur, s.getFaultThreshold(sb.Hash))
+
+			// Replace a leader that is alive but slow or censoring.
+			s.checkRotationPolicy(bcConfig, sb, header, body)
 		}
 	} else {
 		if s.heartbeats.exists(scIDstr) {
@@ -1462,12 +1629,38 @@ func (s *Service) getStateTrie(id skipchain.SkipBlockID) (*stateTrie, error) {
 		if err != nil {
 			return nil, err
 		}
+		if err := s.loadBlockInfo(id, st); err != nil {
+			log.Error(s.ServerIdentity(), "couldn't load block info:", err)
+		}
 		s.stateTries[idStr] = st
 		return s.stateTries[idStr], nil
 	}
 	return col, nil
 }
 
+// loadBlockInfo stores the chain ID and the timestamp of the latest block in
+// a trie written before they were kept as metadata.
+func (s *Service) loadBlockInfo(id skipchain.SkipBlockID, st *stateTrie) error {
+	if _, err := getTrieTimestamp(st); err == nil {
+		return nil
+	}
+	if st.GetIndex() < 0 {
+		return nil
+	}
+	reply, err := s.skService().GetSingleBlockByIndex(&skipchain.GetSingleBlockByIndex{
+		Genesis: id,
+		Index:   st.GetIndex(),
+	})
+	if err != nil {
+		return err
+	}
+	var header DataHeader
+	if err := protobuf.Decode(reply.SkipBlock.Data, &header); err != nil {
+		return err
+	}
+	return st.setBlockInfo(id, header.Timestamp)
+}
+
 func (s *Service) createStateTrie(id skipchain.SkipBlockID, nonce []byte) (*stateTrie, error) {
 	if len(id) == 0 {
 		return nil, errors.New("no skipchain ID")
@@ -1545,7 +1738,7 @@ func (s *Service) LoadBlockInfo(scID skipchain.SkipBlockID) (time.Duration, int,
 	}
 	config, err := LoadConfigFromTrie(st)
 	if err != nil {
-		if err == errKeyNotSet {
+		if err == ErrKeyNotSet {
 			err = nil
 		}
 		return defaultInterval, defaultMaxBlockSize, err
@@ -1667,6 +1860,10 @@ func (s *Service) verifySkipBlock(newID []byte, newSB *skipchain.SkipBlock) bool
 		log.Lvl2(s.ServerIdentity(), "transaction list length mismatch after execution")
 		return false
 	}
+	if err := checkEmptyBlock(sst, txOut); err != nil {
+		log.Lvl2(s.ServerIdentity(), err)
+		return false
+	}
 
 	for i := range txOut {
 		if txOut[i].Accepted != body.TxResults[i].Accepted {
@@ -1708,6 +1905,21 @@ func (s *Service) verifySkipBlock(newID []byte, newSB *skipchain.SkipBlock) bool
 			return false
 		}
 	}
+	weights, err := config.weightsFor(newSB.Roster)
+	if err != nil {
+		log.Error(s.ServerIdentity(), err)
+		return false
+	}
+	if len(weights) != len(newSB.Weights) {
+		log.Error(s.ServerIdentity(), "weights in the block don't match the config")
+		return false
+	}
+	for i := range weights {
+		if weights[i] != newSB.Weights[i] {
+			log.Error(s.ServerIdentity(), "weights in the block don't match the config")
+			return false
+		}
+	}
 
 	window := 4 * config.BlockInterval
 	if window < minTimestampWindow {
@@ -1836,66 +2048,13 @@ func (s *Service) createStateChanges(sst *stagingStateTrie, scID skipchain.SkipB
 // processOneTx takes one transaction and creates a set of StateChanges. It also returns the temporary StateTrie
 // with the StateChanges applied.
 func (s *Service) processOneTx(sst *stagingStateTrie, tx ClientTransaction) (StateChanges, *stagingStateTrie, error) {
-	// Make a new trie for each instruction. If the instruction is
+	// Make a new trie for each transaction. If the transaction is
 	// sucessfully implemented and changes applied, then keep it
 	// otherwise dump it.
 	sst = sst.Clone()
-	h := tx.Instructions.Hash()
-	var statesTemp StateChanges
-	var cin []Coin
-	for _, instr := range tx.Instructions {
-		scs, cout, err := s.executeInstruction(sst, cin, instr, h)
-		if err != nil {
-			_, _, cid, _, err2 := sst.GetValues(instr.InstanceID.Slice())
-			if err2 != nil {
-				err = fmt.Errorf("%s - while getting value: %s", err, err2)
-			}
-			return nil, nil, fmt.Errorf("%s Contract %s got Instruction %s and returned error: %s", s.ServerIdentity(), cid, instr, err)
-		}
-		var counterScs StateChanges
-		if counterScs, err = incrementSignerCounters(sst, instr.SignerIdentities); err != nil {
-			return nil, nil, fmt.Errorf("%s failed to update signature counters: %s", s.ServerIdentity(), err)
-		}
-
-		// Verify the validity of the state-changes:
-		//  - refuse to update non-existing instances
-		//  - refuse to create existing instances
-		//  - refuse to delete non-existing instances
-		for _, sc := range scs {
-			var reason string
-			switch sc.StateAction {
-			case Create:
-				if v, err := sst.Get(sc.InstanceID); err != nil || v != nil {
-					reason = "tried to create existing instanceID"
-				}
-			case Update:
-				if v, err := sst.Get(sc.InstanceID); err != nil || v == nil {
-					reason = "tried to update non-existing instanceID"
-				}
-			case Remove:
-				if v, err := sst.Get(sc.InstanceID); err != nil || v == nil {
-					reason = "tried to remove non-existing instanceID"
-				}
-			}
-			if reason != "" {
-				_, _, contractID, _, err := sst.GetValues(instr.InstanceID.Slice())
-				if err != nil {
-					return nil, nil, fmt.Errorf("%s couldn't get contractID from instruction %+v", s.ServerIdentity(), instr)
-				}
-				return nil, nil, fmt.Errorf("%s: contract %s %s", s.ServerIdentity(), contractID, reason)
-			}
-			log.Lvlf2("StateChange %s for id %x - contract: %s", sc.StateAction, sc.InstanceID, sc.ContractID)
-			err = sst.StoreAll(StateChanges{sc})
-			if err != nil {
-				return nil, nil, fmt.Errorf("%s StoreAll failed: %s", s.ServerIdentity(), err)
-			}
-		}
-		if err = sst.StoreAll(counterScs); err != nil {
-			return nil, nil, fmt.Errorf("%s StoreAll failed to add counter changes: %s", s.ServerIdentity(), err)
-		}
-		statesTemp = append(statesTemp, scs...)
-		statesTemp = append(statesTemp, counterScs...)
-		cin = cout
+	statesTemp, cin, err := applyTransaction(sst, tx, s.GetContractConstructor, s.observeContract)
+	if err != nil {
+		return nil, nil, err
 	}
 	if len(cin) != 0 {
 		log.Warn(s.ServerIdentity(), "Leftover coins detected, discarding.")
@@ -1910,84 +2069,10 @@ func (s *Service) GetContractConstructor(contractName string) (ContractFn, bool)
 	return fn, exists
 }
 
-func (s *Service) executeInstruction(st ReadOnlyStateTrie, cin []Coin, instr Instruction, ctxHash []byte) (scs StateChanges, cout []Coin, err error) {
-	defer func() {
-		if re := recover(); re != nil {
-			err = fmt.Errorf("%s", re)
-		}
-	}()
-
-	contents, _, contractID, _, err := st.GetValues(instr.InstanceID.Slice())
-	if err != errKeyNotSet && err != nil {
-		err = errors.New("Couldn't get contract type of instruction: " + err.Error())
-		return
-	}
-
-	contractFactory, exists := s.contracts[contractID]
-	if !exists && ConfigInstanceID.Equal(instr.InstanceID) {
-		// Special case: first time call to genesis-configuration must return
-		// correct contract type.
-		contractFactory, exists = s.contracts[ContractConfigID]
-	}
-
-	// If the leader does not have a verifier for this contract, it drops the
-	// transaction.
-	if !exists {
-		err = fmt.Errorf("leader is dropping instruction of unknown contract \"%s\" on instance \"%x\"", contractID, instr.InstanceID.Slice())
-		return
-	}
-	// Now we call the contract function with the data of the key.
-	log.Lvlf3("%s Calling contract '%s'", s.ServerIdentity(), contractID)
-
-	c, err := contractFactory(contents)
-	if err != nil {
-		return nil, nil, err
-	}
-	if c == nil {
-		return nil, nil, errors.New("contract factory returned nil contract instance")
-	}
-
-	err = c.VerifyInstruction(st, instr, ctxHash)
-	if err != nil {
-		return nil, nil, fmt.Errorf("instruction verification failed: %v", err)
-	}
-
-	switch instr.GetType() {
-	case SpawnType:
-		scs, cout, err = c.Spawn(st, instr, cin)
-	case InvokeType:
-		scs, cout, err = c.Invoke(st, instr, cin)
-	case DeleteType:
-		scs, cout, err = c.Delete(st, instr, cin)
-	default:
-		return nil, nil, errors.New("unexpected contract type")
-	}
-
-	// As the InstanceID of each sc is not necessarily the same as the
-	// instruction, we need to get the version from the trie
-	vv := make(map[string]uint64)
-	for i, sc := range scs {
-		ver, ok := vv[hex.EncodeToString(sc.InstanceID)]
-		if !ok {
-			_, ver, _, _, err = st.GetValues(sc.InstanceID)
-		}
-
-		// this is done at this scope because we must increase
-		// the version only when it's not the first one
-		if err == errKeyNotSet {
-			ver = 0
-			err = nil
-		} else if err != nil {
-			return
-		} else {
-			ver++
-		}
-
-		scs[i].Version = ver
-		vv[hex.EncodeToString(sc.InstanceID)] = ver
-	}
-
-	return
+// observeContract records the time taken by a contract.
+func (s *Service) observeContract(contractID string, d time.Duration) {
+	metrics.observe(metricContractExecution, newMetricLabels("conode", s.ServerIdentity().Address.String(),
+		"contract", contractID), d.Seconds())
 }
 
 func (s *Service) getLeader(scID skipchain.SkipBlockID) (*network.ServerIdentity, error) {
@@ -2086,6 +2171,8 @@ func (s *Service) cleanupGoroutines() {
 	log.Lvl1(s.ServerIdentity(), "closing go-routines")
 	s.heartbeats.closeAll()
 	s.closeLeaderMonitorChan <- true
+	s.closeObserverChan <- true
+	s.closeForkMonitorChan <- true
 	s.viewChangeMan.closeAll()
 
 	s.pollChanMut.Lock()
@@ -2108,8 +2195,12 @@ func (s *Service) monitorLeaderFailure() {
 	s.closedMutex.Unlock()
 
 	go func() {
+		censorship := time.NewTicker(censorshipCheckInterval)
+		defer censorship.Stop()
 		for {
 			select {
+			case <-censorship.C:
+				s.checkCensorship()
 			case key := <-s.heartbeatsTimeout:
 				log.Lvlf3("%s: missed heartbeat for %x", s.ServerIdentity(), key)
 				gen := []byte(key)
@@ -2131,15 +2222,7 @@ func (s *Service) monitorLeaderFailure() {
 				} else {
 					// Send only if the latest block is consistent as it wouldn't
 					// anyway if we're out of sync with the chain
-					req := viewchange.InitReq{
-						SignerID: s.ServerIdentity().ID,
-						View: viewchange.View{
-							ID:          latest.Hash,
-							Gen:         gen,
-							LeaderIndex: 1,
-						},
-					}
-					s.viewChangeMan.addReq(req)
+					s.requestViewChange(gen, latest)
 				}
 			case <-s.closeLeaderMonitorChan:
 				log.Lvl2(s.ServerIdentity(), "closing heartbeat timeout monitor")
@@ -2236,6 +2319,13 @@ func (s *Service) startAllChains() error {
 		s.darcToSc[string(d.GetBaseID())] = gen
 		s.darcToScMut.Unlock()
 
+		// An observer only keeps a copy of the chain, see
+		// monitorObservedChains.
+		if i, _ := latest.Roster.Search(s.ServerIdentity().ID); i < 0 {
+			log.Lvlf2("%s: observing chain %x", s.ServerIdentity(), gen)
+			continue
+		}
+
 		// start the heartbeat
 		if s.heartbeats.exists(string(gen)) {
 			return errors.New("we are just starting the service, there should be no existing heartbeat monitors")
@@ -2257,6 +2347,8 @@ func (s *Service) startAllChains() error {
 	// services from starting.
 	go func() {
 		s.monitorLeaderFailure()
+		s.monitorObservedChains()
+		s.monitorForks()
 		err := s.catchupAll()
 		if err != nil {
 			log.Error(s.ServerIdentity(), "couldn't sync:", err)
@@ -2274,12 +2366,7 @@ func (s *Service) hasByzCoinVerification(gen skipchain.SkipBlockID) bool {
 		// if it does, just say "not ours".
 		return false
 	}
-	for _, x := range sb.VerifierIDs {
-		if x.Equal(Verify) {
-			return true
-		}
-	}
-	return false
+	return isByzCoinBlock(sb)
 }
 
 // saves this service's config information
@@ -2326,8 +2413,13 @@ func newService(c *onet.Context) (onet.Service, error) {
 		stateChangeStorage:     newStateChangeStorage(c),
 		heartbeatsTimeout:      make(chan string, 1),
 		closeLeaderMonitorChan: make(chan bool, 1),
+		closeObserverChan:      make(chan bool, 1),
+		closeForkMonitorChan:   make(chan bool, 1),
 		heartbeats:             newHeartbeats(),
 		viewChangeMan:          newViewChangeManager(),
+		txInclusion:            newTxInclusion(),
+		trieNodes:              newTrieNodes(),
+		forkMonitor:            newForkMonitor(),
 		streamingMan:           streamingManager{},
 		closed:                 true,
 		catchingUpHistory:      make(map[string]time.Time),
@@ -2345,6 +2437,8 @@ func newService(c *onet.Context) (onet.Service, error) {
 		s.GetLastInstanceVersion,
 		s.GetAllInstanceVersion,
 		s.CheckStateChangeValidity,
+		s.GetEquivocations,
+		s.GetDeferred,
 		s.Debug,
 		s.DebugRemove)
 	if err != nil {
@@ -2355,6 +2449,8 @@ func newService(c *onet.Context) (onet.Service, error) {
 		return nil, err
 	}
 	s.RegisterProcessorFunc(viewChangeMsgID, s.handleViewChangeReq)
+	s.RegisterProcessorFunc(forwardTxMsgID, s.handleForwardTx)
+	metrics.registerCollector(s.ServerIdentity().String(), s.collectMetrics)
 
 	err = s.registerContract(ContractConfigID, contractConfigFromBytes)
 	if err != nil {
@@ -2368,6 +2464,14 @@ func newService(c *onet.Context) (onet.Service, error) {
 	if err != nil {
 		return nil, err
 	}
+	err = s.registerContract(ContractForeignChainsID, contractForeignChainsFromBytes)
+	if err != nil {
+		return nil, err
+	}
+	err = s.registerContract(ContractNamingID, contractNamingFromBytes)
+	if err != nil {
+		return nil, err
+	}
 
 	skipchain.RegisterVerification(c, Verify, s.verifySkipBlock)
 	if _, err := s.ProtocolRegister(collectTxProtocol, NewCollectTxProtocol(s.getTxs)); err != nil {
@@ -5,6 +5,7 @@ import (
 	"crypto/sha256"
 	"encoding/binary"
 	"errors"
+	"fmt"
 	"strings"
 	"testing"
 	"time"
@@ -533,7 +534,7 @@ func TestService_Depending(t *testing.T) {
 	require.NoError(t, err)
 	_, _, _, _, err = cdb.GetValues(in1.Hash())
 	require.Error(t, err)
-	require.Equal(t, errKeyNotSet, err)
+	require.Equal(t, ErrKeyNotSet, err)
 
 	// We need to wait a bit for the propagation to finish because the
 	// skipchain service might decide to update forward links by adding
@@ -1838,6 +1839,22 @@ func TestService_DownloadState(t *testing.T) {
 	require.True(t, length > 40)
 
 	time.Sleep(time.Second)
+	// The trie of a node that didn't apply a block since it was upgraded
+	// doesn't know its chain and the time of its latest block.
+	st, err := s.service().getStateTrie(s.genesis.SkipChainID())
+	require.NoError(t, err)
+	sb, err := s.service().skService().GetSingleBlockByIndex(&skipchain.GetSingleBlockByIndex{
+		Genesis: s.genesis.SkipChainID(),
+		Index:   st.GetIndex(),
+	})
+	require.NoError(t, err)
+	var header DataHeader
+	require.NoError(t, protobuf.Decode(sb.SkipBlock.Data, &header))
+	require.NoError(t, st.DeleteMetadata([]byte(trieChainIDKey)))
+	require.NoError(t, st.DeleteMetadata([]byte(trieTimestampKey)))
+	_, err = getTrieTimestamp(st)
+	require.Error(t, err)
+
 	// Try to re-create the trie on a new service -
 	// do it twice
 	for i := 0; i < 2; i++ {
@@ -1853,7 +1870,25 @@ func TestService_DownloadState(t *testing.T) {
 		require.True(t, len(val) > 0)
 		configCopy := ChainConfig{}
 		err = protobuf.DecodeWithConstructors(val, &configCopy, network.DefaultConstructors(cothority.Suite))
+		// The downloaded trie gets the metadata from the header of its
+		// latest block.
+		ts, err := getTrieTimestamp(st)
+		require.NoError(t, err)
+		require.Equal(t, header.Timestamp, ts)
+		scID, err := getTrieChainID(st)
+		require.NoError(t, err)
+		require.True(t, scID.Equal(s.genesis.SkipChainID()))
 	}
+
+	// The same is done when the trie is loaded again.
+	s.service().stateTriesLock.Lock()
+	delete(s.service().stateTries, fmt.Sprintf("%x", s.genesis.SkipChainID()))
+	s.service().stateTriesLock.Unlock()
+	st, err = s.service().getStateTrie(s.genesis.SkipChainID())
+	require.NoError(t, err)
+	ts, err := getTrieTimestamp(st)
+	require.NoError(t, err)
+	require.Equal(t, header.Timestamp, ts)
 }
 
 func TestService_SetBadConfig(t *testing.T) {
@@ -1891,6 +1926,42 @@ func TestService_SetBadConfig(t *testing.T) {
 	}
 }
 
+func TestService_UpdateConfigWeights(t *testing.T) {
+	s := newSer(t, 1, testInterval)
+	defer s.local.CloseAll()
+
+	updateConfig := func(weights []uint64, args Arguments, counter uint64) []uint64 {
+		config, err := s.service().LoadConfig(s.genesis.SkipChainID())
+		require.NoError(t, err)
+		config.Weights = weights
+		configBuf, err := protobuf.Encode(config)
+		require.NoError(t, err)
+		instr := Instruction{
+			InstanceID: ConfigInstanceID,
+			Invoke: &Invoke{
+				ContractID: ContractConfigID,
+				Command:    "update_config",
+				Args:       append(Arguments{{Name: "config", Value: configBuf}}, args...),
+			},
+			SignerIdentities: []darc.Identity{s.signer.Identity()},
+			SignerCounter:    []uint64{counter},
+		}
+		ctx, err := combineInstrsAndSign(s.signer, instr)
+		require.NoError(t, err)
+		s.sendTxAndWait(t, ctx, 10)
+		config, err = s.service().LoadConfig(s.genesis.SkipChainID())
+		require.NoError(t, err)
+		return config.Weights
+	}
+
+	require.Equal(t, []uint64{1, 1, 1, 2}, updateConfig([]uint64{1, 1, 1, 2}, nil, 1))
+	// Without weights, the old ones are kept.
+	require.Equal(t, []uint64{1, 1, 1, 2}, updateConfig(nil, nil, 2))
+	// Unless they are cleared.
+	clearWeights := Arguments{{Name: "clear_weights", Value: []byte{1}}}
+	require.Nil(t, updateConfig(nil, clearWeights, 3))
+}
+
 func TestService_DarcToSc(t *testing.T) {
 	s := newSer(t, 1, testInterval)
 	defer s.local.CloseAll()
@@ -2002,7 +2073,7 @@ func TestService_StateChangeStorage(t *testing.T) {
 	contract := func(cdb ReadOnlyStateTrie, inst Instruction, c []Coin) ([]StateChange, []Coin, error) {
 		// Check the version is correctly increased for multiple state changes
 		var scs []StateChange
-		if _, _, _, _, err := cdb.GetValues(iid.Slice()); err == errKeyNotSet {
+		if _, _, _, _, err := cdb.GetValues(iid.Slice()); err == ErrKeyNotSet {
 			scs = []StateChange{{
 				StateAction: Create,
 				InstanceID:  iid[:],
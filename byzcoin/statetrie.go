@@ -7,10 +7,14 @@ import (
 
 	"go.dedis.ch/cothority/v3/byzcoin/trie"
 	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/cothority/v3/skipchain"
 	bbolt "go.etcd.io/bbolt"
 )
 
-var errKeyNotSet = errors.New("key not set")
+// ErrKeyNotSet is returned by GetValues if the key is not in the trie. Other
+// implementations of ReadOnlyStateTrie must return it too, as the signer
+// counters of a new identity are only found with it.
+var ErrKeyNotSet = errors.New("key not set")
 
 // ReadOnlyStateTrie is the read-only interface for StagingStateTrie and
 // StateTrie.
@@ -57,7 +61,7 @@ func (t *stagingStateTrie) GetValues(key []byte) (value []byte, version uint64,
 		return
 	}
 	if buf == nil {
-		err = errKeyNotSet
+		err = ErrKeyNotSet
 		return
 	}
 
@@ -86,6 +90,23 @@ func (t *stagingStateTrie) GetIndex() int {
 }
 
 const trieIndexKey = "trieIndexKey"
+const trieChainIDKey = "trieChainIDKey"
+const trieTimestampKey = "trieTimestampKey"
+
+// getTrieChainID returns the ID of the chain the trie belongs to. It is
+// stored as metadata when a block is applied to the trie, so it is missing
+// while the genesis block is created.
+func getTrieChainID(rst ReadOnlyStateTrie) (skipchain.SkipBlockID, error) {
+	mt, ok := rst.(interface{ GetMetadata([]byte) []byte })
+	if !ok {
+		return nil, errors.New("the trie doesn't have metadata")
+	}
+	id := mt.GetMetadata([]byte(trieChainIDKey))
+	if len(id) == 0 {
+		return nil, errors.New("the trie doesn't know its chain")
+	}
+	return skipchain.SkipBlockID(id), nil
+}
 
 // stateTrie is a wrapper around trie.Trie that support the storage of an
 // index.
@@ -133,10 +154,27 @@ func (t *stateTrie) StoreAll(scs StateChanges, index int) error {
 	})
 }
 
-// VerifiedStoreAll stores the state changes, the index as metadata. It checks
-// whether the expectedRoot hash matches the computed root hash and returns an
-// error if it doesn't.
-func (t *stateTrie) VerifiedStoreAll(scs StateChanges, index int, expectedRoot []byte) error {
+// getTrieTimestamp returns the timestamp of the latest block applied to the
+// trie. All the nodes executing the transactions of a block get the same
+// timestamp, which is not the case of the timestamp of the new block. A trie
+// that is downloaded or loaded without it gets it from the header of its
+// latest block.
+func getTrieTimestamp(rst ReadOnlyStateTrie) (int64, error) {
+	mt, ok := rst.(interface{ GetMetadata([]byte) []byte })
+	if !ok {
+		return 0, errors.New("the trie doesn't have metadata")
+	}
+	buf := mt.GetMetadata([]byte(trieTimestampKey))
+	if len(buf) != 8 {
+		return 0, errors.New("the trie doesn't know the time of its latest block")
+	}
+	return int64(binary.LittleEndian.Uint64(buf)), nil
+}
+
+// VerifiedStoreAll stores the state changes, the index, the chain ID and the
+// timestamp of the block as metadata. It checks whether the expectedRoot hash matches the computed root
+// hash and returns an error if it doesn't.
+func (t *stateTrie) VerifiedStoreAll(scs StateChanges, index int, scID skipchain.SkipBlockID, timestamp int64, expectedRoot []byte) error {
 	pairs := make([]trie.KVPair, len(scs))
 	for i := range pairs {
 		pairs[i] = &scs[i]
@@ -150,6 +188,9 @@ func (t *stateTrie) VerifiedStoreAll(scs StateChanges, index int, expectedRoot [
 		if err := t.SetMetadataWithBucket([]byte(trieIndexKey), indexBuf, b); err != nil {
 			return err
 		}
+		if err := t.setBlockInfoWithBucket(scID, timestamp, b); err != nil {
+			return err
+		}
 		if !bytes.Equal(t.GetRootWithBucket(b), expectedRoot) {
 			return errors.New("root verfication failed")
 		}
@@ -157,6 +198,23 @@ func (t *stateTrie) VerifiedStoreAll(scs StateChanges, index int, expectedRoot [
 	})
 }
 
+// setBlockInfo stores the chain ID and the timestamp of the latest block as
+// metadata, for a trie that didn't get them from VerifiedStoreAll.
+func (t *stateTrie) setBlockInfo(scID skipchain.SkipBlockID, timestamp int64) error {
+	return t.DB().Update(func(b trie.Bucket) error {
+		return t.setBlockInfoWithBucket(scID, timestamp, b)
+	})
+}
+
+func (t *stateTrie) setBlockInfoWithBucket(scID skipchain.SkipBlockID, timestamp int64, b trie.Bucket) error {
+	if err := t.SetMetadataWithBucket([]byte(trieChainIDKey), scID, b); err != nil {
+		return err
+	}
+	tsBuf := make([]byte, 8)
+	binary.LittleEndian.PutUint64(tsBuf, uint64(timestamp))
+	return t.SetMetadataWithBucket([]byte(trieTimestampKey), tsBuf, b)
+}
+
 // GetValues returns the associated value, contractID and darcID. An error is
 // returned if the key does not exist.
 func (t *stateTrie) GetValues(key []byte) (value []byte, version uint64, contractID string, darcID darc.ID, err error) {
@@ -166,7 +224,7 @@ func (t *stateTrie) GetValues(key []byte) (value []byte, version uint64, contrac
 		return
 	}
 	if buf == nil {
-		err = errKeyNotSet
+		err = ErrKeyNotSet
 		return
 	}
 
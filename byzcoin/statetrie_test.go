@@ -6,6 +6,7 @@ import (
 
 	"github.com/stretchr/testify/require"
 	"go.dedis.ch/cothority/v3/darc"
+	"go.dedis.ch/cothority/v3/skipchain"
 )
 
 // TestStateTrie is a sanity check for setting and retrieving keys, values and
@@ -33,9 +34,9 @@ func TestStateTrie(t *testing.T) {
 		DarcID:      darcID,
 	}
 	// store with bad expected root hash should fail, value should not be inside
-	require.Error(t, st.VerifiedStoreAll([]StateChange{sc}, 5, []byte("badhash")))
+	require.Error(t, st.VerifiedStoreAll([]StateChange{sc}, 5, s.genesis.SkipChainID(), 0, []byte("badhash")))
 	_, _, _, _, err = st.GetValues(key)
-	require.Equal(t, errKeyNotSet, err)
+	require.Equal(t, ErrKeyNotSet, err)
 
 	// store the state changes normally using StoreAll and it should work
 	require.NoError(t, st.StoreAll([]StateChange{sc}, 5))
@@ -45,7 +46,7 @@ func TestStateTrie(t *testing.T) {
 	require.Equal(t, st.GetIndex(), 6)
 
 	_, _, _, _, err = st.GetValues(append(key, byte(0)))
-	require.Equal(t, errKeyNotSet, err)
+	require.Equal(t, ErrKeyNotSet, err)
 
 	val, ver, cid, did, err := st.GetValues(key)
 	require.Equal(t, value, val)
@@ -53,6 +54,23 @@ func TestStateTrie(t *testing.T) {
 	require.Equal(t, cid, string(contractID))
 	require.True(t, did.Equal(darcID))
 
+	// the chain ID and the timestamp are stored with the verified state
+	// changes
+	ts, err := getTrieTimestamp(st.MakeStagingStateTrie())
+	require.NoError(t, err)
+	require.NotEqual(t, int64(0), ts)
+	scID, err := getTrieChainID(st.MakeStagingStateTrie())
+	require.NoError(t, err)
+	require.True(t, scID.Equal(s.genesis.SkipChainID()))
+	otherID := skipchain.SkipBlockID("other chain")
+	require.NoError(t, st.VerifiedStoreAll([]StateChange{sc}, 7, otherID, ts+1, st.GetRoot()))
+	scID, err = getTrieChainID(st.MakeStagingStateTrie())
+	require.NoError(t, err)
+	require.True(t, scID.Equal(otherID))
+	newTs, err := getTrieTimestamp(st.MakeStagingStateTrie())
+	require.NoError(t, err)
+	require.Equal(t, ts+1, newTs)
+
 	// test the staging state trie, most of the tests are done in the trie package
 	key2 := []byte("key2")
 	val2 := []byte("val2")
@@ -10,8 +10,10 @@ import (
 	"sync"
 	"time"
 
+	"go.dedis.ch/cothority/v3/blscosi/protocol"
 	"go.dedis.ch/cothority/v3/skipchain"
 	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/network"
 	"go.dedis.ch/protobuf"
 	bbolt "go.etcd.io/bbolt"
 )
@@ -637,6 +639,19 @@ func (c ChainConfig) sanityCheck(old *ChainConfig) error {
 	if len(c.Roster.List) < 3 {
 		return errors.New("need at least 3 nodes to have a majority")
 	}
+	if len(c.Weights) > 0 {
+		if len(c.Weights) != len(c.Roster.List) {
+			return errors.New("need one weight per node of the roster")
+		}
+		if protocol.WeightedThreshold(c.Weights) == 0 {
+			return errors.New("the total weight must be positive")
+		}
+	}
+	if p := c.RotationPolicy; p != nil {
+		if p.BlockCount < 0 || p.MaxLatency < 0 || p.InclusionBlocks < 0 {
+			return errors.New("rotation policy cannot have negative values")
+		}
+	}
 	if old != nil {
 		return old.checkNewRoster(c.Roster)
 	}
@@ -669,6 +684,52 @@ func (c ChainConfig) checkNewRoster(newRoster onet.Roster) error {
 	return nil
 }
 
+// weightsFor returns the weights of the configuration in the order of the
+// given roster, or nil if all the nodes have the same weight. A node that is
+// not in the configuration has no weight and is refused.
+func (c ChainConfig) weightsFor(roster *onet.Roster) ([]uint64, error) {
+	return c.WeightsFor(roster, 0)
+}
+
+// WeightsFor returns the weights to use with the given roster: the nodes of
+// the configuration keep their weight wherever they are in the roster, and
+// the other nodes get the given weight. It returns nil if all the nodes have
+// the same weight. The nodes are matched by their public key.
+func (c ChainConfig) WeightsFor(roster *onet.Roster, weight uint64) ([]uint64, error) {
+	if len(c.Weights) == 0 && weight <= 1 {
+		return nil, nil
+	}
+	if len(c.Weights) > 0 && len(c.Weights) != len(c.Roster.List) {
+		return nil, errors.New("need one weight per node of the roster")
+	}
+	weights := make([]uint64, len(roster.List))
+	for i, si := range roster.List {
+		idx := c.indexOf(si)
+		switch {
+		case idx >= 0 && len(c.Weights) > 0:
+			weights[i] = c.Weights[idx]
+		case idx >= 0:
+			weights[i] = 1
+		case weight > 0:
+			weights[i] = weight
+		default:
+			return nil, errors.New("node " + si.Address.String() + " has no weight")
+		}
+	}
+	return weights, nil
+}
+
+// indexOf returns the index of the node in the roster of the configuration,
+// or -1 if it is not in it.
+func (c ChainConfig) indexOf(si *network.ServerIdentity) int {
+	for i, node := range c.Roster.List {
+		if node.Public.Equal(si.Public) {
+			return i
+		}
+	}
+	return -1
+}
+
 // String implements a nicer text representation of a Chainconfig.
 //
 // Here is an example of what it outputs:
@@ -693,5 +754,15 @@ func (c ChainConfig) String() string {
 	for i, darcID := range c.DarcContractIDs {
 		fmt.Fprintf(&res, "-- darc contract ID %d: %s\n", i, darcID)
 	}
+	if len(c.Weights) > 0 {
+		fmt.Fprintf(&res, "- Weights: %v\n", c.Weights)
+	}
+	if p := c.RotationPolicy; p != nil {
+		fmt.Fprintf(&res, "- RotationPolicy: every %d blocks, latency %s, inclusion %d blocks\n",
+			p.BlockCount, p.MaxLatency, p.InclusionBlocks)
+	}
+	if c.Paused {
+		res.WriteString("- Paused\n")
+	}
 	return res.String()
 }
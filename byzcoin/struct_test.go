@@ -1,14 +1,19 @@
 package byzcoin
 
 import (
+	"fmt"
 	"io/ioutil"
 	"math/rand"
 	"os"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3"
 	"go.dedis.ch/cothority/v3/skipchain"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/network"
 	"go.dedis.ch/protobuf"
 	bbolt "go.etcd.io/bbolt"
 )
@@ -343,3 +348,48 @@ func generateDB(t *testing.T) (*stateChangeStorage, string) {
 
 	return &scs, tmpDB.Name()
 }
+
+func TestChainConfig_Weights(t *testing.T) {
+	var list []*network.ServerIdentity
+	for i := 0; i < 4; i++ {
+		addr := network.NewAddress(network.TLS, fmt.Sprintf("127.0.0.1:%d", 7000+i))
+		list = append(list, network.NewServerIdentity(cothority.Suite.Point().Pick(cothority.Suite.RandomStream()), addr))
+	}
+	config := ChainConfig{
+		BlockInterval: time.Second,
+		Roster:        *onet.NewRoster(list),
+		MaxBlockSize:  16000,
+		Weights:       []uint64{5, 3, 1, 1},
+	}
+	require.NoError(t, config.sanityCheck(nil))
+
+	// The weights must follow the nodes in a rotated roster.
+	weights, err := config.weightsFor(rotateRoster(&config.Roster, 1))
+	require.NoError(t, err)
+	require.Equal(t, []uint64{3, 1, 1, 5}, weights)
+	require.Equal(t, weights, rotateWeights(config.Weights, 1))
+
+	// A node without weight is refused.
+	addr := network.NewAddress(network.TLS, "127.0.0.1:7010")
+	unknown := network.NewServerIdentity(cothority.Suite.Point().Pick(cothority.Suite.RandomStream()), addr)
+	_, err = config.weightsFor(onet.NewRoster([]*network.ServerIdentity{list[0], unknown}))
+	require.Error(t, err)
+	// Unless it is given one.
+	weights, err = config.WeightsFor(onet.NewRoster([]*network.ServerIdentity{unknown, list[0]}), 7)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{7, config.Weights[0]}, weights)
+
+	config.Weights = []uint64{1, 1, 1}
+	require.Error(t, config.sanityCheck(nil))
+	config.Weights = []uint64{0, 0, 0, 0}
+	require.Error(t, config.sanityCheck(nil))
+	config.Weights = nil
+	require.NoError(t, config.sanityCheck(nil))
+	weights, err = config.weightsFor(&config.Roster)
+	require.NoError(t, err)
+	require.Nil(t, weights)
+	// A new node with another weight gives a weight to all the nodes.
+	weights, err = config.WeightsFor(onet.NewRoster(append(list, unknown)), 2)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{1, 1, 1, 1, 2}, weights)
+}
@@ -310,6 +310,25 @@ func (instr Instruction) VerifyWithOption(st ReadOnlyStateTrie, msg []byte, chec
 	if err != nil {
 		return errors.New("darc not found: " + err.Error())
 	}
+	return instr.verifyDarc(st, msg, d)
+}
+
+// VerifyWithDarc verifies the instruction like Verify, but against the rules
+// of the given darc instead of the darc of the instance. This allows a
+// contract to let another darc than its own authorize some instructions.
+func (instr Instruction) VerifyWithDarc(st ReadOnlyStateTrie, msg []byte, d *darc.Darc) error {
+	if len(instr.SignerIdentities) != len(instr.Signatures) {
+		return errors.New("lengh of identities does not match the length of signatures")
+	}
+	if err := verifySignerCounters(st, instr.SignerCounter, instr.SignerIdentities); err != nil {
+		return err
+	}
+	return instr.verifyDarc(st, msg, d)
+}
+
+// verifyDarc checks that the signatures of the instruction satisfy the rule
+// of the darc for the action of the instruction.
+func (instr Instruction) verifyDarc(st ReadOnlyStateTrie, msg []byte, d *darc.Darc) error {
 	if len(instr.Signatures) == 0 {
 		return errors.New("no signatures - nothing to verify")
 	}
@@ -329,7 +348,13 @@ func (instr Instruction) VerifyWithOption(st ReadOnlyStateTrie, msg []byte, chec
 	}
 
 	// check the expression
-	getDarc := func(str string, latest bool) *darc.Darc {
+	return darc.EvalExpr(d.Rules.Get(darc.Action(instr.Action())), trieGetDarc(st), goodIdentities...)
+}
+
+// trieGetDarc returns a function getting the darcs of the delegations from
+// the state trie.
+func trieGetDarc(st ReadOnlyStateTrie) darc.GetDarc {
+	return func(str string, latest bool) *darc.Darc {
 		if len(str) < 5 || string(str[0:5]) != "darc:" {
 			return nil
 		}
@@ -343,7 +368,6 @@ func (instr Instruction) VerifyWithOption(st ReadOnlyStateTrie, msg []byte, chec
 		}
 		return d
 	}
-	return darc.EvalExpr(d.Rules.Get(darc.Action(instr.Action())), getDarc, goodIdentities...)
 }
 
 // InstrType is the instruction type, which can be spawn, invoke or delete.
@@ -575,3 +599,10 @@ func (r *txBuffer) add(key string, newTx ClientTransaction) {
 		r.txsMap[key] = txs
 	}
 }
+
+func (r *txBuffer) len(key string) int {
+	r.Lock()
+	defer r.Unlock()
+
+	return len(r.txsMap[key])
+}
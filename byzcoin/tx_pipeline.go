@@ -35,6 +35,9 @@ type txProcessor interface {
 	GetBlockSize() int
 	// GetInterval should return the block interval.
 	GetInterval() time.Duration
+	// EmptyBlocks should return true if a block must be proposed at every
+	// interval, even if there are no transactions.
+	EmptyBlocks() bool
 	// Stop stops the txProcessor. Once it is called, the caller should not
 	// expect the other functions in the interface to work as expected.
 	Stop()
@@ -170,6 +173,13 @@ collectTxLoop:
 
 func (s *defaultTxProcessor) ProcessTx(tx ClientTransaction, inState *txProcessorState) ([]*txProcessorState, error) {
 	scsOut, sstOut, err := s.processOneTx(inState.sst, tx)
+	if err == errChainPaused {
+		// The leader drops the transaction instead of refusing it, so
+		// that the blocks of a paused chain only hold the transactions
+		// that manage it.
+		log.Lvl2(s.ServerIdentity(), "dropping transaction while the chain is paused")
+		return []*txProcessorState{inState}, nil
+	}
 
 	// try to create a new state
 	newState := func() *txProcessorState {
@@ -235,6 +245,18 @@ func (s *defaultTxProcessor) GetInterval() time.Duration {
 	return bcConfig.BlockInterval
 }
 
+// EmptyBlocks returns true while the chain is paused, so that the leader
+// keeps producing blocks.
+func (s *defaultTxProcessor) EmptyBlocks() bool {
+	bcConfig, err := s.LoadConfig(s.scID)
+	if err != nil {
+		log.Error(s.ServerIdentity(), "couldn't get configuration - this is bad and probably "+
+			"a problem with the database! "+err.Error())
+		return false
+	}
+	return bcConfig.Paused
+}
+
 func (s *defaultTxProcessor) GetLatestGoodState() *txProcessorState {
 	st, err := s.getStateTrie(s.scID)
 	if err != nil {
@@ -347,10 +369,10 @@ func (p *txPipeline) processTxs(txChan <-chan ClientTransaction, initialState *t
 				intervalChan = getInterval()
 
 				// wait for the next interval if there are no changes
-				// we do not check for the length because currentState
-				// should always be non-empty, otherwise it's a
-				// programmer error
-				if len(currentState[0].txs) == 0 {
+				// and no empty block is needed. We do not check for
+				// the length because currentState should always be
+				// non-empty, otherwise it's a programmer error
+				if len(currentState[0].txs) == 0 && !p.processor.EmptyBlocks() {
 					break
 				}
 
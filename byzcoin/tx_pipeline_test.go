@@ -101,6 +101,10 @@ func (p *defaultMockTxProc) GetInterval() time.Duration {
 	return 100 * time.Millisecond
 }
 
+func (p *defaultMockTxProc) EmptyBlocks() bool {
+	return false
+}
+
 func (p *defaultMockTxProc) Stop() {
 }
 
@@ -278,6 +278,7 @@ func (s *Service) startViewChangeCosi(req viewchange.NewViewReq) ([]byte, error)
 	cosiProto.CreateProtocol = s.CreateProtocol
 	cosiProto.Timeout = interval * 2
 	cosiProto.Threshold = n - n/3
+	cosiProto.Weights = rotateWeights(sb.Weights, req.GetView().LeaderIndex)
 
 	err = cosiProto.SetNbrSubTree(int(math.Pow(float64(n), 1.0/3.0)))
 	if err != nil {
@@ -328,7 +329,24 @@ func (s *Service) verifyViewChange(msg []byte, data []byte) bool {
 		return len(signers), len(views)
 	}()
 	f := len(sb.Roster.List) / 3
-	if uniqueSigners < 2*f+1 {
+	if len(sb.Weights) > 0 {
+		// With weighted voting, the signers must hold enough weight
+		// instead of being enough nodes.
+		var weight uint64
+		signers := make(map[network.ServerIdentityID]bool)
+		for _, p := range req.Proof {
+			idx, _ := sb.Roster.Search(p.SignerID)
+			if idx < 0 || signers[p.SignerID] {
+				continue
+			}
+			signers[p.SignerID] = true
+			weight += sb.Weights[idx]
+		}
+		if weight < protocol.WeightedThreshold(sb.Weights) {
+			log.Error(s.ServerIdentity(), "not enough weight in proofs")
+			return false
+		}
+	} else if uniqueSigners < 2*f+1 {
 		log.Error(s.ServerIdentity(), "not enough proofs")
 		return false
 	}
@@ -428,3 +446,14 @@ func rotateRoster(roster *onet.Roster, i int) *onet.Roster {
 
 	return onet.NewRoster(append(roster.List[i:], roster.List[:i]...))
 }
+
+// rotateWeights returns the weights in the order of the roster returned by
+// rotateRoster, or nil if there are no weights.
+func rotateWeights(weights []uint64, i int) []uint64 {
+	if len(weights) == 0 {
+		return nil
+	}
+	i = i % len(weights)
+
+	return append(append([]uint64{}, weights[i:]...), weights[:i]...)
+}
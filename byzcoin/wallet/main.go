@@ -3,6 +3,7 @@ package main
 import (
 	"crypto/sha256"
 	"encoding/binary"
+	"encoding/csv"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
@@ -11,6 +12,7 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 
 	"go.dedis.ch/cothority/v3"
 	"go.dedis.ch/cothority/v3/byzcoin"
@@ -56,13 +58,17 @@ var cmds = cli.Commands{
 	{
 		Name:      "transfer",
 		Usage:     "transfer coins from your account to another one",
-		ArgsUsage: "public key of account",
+		ArgsUsage: "amount public-key-of-account|name",
 		Action:    transfer,
 		Flags: []cli.Flag{
 			cli.IntFlag{
-				Name:  "multi",
+				Name:  "repeat",
 				Usage: "to send multiple transactions and measure tps",
 				Value: 1,
+			},
+			cli.StringFlag{
+				Name:  "multi",
+				Usage: "CSV file with lines of 'public key,amount' to send coins to all the accounts in one instruction",
 			}},
 	},
 }
@@ -169,23 +175,33 @@ func show(c *cli.Context) error {
 }
 
 func transfer(c *cli.Context) error {
-	if c.NArg() < 2 {
-		return errors.New("please give the following arguments: balance address")
-	}
-	amount, err := strconv.ParseUint(c.Args().First(), 10, 64)
+	cfg, cl, err := loadConfig()
 	if err != nil {
 		return err
 	}
 
-	targetBuf, err := hex.DecodeString(c.Args().Get(1))
-	if err != nil {
-		return err
+	var transfers []contracts.CoinTransfer
+	if file := c.String("multi"); file != "" {
+		transfers, err = readTransfers(cl, file)
+		if err != nil {
+			return err
+		}
+	} else {
+		if c.NArg() < 2 {
+			return errors.New("please give the following arguments: balance address")
+		}
+		t, err := parseTransfer(cl, c.Args().Get(1), c.Args().First())
+		if err != nil {
+			return err
+		}
+		transfers = append(transfers, t)
 	}
-	target, err := coinHash(targetBuf)
-
-	cfg, cl, err := loadConfig()
-	if err != nil {
-		return err
+	var amount uint64
+	for _, t := range transfers {
+		amount += t.Coins
+		if amount < t.Coins {
+			return errors.New("total amount overflows")
+		}
 	}
 
 	iid, err := coinHashPub(cfg.KeyPair.Public)
@@ -215,15 +231,38 @@ func transfer(c *cli.Context) error {
 
 	signer := darc.NewSignerEd25519(cfg.KeyPair.Public, cfg.KeyPair.Private)
 	counters, err := cl.GetSignerCounters(signer.Identity().String())
-	multi := c.Int("multi")
-	if multi > 200 {
-		log.Warn("Only allowing 200 transactions at a time")
-		multi = 200
+	if err != nil {
+		return err
 	}
-	for tx := 0; tx < multi; tx++ {
-		counters.Counters[0]++
+	var args byzcoin.Arguments
+	if len(transfers) == 1 {
 		amountBuf := make([]byte, 8)
 		binary.LittleEndian.PutUint64(amountBuf, amount)
+		args = byzcoin.Arguments{
+			{
+				Name:  "coins",
+				Value: amountBuf,
+			},
+			{
+				Name:  "destination",
+				Value: transfers[0].Destination.Slice(),
+			},
+		}
+	} else {
+		buf, err := protobuf.Encode(&contracts.CoinTransfers{List: transfers})
+		if err != nil {
+			return err
+		}
+		args = byzcoin.Arguments{{Name: "destinations", Value: buf}}
+	}
+
+	repeat := c.Int("repeat")
+	if repeat > 200 {
+		log.Warn("Only allowing 200 transactions at a time")
+		repeat = 200
+	}
+	for tx := 0; tx < repeat; tx++ {
+		counters.Counters[0]++
 		ctx := byzcoin.ClientTransaction{
 			Instructions: byzcoin.Instructions{
 				{
@@ -231,16 +270,7 @@ func transfer(c *cli.Context) error {
 					Invoke: &byzcoin.Invoke{
 						ContractID: contracts.ContractCoinID,
 						Command:    "transfer",
-						Args: byzcoin.Arguments{
-							{
-								Name:  "coins",
-								Value: amountBuf,
-							},
-							{
-								Name:  "destination",
-								Value: target.Slice(),
-							},
-						},
+						Args:       args,
 					},
 					SignerCounter: counters.Counters,
 				},
@@ -248,9 +278,9 @@ func transfer(c *cli.Context) error {
 		}
 		ctx.FillSignersAndSignWith(signer)
 
-		log.Info("Sending transaction of", amount, "coins to address", c.Args().Get(1))
+		log.Info("Sending transaction of", amount, "coins to", len(transfers), "address(es)")
 		wait := 0
-		if tx == multi-1 {
+		if tx == repeat-1 {
 			wait = 10
 		}
 		_, err = cl.AddTransactionAndWait(ctx, wait)
@@ -264,6 +294,54 @@ func transfer(c *cli.Context) error {
 	return nil
 }
 
+// parseTransfer returns the transfer of the amount to the account of the
+// public key, both given as strings. If the destination is not a public key,
+// it is resolved as a name pointing to a coin instance.
+func parseTransfer(cl *byzcoin.Client, pub, amount string) (t contracts.CoinTransfer, err error) {
+	t.Coins, err = strconv.ParseUint(strings.TrimSpace(amount), 10, 64)
+	if err != nil {
+		return
+	}
+	pub = strings.TrimSpace(pub)
+	targetBuf, err := hex.DecodeString(pub)
+	if err != nil || len(targetBuf) != 32 {
+		t.Destination, err = cl.ResolveName(pub)
+		return
+	}
+	t.Destination, err = coinHash(targetBuf)
+	return
+}
+
+// readTransfers reads a CSV file with one 'public key,amount' line per
+// transfer, where the public key can also be a name. Lines starting with '#'
+// are ignored.
+func readTransfers(cl *byzcoin.Client, file string) ([]contracts.CoinTransfer, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := csv.NewReader(f)
+	r.Comment = '#'
+	r.FieldsPerRecord = 2
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, errors.New("no transfers in " + file)
+	}
+	var transfers []contracts.CoinTransfer
+	for i, rec := range records {
+		t, err := parseTransfer(cl, rec[0], rec[1])
+		if err != nil {
+			return nil, fmt.Errorf("transfer %d: %v", i+1, err)
+		}
+		transfers = append(transfers, t)
+	}
+	return transfers, nil
+}
+
 func coinHashPub(pub kyber.Point) (iid byzcoin.InstanceID, err error) {
 	buf, err := pub.MarshalBinary()
 	if err != nil {
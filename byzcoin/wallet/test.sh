@@ -14,6 +14,7 @@ main(){
   buildConode github.com/dedis/cothority/byzcoin github.com/dedis/cothority/byzcoin/contracts
   build $APPDIR/../bcadmin
   run testMulti
+  run testTransferMulti
   run testLoadSave
   run testCoin
   stopTest
@@ -35,14 +36,43 @@ testMulti(){
   PUB2=$SED
   testOK runBA mint $bc $key $PUB2 1000
 
-  testOK runWallet 2 transfer --multi 10 1 $PUB
+  testOK runWallet 2 transfer --repeat 10 1 $PUB
   testGrep "Balance is: 990" runWallet 2 show
   testGrep "Balance is: 1010" runWallet 1 show
 
-  testGrep "Only allowing" runWallet 2 transfer --multi 300 1 $PUB 2>1
+  testGrep "Only allowing" runWallet 2 transfer --repeat 300 1 $PUB 2>1
   testGrep "Balance is: 790" runWallet 2 show
 }
 
+testTransferMulti(){
+  rm -rf config wallet{1,2,3}
+  runCoBG 1 2 3
+  testOK runBA create public.toml --interval .5s
+  bc=config/bc*cfg
+  key=config/key*cfg
+  for w in 1 2 3; do
+    testOK runWallet $w join $bc
+    runGrepSed "Public key is:" "s/.* //" runWallet $w show
+    eval PUB$w=$SED
+    testOK runBA mint $bc $key $SED 0
+  done
+  testOK runBA mint $bc $key $PUB1 1000
+
+  echo "# payroll" > payroll.csv
+  echo "$PUB2,100" >> payroll.csv
+  echo "$PUB3,200" >> payroll.csv
+  testOK runWallet 1 transfer --multi payroll.csv
+  testGrep "Balance is: 700" runWallet 1 show
+  testGrep "Balance is: 100" runWallet 2 show
+  testGrep "Balance is: 200" runWallet 3 show
+
+  # Nothing is sent if one of the transfers fails.
+  echo "$PUB1,1" >> payroll.csv
+  testFail runWallet 1 transfer --multi payroll.csv
+  testGrep "Balance is: 100" runWallet 2 show
+  rm payroll.csv
+}
+
 testLoadSave(){
   rm -rf config wallet{1,2}
   runCoBG 1 2 3
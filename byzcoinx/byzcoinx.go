@@ -40,6 +40,10 @@ type ByzCoinX struct {
 	SubleaderFailures int
 	// Threshold is the number of nodes to reach for a signature to be valid
 	Threshold int
+	// Weights is the optional voting weight of every node of the roster.
+	// If it is set, a signature is valid when the signers hold the weight
+	// given by protocol.WeightedThreshold, and Threshold is ignored.
+	Weights []uint64
 	// prepCosiProtoName is the ftcosi protocol name for the prepare phase
 	prepCosiProtoName string
 	// commitCosiProtoName is the ftcosi protocol name for the commit phase
@@ -67,8 +71,9 @@ type FinalSignature struct {
 
 type phase int
 
-// VerifierFn is used to verify the final signature
-type VerifierFn func(suite pairing.Suite, msg, sig []byte, pubkeys []kyber.Point) error
+// VerifierFn is used to verify the final signature. The weights are empty
+// if all the nodes have the same weight.
+type VerifierFn func(suite pairing.Suite, msg, sig []byte, pubkeys []kyber.Point, weights []uint64) error
 
 const (
 	phasePrep phase = iota
@@ -130,6 +135,7 @@ func (bft *ByzCoinX) initCosiProtocol(phase phase) (*protocol.BlsCosi, error) {
 	cosiProto.Msg = bft.Msg
 	cosiProto.Data = bft.Data
 	cosiProto.Threshold = bft.Threshold
+	cosiProto.Weights = bft.Weights
 	// For each of the prepare and commit phase we get half of the time.
 	cosiProto.Timeout = bft.Timeout / 2
 	cosiProto.SubleaderFailures = bft.SubleaderFailures
@@ -156,7 +162,7 @@ func (bft *ByzCoinX) Dispatch() error {
 
 	// prepare phase (part 2)
 	prepSig := <-bft.prepSigChan
-	err := bft.verifier(bft.suite, bft.Msg, prepSig, bft.publics)
+	err := bft.verifier(bft.suite, bft.Msg, prepSig, bft.publics, bft.Weights)
 	if err != nil {
 		log.Lvl2("Signature verification failed on root during the prepare phase with error:", err)
 		bft.FinalSignatureChan <- FinalSignature{nil, nil}
@@ -186,7 +192,7 @@ func (bft *ByzCoinX) Dispatch() error {
 		log.Error(bft.ServerIdentity().Address, "timeout should not happen while waiting for signature")
 	}
 
-	err = bft.verifier(bft.suite, bft.Msg, commitSig, bft.publics)
+	err = bft.verifier(bft.suite, bft.Msg, commitSig, bft.publics, bft.Weights)
 	if err != nil {
 		bft.FinalSignatureChan <- FinalSignature{nil, nil}
 		return errors.New("Commit signature is wrong")
@@ -225,8 +231,8 @@ func makeProtocols(vf, ack protocol.VerificationFn, protoName string, suite *pai
 	commitCosiProtoName := protoName + "_cosi_commit"
 	commitCosiSubProtoName := protoName + "_subcosi_commit"
 
-	verifier := func(suite pairing.Suite, msg, sig []byte, pubkeys []kyber.Point) error {
-		return protocol.BlsSignature(sig).Verify(suite, msg, pubkeys)
+	verifier := func(suite pairing.Suite, msg, sig []byte, pubkeys []kyber.Point, weights []uint64) error {
+		return protocol.BlsSignature(sig).VerifyWithWeights(suite, msg, pubkeys, weights)
 	}
 
 	protocolMap[protoName] = func(n *onet.TreeNodeInstance) (onet.ProtocolInstance, error) {
@@ -256,8 +262,8 @@ func makeBdnProtocols(vf, ack protocol.VerificationFn, protoName string, suite *
 	commitCosiProtoName := protoName + "_cosi_commit"
 	commitCosiSubProtoName := protoName + "_subcosi_commit"
 
-	verifier := func(suite pairing.Suite, msg, sig []byte, pubkeys []kyber.Point) error {
-		return bdnproto.BdnSignature(sig).Verify(suite, msg, pubkeys)
+	verifier := func(suite pairing.Suite, msg, sig []byte, pubkeys []kyber.Point, weights []uint64) error {
+		return bdnproto.BdnSignature(sig).VerifyWithWeights(suite, msg, pubkeys, weights)
 	}
 
 	protocolMap[protoName] = func(n *onet.TreeNodeInstance) (onet.ProtocolInstance, error) {
@@ -18,6 +18,7 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"net/http"
 	"os"
 	"path"
 	"reflect"
@@ -25,8 +26,10 @@ import (
 
 	"go.dedis.ch/cothority/v3"
 	_ "go.dedis.ch/cothority/v3/authprox"
-	_ "go.dedis.ch/cothority/v3/byzcoin"
+	"go.dedis.ch/cothority/v3/byzcoin"
 	_ "go.dedis.ch/cothority/v3/byzcoin/contracts"
+	"go.dedis.ch/cothority/v3/byzcoin/explorer"
+	"go.dedis.ch/cothority/v3/byzcoin/gateway"
 	_ "go.dedis.ch/cothority/v3/calypso"
 	_ "go.dedis.ch/cothority/v3/eventlog"
 	_ "go.dedis.ch/cothority/v3/evoting/service"
@@ -35,6 +38,7 @@ import (
 	status "go.dedis.ch/cothority/v3/status/service"
 	"go.dedis.ch/kyber/v3/util/encoding"
 	"go.dedis.ch/kyber/v3/util/key"
+	"go.dedis.ch/onet/v3"
 	"go.dedis.ch/onet/v3/app"
 	"go.dedis.ch/onet/v3/cfgpath"
 	"go.dedis.ch/onet/v3/log"
@@ -88,6 +92,20 @@ func main() {
 			Name:   "server",
 			Usage:  "Start cothority server",
 			Action: runServer,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "metrics",
+					Usage: "address to serve the ByzCoin metrics on, e.g. localhost:9100 (disabled if empty)",
+				},
+				cli.StringFlag{
+					Name:  "gateway",
+					Usage: "address to serve the ByzCoin JSON gateway on, e.g. localhost:7780 (disabled if empty)",
+				},
+				cli.StringFlag{
+					Name:  "explorer",
+					Usage: "address to serve the ByzCoin block explorer on, e.g. localhost:7790 (disabled if empty)",
+				},
+			},
 		},
 		{
 			Name:      "check",
@@ -151,10 +169,56 @@ func runServer(ctx *cli.Context) error {
 	if raiseFdLimit != nil {
 		raiseFdLimit()
 	}
+	if addr := ctx.String("metrics"); addr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", byzcoin.MetricsHandler())
+		go func() {
+			log.Lvl1("Serving metrics on", addr)
+			log.Error(http.ListenAndServe(addr, mux))
+		}()
+	}
+	if addr := ctx.String("gateway"); addr != "" {
+		roster, err := localRoster(config)
+		if err != nil {
+			return err
+		}
+		mux := http.NewServeMux()
+		mux.Handle(gateway.PathPrefix, gateway.New(roster))
+		go func() {
+			log.Lvl1("Serving the ByzCoin gateway on", addr)
+			log.Error(http.ListenAndServe(addr, mux))
+		}()
+	}
+	if addr := ctx.String("explorer"); addr != "" {
+		roster, err := localRoster(config)
+		if err != nil {
+			return err
+		}
+		mux := http.NewServeMux()
+		mux.Handle(explorer.PathPrefix, explorer.New(roster))
+		go func() {
+			log.Lvl1("Serving the ByzCoin explorer on", addr)
+			log.Error(http.ListenAndServe(addr, mux))
+		}()
+	}
 	app.RunServer(config)
 	return nil
 }
 
+// localRoster returns a roster holding only this conode, so that the HTTP
+// services forward all the requests to it.
+func localRoster(config string) (*onet.Roster, error) {
+	ccfg, err := app.LoadCothority(config)
+	if err != nil {
+		return nil, err
+	}
+	si, err := ccfg.GetServerIdentity()
+	if err != nil {
+		return nil, err
+	}
+	return onet.NewRoster([]*network.ServerIdentity{si}), nil
+}
+
 // checkConfig contacts all servers and verifies if it receives a valid
 // signature from each.
 func checkConfig(c *cli.Context) error {
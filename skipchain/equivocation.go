@@ -0,0 +1,158 @@
+package skipchain
+
+import (
+	"errors"
+
+	"go.dedis.ch/cothority/v3/blscosi/protocol"
+	"go.dedis.ch/onet/v3/network"
+)
+
+// Equivocation is the evidence that the roster of a block signed forward
+// links to two different blocks at the same index. An honest node never
+// signs two forward links from the same block to the same index, so the
+// nodes that signed both links misbehaved, whether the blocks are part of
+// a chain or not.
+type Equivocation struct {
+	// From is the block both links come from. It holds the roster and the
+	// weights the signatures are verified with.
+	From SkipBlock
+	// BlockA and BlockB are the two blocks following From.
+	BlockA SkipBlock
+	BlockB SkipBlock
+	// LinkA and LinkB are the signed forward links from From to BlockA
+	// and BlockB.
+	LinkA ForwardLink
+	LinkB ForwardLink
+	// Chain holds the blocks from the genesis to From, each one linked to
+	// the next one by a forward link. It proves that From is a block of the
+	// chain, see VerifyChain.
+	Chain []*SkipBlock
+}
+
+// NewEquivocation returns the evidence that a and b both follow from, with
+// the forward links of from to them, once it is verified.
+func NewEquivocation(from, a, b *SkipBlock, linkA, linkB *ForwardLink) (*Equivocation, error) {
+	e := &Equivocation{
+		From:   *from,
+		BlockA: *a,
+		BlockB: *b,
+		LinkA:  *linkA,
+		LinkB:  *linkB,
+	}
+	if err := e.Verify(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Verify checks that the two blocks are different, follow the same block at
+// the same index, and that both forward links are correctly signed by the
+// roster of that block.
+func (e *Equivocation) Verify() error {
+	for _, sb := range []*SkipBlock{&e.From, &e.BlockA, &e.BlockB} {
+		if !sb.Hash.Equal(sb.CalculateHash()) {
+			return errors.New("wrong hash of block")
+		}
+	}
+	if e.From.Roster == nil {
+		return errors.New("missing roster in the block")
+	}
+	if e.BlockA.Hash.Equal(e.BlockB.Hash) {
+		return errors.New("the blocks are the same")
+	}
+	if e.BlockA.Index != e.BlockB.Index || e.BlockA.Index <= e.From.Index {
+		return errors.New("the blocks are not at the same index after the first block")
+	}
+
+	publics := e.From.Roster.ServicePublics(ServiceName)
+	for _, l := range []struct {
+		fl *ForwardLink
+		sb *SkipBlock
+	}{{&e.LinkA, &e.BlockA}, {&e.LinkB, &e.BlockB}} {
+		if !l.fl.From.Equal(e.From.Hash) || !l.fl.To.Equal(l.sb.Hash) {
+			return errors.New("wrong targets for the forward link")
+		}
+		if err := l.fl.VerifyWithWeights(suite, publics, e.From.Weights); err != nil {
+			return errors.New("wrong signature in forward-link: " + err.Error())
+		}
+	}
+	return nil
+}
+
+// VerifyChain checks that From is a block of the chain with the given
+// genesis: Chain must start at the genesis and every block must have a
+// signed forward link to the next one, up to From. Without it, anybody can
+// sign two blocks following a made-up block with a roster of their own.
+func (e *Equivocation) VerifyChain(genesis SkipBlockID) error {
+	if !e.From.SkipChainID().Equal(genesis) {
+		return errors.New("the block is not from this chain")
+	}
+	if len(e.Chain) == 0 {
+		return errors.New("missing the blocks from the genesis")
+	}
+	if e.Chain[0].Index != 0 || !e.Chain[0].Hash.Equal(genesis) {
+		return errors.New("the blocks don't start at the genesis")
+	}
+	if !e.Chain[len(e.Chain)-1].Hash.Equal(e.From.Hash) {
+		return errors.New("the blocks don't end at the first block")
+	}
+	for i, sb := range e.Chain {
+		if !sb.Hash.Equal(sb.CalculateHash()) {
+			return errors.New("wrong hash of block")
+		}
+		if i == len(e.Chain)-1 {
+			break
+		}
+		if sb.Roster == nil {
+			return errors.New("missing roster in the block")
+		}
+		next := e.Chain[i+1]
+		if next.Index <= sb.Index {
+			return errors.New("the blocks are not in order")
+		}
+		var link *ForwardLink
+		for _, fl := range sb.ForwardLink {
+			if fl.To.Equal(next.Hash) {
+				link = fl
+			}
+		}
+		if link == nil || !link.From.Equal(sb.Hash) {
+			return errors.New("missing forward link between the blocks")
+		}
+		err := link.VerifyWithWeights(suite, sb.Roster.ServicePublics(ServiceName), sb.Weights)
+		if err != nil {
+			return errors.New("wrong signature in forward-link: " + err.Error())
+		}
+	}
+	return nil
+}
+
+// Offenders returns the nodes that signed both forward links. The evidence
+// must have been verified before.
+func (e *Equivocation) Offenders() ([]*network.ServerIdentity, error) {
+	publics := e.From.Roster.ServicePublics(ServiceName)
+	maskA, err := protocol.BlsSignature(e.LinkA.Signature.Sig).GetMask(suite, publics)
+	if err != nil {
+		return nil, err
+	}
+	maskB, err := protocol.BlsSignature(e.LinkB.Signature.Sig).GetMask(suite, publics)
+	if err != nil {
+		return nil, err
+	}
+
+	var offenders []*network.ServerIdentity
+	for i, si := range e.From.Roster.List {
+		a, err := maskA.IndexEnabled(i)
+		if err != nil {
+			return nil, err
+		}
+		b, err := maskB.IndexEnabled(i)
+		if err != nil {
+			return nil, err
+		}
+		if a && b {
+			offenders = append(offenders, si)
+		}
+	}
+	return offenders, nil
+}
@@ -0,0 +1,154 @@
+package skipchain
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/cothority/v3/byzcoinx"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/sign"
+	"go.dedis.ch/kyber/v3/sign/bls"
+	"go.dedis.ch/kyber/v3/util/key"
+	"go.dedis.ch/onet/v3"
+	"go.dedis.ch/onet/v3/network"
+)
+
+// signLink signs the forward link with the given nodes of the roster.
+func signLink(t *testing.T, fl *ForwardLink, publics []kyber.Point, privs []kyber.Scalar, signers ...int) {
+	mask, err := sign.NewMask(suite, publics, nil)
+	require.NoError(t, err)
+	var sigs [][]byte
+	for _, i := range signers {
+		sig, err := bls.Sign(suite, privs[i], fl.Hash())
+		require.NoError(t, err)
+		sigs = append(sigs, sig)
+		require.NoError(t, mask.SetBit(i, true))
+	}
+	agg, err := bls.AggregateSignatures(suite, sigs...)
+	require.NoError(t, err)
+	fl.Signature = byzcoinx.FinalSignature{
+		Msg: fl.Hash(),
+		Sig: append(agg, mask.Mask()...),
+	}
+}
+
+func TestEquivocation(t *testing.T) {
+	var sis []*network.ServerIdentity
+	var privs []kyber.Scalar
+	for i := 0; i < 4; i++ {
+		kp := key.NewKeyPair(suite)
+		sis = append(sis, network.NewServerIdentity(kp.Public,
+			network.Address(fmt.Sprintf("tls://127.0.0.1:%d", 2000+2*i))))
+		privs = append(privs, kp.Private)
+	}
+	roster := onet.NewRoster(sis)
+	publics := roster.ServicePublics(ServiceName)
+
+	from := NewSkipBlock()
+	from.Roster = roster
+	from.updateHash()
+	newBlock := func(data string) *SkipBlock {
+		sb := NewSkipBlock()
+		sb.Index = 1
+		sb.Roster = roster
+		sb.Data = []byte(data)
+		sb.BackLinkIDs = []SkipBlockID{from.Hash}
+		sb.updateHash()
+		return sb
+	}
+	a, b := newBlock("a"), newBlock("b")
+	linkA, linkB := NewForwardLink(from, a), NewForwardLink(from, b)
+	signLink(t, linkA, publics, privs, 0, 1, 2)
+	signLink(t, linkB, publics, privs, 1, 2, 3)
+
+	e, err := NewEquivocation(from, a, b, linkA, linkB)
+	require.NoError(t, err)
+	offenders, err := e.Offenders()
+	require.NoError(t, err)
+	require.Equal(t, []*network.ServerIdentity{sis[1], sis[2]}, offenders)
+
+	// The same block twice is not an equivocation.
+	_, err = NewEquivocation(from, a, a, linkA, linkA)
+	require.Error(t, err)
+
+	// Not enough signers.
+	signLink(t, linkB, publics, privs, 2, 3)
+	_, err = NewEquivocation(from, a, b, linkA, linkB)
+	require.Error(t, err)
+
+	// Blocks at different indexes can be linked from the same block.
+	b.Index = 2
+	b.updateHash()
+	linkB = NewForwardLink(from, b)
+	signLink(t, linkB, publics, privs, 1, 2, 3)
+	_, err = NewEquivocation(from, a, b, linkA, linkB)
+	require.Error(t, err)
+}
+
+func TestEquivocation_VerifyChain(t *testing.T) {
+	newRoster := func() (*onet.Roster, []kyber.Scalar) {
+		var sis []*network.ServerIdentity
+		var privs []kyber.Scalar
+		for i := 0; i < 4; i++ {
+			kp := key.NewKeyPair(suite)
+			sis = append(sis, network.NewServerIdentity(kp.Public,
+				network.Address(fmt.Sprintf("tls://127.0.0.1:%d", 2000+2*i))))
+			privs = append(privs, kp.Private)
+		}
+		return onet.NewRoster(sis), privs
+	}
+	roster, privs := newRoster()
+	publics := roster.ServicePublics(ServiceName)
+
+	genesis := NewSkipBlock()
+	genesis.Roster = roster
+	genesis.updateHash()
+	newBlock := func(prev *SkipBlock, index int, data string, r *onet.Roster) *SkipBlock {
+		sb := NewSkipBlock()
+		sb.Index = index
+		sb.Roster = r
+		sb.Data = []byte(data)
+		sb.GenesisID = genesis.Hash
+		sb.BackLinkIDs = []SkipBlockID{prev.Hash}
+		sb.updateHash()
+		return sb
+	}
+	from := newBlock(genesis, 1, "from", roster)
+	link := NewForwardLink(genesis, from)
+	signLink(t, link, publics, privs, 0, 1, 2)
+	genesis.ForwardLink = []*ForwardLink{link}
+
+	a, b := newBlock(from, 2, "a", roster), newBlock(from, 2, "b", roster)
+	linkA, linkB := NewForwardLink(from, a), NewForwardLink(from, b)
+	signLink(t, linkA, publics, privs, 0, 1, 2)
+	signLink(t, linkB, publics, privs, 1, 2, 3)
+	e, err := NewEquivocation(from, a, b, linkA, linkB)
+	require.NoError(t, err)
+
+	require.Error(t, e.VerifyChain(genesis.Hash))
+	e.Chain = []*SkipBlock{genesis, from}
+	require.NoError(t, e.VerifyChain(genesis.Hash))
+	require.Error(t, e.VerifyChain(SkipBlockID("other chain")))
+
+	// A block with a roster of the attacker, claiming to be part of the
+	// chain, is refused.
+	rogue, rogueprivs := newRoster()
+	roguePublics := rogue.ServicePublics(ServiceName)
+	fakeFrom := newBlock(genesis, 1, "fake", rogue)
+	a, b = newBlock(fakeFrom, 2, "a", rogue), newBlock(fakeFrom, 2, "b", rogue)
+	linkA, linkB = NewForwardLink(fakeFrom, a), NewForwardLink(fakeFrom, b)
+	signLink(t, linkA, roguePublics, rogueprivs, 0, 1, 2)
+	signLink(t, linkB, roguePublics, rogueprivs, 1, 2, 3)
+	forged, err := NewEquivocation(fakeFrom, a, b, linkA, linkB)
+	require.NoError(t, err)
+	forged.Chain = []*SkipBlock{genesis, fakeFrom}
+	require.Error(t, forged.VerifyChain(genesis.Hash))
+
+	fakeLink := NewForwardLink(genesis, fakeFrom)
+	signLink(t, fakeLink, roguePublics, rogueprivs, 0, 1, 2)
+	fakeGenesis := genesis.Copy()
+	fakeGenesis.ForwardLink = []*ForwardLink{fakeLink}
+	forged.Chain = []*SkipBlock{fakeGenesis, fakeFrom}
+	require.Error(t, forged.VerifyChain(genesis.Hash))
+}
@@ -568,8 +568,9 @@ func (s *Service) GetSingleBlockByIndex(id *GetSingleBlockByIndex) (*GetSingleBl
 		return nil, errors.New("No such genesis-block")
 	}
 	links := []*ForwardLink{{
-		To:        id.Genesis,
-		NewRoster: sb.Roster,
+		To:         id.Genesis,
+		NewRoster:  sb.Roster,
+		NewWeights: sb.Weights,
 	}}
 	if sb.Index == id.Index {
 		return &GetSingleBlockByIndexReply{sb, links}, nil
@@ -842,6 +843,21 @@ func (s *Service) ListFollow(list *ListFollow) (*ListFollowReply, error) {
 	return reply, nil
 }
 
+// GetFollowed returns a copy of the latest known block of every skipchain
+// that has been added with FollowSearch or FollowLookup. It is used by other
+// services to follow a chain this conode is not part of.
+func (s *Service) GetFollowed() []*SkipBlock {
+	s.storageMutex.Lock()
+	defer s.storageMutex.Unlock()
+	sbs := make([]*SkipBlock, 0, len(s.Storage.Follow))
+	for _, fct := range s.Storage.Follow {
+		if fct.Block != nil {
+			sbs = append(sbs, fct.Block.Copy())
+		}
+	}
+	return sbs
+}
+
 // WaitBlock returns a block by its ID instantly if already stored in the DB
 // or check if the block is inside the buffer. If the block is known, false will
 // be returned because a catch up is not necessary, true otherwise.
@@ -977,7 +993,7 @@ func (s *Service) forwardLinkLevel0(src, dst *SkipBlock) error {
 		return fmt.Errorf("Couldn't marshal block: %s", err.Error())
 	}
 	fwd := NewForwardLink(src, dst)
-	sig, err := s.startBFT(bftNewBlock, roster, dst.Roster, fwd.Hash(), data)
+	sig, err := s.startBFT(bftNewBlock, roster, dst.Roster, src.Weights, fwd.Hash(), data)
 	if err != nil {
 		log.Error(s.ServerIdentity().Address, "startBFT failed with", err)
 		return err
@@ -1063,6 +1079,23 @@ func mapMask(origRoster *onet.Roster, newRoster *onet.Roster, sig []byte) ([]byt
 	return append(sig[:lenRes], origMask.Mask()...), nil
 }
 
+// mapWeights returns the weights of the nodes of the origRoster in the order
+// of the newRoster. Both rosters must have the same nodes.
+func mapWeights(origRoster, newRoster *onet.Roster, weights []uint64) ([]uint64, error) {
+	if len(weights) != len(origRoster.List) {
+		return nil, errors.New("need one weight per node of the roster")
+	}
+	mapped := make([]uint64, len(newRoster.List))
+	for i, si := range newRoster.List {
+		idx, _ := origRoster.Search(si.ID)
+		if idx < 0 {
+			return nil, errors.New("rosters don't have the same nodes")
+		}
+		mapped[i] = weights[idx]
+	}
+	return mapped, nil
+}
+
 // bftForwardLinkLevel0 makes sure that a signature-request for a forward-link
 // is valid.
 func (s *Service) bftForwardLinkLevel0(msg, data []byte) bool {
@@ -1222,7 +1255,7 @@ func (s *Service) forwardLink(req *network.Envelope) error {
 			return err
 		}
 		fl := NewForwardLink(from, fs.Newest)
-		sig, err := s.startBFT(bftFollowBlock, from.Roster, fs.Newest.Roster, fl.Hash(), data)
+		sig, err := s.startBFT(bftFollowBlock, from.Roster, fs.Newest.Roster, from.Weights, fl.Hash(), data)
 		if err != nil {
 			return errors.New("Couldn't get signature: " + err.Error())
 		}
@@ -1290,15 +1323,17 @@ func (s *Service) bftForwardLink(msg, data []byte) bool {
 		}
 
 		newRoster := src.Roster
+		newWeights := src.Weights
 
 		for i, fl := range fs.Links {
 			publics := newRoster.ServicePublics(ServiceName)
 
-			if err := fl.Verify(suite, publics); err != nil {
+			if err := fl.VerifyWithWeights(suite, publics, newWeights); err != nil {
 				return errors.New("verification failed: " + err.Error())
 			}
 			if fl.NewRoster != nil {
 				newRoster = fl.NewRoster
+				newWeights = fl.NewWeights
 			}
 			if i == 0 {
 				if !src.Hash.Equal(fl.From) {
@@ -1348,8 +1383,9 @@ func (s *Service) bftForwardLinkAck(msg, data []byte) bool {
 // be used if the ID between the two rosters are different but the aggregate is
 // the same. This is an optimisation because the newer roster might have an
 // order that is more likely to give us non-failing subleaders in the byzcoinx
-// protocol.
-func (s *Service) startBFT(proto string, origRoster, newRoster *onet.Roster, msg, data []byte) (*byzcoinx.FinalSignature, error) {
+// protocol. The weights are the optional voting weights of the nodes of the
+// origRoster.
+func (s *Service) startBFT(proto string, origRoster, newRoster *onet.Roster, weights []uint64, msg, data []byte) (*byzcoinx.FinalSignature, error) {
 	roster := origRoster
 	// If the aggregate public key of the two rosters are the same but
 	// their IDs are different, we use the new roster because the byzcoinx
@@ -1387,6 +1423,15 @@ func (s *Service) startBFT(proto string, origRoster, newRoster *onet.Roster, msg
 	root.FinalSignatureChan = make(chan byzcoinx.FinalSignature, 1)
 	root.Timeout = s.propTimeout
 	root.Threshold = byzcoinx.Threshold(len(tree.List()))
+	if len(weights) > 0 {
+		if sameAggr {
+			weights, err = mapWeights(origRoster, roster, weights)
+			if err != nil {
+				return nil, err
+			}
+		}
+		root.Weights = weights
+	}
 	if s.bftTimeout != 0 {
 		root.Timeout = s.bftTimeout
 	}
@@ -336,7 +336,7 @@ func (sbf *SkipBlockFix) CalculateHash() SkipBlockID {
 // be hashed (yet).
 type SkipBlock struct {
 	*SkipBlockFix
-	// Hash is our Block-hash of the SkipBlockFix part.
+	// Hash is our Block-hash of the SkipBlockFix part and the weights.
 	Hash SkipBlockID
 
 	// ForwardLink will be calculated once future SkipBlocks are
@@ -349,6 +349,32 @@ type SkipBlock struct {
 	// using the skipblocks can return simply the SkipBlockFix, as long as they
 	// don't need the payload.
 	Payload []byte `protobuf:"opt"`
+
+	// Weights holds the optional voting weight of each node of the roster,
+	// in the same order. If it is empty, all nodes have the same weight.
+	// Otherwise the forward links of this block need to be signed by nodes
+	// holding more than two thirds of the total weight. As it is not part
+	// of SkipBlockFix for compatibility, it is added to the hash by
+	// SkipBlock.CalculateHash.
+	Weights []uint64 `protobuf:"opt"`
+}
+
+// CalculateHash hashes all fixed fields of the skipblock, and the weights if
+// they are set.
+func (sb *SkipBlock) CalculateHash() SkipBlockID {
+	id := sb.SkipBlockFix.CalculateHash()
+	if len(sb.Weights) == 0 {
+		return id
+	}
+	hash := sha256.New()
+	hash.Write(id)
+	for _, w := range sb.Weights {
+		err := binary.Write(hash, binary.LittleEndian, w)
+		if err != nil {
+			panic("error writing to hash:" + err.Error())
+		}
+	}
+	return hash.Sum(nil)
 }
 
 // NewSkipBlock pre-initialises the block so it can be sent over
@@ -382,7 +408,7 @@ func (sb *SkipBlock) VerifyForwardSignatures() error {
 			// forward-link in place.
 			continue
 		}
-		if err := fl.Verify(suite, publics); err != nil {
+		if err := fl.VerifyWithWeights(suite, publics, sb.Weights); err != nil {
 			return errors.New("Wrong signature in forward-link: " + err.Error())
 		}
 	}
@@ -410,6 +436,9 @@ func (sb *SkipBlock) Copy() *SkipBlock {
 	}
 	copy(b.Hash, sb.Hash)
 	copy(b.Payload, sb.Payload)
+	if len(sb.Weights) > 0 {
+		b.Weights = append([]uint64{}, sb.Weights...)
+	}
 	b.VerifierIDs = make([]VerifierID, len(sb.VerifierIDs))
 	copy(b.VerifierIDs, sb.VerifierIDs)
 
@@ -545,7 +574,7 @@ func (sbs Proof) verifyChain() error {
 			}
 
 			fl := sb.ForwardLink[len(sb.ForwardLink)-1]
-			if err := fl.Verify(pairing.NewSuiteBn256(), sb.Roster.ServicePublics(ServiceName)); err != nil {
+			if err := fl.VerifyWithWeights(pairing.NewSuiteBn256(), sb.Roster.ServicePublics(ServiceName), sb.Weights); err != nil {
 				return err
 			}
 
@@ -567,18 +596,22 @@ type ForwardLink struct {
 	// To - where this forward link points to
 	To SkipBlockID
 	// NewRoster is only set to non-nil if the From block has a
-	// different roster from the To-block.
+	// different roster or different weights from the To-block.
 	NewRoster *onet.Roster
 	// Signature is calculated on the
-	// sha256(From.Hash()|To.Hash()|NewRoster)
+	// sha256(From.Hash()|To.Hash()|NewRoster|NewWeights)
 	// In the case that NewRoster is nil, the signature is
 	// calculated on the sha256(From.Hash()|To.Hash())
 	Signature byzcoinx.FinalSignature
+	// NewWeights are the weights of the To-block. They are only relevant
+	// if NewRoster is set, and are empty if all nodes have the same
+	// weight.
+	NewWeights []uint64 `protobuf:"opt"`
 }
 
 // NewForwardLink creates a new forwardlink structure with
-// the From, To, and NewRoster initialized. If the roster in
-// From and To is identitcal, NewRoster will be nil.
+// the From, To, NewRoster and NewWeights initialized. If the roster
+// and the weights in From and To are identitcal, NewRoster will be nil.
 func NewForwardLink(from, to *SkipBlock) *ForwardLink {
 	fl := &ForwardLink{
 		From: from.Hash,
@@ -586,12 +619,25 @@ func NewForwardLink(from, to *SkipBlock) *ForwardLink {
 	}
 
 	if from.Roster != nil && to.Roster != nil &&
-		!from.Roster.ID.Equal(to.Roster.ID) {
+		(!from.Roster.ID.Equal(to.Roster.ID) || !equalWeights(from.Weights, to.Weights)) {
 		fl.NewRoster = to.Roster
+		fl.NewWeights = to.Weights
 	}
 	return fl
 }
 
+func equalWeights(a, b []uint64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
 // Hash is calculated as
 // sha256(From.Hash()|To.Hash()|NewRoster.ID), except
 // if NewRoster is nil, then it is calculated as
@@ -602,6 +648,12 @@ func (fl *ForwardLink) Hash() SkipBlockID {
 	hash.Write(fl.To)
 	if fl.NewRoster != nil {
 		hash.Write(fl.NewRoster.ID[:])
+		for _, w := range fl.NewWeights {
+			err := binary.Write(hash, binary.LittleEndian, w)
+			if err != nil {
+				panic("error writing to hash:" + err.Error())
+			}
+		}
 	}
 	return hash.Sum(nil)
 }
@@ -618,9 +670,10 @@ func (fl *ForwardLink) Copy() *ForwardLink {
 			Sig: append([]byte{}, fl.Signature.Sig...),
 			Msg: append([]byte{}, fl.Signature.Msg...),
 		},
-		From:      append([]byte{}, fl.From...),
-		To:        append([]byte{}, fl.To...),
-		NewRoster: newRoster,
+		From:       append([]byte{}, fl.From...),
+		To:         append([]byte{}, fl.To...),
+		NewRoster:  newRoster,
+		NewWeights: append([]uint64{}, fl.NewWeights...),
 	}
 }
 
@@ -636,6 +689,18 @@ func (fl *ForwardLink) Verify(suite *pairing.SuiteBn256, pubs []kyber.Point) err
 	return protocol.BlsSignature(fl.Signature.Sig).Verify(suite, fl.Signature.Msg, pubs)
 }
 
+// VerifyWithWeights is like Verify, but if weights are given, the signers
+// need to hold more than two thirds of the total weight instead of more than
+// two thirds of the nodes. The weights must be the ones of the block the
+// forward link comes from.
+func (fl *ForwardLink) VerifyWithWeights(suite *pairing.SuiteBn256, pubs []kyber.Point, weights []uint64) error {
+	if bytes.Compare(fl.Signature.Msg, fl.Hash()) != 0 {
+		return errors.New("wrong hash of forward link")
+	}
+
+	return protocol.BlsSignature(fl.Signature.Sig).VerifyWithWeights(suite, fl.Signature.Msg, pubs, weights)
+}
+
 // IsEmpty indicates whether this forwardlink is merely a placeholder for
 // higher-order forwardlinks to be in the correct place.
 func (fl *ForwardLink) IsEmpty() bool {
@@ -723,7 +788,7 @@ func (db *SkipBlockDB) StoreBlocks(blocks []*SkipBlock) ([]SkipBlockID, error) {
 
 						publics := sbOld.Roster.ServicePublics(ServiceName)
 
-						if err := fl.Verify(suite, publics); err != nil {
+						if err := fl.VerifyWithWeights(suite, publics, sbOld.Weights); err != nil {
 							// Only keep a log of the failing forward links but keep trying others.
 							log.Error("Got a known block with wrong signature in forward-link with error: " + err.Error())
 							continue